@@ -8,6 +8,7 @@ require (
 	github.com/stretchr/testify v1.11.1
 	github.com/urfave/cli/v3 v3.6.1
 	go.yaml.in/yaml/v3 v3.0.4
+	golang.org/x/text v0.30.0
 )
 
 require (
@@ -0,0 +1,97 @@
+package cfgm
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/go-viper/mapstructure/v2"
+)
+
+// byteSizeUnits 是 [WithHumanizedValues] 支持的字节单位（小写），十进制单位按 1000 进位，二进制单位按 1024 进位。
+var byteSizeUnits = map[string]float64{
+	"":    1,
+	"b":   1,
+	"kb":  1e3,
+	"mb":  1e6,
+	"gb":  1e9,
+	"tb":  1e12,
+	"pb":  1e15,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+}
+
+// parseByteSize 解析 "10MB"、"1.5 GiB"、"512" 等字节大小字符串，单位不区分大小写。
+func parseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i < 0 {
+		i = len(s)
+	}
+
+	num, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	multiplier, ok := byteSizeUnits[unit]
+	if num == "" || !ok {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+	val, err := strconv.ParseFloat(num, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid byte size %q", s)
+	}
+
+	size := val * multiplier
+	if size >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size %q overflows uint64", s)
+	}
+
+	return uint64(size), nil
+}
+
+// byteSizeHookFunc 将字节大小字符串解码为整数字段；纯整数字符串与 time.Duration 字段保持原有规则。
+func byteSizeHookFunc() mapstructure.DecodeHookFuncType {
+	return func(from, to reflect.Type, data any) (any, error) {
+		str, ok := data.(string)
+		if !ok || from.Kind() != reflect.String || to == durationType || !isIntegerKind(to.Kind()) {
+			return data, nil
+		}
+		if _, err := strconv.ParseInt(strings.TrimSpace(str), 0, 64); err == nil {
+			return data, nil
+		}
+
+		size, err := parseByteSize(str)
+		if err != nil {
+			return nil, err
+		}
+
+		out := reflect.New(to).Elem()
+		switch {
+		case out.CanUint():
+			if out.OverflowUint(size) {
+				return nil, fmt.Errorf("byte size %q overflows %s", str, to)
+			}
+			out.SetUint(size)
+		default:
+			if size > math.MaxInt64 || out.OverflowInt(int64(size)) {
+				return nil, fmt.Errorf("byte size %q overflows %s", str, to)
+			}
+			out.SetInt(int64(size))
+		}
+
+		return out.Interface(), nil
+	}
+}
+
+func isIntegerKind(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,63 @@
+// Package cfgmtest 提供基于 cfgm 的配置测试辅助函数。
+//
+// 配置内容以字符串传入，无需在磁盘上准备配置文件；加载经过与 [cfgm.Load] 相同的流程
+// （模板展开、环境变量、解码 hook 等），测试结果与实际运行一致。
+//
+// 示例：
+//
+//	func TestServerConfig(t *testing.T) {
+//	    cfgmtest.Setenv(t, map[string]string{"MYAPP_SERVER_PORT": "9090"})
+//
+//	    cfg := cfgmtest.LoadFromString[Config](t, `
+//	server:
+//	  host: example.com
+//	`, cfgm.WithEnvPrefix("MYAPP_"))
+//
+//	    assert.Equal(t, 9090, cfg.Server.Port)
+//	}
+package cfgmtest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/cfgm"
+)
+
+// LoadFromString 将 yaml 作为配置文件内容加载配置，加载失败时终止测试。
+//
+// 默认值为 T 的零值；需要应用默认配置时传入 [cfgm.WithDefaultConfigFunc]。
+// yaml 也可以是 JSON 内容。配置文件搜索（[cfgm.WithConfigPaths] 等）不生效。
+func LoadFromString[T any](t testing.TB, yaml string, opts ...cfgm.Option) *T {
+	t.Helper()
+
+	cfg, err := LoadFromStringErr[T](yaml, opts...)
+	if err != nil {
+		t.Fatalf("cfgmtest: load config: %v", err)
+	}
+
+	return cfg
+}
+
+// LoadFromStringErr 与 [LoadFromString] 相同，但返回加载错误，用于测试无效配置。
+func LoadFromStringErr[T any](yaml string, opts ...cfgm.Option) (*T, error) {
+	var zero T
+
+	return cfgm.LoadReader(zero, strings.NewReader(yaml), "yaml", opts...)
+}
+
+// Setenv 在当前测试范围内设置环境变量，测试结束后自动恢复原值。
+//
+// 基于 [testing.T.Setenv]，不能用于并行测试；并行测试请使用 [Env]。
+func Setenv(t *testing.T, vars map[string]string) {
+	t.Helper()
+
+	for key, value := range vars {
+		t.Setenv(key, value)
+	}
+}
+
+// Env 返回以 vars 作为全部环境变量的加载选项（见 [cfgm.WithEnvSnapshot]），不修改进程环境，可用于并行测试。
+func Env(vars map[string]string) cfgm.Option {
+	return cfgm.WithEnvSnapshot(vars)
+}
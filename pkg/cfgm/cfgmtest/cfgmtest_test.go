@@ -0,0 +1,52 @@
+package cfgmtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/cfgm"
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/cfgm/cfgmtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testConfig struct {
+	Name    string        `json:"name"`
+	Port    int           `json:"port"`
+	Timeout time.Duration `json:"timeout"`
+}
+
+func defaults(cfgm.LoadContext) (testConfig, error) {
+	return testConfig{Name: "default", Port: 80, Timeout: time.Second}, nil
+}
+
+func TestLoadFromString(t *testing.T) {
+	cfg := cfgmtest.LoadFromString[testConfig](t, "name: app\ntimeout: 5s\n")
+	assert.Equal(t, testConfig{Name: "app", Timeout: 5 * time.Second}, *cfg)
+
+	cfg = cfgmtest.LoadFromString[testConfig](t, `{"port": 8080}`, cfgm.WithDefaultConfigFunc(defaults))
+	assert.Equal(t, testConfig{Name: "default", Port: 8080, Timeout: time.Second}, *cfg)
+}
+
+func TestLoadFromStringErr(t *testing.T) {
+	_, err := cfgmtest.LoadFromStringErr[testConfig]("port: [1, 2]\n")
+	require.Error(t, err)
+}
+
+func TestSetenv(t *testing.T) {
+	cfgmtest.Setenv(t, map[string]string{"CFGMTEST_PORT": "9090", "CFGMTEST_HOST": "h"})
+
+	cfg := cfgmtest.LoadFromString[testConfig](t, "name: ${CFGMTEST_HOST}\n", cfgm.WithEnvPrefix("CFGMTEST_"))
+	assert.Equal(t, "h", cfg.Name)
+	assert.Equal(t, 9090, cfg.Port)
+}
+
+func TestEnv(t *testing.T) {
+	t.Parallel()
+
+	cfg := cfgmtest.LoadFromString[testConfig](t, "name: ${APP_NAME:-none}\n",
+		cfgmtest.Env(map[string]string{"APP_PORT": "7070", "APP_NAME": "snap"}),
+		cfgm.WithEnvPrefix("APP_"),
+	)
+	assert.Equal(t, testConfig{Name: "snap", Port: 7070}, *cfg)
+}
@@ -0,0 +1,29 @@
+package cfgm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// verifyChecksum 校验配置文件原始内容的 SHA-256，见 [WithConfigChecksum]。
+func verifyChecksum(options *options, path string, content []byte) error {
+	want := options.checksum
+	if options.checksums != nil {
+		var ok bool
+		if want, ok = options.checksums[path]; !ok {
+			return fmt.Errorf("config file %s: no checksum configured", path)
+		}
+	}
+	if want == "" {
+		return nil
+	}
+
+	sum := sha256.Sum256(content)
+	if got := hex.EncodeToString(sum[:]); !strings.EqualFold(got, want) {
+		return fmt.Errorf("config file %s: checksum mismatch: got sha256:%s, want sha256:%s", path, got, want)
+	}
+
+	return nil
+}
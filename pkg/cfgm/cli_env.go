@@ -0,0 +1,96 @@
+package cfgm
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// cliFlagFilter 决定某个已设置的 CLI flag 是否在当前阶段写入配置 map。
+type cliFlagFilter func(cmd *cli.Command, name string) bool
+
+// cliFlagFromOwnEnv 判断 flag 的值是否来自其自身声明的环境变量 (Sources)。
+//
+// urfave/cli 不暴露值的来源，这里按 cli 的查找顺序取第一个存在的环境变量，
+// 并与 flag 当前值比较：相等即视为来自环境变量。命令行传入与环境变量完全相同的值时
+// 同样被视为环境变量来源，两者结果一致。
+func cliFlagFromOwnEnv(cmd *cli.Command, name string) bool {
+	flag := lookupCLIFlag(cmd, name)
+	if flag == nil {
+		return false
+	}
+	envFlag, ok := flag.(interface{ GetEnvVars() []string })
+	if !ok {
+		return false
+	}
+
+	for _, envKey := range envFlag.GetEnvVars() {
+		if raw, found := os.LookupEnv(envKey); found {
+			return cliValueMatches(cmd.Value(name), raw)
+		}
+	}
+
+	return false
+}
+
+// cliFlagFromArgs 是 [cliFlagFromOwnEnv] 的取反，即值由命令行显式传入。
+func cliFlagFromArgs(cmd *cli.Command, name string) bool {
+	return !cliFlagFromOwnEnv(cmd, name)
+}
+
+// lookupCLIFlag 沿命令层级查找名称为 name 的 flag。
+func lookupCLIFlag(cmd *cli.Command, name string) cli.Flag {
+	for _, c := range cmd.Lineage() {
+		for _, flag := range c.Flags {
+			if slices.Contains(flag.Names(), name) {
+				return flag
+			}
+		}
+	}
+
+	return nil
+}
+
+// cliValueMatches 判断 flag 值与环境变量原始字符串解析后的值是否相同。
+func cliValueMatches(value any, raw string) bool {
+	switch v := value.(type) {
+	case nil:
+		return false
+	case time.Duration:
+		d, err := time.ParseDuration(raw)
+
+		return err == nil && d == v
+	case []string:
+		return strings.Join(v, ",") == raw
+	}
+
+	rv := reflect.ValueOf(value)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String() == raw
+	case reflect.Bool:
+		b, err := strconv.ParseBool(raw)
+
+		return err == nil && b == rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(raw, 0, 64)
+
+		return err == nil && n == rv.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(raw, 0, 64)
+
+		return err == nil && n == rv.Uint()
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(raw, 64)
+
+		return err == nil && f == rv.Float()
+	default:
+		return fmt.Sprint(value) == raw
+	}
+}
@@ -0,0 +1,41 @@
+package cfgm
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// gzipExt 是 gzip 压缩配置文件的扩展名。
+const gzipExt = ".gz"
+
+// gzipMagic 是 gzip 数据的文件头。
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// trimGzipExt 去掉路径末尾的 .gz，用于从 config.yaml.gz 推断内层格式。
+func trimGzipExt(path string) string {
+	if strings.EqualFold(filepath.Ext(path), gzipExt) {
+		return path[:len(path)-len(gzipExt)]
+	}
+
+	return path
+}
+
+// decompressConfig 解压 gzip 配置内容，非 gzip 内容原样返回。
+//
+// 以 .gz 扩展名或 gzip 文件头识别；limit > 0 时限制解压后的大小，防止解压炸弹。
+func decompressConfig(name string, content []byte, limit int64) ([]byte, error) {
+	if !strings.EqualFold(filepath.Ext(name), gzipExt) && !bytes.HasPrefix(content, gzipMagic) {
+		return content, nil
+	}
+
+	zr, err := gzip.NewReader(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("decompress config %s: %w", name, err)
+	}
+	defer func() { _ = zr.Close() }()
+
+	return readLimited(zr, name, limit)
+}
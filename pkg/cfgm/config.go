@@ -1,12 +1,18 @@
 package cfgm
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/urfave/cli/v3"
@@ -25,15 +31,27 @@ import (
 //  3. /etc/appname/config.yaml - 系统级配置
 //  4. config.yaml - 当前目录通用配置
 //  5. config/config.yaml - 子目录通用配置
+//
+// 用户主目录由 [os.UserHomeDir] 确定（Unix/macOS 为 $HOME，Windows 为 %USERPROFILE%），
+// 无法确定时省略该路径；加载时可用 [WithoutHomeConfig] 排除。
 func DefaultPaths(appName ...string) []string {
+	name := ""
+	if len(appName) > 0 {
+		name = appName[0]
+	}
+
+	return defaultPaths(name, true)
+}
+
+// defaultPaths 是 [DefaultPaths] 的实现，includeHome 为 false 时省略用户主目录路径。
+func defaultPaths(name string, includeHome bool) []string {
 	var paths []string
 
-	if len(appName) > 0 && appName[0] != "" {
-		name := appName[0]
+	if name != "" {
 		// 当前目录应用配置 (最高优先级)
 		paths = append(paths, "."+name+".yaml")
 		// 用户主目录
-		if home, err := os.UserHomeDir(); err == nil {
+		if home, err := os.UserHomeDir(); err == nil && includeHome {
 			paths = append(paths, filepath.Join(home, "."+name+".yaml"))
 		}
 		// 系统配置目录
@@ -55,114 +73,1190 @@ func DefaultPaths(appName ...string) []string {
 //  4. CLI flags - [WithCommand]
 //
 // 配置 key 由 json tag 定义，YAML 与 JSON 共享同一套 key。
-// 配置文件按顺序查找，命中首个文件即停止。
+// 配置文件按顺序查找，命中首个文件即停止；[WithMergeAllPaths] 可合并全部文件。
+//
+// 指针字段（如 *int、*bool、*struct）仅在默认值或某个来源显式提供了值时才会分配，
+// 否则保持 nil（YAML 中的 null 同样视为未提供），可用于区分「未设置」与零值。
 func Load[T any](defaultConfig T, opts ...Option) (*T, error) {
-	return load(defaultConfig, 1, opts...)
+	cfg, _, err := load(defaultConfig, 1, opts...)
+
+	return cfg, err
 }
 
 // load 是内部加载实现，callerSkip 用于控制 FindProjectRoot 的跳过层数。
 // 各入口函数会根据自身调用深度传入合适的 skip 值。
-func load[T any](defaultConfig T, callerSkip int, opts ...Option) (*T, error) {
-	// 解析选项
-	options := &options{}
-	for _, opt := range opts {
-		opt(options)
+//
+// 除配置外还返回本次加载的诊断信息（见 loadReport），供 LoadWith* 系列入口使用。
+func load[T any](defaultConfig T, callerSkip int, opts ...Option) (*T, *loadReport, error) {
+	start := time.Now()
+	options := newOptions(opts...)
+	options.resolve(callerSkip)
+
+	configMap, report, err := buildConfigMap(defaultConfig, options)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 如果用户显式设置了 callerSkip，则优先使用
-	if options.callerSkip > 0 {
-		callerSkip = options.callerSkip
+	cfg, err := finishConfig[T](configMap, options, report)
+	if err != nil {
+		return nil, nil, err
 	}
+	report.finishMetrics(options, start)
 
-	// 默认使用项目根目录作为相对路径基准
-	if !options.baseDirSet {
-		if root, err := FindProjectRoot(callerSkip); err == nil {
-			options.baseDir = root
+	return &cfg, report, nil
+}
+
+// finishConfig 将合并后的配置树解码为 T，并执行解码后的处理与检查（[WithValueProcessor]、[WithRequiredTags]）。
+//
+// [Load] 与 [Loader.Reload] 共用，解码后的新步骤只需在此添加。
+func finishConfig[T any](configMap map[string]any, options *options, report *loadReport) (T, error) {
+	var cfg T
+	decodeStart := time.Now()
+	if err := decodeConfigMap(configMap, &cfg, options); err != nil {
+		return cfg, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+	report.observe(&report.metrics.Unmarshal, decodeStart)
+	if err := applyValueProcessors(&cfg, options.valueProcessors, options.keyDelim(), options.tagChain()); err != nil {
+		return cfg, err
+	}
+	if options.requiredTags {
+		if err := checkRequiredFields(&cfg, options.keyDelim(), options.tagChain()); err != nil {
+			return cfg, err
 		}
 	}
 
-	// 默认使用 DefaultPaths 作为配置文件搜索路径
-	// 如果设置了 appName，使用 DefaultPaths(appName) 生成应用专属路径
-	if len(options.configPaths) == 0 {
-		if options.appName != "" {
-			options.configPaths = DefaultPaths(options.appName)
-		} else {
-			options.configPaths = DefaultPaths()
+	return cfg, nil
+}
+
+// buildConfigMap 按优先级合并默认值、配置文件、环境变量与 CLI flags，返回合并后的配置树。
+func buildConfigMap[T any](defaultConfig T, options *options) (map[string]any, *loadReport, error) {
+	// WithEnvConfigFile: env 文件中的变量作为进程环境的后备值，本次加载内有效
+	options, err := options.withEnvConfigFile()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 1️⃣ 默认值 (WithDefaultConfigFunc 时按加载环境生成)
+	if options.defaultConfigFunc != nil {
+		fn, ok := options.defaultConfigFunc.(func(LoadContext) (T, error))
+		if !ok {
+			return nil, nil, fmt.Errorf("default config func: got %T, want func(cfgm.LoadContext) (%T, error)",
+				options.defaultConfigFunc, defaultConfig)
+		}
+		var err error
+		if defaultConfig, err = fn(options.loadContext()); err != nil {
+			return nil, nil, fmt.Errorf("default config: %w", err)
 		}
 	}
 
-	configMap := structToMap(defaultConfig)
+	report := &loadReport{}
+	configMap := structToMap(defaultConfig, options.tagChain())
+	if options.normalizeKeys {
+		configMap = normalizeKeyCase(configMap)
+	}
+	options.notifyLayer("default", configMap)
+
+	// WithMapConfig: 程序提供的配置树，位于默认值之上、全部配置文件之下
+	for i, values := range options.mapConfigs {
+		layer := mapConfigLayer(i, values, options)
+		checkUnknownKeys(layer, reflect.TypeOf(defaultConfig), options, report)
+		options.notifyLayer(layer.path, layer.data)
+		mergeMapsFunc(configMap, layer.data, "", options.keyDelim(), options.mergeFunc)
+	}
 
-	// 2️⃣ 加载配置文件 (按顺序搜索，找到第一个即停止)
-	configLoaded := false
-	paths := options.configPaths
-	if options.baseDir != "" {
-		paths = make([]string, len(options.configPaths))
-		for i, p := range options.configPaths {
-			if !filepath.IsAbs(p) {
-				paths[i] = filepath.Join(options.baseDir, p)
-			} else {
-				paths[i] = p
+	// 2️⃣ 加载配置文件 (按顺序搜索，找到第一个即停止；WithMergeAllPaths 时合并全部)
+	filesStart := time.Now()
+	layers, err := loadConfigFiles(options, report)
+	if err != nil {
+		return nil, nil, err
+	}
+	report.metrics.Files = len(layers)
+	report.metrics.Discovery = time.Since(filesStart) - report.metrics.Read - report.metrics.Template - report.metrics.Parse
+	for _, layer := range layers {
+		if options.migrations != nil {
+			if err := options.migrations.apply(layer); err != nil {
+				return nil, nil, err
+			}
+		}
+		if options.configVersion != nil {
+			if err := checkConfigVersion(layer, options, report); err != nil {
+				return nil, nil, err
 			}
 		}
+		checkUnknownKeys(layer, reflect.TypeOf(defaultConfig), options, report)
+		if options.envBindingsInverse {
+			report.recordFileKeys(layer, options.keyDelim())
+		}
+		options.notifyLayer(layer.path, layer.data)
+		mergeMapsFunc(configMap, layer.data, "", options.keyDelim(), options.mergeFunc)
 	}
-	for _, path := range paths {
-		// 尝试读取配置文件
-		content, err := os.ReadFile(path) //nolint:gosec // path is from trusted config
+
+	// WithEtcdConfig: etcd 中的配置，优先级位于配置文件之上
+	for _, src := range options.etcdSources {
+		layer, err := readEtcdLayer(src, options)
 		if err != nil {
-			continue // 文件不存在或无法读取，尝试下一个路径
+			return nil, nil, err
+		}
+		checkUnknownKeys(layer, reflect.TypeOf(defaultConfig), options, report)
+		options.notifyLayer(layer.path, layer.data)
+		mergeMapsFunc(configMap, layer.data, "", options.keyDelim(), options.mergeFunc)
+	}
+
+	// WithFlatEnvKeys: 单个环境变量承载的配置子树，优先级位于配置文件之上
+	for _, envKey := range options.flatEnvKeys {
+		layer, ok, err := readFlatEnvLayer(envKey, options)
+		if err != nil {
+			return nil, nil, err
+		}
+		if !ok {
+			continue
+		}
+		checkUnknownKeys(layer, reflect.TypeOf(defaultConfig), options, report)
+		options.notifyLayer(layer.path, layer.data)
+		mergeMapsFunc(configMap, layer.data, "", options.keyDelim(), options.mergeFunc)
+	}
+
+	// CLI flag 名称由 json tag 生成，WithNormalizeKeys 时先写入临时 map 再统一转为小写
+	applyCLI := func(filter cliFlagFilter) error {
+		defer report.observe(&report.metrics.CLI, time.Now())
+		if err := checkCLILeafKeys(options.cmd, configMap, reflect.TypeOf(defaultConfig), options, filter); err != nil {
+			return err
+		}
+		if !options.normalizeKeys && options.onValueSet == nil {
+			applyCLIFlagsGeneric(options.cmd, configMap, defaultConfig, options.cliFlagPrefix, options.tagChain(), filter)
+
+			return nil
+		}
+		overlay := make(map[string]any)
+		applyCLIFlagsGeneric(options.cmd, overlay, defaultConfig, options.cliFlagPrefix, options.tagChain(), filter)
+		if options.normalizeKeys {
+			overlay = normalizeKeyCase(overlay)
+		}
+		options.notifyLayer("cli", overlay)
+		mergeMaps(configMap, overlay)
+
+		return nil
+	}
+
+	// WithCLIEnvAware: 来自 flag 自身环境变量的值按环境变量优先级处理
+	var cliFilter cliFlagFilter
+	if options.cmd != nil && options.cliEnvAware {
+		if err := applyCLI(cliFlagFromOwnEnv); err != nil {
+			return nil, nil, err
+		}
+	}
+	if options.cliEnvAware || options.envBindingsFromFlags {
+		cliFilter = cliFlagFromArgs
+	}
+
+	// 3️⃣ 自动生成环境变量绑定 (基于配置结构体的 key)
+	envStart := time.Now()
+	if err := applyEnvBindings(configMap, reflect.TypeOf(defaultConfig), options, report); err != nil {
+		return nil, nil, err
+	}
+	report.observe(&report.metrics.Env, envStart)
+
+	// 4️⃣ 加载 CLI flags (最高优先级，仅当用户明确指定时)
+	if options.cmd != nil {
+		if err := applyCLI(cliFilter); err != nil {
+			return nil, nil, err
 		}
+	}
 
-		// 默认启用模板展开，在解析前处理模板
-		if !options.noTemplateExpansion {
-			expanded, expandErr := templexp.ExpandTemplate(string(content))
-			if expandErr != nil {
-				return nil, fmt.Errorf("expand template in %s: %w", path, expandErr)
+	// WithEnvBindingTop: 指定的环境变量绑定覆盖 CLI flags
+	if err := applyTopEnvBindings(configMap, reflect.TypeOf(defaultConfig), options, report); err != nil {
+		return nil, nil, err
+	}
+
+	// 5️⃣ WithForcedValues: 覆盖全部来源
+	for _, values := range options.forcedValues {
+		layer, indexed := forcedValuesLayer(values, options)
+		checkUnknownKeys(layer, reflect.TypeOf(defaultConfig), options, report)
+		options.notifyLayer(layer.path, layer.data)
+		mergeMapsFunc(configMap, layer.data, "", options.keyDelim(), options.mergeFunc)
+
+		// 带数组下标的 key 修改合并后已有的数组元素
+		for _, key := range indexed {
+			parts := options.splitKey(key)
+			value := cloneConfigValue(values[key])
+			if err := setPathValue(configMap, parts, value, options.keyDelim()); err != nil {
+				return nil, nil, fmt.Errorf("forced value %s: %w", key, err)
 			}
-			content = []byte(expanded)
+			options.notifyValueSet(layer.path, parts, value)
+		}
+	}
+
+	// WithTrimWhitespace / WithTrimAllStrings: 合并完成后去除字符串首尾空白
+	if options.trimAllStrings {
+		configMap = trimStrings(configMap).(map[string]any)
+	}
+	for _, key := range options.trimKeys {
+		parts := options.splitKey(key)
+		if val, ok := getByPath(configMap, parts); ok {
+			_ = setPathValue(configMap, parts, trimStrings(val), options.keyDelim()) // 已存在的路径不会越界
 		}
+	}
 
-		fileMap, err := parseConfigBytes(path, content)
+	// WithDurationUnit: 不带单位的数字时长按指定单位转换
+	if err := applyDurationUnits(configMap, options); err != nil {
+		return nil, nil, err
+	}
+
+	// WithSchema: 解码前校验合并后的配置树
+	if options.schema != nil {
+		schema, err := parseSchema(options.schema)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := validateSchema(schema, configMap, options.keyDelim()); err != nil {
+			return nil, nil, fmt.Errorf("config schema validation failed: %w", err)
+		}
+	}
+	if options.schemaFile != nil {
+		schema, err := options.schemaFile.load(options.baseDir)
 		if err != nil {
-			return nil, fmt.Errorf("parse config file %s: %w", path, err)
+			return nil, nil, err
+		}
+		if err := validateSchema(schema, configMap, options.keyDelim()); err != nil {
+			return nil, nil, fmt.Errorf("config schema validation failed: %w", err)
+		}
+	}
+
+	// WithFieldInterceptor: 解码前按字段路径转换或拒绝原始值
+	if err := applyFieldInterceptors(configMap, reflect.TypeOf(defaultConfig), options); err != nil {
+		return nil, nil, err
+	}
+
+	return configMap, report, nil
+}
+
+// forcedValuesLayer 将 [WithForcedValues] 的值展开为配置层，含分隔符的 key 按路径拆分。
+//
+// 带数组下标的 key（如 "servers[0].url"）无法作为独立的配置层合并，按字典序单独返回，由调用方写入合并结果。
+func forcedValuesLayer(values map[string]any, options *options) (configLayer, []string) {
+	data := make(map[string]any)
+	var indexed []string
+	for _, key := range slices.Sorted(maps.Keys(values)) {
+		value := cloneConfigValue(values[key])
+		parts := options.splitKey(key)
+		if hasIndexPart(parts) {
+			indexed = append(indexed, key)
+
+			continue
+		}
+		existing, _ := getByPath(data, parts)
+		existingMap, okExisting := existing.(map[string]any)
+		valueMap, okValue := value.(map[string]any)
+		if okExisting && okValue {
+			mergeMaps(existingMap, valueMap)
+
+			continue
 		}
-		mergeMaps(configMap, fileMap)
+		setByPath(data, parts, value)
+	}
+	if options.normalizeKeys {
+		data = normalizeKeyCase(data)
+	}
+
+	return configLayer{path: "forced values", data: data}, indexed
+}
+
+// mapConfigLayer 将第 i 个 [WithMapConfig] 的配置树复制为配置层，避免合并后的修改写回选项中保存的 map。
+func mapConfigLayer(i int, values map[string]any, options *options) configLayer {
+	data := cloneConfigValue(values).(map[string]any)
+	if options.normalizeKeys {
+		data = normalizeKeyCase(data)
+	}
+
+	return configLayer{path: fmt.Sprintf("map config #%d", i+1), data: data}
+}
+
+// configLayer 表示一个已解析的配置文件。
+type configLayer struct {
+	path string
+	data map[string]any
+}
+
+// loadConfigFiles 读取并解析配置文件，按合并顺序（优先级从低到高）返回。
+//
+// 默认只返回首个存在的文件；[WithMergeAllPaths] 时返回全部存在的文件，
+// 列表靠前的路径优先级更高，因此逆序返回。
+func loadConfigFiles(options *options, report *loadReport) ([]configLayer, error) {
+	if err := options.checkConfigFormats(); err != nil {
+		return nil, err
+	}
 
-		slog.Debug("Loaded config from file", "path", path, "templateExpansion", !options.noTemplateExpansion)
-		configLoaded = true
+	var layers []configLayer
 
-		break
+	// WithEmbeddedDefault: 内嵌配置作为最低优先级的基础层
+	for _, src := range options.embedded {
+		layer, err := readEmbeddedLayer(src, options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	}
+
+	// WithConfigPathsFromXDGDirs: 系统级配置位于用户配置之下
+	for _, path := range options.xdgConfigPaths() {
+		layer, ok, err := readConfigLayer(path, options, report)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			layers = append(layers, layer)
+		}
 	}
 
-	if len(options.configPaths) > 0 && !configLoaded {
+	// LoadReader / WithConfigSources / WithConfigManifest: 以数据流、配置来源或清单替代配置文件搜索
+	switch {
+	case options.reader != nil:
+		layer, err := readReaderLayer(options.reader, options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, layer)
+	case len(options.sources) > 0:
+		sourceLayers, err := readSourceLayers(options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, sourceLayers...)
+	case options.manifest != "":
+		manifestLayers, err := readManifestLayers(options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, manifestLayers...)
+	default:
+		fileLayers, err := searchConfigFiles(options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, fileLayers...)
+	}
+
+	// WithS3Config / WithGCSConfig: 对象存储中的配置与配置文件同一优先级，位于搜索到的文件之上
+	objectLayers, err := readObjectLayers(options, report)
+	if err != nil {
+		return nil, err
+	}
+	layers = append(layers, objectLayers...)
+
+	// WithConfigPathsDir: 目录片段优先级高于配置文件，按文件名字典序合并
+	for _, dir := range options.resolvedDirs() {
+		fragments, err := loadConfigDir(dir, options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, fragments...)
+	}
+
+	// WithConfigDirRecursive: 递归目录中的片段，按相对路径字典序合并
+	for _, dir := range options.resolveDirs(options.configDirsRecursive) {
+		fragments, err := loadConfigDirRecursive(dir, options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, fragments...)
+	}
+
+	// WithK8sConfigMapDir: ConfigMap 挂载的每个 key 作为一个顶层节点
+	for _, dir := range options.resolvedK8sDirs() {
+		sections, err := loadK8sConfigMapDir(dir, options, report)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, sections...)
+	}
+
+	if len(layers) == 0 {
 		slog.Debug("No config file found, using defaults")
 	}
 
-	// 3️⃣ 自动生成环境变量绑定 (基于配置结构体的 key)
-	// 支持包含连字符的 key，例如 rev-auth-user
-	if options.envPrefix != "" {
-		autoBindings := generateEnvBindings(options.envPrefix, collectConfigKeys(defaultConfig))
-		slog.Debug("Generated auto env bindings", "prefix", options.envPrefix, "count", len(autoBindings))
-		for envKey, configPath := range autoBindings {
-			if val := os.Getenv(envKey); val != "" {
-				setByPath(configMap, configPath, val)
-				slog.Debug("Loaded env binding", "env", envKey, "path", configPath)
+	return layers, nil
+}
+
+// searchConfigFiles 按候选路径查找配置文件，按合并顺序（优先级从低到高）返回。
+//
+// glob 模式的候选路径展开为全部匹配的文件，按字典序合并（靠后的优先），整体视为一次命中。
+func searchConfigFiles(options *options, report *loadReport) ([]configLayer, error) {
+	paths := options.resolvedPaths()
+	if err := checkConfigPathsLimit(options, paths); err != nil {
+		return nil, err
+	}
+
+	// WithAggregatePathErrors: 检查、读取与解析错误全部收集后一并返回
+	var errs []error
+	if err := checkConfigPaths(options, paths); err != nil {
+		if !options.aggregatePathErrors {
+			return nil, err
+		}
+		errs = append(errs, err)
+	}
+
+	// WithConfigPathsTrace: 按检查顺序记录每个候选路径，加载失败时同样报告已检查的部分
+	var trace *probeTrace
+	if options.onProbe != nil {
+		trace = &probeTrace{}
+		defer func() { options.onProbe(trace.probes) }()
+	}
+
+	host := options.overlayHostname()
+
+	var groups [][]configLayer
+	var selected string
+	loaded := make(map[string]string) // WithConfigPathsDeduplicate: 规范化路径 → 首次出现时的路径
+	for i, path := range paths {
+		if selected != "" {
+			trace.shadowed(paths[i:], selected)
+
+			break
+		}
+
+		var group []configLayer
+		matches, ok := expandConfigPathTimeout(path, options, report)
+		files := options.preferFormats(matches)
+		switch {
+		case !ok:
+			trace.add(PathProbe{Path: path, Reason: "skipped: glob timed out (see WithStatTimeout)"})
+		case len(files) == 0:
+			trace.add(PathProbe{Path: path, Reason: "no files match pattern"})
+		}
+		for _, file := range files {
+			if options.statTimeout > 0 && !probeFile(file, options, report) {
+				trace.add(PathProbe{Path: file, Reason: "skipped: stat timed out (see WithStatTimeout)"})
+
+				continue
+			}
+			if options.statCache != nil && !options.statCache.exists(file) {
+				trace.add(PathProbe{Path: file, Reason: "not found (cached, see WithStatCache)"})
+
+				continue
+			}
+			if options.dedupePaths {
+				key := canonicalPath(file)
+				if first, ok := loaded[key]; ok {
+					trace.add(PathProbe{Path: file, Exists: true, Reason: "skipped: same file as " + first})
+
+					continue
+				}
+				loaded[key] = file
+			}
+			layer, ok, err := readConfigLayer(file, options, report)
+			if err != nil {
+				trace.add(PathProbe{Path: file, Exists: true, Reason: err.Error()})
+				if options.aggregatePathErrors {
+					errs = append(errs, err)
+
+					continue
+				}
+
+				return nil, err
+			}
+			if ok {
+				group = append(group, layer)
 			}
+			trace.file(file, options, ok)
+
+			// WithHostnameOverlay: 同目录下以主机名命名的文件紧随其后合并，不存在时仅使用基础文件
+			if ok && host != "" {
+				overlay, found, err := readConfigLayer(hostnameOverlayPath(file, host), options, report)
+				if err != nil {
+					if !options.aggregatePathErrors {
+						return nil, err
+					}
+					errs = append(errs, err)
+				}
+				if found {
+					group = append(group, overlay)
+				}
+			}
+		}
+		if len(group) == 0 {
+			continue // 文件不存在或无法读取，尝试下一个路径
+		}
+		groups = append(groups, group)
+
+		if !options.mergeAllPaths && len(errs) == 0 {
+			selected = path
 		}
 	}
 
-	// 4️⃣ 加载 CLI flags (最高优先级，仅当用户明确指定时)
-	if options.cmd != nil {
-		applyCLIFlagsGeneric(options.cmd, configMap, defaultConfig)
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
 	}
+	slices.Reverse(groups)
 
-	// 解析到结构体
-	var cfg T
-	if err := decodeConfigMap(configMap, &cfg); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	return slices.Concat(groups...), nil
+}
+
+// osHostname 供 [WithHostnameOverlay] 获取主机名，测试中替换以模拟不同主机。
+var osHostname = os.Hostname
+
+// overlayHostname 返回 [WithHostnameOverlay] 使用的短主机名（第一个 "." 之前的部分），
+// 未启用或无法获取主机名时返回空字符串。
+func (o *options) overlayHostname() string {
+	if !o.hostnameOverlay {
+		return ""
+	}
+	host, err := osHostname()
+	if err != nil {
+		slog.Debug("Hostname unavailable, skipping hostname overlay", "error", err)
+
+		return ""
+	}
+	host, _, _ = strings.Cut(host, ".")
+
+	return host
+}
+
+// hostnameOverlayPath 返回 file 同目录下的主机名覆盖文件：config.yaml → config.<host>.yaml，
+// config.yaml.gz → config.<host>.yaml.gz。
+func hostnameOverlayPath(file, host string) string {
+	inner := trimGzipExt(file)
+	ext := filepath.Ext(inner)
+
+	return strings.TrimSuffix(inner, ext) + "." + host + ext + file[len(inner):]
+}
+
+// errStatTimeout 表示配置文件发现中的文件系统调用超过 [WithStatTimeout] 的时限。
+var errStatTimeout = errors.New("file system access timed out")
+
+// osStat 供 probeFile 调用，测试中替换以模拟无响应的挂载点。
+var osStat = os.Stat
+
+// osGlob 供 expandConfigPath 展开 glob，测试中替换以模拟无响应的挂载点。
+var osGlob = filepath.Glob
+
+// openConfigFile 供 readConfigFile 打开配置文件，测试中替换以模拟读取时阻塞的挂载点。
+var openConfigFile = func(path string) (configFileHandle, error) {
+	return os.Open(path) //nolint:gosec // path is from trusted config
+}
+
+// configFileHandle 是 readConfigFile 读取配置文件所需的方法，由 *os.File 实现。
+type configFileHandle interface {
+	io.ReadCloser
+	Stat() (fs.FileInfo, error)
+}
+
+// withTimeout 在独立 goroutine 中执行 fn，超过 timeout 时返回 errStatTimeout；timeout <= 0 时直接调用 fn。
+//
+// 超时后 goroutine 仍会等待 fn 返回，结果通道带缓冲，返回后即退出；fn 不应修改调用方之后还会访问的状态。
+func withTimeout[V any](timeout time.Duration, fn func() (V, error)) (V, error) {
+	if timeout <= 0 {
+		return fn()
+	}
+
+	type result struct {
+		value V
+		err   error
+	}
+	ch := make(chan result, 1)
+	go func() {
+		value, err := fn()
+		ch <- result{value, err}
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case r := <-ch:
+		return r.value, r.err
+	case <-timer.C:
+		var zero V
+
+		return zero, errStatTimeout
+	}
+}
+
+// reportAccessTimeout 记录候选路径的文件系统调用（op 为 stat、read 或 glob）超过 [WithStatTimeout] 的时限。
+func reportAccessTimeout(path, op string, options *options, report *loadReport) {
+	slog.Warn("Config file "+op+" timed out, treating as not found", "path", path, "timeout", options.statTimeout)
+	report.addWarning(WarnStatTimeout, path, "config file %s: %s timed out after %s", path, op, options.statTimeout)
+}
+
+// statCache 是 [WithStatCache] 的缓存，记录 TTL 内确认不存在的候选路径。
+//
+// 只缓存不存在的结果：存在的文件每次都会重新读取，缓存其 stat 结果并不能省去 I/O。
+type statCache struct {
+	ttl     time.Duration
+	mu      sync.Mutex
+	missing map[string]time.Time // 路径 → 确认不存在的时间
+}
+
+// exists 报告 path 是否可能存在，TTL 内已确认不存在的路径不再 stat。
+func (c *statCache) exists(path string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if at, ok := c.missing[path]; ok && time.Since(at) < c.ttl {
+		return false
+	}
+	if _, err := osStat(path); errors.Is(err, fs.ErrNotExist) {
+		c.missing[path] = time.Now()
+
+		return false
+	}
+	delete(c.missing, path)
+
+	return true
+}
+
+// invalidate 移除 paths 的缓存结果，[Loader.Watch] 发现变化时调用。
+func (c *statCache) invalidate(paths ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, path := range paths {
+		delete(c.missing, path)
+	}
+}
+
+// probeFile 按 [WithStatTimeout] 检查候选文件能否及时访问，超时时记录警告并返回 false。
+func probeFile(path string, options *options, report *loadReport) bool {
+	stat := osStat
+	if _, err := withTimeout(options.statTimeout, func() (fs.FileInfo, error) { return stat(path) }); errors.Is(err, errStatTimeout) {
+		reportAccessTimeout(path, "stat", options, report)
+
+		return false
+	}
+
+	return true
+}
+
+// expandConfigPathTimeout 按 [WithStatTimeout] 的时限展开 glob 候选路径，超时时记录警告并返回 false。
+func expandConfigPathTimeout(path string, options *options, report *loadReport) ([]string, bool) {
+	if options.statTimeout <= 0 || !isGlobPattern(path) {
+		return expandConfigPath(path), true
+	}
+	files, err := globWithTimeout(path, options.statTimeout)
+	if errors.Is(err, errStatTimeout) {
+		reportAccessTimeout(path, "glob", options, report)
+
+		return nil, false
+	}
+
+	return files, true
+}
+
+// globWithTimeout 在 timeout 内展开 glob 候选路径，超时时返回 errStatTimeout。
+func globWithTimeout(path string, timeout time.Duration) ([]string, error) {
+	glob := osGlob
+
+	return withTimeout(timeout, func() ([]string, error) { return expandConfigPathGlob(path, glob), nil })
+}
+
+// checkConfigPathsLimit 按 [WithConfigPathsLimit] 检查 glob 与 [WithSearchUp] 展开后的候选文件数。
+func checkConfigPathsLimit(options *options, paths []string) error {
+	if limit := options.pathsLimit(); limit > 0 {
+		count := 0
+		for _, path := range paths {
+			// WithStatTimeout: 超时的 glob 不计入，由候选搜索记录警告并跳过
+			files, _ := globWithTimeout(path, options.statTimeout)
+			count += len(files)
+			if count > limit {
+				return fmt.Errorf("config paths: more than %d candidate files to probe (see WithConfigPathsLimit)", limit)
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkConfigPaths 在读取前按 [WithFailFastPaths]、[WithConfigPathsStopOnError] 与
+// [WithConfigPathsValidate] 检查候选路径。
+//
+// 默认返回第一个错误；[WithAggregatePathErrors] 时以 errors.Join 返回全部错误。
+func checkConfigPaths(options *options, paths []string) error {
+	var errs []error
+
+	// WithFailFastPaths / WithConfigPathsStopOnError: 显式指定的路径必须全部存在
+	if (options.failFastPaths || options.onMissingFile == ErrorModeFail) && options.configPathsSet {
+		for _, path := range paths {
+			if isGlobPattern(path) {
+				if matches, _ := filepath.Glob(path); len(matches) == 0 {
+					errs = append(errs, fmt.Errorf("config pattern %s: no files matched", path))
+				}
+
+				continue
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config file %s: %w", path, err))
+
+				continue
+			}
+			if options.isStale(info) {
+				errs = append(errs, fmt.Errorf("config file %s: modified at %s, before %s (see WithConfigPathsNewerThan)",
+					path, info.ModTime().Format(time.RFC3339), options.newerThan.Format(time.RFC3339)))
+			}
+		}
+	}
+
+	// WithConfigPathsValidate: 解析前确认已存在的候选文件均可读取
+	if options.validatePaths {
+		errs = append(errs, validateConfigPaths(paths)...)
+	}
+
+	if len(errs) > 0 && !options.aggregatePathErrors {
+		return errs[0]
+	}
+
+	return errors.Join(errs...)
+}
+
+// validateConfigPaths 打开每个已存在的候选文件（glob 按匹配结果展开），返回无法读取的文件对应的错误。
+func validateConfigPaths(paths []string) []error {
+	var errs []error
+	for _, pattern := range paths {
+		for _, path := range expandConfigPath(pattern) {
+			info, err := os.Stat(path)
+			if err != nil || info.IsDir() {
+				continue // 不存在的路径由候选搜索与 WithFailFastPaths 处理
+			}
+			file, err := os.Open(path) //nolint:gosec // path is from trusted config
+			if err != nil {
+				var pathErr *fs.PathError
+				if errors.As(err, &pathErr) {
+					err = pathErr.Err
+				}
+				errs = append(errs, fmt.Errorf("cannot read %s: %w", path, err))
+
+				continue
+			}
+			_ = file.Close()
+		}
+	}
+
+	return errs
+}
+
+// isGlobPattern 判断候选路径是否包含 glob 元字符。
+func isGlobPattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// expandConfigPath 将 glob 模式展开为按完整路径逐字节字典序排列的匹配路径，普通路径原样返回。
+//
+// filepath.Glob 逐级展开目录，多级模式的结果按各级目录分组，并非整体有序（如 "a/x" 排在 "a-b/x" 之前），
+// 因此统一重新排序，使合并结果不依赖 Glob 的实现与平台。
+func expandConfigPath(path string) []string {
+	return expandConfigPathGlob(path, osGlob)
+}
+
+// expandConfigPathGlob 与 expandConfigPath 相同，使用 glob 展开模式。
+func expandConfigPathGlob(path string, glob func(string) ([]string, error)) []string {
+	if !isGlobPattern(path) {
+		return []string{path}
+	}
+
+	matches, err := glob(path)
+	if err != nil {
+		slog.Debug("Invalid config path pattern", "pattern", path, "error", err)
+
+		return nil
+	}
+	slices.Sort(matches)
+
+	return matches
+}
+
+// loadConfigDirRecursive 递归收集目录树中可识别的配置文件，按相对路径（以 "/" 分隔）字典序读取。
+//
+// 目录不存在时返回空结果；名称匹配 [WithConfigDirExclude] 模式的子目录整体跳过，符号链接目录不跟随。
+func loadConfigDirRecursive(root string, options *options, report *loadReport) ([]configLayer, error) {
+	files, err := configDirRecursiveFiles(root, options)
+	if err != nil {
+		return nil, err
+	}
+
+	var layers []configLayer
+	for _, rel := range files {
+		layer, ok, err := readConfigLayer(filepath.Join(root, filepath.FromSlash(rel)), options, report)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			layers = append(layers, layer)
+		}
+	}
+
+	return layers, nil
+}
+
+// configDirRecursiveFiles 返回 root 下可识别格式的配置片段，为按字典序排序的 "/" 分隔相对路径。
+//
+// 超过 [WithMaxDepth] 层数的子目录整体跳过。
+func configDirRecursiveFiles(root string, options *options) ([]string, error) {
+	maxDepth := options.walkDepth()
+	var files []string
+	err := filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == root && errors.Is(err, fs.ErrNotExist) {
+				return fs.SkipAll
+			}
+
+			return err
+		}
+		if entry.IsDir() {
+			if path == root {
+				return nil
+			}
+			if options.excludedConfigDir(entry.Name()) {
+				return fs.SkipDir
+			}
+			if rel, err := filepath.Rel(root, path); err == nil && maxDepth >= 0 && pathDepth(rel) > maxDepth {
+				return fs.SkipDir
+			}
+
+			return nil
+		}
+		if options.fileFormat(entry.Name()) != "" {
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, filepath.ToSlash(rel))
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("read config dir %s: %w", root, err)
+	}
+	slices.Sort(files)
+
+	return options.preferFormats(files), nil
+}
+
+// pathDepth 返回相对路径包含的层数，如 "a/b" 为 2。
+func pathDepth(rel string) int {
+	return strings.Count(filepath.ToSlash(rel), "/") + 1
+}
+
+// loadConfigDir 按文件名字典序读取目录中的 .yaml/.yml/.json 文件（可带 .gz 后缀）。
+//
+// 目录不存在时返回空结果；其他扩展名的文件与子目录会被忽略。
+func loadConfigDir(dir string, options *options, report *loadReport) ([]configLayer, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read config dir %s: %w", dir, err)
+	}
+
+	// os.ReadDir 按文件名逐字节排序，与 expandConfigPath 的顺序规则一致
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && options.fileFormat(entry.Name()) != "" {
+			files = append(files, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	var layers []configLayer
+	for _, file := range options.preferFormats(files) {
+		layer, ok, err := readConfigLayer(file, options, report)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			layers = append(layers, layer)
+		}
+	}
+
+	return layers, nil
+}
+
+// readConfigLayer 读取并解析单个配置文件，文件不存在或不可读时 ok 为 false。
+//
+// 读取或解析失败时按 [WithReadRetries] 重试；[WithConfigPathsStopOnError] 的 onParse 为 [ErrorModeSkip] 时，
+// 重试后仍无法解压或解析的文件记录 [WarnInvalidFile] 后同样返回 false。
+func readConfigLayer(path string, options *options, report *loadReport) (configLayer, bool, error) {
+	layer, ok, err := readConfigLayerOnce(path, options, report)
+	for attempt := 1; err != nil && attempt <= options.readRetries; attempt++ {
+		slog.Debug("Retrying config file read", "path", path, "attempt", attempt, "error", err)
+		time.Sleep(time.Duration(attempt) * options.readRetryDelay)
+		layer, ok, err = readConfigLayerOnce(path, options, report)
+	}
+
+	var invalid *invalidFileError
+	if errors.As(err, &invalid) {
+		if options.onParseError == ErrorModeSkip {
+			report.addWarning(WarnInvalidFile, invalid.path, "skipped invalid config file: %v", invalid.err)
+
+			return configLayer{}, false, nil
+		}
+
+		return configLayer{}, false, invalid.err
+	}
+
+	return layer, ok, err
+}
+
+// invalidFileError 标记无法解压或解析的配置文件，供 readConfigLayer 按 onParse 决定跳过或失败。
+type invalidFileError struct {
+	path string
+	err  error
+}
+
+func (e *invalidFileError) Error() string { return e.err.Error() }
+func (e *invalidFileError) Unwrap() error { return e.err }
+
+// rawConfigFile 是 readConfigFile 读到的配置文件：found 为 false 时按不存在处理，stale 时未读取内容。
+type rawConfigFile struct {
+	path    string // 解析符号链接后的路径（见 WithConfigPathsResolveSymlinks）
+	info    fs.FileInfo
+	content []byte
+	found   bool
+	stale   bool
+}
+
+// readConfigFile 完成配置文件的全部文件系统访问（解析符号链接、打开、stat 与读取），
+// 不访问 report 与包级变量（open 由调用方取得），以便在 [WithStatTimeout] 超时后仍可在独立 goroutine 中安全地执行完毕。
+func readConfigFile(path string, open func(string) (configFileHandle, error), options *options) (rawConfigFile, error) {
+	// WithConfigPathsResolveSymlinks: 读取并记录符号链接指向的真实路径
+	if options.resolveSymlinks {
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return rawConfigFile{}, nil
+		}
+		path = realPath
+	}
+
+	file, err := open(path)
+	if err != nil {
+		return rawConfigFile{}, nil
+	}
+	defer func() { _ = file.Close() }()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		return rawConfigFile{}, nil
 	}
+	raw := rawConfigFile{path: path, info: info, found: true}
+	// WithConfigPathsNewerThan: 过旧的文件视为不存在
+	if options.isStale(info) {
+		raw.stale = true
 
-	return &cfg, nil
+		return raw, nil
+	}
+	// WithSecureFilePermissions: 组用户或其他用户可读的文件拒绝加载
+	if options.securePermissions {
+		if err := checkFilePermissions(path, info); err != nil {
+			return rawConfigFile{}, err
+		}
+	}
+	raw.content, err = readLimited(file, path, options.maxFileSize)
+
+	return raw, err
+}
+
+// readConfigLayerOnce 执行一次 readConfigLayer 的读取与解析，不做重试与跳过处理。
+//
+// 设置 [WithStatTimeout] 时文件系统访问整体受该时限约束，超时的文件按不存在处理。
+func readConfigLayerOnce(path string, options *options, report *loadReport) (configLayer, bool, error) {
+	readStart := time.Now()
+	open := openConfigFile
+	raw, err := withTimeout(options.statTimeout, func() (rawConfigFile, error) { return readConfigFile(path, open, options) })
+	switch {
+	case errors.Is(err, errStatTimeout):
+		reportAccessTimeout(path, "read", options, report)
+
+		return configLayer{}, false, nil
+	case err != nil:
+		return configLayer{}, false, err
+	case !raw.found:
+		return configLayer{}, false, nil
+	case raw.stale:
+		report.addWarning(WarnStaleFile, raw.path, "skipped config file modified at %s, before %s",
+			raw.info.ModTime().Format(time.RFC3339), options.newerThan.Format(time.RFC3339))
+
+		return configLayer{}, false, nil
+	}
+	path, content := raw.path, raw.content
+
+	if err := verifyChecksum(options, path, content); err != nil {
+		return configLayer{}, false, err
+	}
+	content, err = decompressConfig(path, content, options.maxFileSize)
+	report.observe(&report.metrics.Read, readStart)
+	var fileMap map[string]any
+	if err == nil {
+		fileMap, err = parseConfigFile(path, filepath.Dir(path), content, options, report)
+	}
+	if err != nil {
+		return configLayer{}, false, &invalidFileError{path: path, err: err}
+	}
+
+	slog.Debug("Loaded config from file", "path", path, "templateExpansion", !options.noTemplateExpansion)
+
+	return configLayer{path: path, data: fileMap}, true, nil
+}
+
+// readEmbeddedLayer 读取 [WithEmbeddedDefault] 指定的内嵌配置文件。
+//
+// 内嵌文件随二进制发布，不做 [WithConfigChecksum] 校验；文件不存在时返回错误。
+func readEmbeddedLayer(src embeddedSource, options *options, report *loadReport) (configLayer, error) {
+	readStart := time.Now()
+	file, err := src.fsys.Open(src.path)
+	if err != nil {
+		return configLayer{}, fmt.Errorf("embedded config %s: %w", src.path, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	content, err := readLimited(file, src.path, options.maxFileSize)
+	if err != nil {
+		return configLayer{}, err
+	}
+	if content, err = decompressConfig(src.path, content, options.maxFileSize); err != nil {
+		return configLayer{}, err
+	}
+	report.observe(&report.metrics.Read, readStart)
+
+	data, err := parseStaticConfigFile(src.path, options.baseDir, content, options, report)
+	if err != nil {
+		return configLayer{}, err
+	}
+
+	return configLayer{path: src.path, data: data}, nil
+}
+
+// readReaderLayer 读取 [LoadReader] 传入的数据流并解析为配置层。
+func readReaderLayer(src *readerSource, options *options, report *loadReport) (configLayer, error) {
+	readStart := time.Now()
+	content, err := readLimited(src.r, src.name, options.maxFileSize)
+	if err != nil {
+		return configLayer{}, err
+	}
+
+	if err := verifyChecksum(options, src.name, content); err != nil {
+		return configLayer{}, err
+	}
+	if content, err = decompressConfig(src.name, content, options.maxFileSize); err != nil {
+		return configLayer{}, err
+	}
+	report.observe(&report.metrics.Read, readStart)
+
+	data, err := parseConfigFile(src.name, options.baseDir, content, options, report)
+	if err != nil {
+		return configLayer{}, err
+	}
+
+	return configLayer{path: src.name, data: data}, nil
+}
+
+// readLimited 读取 r 的全部内容，limit > 0 时超过 limit 字节返回错误（见 [WithMaxFileSize]）。
+func readLimited(r io.Reader, name string, limit int64) ([]byte, error) {
+	if limit <= 0 {
+		content, err := io.ReadAll(r)
+		if err != nil {
+			return nil, fmt.Errorf("read config %s: %w", name, err)
+		}
+
+		return content, nil
+	}
+
+	content, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, fmt.Errorf("read config %s: %w", name, err)
+	}
+	if int64(len(content)) > limit {
+		return nil, fmt.Errorf("config %s exceeds max size of %d bytes", name, limit)
+	}
+
+	return content, nil
+}
+
+// parseConfigFile 对文件内容执行模板展开并解析为配置 map。
+//
+// [WithLazyKeys] 指定的 key 会保留展开前的原始模板字符串。
+// dir 为 [WithYAMLInclude] 中相对路径的解析基准。
+func parseConfigFile(path, dir string, content []byte, options *options, report *loadReport) (map[string]any, error) {
+	return parseConfigData(path, dir, content, false, options, report)
+}
+
+// parseStaticConfigFile 与 parseConfigFile 相同，但内容不随时间变化（内嵌文件、[BytesSource]），
+// 解析结果经 parsedLayers 缓存，在多次加载间复用。
+func parseStaticConfigFile(path, dir string, content []byte, options *options, report *loadReport) (map[string]any, error) {
+	return parseConfigData(path, dir, content, true, options, report)
+}
+
+// parseConfigData 实现 parseConfigFile，cached 为 true 时复用展开后内容相同的解析结果。
+func parseConfigData(path, dir string, content []byte, cached bool, options *options, report *loadReport) (map[string]any, error) {
+	content, err := decodeConfigContent(path, content, options.configEncoding)
+	if err != nil {
+		return nil, err
+	}
+	raw := content
+
+	// 默认启用模板展开，在解析前处理模板
+	if !options.noTemplateExpansion {
+		templateStart := time.Now()
+		expanded, expandErr := templexp.ExpandTemplate(string(content), options.templateOptions()...)
+		if expandErr != nil {
+			return nil, fmt.Errorf("expand template in %s: %w", path, expandErr)
+		}
+		content = []byte(expanded)
+		report.observe(&report.metrics.Template, templateStart)
+	}
+
+	parseStart := time.Now()
+	defer report.observe(&report.metrics.Parse, parseStart)
+	var fileMap map[string]any
+	if cached {
+		fileMap, err = parsedLayers.parse(path, dir, content, options)
+	} else {
+		fileMap, err = parseConfigContent(path, dir, content, options)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parse config file %s: %w", path, err)
+	}
+	if options.normalizeKeys {
+		fileMap = normalizeKeyCase(fileMap)
+	}
+
+	if len(options.lazyKeys) > 0 && !options.noTemplateExpansion {
+		rawMap, err := parseConfigFormat(options.fileFormat(path), raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse lazy keys in %s: %w", path, err)
+		}
+		if options.normalizeKeys {
+			rawMap = normalizeKeyCase(rawMap)
+		}
+		for _, key := range options.lazyKeys {
+			path := options.splitKey(key)
+			if val, ok := getByPath(rawMap, path); ok {
+				setByPath(fileMap, path, val)
+			}
+		}
+	}
+
+	return fileMap, nil
+}
+
+// parseConfigContent 解析展开后的配置内容，[WithYAMLInclude] 时展开 YAML 中的 !include。
+func parseConfigContent(path, dir string, content []byte, options *options) (map[string]any, error) {
+	// WithStrictDuplicateKeys: encoding/json 保留重复 key 的最后一个值，解析前单独检查
+	format := options.fileFormat(path)
+	if options.strictDuplicateKeys && format == formatJSON {
+		if err := checkDuplicateJSONKeys(content); err != nil {
+			return nil, err
+		}
+	}
+	if !options.yamlInclude || format == formatJSON {
+		return parseConfigFormat(format, content)
+	}
+
+	var stack []string
+	if realPath, err := filepath.EvalSymlinks(path); err == nil {
+		stack = []string{realPath}
+	}
+	val, err := parseYAMLWithIncludes(path, content, dir, options, stack)
+	if err != nil {
+		return nil, err
+	}
+
+	return configRoot(val)
 }
 
 // LoadCmd 是 [Load] 的便捷版本，适用于 CLI 场景。
@@ -191,7 +1285,35 @@ func LoadCmd[T any](cmd *cli.Command, defaultConfig T, appName string, opts ...O
 	if appName != "" {
 		baseOpts = append(baseOpts, WithAppName(appName))
 	}
-	return load(defaultConfig, 1, append(baseOpts, opts...)...)
+	cfg, _, err := load(defaultConfig, 1, append(baseOpts, opts...)...)
+
+	return cfg, err
+}
+
+// LoadReader 从数据流读取配置文件内容，并按 [Load] 的规则合并默认值、环境变量与 CLI flags。
+//
+// format 为 "yaml"/"yml"/"json"（可带前导点），数据流替代配置文件搜索（[WithConfigPaths] 等不生效），
+// 模板展开、[WithMaxFileSize] 等选项照常适用。r 会被完整读取，但不会被关闭。
+//
+// 示例：
+//
+//	resp, err := http.Get(url)
+//	// ...
+//	defer resp.Body.Close()
+//	cfg, err := cfgm.LoadReader(DefaultConfig(), resp.Body, "yaml",
+//	    cfgm.WithMaxFileSize(1<<20),
+//	)
+func LoadReader[T any](defaultConfig T, r io.Reader, format string, opts ...Option) (*T, error) {
+	normalized := normalizeFormat(format)
+	if normalized == "" {
+		return nil, fmt.Errorf("unsupported config format %q", format)
+	}
+
+	src := &readerSource{name: "<reader>." + normalized, r: r}
+	opts = append(slices.Clip(opts), func(o *options) { o.reader = src })
+	cfg, _, err := load(defaultConfig, 1, opts...)
+
+	return cfg, err
 }
 
 // MustLoad 调用 [Load] 并在失败时 panic，适合启动阶段。
@@ -203,7 +1325,7 @@ func LoadCmd[T any](cmd *cli.Command, defaultConfig T, appName string, opts ...O
 //	    cfgm.WithEnvPrefix("MYAPP_"),
 //	)
 func MustLoad[T any](defaultConfig T, opts ...Option) *T {
-	cfg, err := load(defaultConfig, 2, opts...)
+	cfg, _, err := load(defaultConfig, 2, opts...)
 	if err != nil {
 		panic(fmt.Sprintf("cfgm: failed to load config: %v", err))
 	}
@@ -223,7 +1345,7 @@ func MustLoadCmd[T any](cmd *cli.Command, defaultConfig T, appName string, opts
 	if appName != "" {
 		baseOpts = append(baseOpts, WithAppName(appName))
 	}
-	cfg, err := load(defaultConfig, 2, append(baseOpts, opts...)...)
+	cfg, _, err := load(defaultConfig, 2, append(baseOpts, opts...)...)
 	if err != nil {
 		panic(fmt.Sprintf("cfgm: failed to load config: %v", err))
 	}
@@ -236,13 +1358,30 @@ func MustLoadCmd[T any](cmd *cli.Command, defaultConfig T, appName string, opts
 // 以 json tag 为准，返回叶子路径（如 client.rev-auth-user）。
 func collectConfigKeys[T any](defaultConfig T) []string {
 	var keys []string
-	collectConfigKeysRecursive(reflect.TypeOf(defaultConfig), "", &keys)
+	collectConfigKeysRecursive(reflect.TypeOf(defaultConfig), "", defaultKeyDelim, defaultConfigTags, &keys)
 
 	return keys
 }
 
-// collectConfigKeysRecursive 递归遍历字段并拼接完整 key 路径。
-func collectConfigKeysRecursive(typ reflect.Type, prefix string, keys *[]string) {
+// collectConfigKeysRecursive 递归遍历字段并以 delim 拼接完整 key 路径。
+func collectConfigKeysRecursive(typ reflect.Type, prefix, delim string, tags []string, keys *[]string) {
+	walkConfigFields(typ, prefix, delim, tags, func(key string, _ reflect.StructField) {
+		*keys = append(*keys, key)
+	})
+}
+
+// collectConfigKeyTypes 返回叶子 key 到字段类型的映射。
+func collectConfigKeyTypes(typ reflect.Type, delim string, tags []string) map[string]reflect.Type {
+	types := make(map[string]reflect.Type)
+	walkConfigFields(typ, "", delim, tags, func(key string, field reflect.StructField) {
+		types[key] = field.Type
+	})
+
+	return types
+}
+
+// walkConfigFields 递归遍历结构体叶子字段，以 delim 拼接的完整 key 路径回调 fn。
+func walkConfigFields(typ reflect.Type, prefix, delim string, tags []string, fn func(key string, field reflect.StructField)) {
 	// 处理指针类型
 	if typ.Kind() == reflect.Pointer {
 		typ = typ.Elem()
@@ -255,24 +1394,24 @@ func collectConfigKeysRecursive(typ reflect.Type, prefix string, keys *[]string)
 	for i := range typ.NumField() {
 		field := typ.Field(i)
 
-		key := configTagName(field)
+		key := configTagName(field, tags)
 		if key == "" {
 			continue
 		}
 
 		fullKey := key
 		if prefix != "" {
-			fullKey = prefix + "." + key
+			fullKey = prefix + delim + key
 		}
 
 		// 如果是嵌套结构体（非特殊类型），递归处理
 		if isStructType(field.Type) {
-			collectConfigKeysRecursive(field.Type, fullKey, keys)
+			walkConfigFields(field.Type, fullKey, delim, tags, fn)
 
 			continue
 		}
 
-		*keys = append(*keys, fullKey)
+		fn(fullKey, field)
 	}
 }
 
@@ -286,17 +1425,25 @@ func collectConfigKeysRecursive(typ reflect.Type, prefix string, keys *[]string)
 // 示例 (前缀 "APP_")：
 //   - client.rev-auth-user → APP_CLIENT_REV_AUTH_USER
 //   - server.idle-timeout → APP_SERVER_IDLE_TIMEOUT
-func generateEnvBindings(prefix string, keys []string) map[string]string {
+//
+// delim 为 key 路径分隔符（见 [WithKeyDelim]），同样转为 "_"。
+func generateEnvBindings(prefix string, keys []string, delim string) map[string]string {
 	bindings := make(map[string]string, len(keys))
 	for _, key := range keys {
-		// 将 "." 和 "-" 都转为 "_"，然后大写
-		envKey := strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key))
-		bindings[prefix+envKey] = key
+		bindings[envKeyName(prefix, key, delim)] = key
 	}
 
 	return bindings
 }
 
+// envKeyName 将配置 key 转为带前缀的环境变量名，规则见 [generateEnvBindings]。
+func envKeyName(prefix, key, delim string) string {
+	// 将分隔符、"." 和 "-" 都转为 "_"，然后大写
+	replacer := strings.NewReplacer(delim, "_", ".", "_", "-", "_")
+
+	return prefix + strings.ToUpper(replacer.Replace(key))
+}
+
 // applyCLIFlagsGeneric 将用户显式设置的 CLI flags 写入配置 map。
 //
 // 根据 json tag 生成 CLI flag 名称，仅替换 "." 为 "-"。
@@ -313,12 +1460,16 @@ func generateEnvBindings(prefix string, keys []string) map[string]string {
 //   - 时间类型: time.Duration, time.Time
 //   - 切片类型: []string, []int, []int64, []float64 等
 //   - Map 类型: map[string]string
-func applyCLIFlagsGeneric[T any](cmd *cli.Command, config map[string]any, defaultConfig T) {
-	applyCLIFlagsRecursive(cmd, config, reflect.TypeOf(defaultConfig), "")
+//
+// flagPrefix 为 [WithCLIFlagPrefix] 声明的前缀，拼接在生成的 flag 名称之前。
+// filter 非 nil 时仅写入 filter 返回 true 的 flag。
+func applyCLIFlagsGeneric[T any](cmd *cli.Command, config map[string]any, defaultConfig T, flagPrefix string, tags []string, filter cliFlagFilter) {
+	applyCLIFlagsRecursive(cmd, config, reflect.TypeOf(defaultConfig), nil, flagPrefix, tags, filter)
 }
 
 // applyCLIFlagsRecursive 递归遍历结构体字段并应用 CLI flags。
-func applyCLIFlagsRecursive(cmd *cli.Command, config map[string]any, typ reflect.Type, prefix string) {
+// prefix 为父级 key 路径的各段，flag 名称为 flagPrefix 加上各段以 "-" 拼接的结果。
+func applyCLIFlagsRecursive(cmd *cli.Command, config map[string]any, typ reflect.Type, prefix []string, flagPrefix string, tags []string, filter cliFlagFilter) {
 	if typ.Kind() == reflect.Pointer {
 		typ = typ.Elem()
 	}
@@ -329,37 +1480,67 @@ func applyCLIFlagsRecursive(cmd *cli.Command, config map[string]any, typ reflect
 	for i := range typ.NumField() {
 		field := typ.Field(i)
 
-		// 获取 json 标签作为配置 key
-		key := configTagName(field)
+		// 获取 json 标签（或 WithUnmarshalTagChain 指定的标签）作为配置 key
+		key := configTagName(field, tags)
 		if key == "" {
 			continue
 		}
 
 		// 构建完整的配置 key
-		fullKey := key
-		if prefix != "" {
-			fullKey = prefix + "." + key
-		}
+		fullKey := append(slices.Clip(prefix), key)
 
 		// 如果是嵌套结构体，递归处理
 		if isStructType(field.Type) {
-			applyCLIFlagsRecursive(cmd, config, field.Type, fullKey)
+			applyCLIFlagsRecursive(cmd, config, field.Type, fullKey, flagPrefix, tags, filter)
 
 			continue
 		}
 
-		cliFlag := strings.ReplaceAll(fullKey, ".", "-")
+		cliFlag := flagPrefix + strings.Join(fullKey, "-")
 		if !cmd.IsSet(cliFlag) {
 			continue
 		}
+		if filter != nil && !filter(cmd, cliFlag) {
+			continue
+		}
 
 		// 根据字段类型获取值并设置
 		setCLIFlagValue(cmd, config, fullKey, cliFlag, field.Type)
 	}
 }
 
+// checkCLILeafKeys 检查将要应用的 CLI flag 不会覆盖配置中的子树。
+//
+// flag 对应结构体叶子字段，但配置文件等来源可能在同一 key 下写入了嵌套对象；
+// 直接覆盖会静默丢弃整个子树，因此返回错误。map 类型字段的 flag 本身即为子树，不做检查。
+func checkCLILeafKeys(cmd *cli.Command, configMap map[string]any, typ reflect.Type, options *options, filter cliFlagFilter) error {
+	delim := options.keyDelim()
+
+	var err error
+	walkConfigFields(typ, "", delim, options.tagChain(), func(key string, field reflect.StructField) {
+		if err != nil || isDynamicType(field.Type) {
+			return
+		}
+		parts := strings.Split(key, delim)
+		cliFlag := options.cliFlagPrefix + strings.Join(parts, "-")
+		if !cmd.IsSet(cliFlag) || (filter != nil && !filter(cmd, cliFlag)) {
+			return
+		}
+		if options.normalizeKeys {
+			parts = options.splitKey(strings.ToLower(key))
+		}
+		existing, _ := getByPath(configMap, parts)
+		if subtree, ok := existing.(map[string]any); ok && len(subtree) > 0 {
+			err = fmt.Errorf("cli flag --%s would overwrite nested config under %q (keys: %s)",
+				cliFlag, key, strings.Join(slices.Sorted(maps.Keys(subtree)), ", "))
+		}
+	})
+
+	return err
+}
+
 // setCLIFlagValue 按字段类型读取 CLI 值并写入配置 map。
-func setCLIFlagValue(cmd *cli.Command, config map[string]any, configPath, cliFlag string, fieldType reflect.Type) {
+func setCLIFlagValue(cmd *cli.Command, config map[string]any, configPath []string, cliFlag string, fieldType reflect.Type) {
 	// 先检查特殊类型 (time.Duration, time.Time)
 	switch fieldType {
 	case reflect.TypeFor[time.Duration]():
@@ -428,7 +1609,7 @@ func setCLIFlagValue(cmd *cli.Command, config map[string]any, configPath, cliFla
 }
 
 // setSliceFlagValue 处理切片类型的 CLI flag 值。
-func setSliceFlagValue(cmd *cli.Command, config map[string]any, configPath, cliFlag string, fieldType reflect.Type) {
+func setSliceFlagValue(cmd *cli.Command, config map[string]any, configPath []string, cliFlag string, fieldType reflect.Type) {
 	elemType := fieldType.Elem()
 
 	// 先检查特殊元素类型
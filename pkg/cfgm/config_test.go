@@ -1,15 +1,40 @@
 package cfgm
 
 import (
+	"bytes"
+	"cmp"
+	"compress/gzip"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"reflect"
+	"runtime"
+	"slices"
+	"strings"
 	"testing"
+	"testing/fstest"
 	"time"
 
+	"github.com/go-viper/mapstructure/v2"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/urfave/cli/v3"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/templexp"
 )
 
 // =============================================================================
@@ -78,6 +103,73 @@ func TestLoadWithEnvPrefix(t *testing.T) {
 	assert.Equal(t, "http://test:8080", cfg.Server.URL)
 }
 
+func TestLoadWithEnvPrefixes(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+
+	t.Setenv("OLD_NAME", "old-name")
+	t.Setenv("OLD_PORT", "1111")
+	t.Setenv("NEW_PORT", "2222")
+	t.Setenv("NEW_DEBUG", "true")
+
+	t.Run("later prefix wins", func(t *testing.T) {
+		cfg, err := Load(Config{Name: "default"}, WithEnvPrefixes("OLD_", "NEW_"))
+		require.NoError(t, err)
+
+		a := assert.New(t)
+		a.Equal("old-name", cfg.Name, "only OLD_ set")
+		a.Equal(2222, cfg.Port, "NEW_ declared later wins")
+		a.True(cfg.Debug, "only NEW_ set")
+	})
+
+	t.Run("repeated WithEnvPrefix", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithEnvPrefix("NEW_"), WithEnvPrefix("OLD_"))
+		require.NoError(t, err)
+		assert.Equal(t, 1111, cfg.Port, "OLD_ declared later wins")
+	})
+}
+
+func TestLoadWithStrictEnvTypes(t *testing.T) {
+	type Config struct {
+		Port    int           `json:"port"`
+		Debug   bool          `json:"debug"`
+		Timeout time.Duration `json:"timeout"`
+		Name    string        `json:"name"`
+	}
+
+	tests := []struct {
+		name    string
+		env     map[string]string
+		wantErr string
+	}{
+		{"valid values", map[string]string{"STRICT_PORT": "0x1F90", "STRICT_DEBUG": "1", "STRICT_TIMEOUT": "5s", "STRICT_NAME": "x"}, ""},
+		{"invalid int", map[string]string{"STRICT_PORT": "abc"}, `env STRICT_PORT="abc": expected int`},
+		{"invalid bool", map[string]string{"STRICT_DEBUG": "yes"}, `env STRICT_DEBUG="yes": expected bool`},
+		{"invalid duration", map[string]string{"STRICT_TIMEOUT": "30"}, `env STRICT_TIMEOUT="30": expected time.Duration`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			cfg, err := Load(Config{}, WithEnvPrefix("STRICT_"), WithStrictEnvTypes())
+			if tt.wantErr != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, 8080, cfg.Port)
+			assert.Equal(t, 5*time.Second, cfg.Timeout)
+		})
+	}
+}
+
 func TestAutoEnvBinding(t *testing.T) {
 	//nolint:tagliatelle
 	type ClientConfig struct {
@@ -582,6 +674,82 @@ func TestExampleYAML(t *testing.T) {
 	}
 }
 
+// =============================================================================
+// Marshal 测试
+// =============================================================================
+
+func TestMarshal(t *testing.T) {
+	type ServerConfig struct {
+		Addr    string        `json:"addr"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	type Config struct {
+		Name   string       `json:"name"`
+		Hosts  []string     `json:"hosts"`
+		Server ServerConfig `json:"server"`
+	}
+
+	defaultCfg := Config{
+		Name:   "app",
+		Hosts:  []string{"a", "b"},
+		Server: ServerConfig{Addr: ":8080", Timeout: 30 * time.Second},
+	}
+
+	for _, format := range []string{"yaml", "YML", ".json"} {
+		t.Run(format, func(t *testing.T) {
+			data, err := Marshal(&defaultCfg, format)
+			require.NoError(t, err)
+
+			path := writeTempConfig(t, string(data))
+			cfg, err := Load(Config{}, WithConfigPaths(path))
+			require.NoError(t, err)
+			assert.Equal(t, defaultCfg, *cfg, "marshal output should round-trip through Load")
+		})
+	}
+
+	t.Run("yaml uses json tags", func(t *testing.T) {
+		data, err := Marshal(&defaultCfg, "yaml")
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "timeout: 30s")
+		assert.Contains(t, string(data), "addr: :8080")
+	})
+
+	t.Run("commented defaults", func(t *testing.T) {
+		type Commented struct {
+			Name  string `json:"name" desc:"应用名称"`
+			Port  int    `json:"port" usage:"listen port"`
+			Debug bool   `json:"debug" comment:"enable debug"`
+		}
+		cfg := Commented{Name: "app", Port: 8080}
+
+		data, err := Marshal(&cfg, "yaml", WithCommentedDefaults())
+		require.NoError(t, err)
+		out := string(data)
+		assert.Contains(t, out, "# 应用名称\nname: \"app\"")
+		assert.Contains(t, out, "# listen port\nport: 8080")
+		assert.Contains(t, out, "# enable debug\ndebug: false")
+
+		loaded, err := Load(Commented{}, WithConfigPaths(writeTempConfig(t, out)))
+		require.NoError(t, err)
+		assert.Equal(t, cfg, *loaded)
+
+		jsonData, err := Marshal(&cfg, "json", WithCommentedDefaults())
+		require.NoError(t, err)
+		assert.NotContains(t, string(jsonData), "listen port", "json ignores comments")
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := Marshal(&defaultCfg, "toml")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "toml")
+	})
+
+	t.Run("nil config", func(t *testing.T) {
+		_, err := Marshal[Config](nil, "yaml")
+		require.Error(t, err)
+	})
+}
+
 // =============================================================================
 // DefaultPaths 测试
 // =============================================================================
@@ -754,7 +922,7 @@ func TestGenerateEnvBindings(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			bindings := generateEnvBindings(tt.prefix, tt.keys)
+			bindings := generateEnvBindings(tt.prefix, tt.keys, defaultKeyDelim)
 			assert.Equal(t, tt.expected, bindings)
 		})
 	}
@@ -879,6 +1047,45 @@ model: "test"
 		assert.Contains(t, err.Error(), "expand template")
 	})
 
+	t.Run("WithTemplateStrictMissing", func(t *testing.T) {
+		configPath := writeTempConfig(t, `
+api_key: '${TEST_TYPO_KEY}'
+model: '${TEST_MODEL:-gpt-4}'
+`)
+		cfg, err := Load(Config{}, WithConfigPaths(configPath))
+		require.NoError(t, err)
+		assert.Empty(t, cfg.APIKey, "missing expands to empty by default")
+
+		_, err = Load(Config{}, WithConfigPaths(configPath), WithTemplateStrictMissing())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "TEST_TYPO_KEY: parameter not set")
+	})
+
+	t.Run("templated keys", func(t *testing.T) {
+		type Region struct {
+			Endpoint string `json:"endpoint"`
+		}
+		type RegionConfig struct {
+			Regions map[string]Region `json:"regions"`
+		}
+		t.Setenv("TEST_REGION", "cn-north")
+
+		configPath := writeTempConfig(t, `
+regions:
+  ${TEST_REGION}:
+    endpoint: "https://${TEST_REGION}.example.com"
+  ${TEST_BACKUP_REGION:-us-east}:
+    endpoint: "https://backup.example.com"
+`)
+		cfg, err := Load(RegionConfig{}, WithConfigPaths(configPath))
+		require.NoError(t, err)
+
+		assert.Equal(t, map[string]Region{
+			"cn-north": {Endpoint: "https://cn-north.example.com"},
+			"us-east":  {Endpoint: "https://backup.example.com"},
+		}, cfg.Regions)
+	})
+
 	t.Run("complex real-world example", func(t *testing.T) {
 		t.Setenv("OPENROUTER_API_KEY", "or-key-12345")
 		t.Setenv("LLM_MODEL", "anthropic/claude-haiku-4.5")
@@ -968,6 +1175,8 @@ func TestIsJSONPath(t *testing.T) {
 		{"unknown extension", "config.conf", false},
 		{"json in path", "/path/to/config.json", true},
 		{"yaml in path", "/etc/app/config.yaml", false},
+		{"gzipped json", "config.json.gz", true},
+		{"gzipped yaml", "config.yaml.GZ", false},
 	}
 
 	for _, tt := range tests {
@@ -1248,9 +1457,9 @@ func TestSetCLIFlagValue_UnsupportedTypes(t *testing.T) {
 		Action: func(ctx context.Context, c *cli.Command) error {
 			cfg := map[string]any{"dummy": "initial"}
 			// 测试不支持的基本类型 (complex128)
-			setCLIFlagValue(c, cfg, "dummy", "dummy", reflect.TypeFor[complex128]())
+			setCLIFlagValue(c, cfg, []string{"dummy"}, "dummy", reflect.TypeFor[complex128]())
 			// 测试不支持的切片元素类型
-			setSliceFlagValue(c, cfg, "dummy", "dummy", reflect.TypeFor[[]complex128]())
+			setSliceFlagValue(c, cfg, []string{"dummy"}, "dummy", reflect.TypeFor[[]complex128]())
 
 			loadedCfg = cfg
 
@@ -1263,3 +1472,4651 @@ func TestSetCLIFlagValue_UnsupportedTypes(t *testing.T) {
 	// 不支持的类型不会修改值
 	assert.Equal(t, "initial", loadedCfg["dummy"])
 }
+
+// =============================================================================
+// 配置文件编码测试
+// =============================================================================
+
+func TestLoadWithConfigEncoding(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	t.Run("gbk", func(t *testing.T) {
+		content, err := simplifiedchinese.GBK.NewEncoder().String("name: \"中文配置\"\n")
+		require.NoError(t, err)
+		path := writeTempConfig(t, content)
+
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding("gbk"))
+		require.NoError(t, err)
+		assert.Equal(t, "中文配置", cfg.Name)
+	})
+
+	t.Run("latin1 before template expansion", func(t *testing.T) {
+		t.Setenv("ENC_SUFFIX", "!")
+		content, err := charmap.ISO8859_1.NewEncoder().String("name: \"café${ENC_SUFFIX}\"\n")
+		require.NoError(t, err)
+		path := writeTempConfig(t, content)
+
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding("ISO-8859-1"))
+		require.NoError(t, err)
+		assert.Equal(t, "café!", cfg.Name)
+	})
+
+	t.Run("invalid sequence names file", func(t *testing.T) {
+		path := writeTempConfig(t, "name: \"\x81\x20\"\n")
+
+		_, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding("gbk"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), path)
+		assert.Contains(t, err.Error(), "invalid byte sequence")
+	})
+
+	t.Run("encoded replacement character", func(t *testing.T) {
+		for _, tc := range []struct {
+			name string
+			enc  encoding.Encoding
+		}{
+			{"gb18030", simplifiedchinese.GB18030},
+			{"utf-16le", unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM)},
+		} {
+			content, err := tc.enc.NewEncoder().String("name: \"中\uFFFD文\"\n")
+			require.NoError(t, err)
+			path := writeTempConfig(t, content)
+
+			cfg, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding(tc.name))
+			require.NoError(t, err, tc.name)
+			assert.Equal(t, "中\uFFFD文", cfg.Name, tc.name)
+		}
+
+		// GB18030 可以表示 U+FFFD，非法字节序列仍需报告
+		path := writeTempConfig(t, "name: \"\x81\x20\"\n")
+		_, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding("gb18030"))
+		require.ErrorContains(t, err, "invalid byte sequence")
+	})
+
+	t.Run("unknown encoding", func(t *testing.T) {
+		path := writeTempConfig(t, "name: x\n")
+
+		_, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding("no-such-encoding"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported config encoding")
+	})
+
+	t.Run("utf-8 default untouched", func(t *testing.T) {
+		path := writeTempConfig(t, "name: \"中文\"\n")
+
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithConfigEncoding("UTF-8"))
+		require.NoError(t, err)
+		assert.Equal(t, "中文", cfg.Name)
+	})
+
+	t.Run("utf-8 bom stripped", func(t *testing.T) {
+		t.Setenv("ENC_KEY", "name")
+		dir := t.TempDir()
+		for file, content := range map[string]string{
+			"bom.json": "\xef\xbb\xbf{\"name\": \"json\"}",
+			"bom.yaml": "\xef\xbb\xbf${ENC_KEY}: yaml\n",
+		} {
+			path := filepath.Join(dir, file)
+			require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+			cfg, err := Load(Config{}, WithConfigPaths(path))
+			require.NoError(t, err, file)
+			assert.Equal(t, strings.TrimPrefix(filepath.Ext(file), "."), cfg.Name)
+		}
+	})
+}
+
+// =============================================================================
+// 多文件合并测试
+// =============================================================================
+
+func TestLoadWithMergeAllPaths(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+		Mode string `json:"mode"`
+	}
+
+	local := writeTempConfig(t, "name: local\n")
+	system := writeTempConfig(t, "name: system\nport: 9000\n")
+
+	t.Run("first hit only by default", func(t *testing.T) {
+		cfg, err := Load(Config{Mode: "default"}, WithConfigPaths(local, system))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "local", Mode: "default"}, *cfg)
+	})
+
+	t.Run("earlier path wins when merging", func(t *testing.T) {
+		cfg, err := Load(Config{Mode: "default"}, WithConfigPaths(local, "/nonexistent.yaml", system), WithMergeAllPaths())
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "local", Port: 9000, Mode: "default"}, *cfg)
+	})
+}
+
+func TestMergePreview(t *testing.T) {
+	local := writeTempConfig(t, "name: local\nserver: {port: 1}\n")
+	system := writeTempConfig(t, "name: system\nserver: {host: h, port: 2}\n")
+
+	layers, err := MergePreview(WithConfigPaths(local, system), WithMergeAllPaths())
+	require.NoError(t, err)
+	require.Len(t, layers, 2)
+
+	a := assert.New(t)
+	a.Equal(system, layers[0].Path, "lowest priority first")
+	a.Equal([]string{"name", "server.host", "server.port"}, layers[0].Keys)
+	a.Empty(layers[0].Overridden)
+	a.Equal(local, layers[1].Path)
+	a.Equal([]string{"name", "server.port"}, layers[1].Keys)
+	a.Equal([]string{"name", "server.port"}, layers[1].Overridden)
+
+	t.Run("parse error", func(t *testing.T) {
+		broken := writeTempConfig(t, "name: [\n")
+		_, err := MergePreview(WithConfigPaths(broken))
+		require.Error(t, err)
+	})
+}
+
+func TestLoadWithFailFastPaths(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	existing := writeTempConfig(t, "name: from-file\n")
+
+	t.Run("missing explicit path errors", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(existing, "/nonexistent/config.yaml"), WithFailFastPaths())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "/nonexistent/config.yaml")
+	})
+
+	t.Run("all present", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(existing), WithFailFastPaths())
+		require.NoError(t, err)
+		assert.Equal(t, "from-file", cfg.Name)
+	})
+
+	t.Run("default paths stay best-effort", func(t *testing.T) {
+		cfg, err := Load(Config{Name: "default"}, WithAppName("cfgm-failfast-test"), WithBaseDir(t.TempDir()), WithFailFastPaths())
+		require.NoError(t, err)
+		assert.Equal(t, "default", cfg.Name)
+	})
+}
+
+// =============================================================================
+// 加载报告测试
+// =============================================================================
+
+func TestLoadWithEnvReport(t *testing.T) {
+	type ServerConfig struct {
+		URL string `json:"url"`
+	}
+	type Config struct {
+		Debug  bool         `json:"debug"`
+		Server ServerConfig `json:"server"`
+	}
+
+	t.Setenv("REPORT_SERVER_URL", "http://env:8080")
+
+	cfg, bindings, err := LoadWithEnvReport(Config{}, WithEnvPrefix("REPORT_"), WithConfigPaths("nonexistent.yaml"))
+	require.NoError(t, err)
+
+	assert.Equal(t, "http://env:8080", cfg.Server.URL)
+	assert.Equal(t, []EnvBindingResult{
+		{EnvKey: "REPORT_DEBUG", ConfigPath: "debug", Applied: false, Source: EnvSourcePrefix},
+		{EnvKey: "REPORT_SERVER_URL", ConfigPath: "server.url", Applied: true, Source: EnvSourcePrefix},
+	}, bindings)
+}
+
+// =============================================================================
+// WithCLIEnvAware 测试
+// =============================================================================
+
+func TestLoadWithCLIEnvAware(t *testing.T) {
+	type Config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	newFlags := func() []cli.Flag {
+		return []cli.Flag{
+			&cli.StringFlag{Name: "host", Sources: cli.EnvVars("CLIENV_FLAG_HOST")},
+			&cli.IntFlag{Name: "port", Sources: cli.EnvVars("CLIENV_FLAG_PORT")},
+		}
+	}
+
+	t.Setenv("CLIENV_FLAG_HOST", "flag-env-host")
+	t.Setenv("CLIENV_FLAG_PORT", "9000")
+	t.Setenv("CLIENV_HOST", "prefix-env-host")
+
+	t.Run("default treats flag env as CLI", func(t *testing.T) {
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test"}, WithEnvPrefix("CLIENV_"))
+		assert.Equal(t, "flag-env-host", cfg.Host)
+		assert.Equal(t, 9000, cfg.Port)
+	})
+
+	t.Run("flag env below prefix env", func(t *testing.T) {
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test"}, WithEnvPrefix("CLIENV_"), WithCLIEnvAware())
+		assert.Equal(t, "prefix-env-host", cfg.Host)
+		assert.Equal(t, 9000, cfg.Port, "flag env still overrides defaults")
+	})
+
+	t.Run("flag env above config file", func(t *testing.T) {
+		configPath := writeTempConfig(t, "port: 7000\n")
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test"}, WithConfigPaths(configPath), WithCLIEnvAware())
+		assert.Equal(t, 9000, cfg.Port)
+	})
+
+	t.Run("explicit args keep CLI priority", func(t *testing.T) {
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test", "--host", "arg-host", "--port", "8000"},
+			WithEnvPrefix("CLIENV_"), WithCLIEnvAware())
+		assert.Equal(t, "arg-host", cfg.Host)
+		assert.Equal(t, 8000, cfg.Port)
+	})
+}
+
+func TestLoadWithConfigPathsResolveSymlinks(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	// 模拟 Kubernetes ConfigMap: config.yaml -> ..data/config.yaml, ..data -> ..v1
+	dir := t.TempDir()
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "..v1"), 0o750))
+	target := filepath.Join(dir, "..v1", "config.yaml")
+	require.NoError(t, os.WriteFile(target, []byte("name: linked\n"), 0o600))
+	require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+	link := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), link))
+
+	realTarget, err := filepath.EvalSymlinks(target)
+	require.NoError(t, err)
+
+	cfg, err := Load(Config{}, WithConfigPaths(link), WithConfigPathsResolveSymlinks())
+	require.NoError(t, err)
+	assert.Equal(t, "linked", cfg.Name)
+
+	layers, err := MergePreview(WithConfigPaths(link), WithConfigPathsResolveSymlinks())
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	assert.Equal(t, realTarget, layers[0].Path)
+
+	layers, err = MergePreview(WithConfigPaths(link))
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	assert.Equal(t, link, layers[0].Path, "symlink kept by default")
+
+	t.Run("dangling link skipped", func(t *testing.T) {
+		dangling := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.Symlink("missing.yaml", dangling))
+		cfg, err := Load(Config{Name: "default"}, WithConfigPaths(dangling), WithConfigPathsResolveSymlinks())
+		require.NoError(t, err)
+		assert.Equal(t, "default", cfg.Name)
+	})
+}
+
+func TestLoadWithDefaultConfigFunc(t *testing.T) {
+	type Config struct {
+		Name    string `json:"name"`
+		DataDir string `json:"data_dir"`
+	}
+
+	baseDir := t.TempDir()
+	configPath := writeTempConfig(t, "name: from-file\n")
+
+	var got LoadContext
+	cfg, err := Load(Config{Name: "ignored"},
+		WithBaseDir(baseDir),
+		WithConfigPaths(configPath),
+		WithEnvPrefix("DEFFUNC_"),
+		WithDefaultConfigFunc(func(ctx LoadContext) (Config, error) {
+			got = ctx
+
+			return Config{Name: "default", DataDir: filepath.Join(ctx.BaseDir, "data")}, nil
+		}),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("from-file", cfg.Name, "config file still overrides defaults")
+	a.Equal(filepath.Join(baseDir, "data"), cfg.DataDir)
+	a.Equal(baseDir, got.BaseDir)
+	a.Equal([]string{configPath}, got.ConfigPaths)
+	a.Equal([]string{"DEFFUNC_"}, got.EnvPrefixes)
+
+	t.Run("error aborts load", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(configPath),
+			WithDefaultConfigFunc(func(LoadContext) (Config, error) {
+				return Config{}, assert.AnError
+			}),
+		)
+		require.ErrorIs(t, err, assert.AnError)
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(configPath),
+			WithDefaultConfigFunc(func(LoadContext) (string, error) { return "", nil }),
+		)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "default config func")
+	})
+}
+
+func TestLoadWithConfigPathsCaseInsensitive(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	dir := t.TempDir()
+	onDisk := filepath.Join(dir, "Config.yaml")
+	require.NoError(t, os.WriteFile(onDisk, []byte("name: mixed-case\n"), 0o600))
+	probe := filepath.Join(dir, "config.yaml")
+
+	cfg, err := Load(Config{}, WithConfigPaths(probe), WithConfigPathsCaseInsensitive())
+	require.NoError(t, err)
+	assert.Equal(t, "mixed-case", cfg.Name)
+
+	layers, err := MergePreview(WithConfigPaths(probe), WithConfigPathsCaseInsensitive())
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	assert.Equal(t, onDisk, layers[0].Path, "actual on-disk casing")
+
+	t.Run("exact match preferred", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "APP.yaml"), []byte("name: upper\n"), 0o600))
+		exact := filepath.Join(dir, "app.yaml")
+		if _, err := os.Stat(exact); err == nil {
+			t.Skip("case-insensitive filesystem")
+		}
+		require.NoError(t, os.WriteFile(exact, []byte("name: exact\n"), 0o600))
+
+		cfg, err := Load(Config{}, WithConfigPaths(exact), WithConfigPathsCaseInsensitive())
+		require.NoError(t, err)
+		assert.Equal(t, "exact", cfg.Name)
+	})
+}
+
+func TestLoadWithConfigChecksum(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	content := "name: '${CHECKSUM_NAME:-locked}'\n"
+	path := writeTempConfig(t, content)
+	sum := sha256.Sum256([]byte(content))
+	digest := hex.EncodeToString(sum[:])
+
+	t.Run("match", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithConfigChecksum(strings.ToUpper(digest)))
+		require.NoError(t, err)
+		assert.Equal(t, "locked", cfg.Name, "digest covers raw bytes before expansion")
+	})
+
+	t.Run("mismatch", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path), WithConfigChecksum(strings.Repeat("0", 64)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "checksum mismatch")
+	})
+
+	t.Run("per path", func(t *testing.T) {
+		other := writeTempConfig(t, "name: other\n")
+		_, err := Load(Config{}, WithConfigPaths(other, path), WithMergeAllPaths(),
+			WithConfigChecksums(map[string]string{path: digest}))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no checksum configured")
+
+		otherSum := sha256.Sum256([]byte("name: other\n"))
+		cfg, err := Load(Config{}, WithConfigPaths(other, path), WithMergeAllPaths(),
+			WithConfigChecksums(map[string]string{path: digest, other: hex.EncodeToString(otherSum[:])}))
+		require.NoError(t, err)
+		assert.Equal(t, "other", cfg.Name)
+	})
+}
+
+func TestLoadWithSecureFilePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("file permission bits are not enforced on Windows")
+	}
+	type Config struct {
+		Token string `json:"token"`
+	}
+
+	path := writeTempConfig(t, "token: secret\n")
+	for _, mode := range []os.FileMode{0o644, 0o640, 0o604} {
+		require.NoError(t, os.Chmod(path, mode))
+		_, err := Load(Config{}, WithConfigPaths(path), WithSecureFilePermissions())
+		require.Error(t, err, "mode %#o", mode)
+		assert.Contains(t, err.Error(), path)
+		assert.Contains(t, err.Error(), fmt.Sprintf("%#o", mode))
+
+		_, err = Load(Config{}, WithConfigSources(FileSource(path)), WithSecureFilePermissions())
+		require.Error(t, err, "file source, mode %#o", mode)
+	}
+
+	for _, mode := range []os.FileMode{0o600, 0o400} {
+		require.NoError(t, os.Chmod(path, mode))
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithSecureFilePermissions())
+		require.NoError(t, err, "mode %#o", mode)
+		assert.Equal(t, "secret", cfg.Token)
+	}
+
+	require.NoError(t, os.Chmod(path, 0o644))
+	cfg, err := Load(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+	assert.Equal(t, "secret", cfg.Token, "not checked without the option")
+}
+
+func TestLoadWithConfigPathsDir(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+
+	dir := t.TempDir()
+	writeFragment := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	writeFragment("10-base.yaml", "name: fragment\nport: 1000\n")
+	writeFragment("20-override.json", `{"port": 2000}`)
+	writeFragment("README.md", "name: ignored\n")
+	writeFragment("30-disabled.yaml.bak", "debug: false\n")
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "99-subdir.yaml"), 0o750))
+
+	base := writeTempConfig(t, "name: base\ndebug: true\n")
+
+	cfg, err := Load(Config{}, WithConfigPaths(base), WithConfigPathsDir(dir))
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("fragment", cfg.Name, "fragments override base file")
+	a.Equal(2000, cfg.Port, "later fragment wins")
+	a.True(cfg.Debug, "base file value kept")
+
+	layers, err := MergePreview(WithConfigPaths(base), WithConfigPathsDir(dir))
+	require.NoError(t, err)
+	require.Len(t, layers, 3)
+	a.Equal(base, layers[0].Path)
+	a.Equal(filepath.Join(dir, "20-override.json"), layers[2].Path)
+
+	t.Run("missing dir is a no-op", func(t *testing.T) {
+		cfg, err := Load(Config{Name: "default"}, WithConfigPaths("missing.yaml"),
+			WithConfigPathsDir(filepath.Join(dir, "missing")))
+		require.NoError(t, err)
+		assert.Equal(t, "default", cfg.Name)
+	})
+}
+
+func TestLoadWithMergeFunc(t *testing.T) {
+	type Config struct {
+		Name     string   `json:"name"`
+		MaxConns int      `json:"max_conns"`
+		Tags     []string `json:"tags"`
+	}
+
+	low := writeTempConfig(t, "name: low\nmax_conns: 50\ntags: [a, b]\n")
+	high := writeTempConfig(t, "name: high\nmax_conns: 10\ntags: [b, c]\n")
+
+	var paths []string
+	cfg, err := Load(Config{MaxConns: 1},
+		WithConfigPaths(high, low),
+		WithMergeAllPaths(),
+		WithMergeFunc(func(path string, existing, incoming any) (any, bool) {
+			paths = append(paths, path)
+			switch path {
+			case "max_conns":
+				a, _ := existing.(int)
+				b, _ := incoming.(int)
+
+				return max(a, b), true
+			case "tags":
+				prev, _ := existing.([]any)
+				merged := slices.Clone(prev)
+				for _, tag := range incoming.([]any) {
+					if !slices.Contains(merged, tag) {
+						merged = append(merged, tag)
+					}
+				}
+
+				return merged, true
+			default:
+				return nil, false
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("high", cfg.Name, "default strategy for unhandled paths")
+	a.Equal(50, cfg.MaxConns)
+	a.Equal([]string{"a", "b", "c"}, cfg.Tags)
+	a.Contains(paths, "name")
+}
+
+func TestLoadWithEnvBinding(t *testing.T) {
+	type RedisConfig struct {
+		URL string `json:"url"`
+	}
+	type Config struct {
+		Redis  RedisConfig       `json:"redis"`
+		Port   int               `json:"port"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	t.Setenv("REDIS_URL", "redis://bound")
+	t.Setenv("BINDTEST_REDIS_URL", "redis://prefix")
+	t.Setenv("TEAM_LABEL", "core")
+
+	cfg, bindings, err := LoadWithEnvReport(Config{},
+		WithConfigPaths("nonexistent.yaml"),
+		WithEnvPrefix("BINDTEST_"),
+		WithEnvBinding("REDIS_URL", "redis.url"),
+		WithEnvBinding("TEAM_LABEL", "labels.team"),
+		WithEnvBinding("BINDTEST_UNSET_PORT", "port"),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("redis://bound", cfg.Redis.URL, "explicit binding wins over prefix")
+	a.Equal("core", cfg.Labels["team"])
+	a.Contains(bindings, EnvBindingResult{EnvKey: "REDIS_URL", ConfigPath: "redis.url", Applied: true, Source: EnvSourceBinding})
+	a.Contains(bindings, EnvBindingResult{EnvKey: "BINDTEST_UNSET_PORT", ConfigPath: "port", Source: EnvSourceBinding})
+
+	t.Run("validate rejects unknown path", func(t *testing.T) {
+		_, err := Load(Config{},
+			WithConfigPaths("nonexistent.yaml"),
+			WithEnvBinding("REDIS_URL", "reids.url"),
+			WithEnvBindingsValidate(),
+		)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `env binding REDIS_URL: unknown config path "reids.url"`)
+	})
+
+	t.Run("validate accepts dynamic map paths", func(t *testing.T) {
+		cfg, err := Load(Config{},
+			WithConfigPaths("nonexistent.yaml"),
+			WithEnvBinding("TEAM_LABEL", "labels.team"),
+			WithEnvBinding("REDIS_URL", "redis.url"),
+			WithEnvBindingsValidate(),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "core", cfg.Labels["team"])
+	})
+
+	t.Run("validate rejects struct section", func(t *testing.T) {
+		_, err := Load(Config{},
+			WithConfigPaths("nonexistent.yaml"),
+			WithEnvBinding("REDIS_URL", "redis"),
+			WithEnvBindingsValidate(),
+		)
+		require.Error(t, err)
+	})
+}
+
+func TestLoadReader(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	t.Setenv("READER_NAME", "from-template")
+	t.Setenv("READERTEST_PORT", "9090")
+
+	cfg, err := LoadReader(Config{Port: 80}, strings.NewReader("name: ${READER_NAME}\n"), "yml",
+		WithEnvPrefix("READERTEST_"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-template", cfg.Name)
+	assert.Equal(t, 9090, cfg.Port, "env still overrides the stream")
+
+	t.Run("json", func(t *testing.T) {
+		cfg, err := LoadReader(Config{}, strings.NewReader(`{"name": "json"}`), ".JSON")
+		require.NoError(t, err)
+		assert.Equal(t, "json", cfg.Name)
+	})
+
+	t.Run("replaces file discovery", func(t *testing.T) {
+		path := writeTempConfig(t, "name: from-file\n")
+		cfg, err := LoadReader(Config{}, strings.NewReader("port: 1\n"), "yaml", WithConfigPaths(path))
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Name)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := LoadReader(Config{}, strings.NewReader(""), "toml")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "unsupported config format")
+	})
+
+	t.Run("parse error names the stream", func(t *testing.T) {
+		_, err := LoadReader(Config{}, strings.NewReader("name: [\n"), "yaml")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "<reader>.yaml")
+	})
+}
+
+func TestLoadWithMaxFileSize(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	content := "name: sized\n"
+	path := writeTempConfig(t, content)
+
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithMaxFileSize(int64(len(content))))
+	require.NoError(t, err)
+	assert.Equal(t, "sized", cfg.Name)
+
+	_, err = Load(Config{}, WithConfigPaths(path), WithMaxFileSize(int64(len(content)-1)))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds max size")
+
+	_, err = LoadReader(Config{}, strings.NewReader(content), "yaml", WithMaxFileSize(4))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "exceeds max size")
+}
+
+func TestLoadGzipConfig(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+
+	gz := func(content string) []byte {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		_, err := zw.Write([]byte(content))
+		require.NoError(t, err)
+		require.NoError(t, zw.Close())
+
+		return buf.Bytes()
+	}
+	dir := t.TempDir()
+
+	t.Run("yaml.gz", func(t *testing.T) {
+		t.Setenv("GZIP_NAME", "expanded")
+		path := filepath.Join(dir, "config.yaml.gz")
+		require.NoError(t, os.WriteFile(path, gz("name: ${GZIP_NAME}\nport: 1\n"), 0o600))
+
+		cfg, err := Load(Config{}, WithConfigPaths(path))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "expanded", Port: 1}, *cfg)
+	})
+
+	t.Run("json.gz uses stem format", func(t *testing.T) {
+		path := filepath.Join(dir, "config.json.gz")
+		require.NoError(t, os.WriteFile(path, gz(`{"name": "json", "port": 2}`), 0o600))
+
+		cfg, err := Load(Config{}, WithConfigPaths(path))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "json", Port: 2}, *cfg)
+	})
+
+	t.Run("magic bytes without extension", func(t *testing.T) {
+		cfg, err := LoadReader(Config{}, bytes.NewReader(gz("name: stream\n")), "yaml")
+		require.NoError(t, err)
+		assert.Equal(t, "stream", cfg.Name)
+	})
+
+	t.Run("max size applies to decompressed data", func(t *testing.T) {
+		content := "name: " + strings.Repeat("x", 4096) + "\n"
+		path := filepath.Join(dir, "bomb.yaml.gz")
+		compressed := gz(content)
+		require.NoError(t, os.WriteFile(path, compressed, 0o600))
+
+		_, err := Load(Config{}, WithConfigPaths(path), WithMaxFileSize(int64(len(compressed)+1)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "exceeds max size")
+	})
+
+	t.Run("corrupt gzip", func(t *testing.T) {
+		path := filepath.Join(dir, "broken.yaml.gz")
+		require.NoError(t, os.WriteFile(path, []byte("not gzip"), 0o600))
+
+		_, err := Load(Config{}, WithConfigPaths(path))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "decompress config")
+	})
+}
+
+func TestLoadWithNormalizeKeys(t *testing.T) {
+	type ServerConfig struct {
+		URL  string `json:"url"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		APIKey string       `json:"apiKey"`
+		Server ServerConfig `json:"server"`
+	}
+
+	path := writeTempConfig(t, `
+APIKEY: from-file
+Server:
+  URL: http://file
+server:
+  port: 8080
+`)
+	t.Setenv("NORMKEYS_SERVER_URL", "http://env")
+
+	cfg, warnings, err := LoadWithWarnings(Config{APIKey: "default"},
+		WithConfigPaths(path),
+		WithEnvPrefix("NORMKEYS_"),
+		WithNormalizeKeys(),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("from-file", cfg.APIKey, "mixed-case tag matches normalized key")
+	a.Equal("http://env", cfg.Server.URL, "env still overrides file after normalization")
+	a.Equal(8080, cfg.Server.Port, "case variants of a section are merged")
+	a.Empty(warnings)
+
+	t.Run("collision within a layer", func(t *testing.T) {
+		path := writeTempConfig(t, "Server: {port: 1}\nserver: {port: 2}\n")
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithNormalizeKeys())
+		require.NoError(t, err)
+		assert.Equal(t, 2, cfg.Server.Port, "lowercase key sorts last and wins")
+	})
+
+	t.Run("CLI flags", func(t *testing.T) {
+		flags := []cli.Flag{&cli.StringFlag{Name: "apiKey"}}
+		cfg := runCLITest(t, Config{}, flags, []string{"test", "--apiKey", "from-cli"},
+			WithConfigPaths(path), WithNormalizeKeys())
+		assert.Equal(t, "from-cli", cfg.APIKey)
+	})
+
+	t.Run("loader lookups", func(t *testing.T) {
+		loader, err := NewLoader(Config{}, WithConfigPaths(path), WithNormalizeKeys())
+		require.NoError(t, err)
+		assert.Equal(t, "http://file", loader.GetString("Server.URL"))
+	})
+}
+
+func TestLoadWithEnvBindingList(t *testing.T) {
+	type Server struct {
+		Hosts []string `json:"hosts"`
+		Ports []int    `json:"ports"`
+		Tags  []string `json:"tags"`
+	}
+	type Config struct {
+		Server Server `json:"server"`
+	}
+
+	t.Setenv("EBL_HOSTS", "a.example, b.example,,c.example")
+	t.Setenv("EBL_PORTS", "80;443")
+	t.Setenv("EBL_TAGS", `["x,y"]`)
+
+	cfg, err := Load(Config{Server: Server{Hosts: []string{"default"}}},
+		WithConfigPaths("nonexistent.yaml"),
+		WithEnvBindingList("EBL_HOSTS", "server.hosts", ""),
+		WithEnvBindingList("EBL_PORTS", "server.ports", ";"),
+		WithEnvBindingList("EBL_TAGS", "server.tags", ","),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a.example", "b.example", "c.example"}, cfg.Server.Hosts)
+	assert.Equal(t, []int{80, 443}, cfg.Server.Ports)
+	assert.Equal(t, []string{"x,y"}, cfg.Server.Tags, "JSON arrays are not split")
+}
+
+func TestLoadWithEnvBindingsNamespace(t *testing.T) {
+	type RedisConfig struct {
+		URL string `json:"url"`
+		DB  int    `json:"db"`
+	}
+	type CacheConfig struct {
+		Redis RedisConfig `json:"redis"`
+	}
+	type Config struct {
+		Cache CacheConfig `json:"cache"`
+	}
+	library := map[string]string{"NSTEST_REDIS_URL": "url", "NSTEST_REDIS_DB": "db"}
+
+	t.Setenv("NSTEST_REDIS_URL", "redis://ns")
+	t.Setenv("NSTEST_REDIS_DB", "3")
+
+	cfg, bindings, err := LoadWithEnvReport(Config{},
+		WithConfigPaths("nonexistent.yaml"),
+		WithEnvBindingsNamespace("cache.redis", library),
+		WithEnvBindingsValidate(),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "redis://ns", cfg.Cache.Redis.URL)
+	assert.Equal(t, 3, cfg.Cache.Redis.DB)
+	assert.Contains(t, bindings, EnvBindingResult{EnvKey: "NSTEST_REDIS_URL", ConfigPath: "cache.redis.url", Applied: true, Source: EnvSourceBinding})
+
+	t.Run("custom delimiter declared later", func(t *testing.T) {
+		cfg, err := Load(Config{},
+			WithConfigPaths("nonexistent.yaml"),
+			WithEnvBindingsNamespace("cache/redis", library),
+			WithKeyDelim("/"),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "redis://ns", cfg.Cache.Redis.URL)
+	})
+}
+
+func TestLoadWithEnvBindingsFromFlags(t *testing.T) {
+	type ServerConfig struct {
+		URL string `json:"url"`
+	}
+	type Config struct {
+		Server ServerConfig `json:"server"`
+		Token  string       `json:"token"`
+	}
+	newFlags := func() []cli.Flag {
+		return []cli.Flag{
+			&cli.StringFlag{Name: "server-url", Sources: cli.EnvVars("FLAGBIND_URL", "FLAGBIND_URL_LEGACY")},
+			&cli.StringFlag{Name: "token"},
+		}
+	}
+
+	t.Setenv("FLAGBIND_URL", "http://primary")
+	t.Setenv("FLAGBIND_URL_LEGACY", "http://legacy")
+	t.Setenv("FLAGBINDPFX_SERVER_URL", "http://prefix")
+
+	t.Run("bindings from flag sources", func(t *testing.T) {
+		var bindings []EnvBindingResult
+		cmd := &cli.Command{
+			Name:  "test",
+			Flags: newFlags(),
+			Action: func(_ context.Context, cmd *cli.Command) error {
+				var err error
+				_, bindings, err = LoadWithEnvReport(Config{},
+					WithCommand(cmd),
+					WithConfigPaths("nonexistent.yaml"),
+					WithEnvBindingsFromFlags(),
+				)
+
+				return err
+			},
+		}
+		require.NoError(t, cmd.Run(context.Background(), []string{"test"}))
+		assert.Equal(t, []EnvBindingResult{
+			{EnvKey: "FLAGBIND_URL_LEGACY", ConfigPath: "server.url", Applied: true, Source: EnvSourceFlag},
+			{EnvKey: "FLAGBIND_URL", ConfigPath: "server.url", Applied: true, Source: EnvSourceFlag},
+		}, bindings)
+	})
+
+	t.Run("first declared env wins over prefix", func(t *testing.T) {
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test"},
+			WithEnvPrefix("FLAGBINDPFX_"), WithEnvBindingsFromFlags())
+		assert.Equal(t, "http://primary", cfg.Server.URL)
+	})
+
+	t.Run("explicit binding wins over flag binding", func(t *testing.T) {
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test"},
+			WithEnvBindingsFromFlags(), WithEnvBinding("FLAGBINDPFX_SERVER_URL", "server.url"))
+		assert.Equal(t, "http://prefix", cfg.Server.URL)
+	})
+
+	t.Run("explicit args keep CLI priority", func(t *testing.T) {
+		cfg := runCLITest(t, Config{}, newFlags(), []string{"test", "--server-url", "http://arg"},
+			WithEnvBindingsFromFlags(), WithEnvBinding("FLAGBINDPFX_SERVER_URL", "server.url"))
+		assert.Equal(t, "http://arg", cfg.Server.URL)
+	})
+}
+
+func TestEnvSourcePriority(t *testing.T) {
+	type Config struct {
+		URL string `json:"url" env:"PRIO_TAG_URL,PRIO_TAG_URL_OLD"`
+	}
+	load := func(t *testing.T, opts ...Option) string {
+		t.Helper()
+		cfg, err := Load(Config{}, append([]Option{WithConfigPaths("nonexistent.yaml")}, opts...)...)
+		require.NoError(t, err)
+
+		return cfg.URL
+	}
+
+	t.Run("later prefix wins", func(t *testing.T) {
+		t.Setenv("PRIOA_URL", "a")
+		t.Setenv("PRIOB_URL", "b")
+		assert.Equal(t, "b", load(t, WithEnvPrefixes("PRIOA_", "PRIOB_")))
+		assert.Equal(t, "a", load(t, WithEnvPrefixes("PRIOB_", "PRIOA_")))
+	})
+
+	t.Run("tag over prefix", func(t *testing.T) {
+		t.Setenv("PRIOA_URL", "prefix")
+		t.Setenv("PRIO_TAG_URL", "tag")
+		assert.Equal(t, "tag", load(t, WithEnvPrefix("PRIOA_"), WithEnvBindKey("env")))
+	})
+
+	t.Run("first tag env wins", func(t *testing.T) {
+		t.Setenv("PRIO_TAG_URL", "new")
+		t.Setenv("PRIO_TAG_URL_OLD", "old")
+		assert.Equal(t, "new", load(t, WithEnvBindKey("env")))
+	})
+
+	t.Run("unset tag env falls back", func(t *testing.T) {
+		t.Setenv("PRIO_TAG_URL_OLD", "old")
+		assert.Equal(t, "old", load(t, WithEnvBindKey("env")))
+	})
+
+	t.Run("explicit binding over tag", func(t *testing.T) {
+		t.Setenv("PRIO_TAG_URL", "tag")
+		t.Setenv("PRIO_EXPLICIT_URL", "explicit")
+		assert.Equal(t, "explicit", load(t, WithEnvBindKey("env"), WithEnvBinding("PRIO_EXPLICIT_URL", "url")))
+	})
+
+	t.Run("last registered binding wins", func(t *testing.T) {
+		t.Setenv("PRIO_ONE", "one")
+		t.Setenv("PRIO_TWO", "two")
+		assert.Equal(t, "two", load(t, WithEnvBinding("PRIO_ONE", "url"), WithEnvBinding("PRIO_TWO", "url")))
+		assert.Equal(t, "one", load(t, WithEnvBinding("PRIO_TWO", "url"), WithEnvBinding("PRIO_ONE", "url")))
+	})
+
+	t.Run("set binding wins over unset later binding", func(t *testing.T) {
+		t.Setenv("PRIO_ONE", "one")
+		assert.Equal(t, "one", load(t, WithEnvBinding("PRIO_ONE", "url"), WithEnvBinding("PRIO_UNSET", "url")))
+	})
+
+	t.Run("report order matches application order", func(t *testing.T) {
+		_, bindings, err := LoadWithEnvReport(Config{},
+			WithConfigPaths("nonexistent.yaml"),
+			WithEnvPrefix("PRIOA_"),
+			WithEnvBindKey("env"),
+			WithEnvBinding("PRIO_ONE", "url"),
+		)
+		require.NoError(t, err)
+		sources := make([]EnvSource, 0, len(bindings))
+		for _, b := range bindings {
+			sources = append(sources, b.Source)
+		}
+		assert.Equal(t, []EnvSource{EnvSourcePrefix, EnvSourceTag, EnvSourceTag, EnvSourceBinding}, sources)
+	})
+}
+
+func TestLoadWithEmbeddedDefault(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+
+	fsys := fstest.MapFS{
+		"defaults/config.yaml": {Data: []byte("name: embedded\nport: 8080\ndebug: true\n")},
+	}
+	override := writeTempConfig(t, "port: 9090\n")
+
+	cfg, err := Load(Config{Name: "struct"},
+		WithConfigPaths(override),
+		WithEmbeddedDefault(fsys, "defaults/config.yaml"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "embedded", Port: 9090, Debug: true}, *cfg)
+
+	t.Run("without on-disk config", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths("nonexistent.yaml"),
+			WithEmbeddedDefault(fsys, "defaults/config.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, 8080, cfg.Port)
+	})
+
+	t.Run("missing embedded file", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths("nonexistent.yaml"),
+			WithEmbeddedDefault(fsys, "defaults/missing.yaml"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "embedded config defaults/missing.yaml")
+	})
+
+	t.Run("parsed layer reused across loads", func(t *testing.T) {
+		type Server struct {
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		}
+		type Nested struct {
+			Server Server `json:"server"`
+		}
+		content := []byte("server:\n  host: cached-host\n  port: 1\n")
+		nested := fstest.MapFS{"nested.yaml": {Data: content}}
+		disk := writeTempConfig(t, "server:\n  port: 2\n")
+
+		cfg, err := Load(Nested{}, WithConfigPaths(disk), WithEmbeddedDefault(nested, "nested.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, Server{Host: "cached-host", Port: 2}, cfg.Server)
+
+		key := parseCacheKey{sum: sha256.Sum256(content), format: formatYAML}
+		parsedLayers.mu.Lock()
+		_, ok := parsedLayers.entries[key]
+		parsedLayers.mu.Unlock()
+		assert.True(t, ok, "embedded layer cached")
+
+		cfg, err = Load(Nested{}, WithConfigPaths("nonexistent.yaml"), WithEmbeddedDefault(nested, "nested.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, Server{Host: "cached-host", Port: 1}, cfg.Server, "merge did not modify the cached layer")
+	})
+}
+
+func BenchmarkLoadEmbeddedDefault(b *testing.B) {
+	type Config struct {
+		Items map[string]map[string]string `json:"items"`
+	}
+	var content strings.Builder
+	content.WriteString("items:\n")
+	for i := range 200 {
+		fmt.Fprintf(&content, "  item%d:\n    name: item-%d\n    value: \"%d\"\n", i, i, i)
+	}
+	fsys := fstest.MapFS{"config.yaml": {Data: []byte(content.String())}}
+	path := filepath.Join(b.TempDir(), "config.yaml")
+	require.NoError(b, os.WriteFile(path, []byte(content.String()), 0o600))
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"file", []Option{WithConfigPaths(path)}},
+		{"embedded", []Option{WithConfigPaths("nonexistent.yaml"), WithEmbeddedDefault(fsys, "config.yaml")}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := Load(Config{}, bc.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWithTrimWhitespace(t *testing.T) {
+	type Config struct {
+		URL   string   `json:"url"`
+		Notes string   `json:"notes"`
+		Hosts []string `json:"hosts"`
+	}
+
+	path := writeTempConfig(t, `
+url: |
+  http://example.com
+notes: "  keep me  "
+hosts: [" a ", "b "]
+`)
+
+	t.Run("selected keys", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithTrimWhitespace("url", "hosts", "missing"))
+		require.NoError(t, err)
+		assert.Equal(t, "http://example.com", cfg.URL)
+		assert.Equal(t, []string{"a", "b"}, cfg.Hosts)
+		assert.Equal(t, "  keep me  ", cfg.Notes, "other keys untouched")
+	})
+
+	t.Run("all strings", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithTrimAllStrings())
+		require.NoError(t, err)
+		assert.Equal(t, Config{URL: "http://example.com", Notes: "keep me", Hosts: []string{"a", "b"}}, *cfg)
+	})
+
+	t.Run("default keeps whitespace", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path))
+		require.NoError(t, err)
+		assert.Equal(t, "http://example.com\n", cfg.URL)
+	})
+}
+
+func TestEnvBindingsOverrideFile(t *testing.T) {
+	type Config struct {
+		URL string `json:"url" env:"OVERRIDE_TAG_URL"`
+	}
+	path := writeTempConfig(t, "url: http://file\n")
+
+	tests := []struct {
+		name string
+		env  map[string]string
+		opts []Option
+		want string
+	}{
+		{
+			name: "explicit binding beats file value",
+			env:  map[string]string{"OVERRIDE_URL": "http://env"},
+			opts: []Option{WithEnvBinding("OVERRIDE_URL", "url")},
+			want: "http://env",
+		},
+		{
+			name: "tag binding beats file value",
+			env:  map[string]string{"OVERRIDE_TAG_URL": "http://tag"},
+			opts: []Option{WithEnvBindKey("env")},
+			want: "http://tag",
+		},
+		{
+			name: "prefix binding beats file value",
+			env:  map[string]string{"OVERRIDEPFX_URL": "http://prefix"},
+			opts: []Option{WithEnvPrefix("OVERRIDEPFX_")},
+			want: "http://prefix",
+		},
+		{
+			name: "unset binding keeps file value",
+			opts: []Option{WithEnvBinding("OVERRIDE_URL", "url"), WithEnvBindKey("env")},
+			want: "http://file",
+		},
+		{
+			name: "empty binding keeps file value",
+			env:  map[string]string{"OVERRIDE_URL": ""},
+			opts: []Option{WithEnvBinding("OVERRIDE_URL", "url")},
+			want: "http://file",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, val := range tt.env {
+				t.Setenv(key, val)
+			}
+			cfg, err := Load(Config{}, append([]Option{WithConfigPaths(path)}, tt.opts...)...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.URL)
+		})
+	}
+}
+
+func TestLoadWithConfigPathGlob(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+
+	baseDir := t.TempDir()
+	confDir := filepath.Join(baseDir, "conf.d")
+	require.NoError(t, os.Mkdir(confDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "10-a.yaml"), []byte("name: a\nport: 1\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "20-b.yaml"), []byte("port: 2\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(confDir, "30-c.json"), []byte(`{"debug": true}`), 0o600))
+	fallback := filepath.Join(baseDir, "fallback.yaml")
+	require.NoError(t, os.WriteFile(fallback, []byte("name: fallback\n"), 0o600))
+
+	t.Run("matches merge in sorted order", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithBaseDir(baseDir), WithConfigPaths("conf.d/*.yaml", "fallback.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "a", Port: 2}, *cfg, "glob hit stops the search")
+	})
+
+	t.Run("merge all keeps path priority", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithBaseDir(baseDir), WithConfigPaths("fallback.yaml", "conf.d/*.yaml"),
+			WithMergeAllPaths())
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "fallback", Port: 2}, *cfg)
+	})
+
+	t.Run("no match is a soft skip", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithBaseDir(baseDir), WithConfigPaths("conf.d/*.toml", "fallback.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "fallback", cfg.Name)
+	})
+
+	t.Run("no match fails fast", func(t *testing.T) {
+		_, err := Load(Config{}, WithBaseDir(baseDir), WithConfigPaths("conf.d/*.toml"), WithFailFastPaths())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "no files matched")
+	})
+	t.Run("multi-level matches sorted byte-wise", func(t *testing.T) {
+		dir := t.TempDir()
+		for sub, name := range map[string]string{"a": "plain", "a-b": "dashed"} {
+			require.NoError(t, os.Mkdir(filepath.Join(dir, sub), 0o750))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, sub, "x.yaml"), []byte("name: "+name+"\n"), 0o600))
+		}
+		pattern := filepath.Join(dir, "*", "x.yaml")
+		raw, err := filepath.Glob(pattern)
+		require.NoError(t, err)
+		require.False(t, slices.IsSorted(raw), "filepath.Glob groups matches by directory")
+
+		assert.Equal(t, []string{filepath.Join(dir, "a-b", "x.yaml"), filepath.Join(dir, "a", "x.yaml")}, expandConfigPath(pattern))
+		cfg, err := Load(Config{}, WithConfigPaths(pattern))
+		require.NoError(t, err)
+		assert.Equal(t, "plain", cfg.Name, "a/x.yaml sorts last")
+	})
+}
+
+func TestLoadWithFlatEnvKeys(t *testing.T) {
+	type Server struct {
+		URL     string        `json:"url"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	type Config struct {
+		Name   string `json:"name"`
+		Server Server `json:"server"`
+	}
+	path := writeTempConfig(t, "name: file\nserver:\n  url: http://file\n  timeout: 1s\n")
+
+	tests := []struct {
+		name   string
+		value  string
+		opts   []Option
+		want   Config
+		errMsg string
+	}{
+		{
+			name:  "key value lines",
+			value: "# injected\nserver.url=http://env\n\nserver.timeout = 5s\n",
+			want:  Config{Name: "file", Server: Server{URL: "http://env", Timeout: 5 * time.Second}},
+		},
+		{
+			name:  "json object",
+			value: `{"server": {"url": "http://json"}}`,
+			want:  Config{Name: "file", Server: Server{URL: "http://json", Timeout: time.Second}},
+		},
+		{
+			name:  "prefix binding wins",
+			value: "server.url=http://flat",
+			opts:  []Option{WithEnvPrefix("FLATPFX_")},
+			want:  Config{Name: "file", Server: Server{URL: "http://prefix", Timeout: time.Second}},
+		},
+		{
+			name:  "empty keeps file",
+			value: "",
+			want:  Config{Name: "file", Server: Server{URL: "http://file", Timeout: time.Second}},
+		},
+		{
+			name:   "invalid line names env var",
+			value:  "server.url",
+			errMsg: "env FLAT_CONFIG: line 1",
+		},
+		{
+			name:   "invalid json names env var",
+			value:  `{"server": }`,
+			errMsg: "env FLAT_CONFIG: parse json",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("FLAT_CONFIG", tt.value)
+			t.Setenv("FLATPFX_SERVER_URL", "http://prefix")
+			opts := append([]Option{WithConfigPaths(path), WithFlatEnvKeys("FLAT_CONFIG")}, tt.opts...)
+			cfg, err := Load(Config{}, opts...)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *cfg)
+		})
+	}
+}
+
+func TestLoadWithSchema(t *testing.T) {
+	type Server struct {
+		Port    int           `json:"port"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	type Config struct {
+		Level  string   `json:"level"`
+		Tags   []string `json:"tags"`
+		Server Server   `json:"server"`
+	}
+	schema := []byte(`{
+		"type": "object",
+		"properties": {
+			"level": {"type": "string", "enum": ["debug", "info", "error"]},
+			"tags": {"type": "array", "maxItems": 2, "items": {"type": "string", "pattern": "^[a-z]+$"}},
+			"server": {
+				"type": "object",
+				"additionalProperties": false,
+				"properties": {
+					"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+					"timeout": {"type": "string", "pattern": "^[0-9]+(ms|s|m)$"}
+				}
+			}
+		}
+	}`)
+	defaults := Config{Level: "info", Server: Server{Port: 8080, Timeout: 5 * time.Second}}
+
+	tests := []struct {
+		name    string
+		content string
+		env     map[string]string
+		errMsgs []string
+	}{
+		{
+			name:    "valid file",
+			content: "level: debug\ntags: [a, b]\nserver:\n  port: 9000\n  timeout: 10s\n",
+		},
+		{
+			name: "defaults only",
+		},
+		{
+			name: "env string coerced to integer",
+			env:  map[string]string{"SCHEMA_SERVER_PORT": "443"},
+		},
+		{
+			name:    "enum and range",
+			content: "level: trace\nserver:\n  port: 70000\n",
+			errMsgs: []string{`level: value "trace" is not one of`, "server.port: must be <= 65535"},
+		},
+		{
+			name:    "items and additional keys",
+			content: "tags: [a, B, c]\nserver:\n  extra: 1\n",
+			errMsgs: []string{"tags: expected at most 2 items", `tags.1: value "B" does not match pattern`, "server.extra: additional key is not allowed"},
+		},
+		{
+			name:    "type mismatch from env",
+			env:     map[string]string{"SCHEMA_SERVER_PORT": "http"},
+			errMsgs: []string{"server.port: expected integer, got string"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for key, val := range tt.env {
+				t.Setenv(key, val)
+			}
+			opts := []Option{WithSchema(schema), WithEnvPrefix("SCHEMA_")}
+			if tt.content != "" {
+				opts = append(opts, WithConfigPaths(writeTempConfig(t, tt.content)))
+			} else {
+				opts = append(opts, WithConfigPaths(filepath.Join(t.TempDir(), "missing.yaml")))
+			}
+			_, err := Load(defaults, opts...)
+			if len(tt.errMsgs) == 0 {
+				require.NoError(t, err)
+
+				return
+			}
+			require.Error(t, err)
+			for _, msg := range tt.errMsgs {
+				assert.Contains(t, err.Error(), msg)
+			}
+			var schemaErr *SchemaError
+			assert.ErrorAs(t, err, &schemaErr)
+		})
+	}
+
+	t.Run("invalid schema", func(t *testing.T) {
+		_, err := Load(defaults, WithSchema([]byte(`{"pattern": "("}`)))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid schema")
+	})
+}
+
+func TestGenerateSchema(t *testing.T) {
+	type Server struct {
+		URL     string        `json:"url"     desc:"服务地址"`
+		Timeout time.Duration `json:"timeout" desc:"超时时间"`
+	}
+	type Config struct {
+		Name    string            `json:"name"  desc:"应用名称"`
+		Port    uint16            `json:"port"`
+		Ratio   float64           `json:"ratio"`
+		Debug   bool              `json:"debug"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]int    `json:"labels"`
+		Server  Server            `json:"server"`
+		Backup  *Server           `json:"backup"`
+		Extra   any               `json:"extra"`
+		Started time.Time         `json:"started"`
+		Ignored string            `json:"-"`
+		Headers map[string]string `json:"headers"`
+	}
+	defaults := Config{
+		Name:   "app",
+		Port:   8080,
+		Tags:   []string{"a"},
+		Server: Server{URL: "http://localhost", Timeout: 5 * time.Second},
+	}
+
+	data, err := GenerateSchema(defaults)
+	require.NoError(t, err)
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, schemaDraft, doc["$schema"])
+	assert.Equal(t, "object", doc["type"])
+	assert.Equal(t, false, doc["additionalProperties"])
+
+	props := doc["properties"].(map[string]any)
+	assert.ElementsMatch(t,
+		[]string{"name", "port", "ratio", "debug", "tags", "labels", "server", "backup", "extra", "started", "headers"},
+		slices.Collect(maps.Keys(props)))
+	assert.Equal(t, map[string]any{"type": "string", "description": "应用名称", "default": "app"}, props["name"])
+	assert.Equal(t, map[string]any{"type": "integer", "minimum": float64(0), "default": float64(8080)}, props["port"])
+	assert.Equal(t, map[string]any{"type": "number", "default": float64(0)}, props["ratio"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"type": "string"}, "default": []any{"a"}}, props["tags"])
+	assert.Equal(t, map[string]any{"type": "object", "additionalProperties": map[string]any{"type": "integer"}}, props["labels"])
+	assert.Equal(t, map[string]any{}, props["extra"])
+	assert.Equal(t, "date-time", props["started"].(map[string]any)["format"])
+
+	server := props["server"].(map[string]any)
+	serverProps := server["properties"].(map[string]any)
+	assert.Equal(t, map[string]any{"type": "string", "pattern": durationPattern, "description": "超时时间", "default": "5s"},
+		serverProps["timeout"])
+	assert.Equal(t, "http://localhost", serverProps["url"].(map[string]any)["default"])
+
+	backup := props["backup"].(map[string]any)
+	assert.Equal(t, "object", backup["type"], "nil pointer still describes the struct")
+	assert.NotContains(t, backup["properties"].(map[string]any)["url"], "default")
+
+	t.Run("usable with WithSchema", func(t *testing.T) {
+		path := writeTempConfig(t, "name: svc\nserver:\n  timeout: 10s\n")
+		_, err := Load(defaults, WithConfigPaths(path), WithSchema(data))
+		require.NoError(t, err)
+
+		path = writeTempConfig(t, "server:\n  timeout: soon\n  typo: 1\n")
+		_, err = Load(defaults, WithConfigPaths(path), WithSchema(data))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "server.timeout: value \"soon\" does not match pattern")
+		assert.Contains(t, err.Error(), "server.typo: additional key is not allowed")
+	})
+
+	t.Run("non struct", func(t *testing.T) {
+		_, err := GenerateSchema(42)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "is not a struct")
+	})
+}
+
+func TestLoadWithConfigPathsRelativeToExecutable(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	exeDir, err := executableDir()
+	require.NoError(t, err)
+	name := filepath.Base(t.TempDir()) + ".yaml"
+	path := filepath.Join(exeDir, name)
+	require.NoError(t, os.WriteFile(path, []byte("name: beside-binary\n"), 0o600))
+	t.Cleanup(func() { _ = os.Remove(path) })
+
+	otherDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(otherDir, name), []byte("name: base-dir\n"), 0o600))
+
+	t.Run("resolves next to executable", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(name), WithConfigPathsRelativeToExecutable())
+		require.NoError(t, err)
+		assert.Equal(t, "beside-binary", cfg.Name)
+	})
+
+	t.Run("takes precedence over WithBaseDir", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPathsRelativeToExecutable(), WithBaseDir(otherDir), WithConfigPaths(name))
+		require.NoError(t, err)
+		assert.Equal(t, "beside-binary", cfg.Name)
+	})
+
+	t.Run("absolute paths unaffected", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(filepath.Join(otherDir, name)), WithConfigPathsRelativeToExecutable())
+		require.NoError(t, err)
+		assert.Equal(t, "base-dir", cfg.Name)
+	})
+}
+
+func TestLoadWithForcedValues(t *testing.T) {
+	type Server struct {
+		URL  string `json:"url"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Debug  bool   `json:"debug"`
+		Server Server `json:"server"`
+	}
+
+	t.Setenv("FORCED_SERVER_PORT", "9000")
+	path := writeTempConfig(t, "debug: false\nserver:\n  url: http://file\n  port: 1\n")
+	forced := map[string]any{
+		"server":     map[string]any{"port": 7000},
+		"server.url": "http://forced",
+		"debug":      "true",
+	}
+
+	var cfg *Config
+	cmd := &cli.Command{
+		Name: "test",
+		Flags: []cli.Flag{
+			&cli.StringFlag{Name: "server-url"},
+			&cli.IntFlag{Name: "server-port"},
+		},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			var err error
+			cfg, err = Load(Config{},
+				WithConfigPaths(path),
+				WithEnvPrefix("FORCED_"),
+				WithCommand(cmd),
+				WithForcedValues(forced),
+			)
+
+			return err
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background(), []string{"test", "--server-url", "http://cli", "--server-port", "8000"}))
+	assert.Equal(t, Config{Debug: true, Server: Server{URL: "http://forced", Port: 7000}}, *cfg)
+
+	t.Run("copied at option time", func(t *testing.T) {
+		values := map[string]any{"server": map[string]any{"url": "http://before"}}
+		opt := WithForcedValues(values)
+		values["server"].(map[string]any)["url"] = "http://after"
+
+		cfg, err := Load(Config{}, WithConfigPaths(path), opt)
+		require.NoError(t, err)
+		assert.Equal(t, "http://before", cfg.Server.URL)
+	})
+
+	t.Run("unknown keys warn", func(t *testing.T) {
+		_, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(path),
+			WithForcedValues(map[string]any{"server.typo": 1}))
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "server.typo", warnings[0].Path)
+		assert.Contains(t, warnings[0].Message, "forced values")
+	})
+
+	t.Run("later call wins", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path),
+			WithForcedValues(map[string]any{"server.port": 1}),
+			WithForcedValues(map[string]any{"server.port": 2}))
+		require.NoError(t, err)
+		assert.Equal(t, 2, cfg.Server.Port)
+	})
+}
+
+func TestLoadWithTemplateData(t *testing.T) {
+	type Config struct {
+		Cluster  string `json:"cluster"`
+		Replicas int    `json:"replicas"`
+		Region   string `json:"region"`
+	}
+	t.Setenv("TPLDATA_REGION", "env-region")
+	t.Setenv("TPLDATA_CLUSTER", "env-cluster")
+	path := writeTempConfig(t, "cluster: ${TPLDATA_CLUSTER}\nreplicas: ${TPLDATA_REPLICAS:-1}\nregion: ${TPLDATA_REGION}\n")
+
+	cfg, err := Load(Config{}, WithConfigPaths(path),
+		WithTemplateData(map[string]any{"TPLDATA_CLUSTER": "prod", "TPLDATA_REPLICAS": 3}))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Cluster: "prod", Replicas: 3, Region: "env-region"}, *cfg, "data wins over env, env still available")
+}
+
+func TestLoadWithEnvSnapshot(t *testing.T) {
+	type Config struct {
+		Name   string `json:"name"`
+		URL    string `json:"url"`
+		Region string `json:"region"`
+	}
+	t.Setenv("SNAP_NAME", "process")
+	t.Setenv("SNAP_REGION", "process-region")
+	t.Setenv("SNAP_FLAT", "url=http://process")
+	path := writeTempConfig(t, "region: ${SNAP_REGION:-none}\n")
+	opts := []Option{WithConfigPaths(path), WithEnvPrefix("SNAP_"), WithEnvBinding("SNAP_URL", "url")}
+
+	env := map[string]string{"SNAP_NAME": "snapshot", "SNAP_REGION": "snapshot-region", "SNAP_URL": "http://snapshot"}
+	cfg, err := Load(Config{}, append(opts, WithEnvSnapshot(env))...)
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "snapshot", URL: "http://snapshot", Region: "snapshot-region"}, *cfg)
+
+	env["SNAP_NAME"] = "mutated"
+	cfg, err = Load(Config{}, append(opts, WithEnvSnapshot(nil))...)
+	require.NoError(t, err)
+	assert.Equal(t, Config{Region: "none"}, *cfg, "nil snapshot ignores the process environment")
+
+	cfg, err = Load(Config{}, WithFlatEnvKeys("SNAP_FLAT"), WithConfigPaths(path),
+		WithEnvSnapshot(map[string]string{"SNAP_FLAT": "url=http://flat"}))
+	require.NoError(t, err)
+	assert.Equal(t, "http://flat", cfg.URL)
+}
+
+func TestLoadWithOnValueSet(t *testing.T) {
+	type Server struct {
+		URL  string `json:"url"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Name   string `json:"name"`
+		Server Server `json:"server"`
+	}
+	t.Setenv("AUDIT_SERVER_PORT", "9000")
+	path := writeTempConfig(t, "name: file\nserver:\n  url: http://file\n")
+
+	type write struct {
+		source, path string
+		value        any
+	}
+	var writes []write
+	var cfg *Config
+	cmd := &cli.Command{
+		Name:  "test",
+		Flags: []cli.Flag{&cli.StringFlag{Name: "server-url"}},
+		Action: func(_ context.Context, cmd *cli.Command) error {
+			var err error
+			cfg, err = Load(Config{Name: "default"},
+				WithConfigPaths(path),
+				WithEnvPrefix("AUDIT_"),
+				WithCommand(cmd),
+				WithForcedValues(map[string]any{"name": "forced"}),
+				WithOnValueSet(func(source, path string, value any) {
+					writes = append(writes, write{source, path, value})
+				}),
+			)
+
+			return err
+		},
+	}
+	require.NoError(t, cmd.Run(context.Background(), []string{"test", "--server-url", "http://cli"}))
+
+	assert.Equal(t, Config{Name: "forced", Server: Server{URL: "http://cli", Port: 9000}}, *cfg)
+	assert.Equal(t, []write{
+		{"default", "name", "default"},
+		{"default", "server.port", 0},
+		{"default", "server.url", ""},
+		{path, "name", "file"},
+		{path, "server.url", "http://file"},
+		{"env AUDIT_SERVER_PORT", "server.port", "9000"},
+		{"cli", "server.url", "http://cli"},
+		{"forced values", "name", "forced"},
+	}, writes)
+}
+
+func TestParseByteSize(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    uint64
+		wantErr bool
+	}{
+		{in: "512", want: 512},
+		{in: "10B", want: 10},
+		{in: "10KB", want: 10_000},
+		{in: "10kb", want: 10_000},
+		{in: "10MB", want: 10_000_000},
+		{in: "1.5 GB", want: 1_500_000_000},
+		{in: "512KiB", want: 512 << 10},
+		{in: "10MiB", want: 10 << 20},
+		{in: "2gib", want: 2 << 30},
+		{in: "1TiB", want: 1 << 40},
+		{in: "", wantErr: true},
+		{in: "MB", wantErr: true},
+		{in: "10XB", wantErr: true},
+		{in: "-1MB", wantErr: true},
+		{in: "1.2.3MB", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseByteSize(tt.in)
+			if tt.wantErr {
+				require.Error(t, err)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadWithHumanizedValues(t *testing.T) {
+	type Config struct {
+		MaxBody  int64         `json:"max_body"`
+		Cache    uint64        `json:"cache"`
+		Small    int8          `json:"small"`
+		Retries  int           `json:"retries"`
+		Timeout  time.Duration `json:"timeout"`
+		Interval time.Duration `json:"interval"`
+	}
+	path := writeTempConfig(t, "max_body: 10MB\ncache: 2GiB\nretries: \"3\"\ntimeout: 30s\ninterval: 5m\n")
+
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithHumanizedValues())
+	require.NoError(t, err)
+	assert.Equal(t, Config{MaxBody: 10_000_000, Cache: 2 << 30, Retries: 3, Timeout: 30 * time.Second, Interval: 5 * time.Minute}, *cfg)
+
+	t.Run("disabled by default", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path))
+		require.Error(t, err)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(writeTempConfig(t, "small: 1KB\n")), WithHumanizedValues())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "overflows int8")
+	})
+
+	t.Run("strict env types accept byte sizes", func(t *testing.T) {
+		t.Setenv("HUMAN_MAX_BODY", "1MiB")
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithHumanizedValues(), WithEnvPrefix("HUMAN_"), WithStrictEnvTypes())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1<<20), cfg.MaxBody)
+	})
+}
+
+func TestLoadWithEnvValueTransform(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		URL   string `json:"url"   env:"XFORM_TAG_URL"`
+		Port  int    `json:"port"`
+		Empty string `json:"empty"`
+	}
+	t.Setenv("XFORM_NAME", `"quoted"`)
+	t.Setenv("XFORM_PORT", `'8080'`)
+	t.Setenv("XFORM_TAG_URL", `"http://tag"`)
+	t.Setenv("XFORM_EMPTY", `""`)
+	path := writeTempConfig(t, "empty: file\n")
+
+	var seen []string
+	trimQuotes := func(envKey, raw string) (string, error) {
+		seen = append(seen, envKey)
+
+		return strings.Trim(raw, `"'`), nil
+	}
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithEnvPrefix("XFORM_"), WithEnvBindKey("env"),
+		WithEnvValueTransform(trimQuotes), WithStrictEnvTypes())
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "quoted", URL: "http://tag", Port: 8080, Empty: "file"}, *cfg)
+	assert.Subset(t, seen, []string{"XFORM_NAME", "XFORM_PORT", "XFORM_TAG_URL", "XFORM_EMPTY"})
+	assert.NotContains(t, seen, "XFORM_URL", "unset variables are not transformed")
+
+	t.Run("error names env var", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path), WithEnvBinding("XFORM_NAME", "name"),
+			WithEnvValueTransform(func(string, string) (string, error) { return "", errors.New("bad value") }))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "env XFORM_NAME: transform value: bad value")
+	})
+}
+
+func TestLoadWithYAMLInclude(t *testing.T) {
+	type Server struct {
+		URL  string `json:"url"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Name    string   `json:"name"`
+		Server  Server   `json:"server"`
+		Users   []string `json:"users"`
+		Secret  string   `json:"secret"`
+		Backups []Server `json:"backups"`
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+	t.Setenv("INCLUDE_PORT", "8443")
+	write("parts/server.yaml", "url: http://included\nport: ${INCLUDE_PORT}\n")
+	write("parts/users.json", `["alice", "bob"]`)
+	write("parts/backups.yaml", "- !include server.yaml\n- {url: http://backup, port: 1}\n")
+	path := write("config.yaml", "name: main\nserver: !include parts/server.yaml\nusers: !include parts/users.json\n"+
+		"backups: !include parts/backups.yaml\nsecret: '${INCLUDE_SECRET:-s}'\n")
+
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithYAMLInclude(), WithLazyKeys("secret"))
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Name:    "main",
+		Server:  Server{URL: "http://included", Port: 8443},
+		Users:   []string{"alice", "bob"},
+		Secret:  "${INCLUDE_SECRET:-s}",
+		Backups: []Server{{URL: "http://included", Port: 8443}, {URL: "http://backup", Port: 1}},
+	}, *cfg)
+
+	t.Run("disabled by default", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(write("plain.yaml", "name: !include parts/users.json\n")))
+		require.NoError(t, err)
+		assert.Equal(t, "parts/users.json", cfg.Name)
+	})
+
+	t.Run("cycle", func(t *testing.T) {
+		write("cycle/a.yaml", "b: !include b.yaml\n")
+		write("cycle/b.yaml", "a: !include a.yaml\n")
+		_, err := Load(map[string]any{}, WithConfigPaths(filepath.Join(dir, "cycle/a.yaml")), WithYAMLInclude())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cycle detected")
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(write("missing.yaml", "server: !include nope.yaml\n")), WithYAMLInclude())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "include nope.yaml")
+	})
+
+	t.Run("reader resolves against base dir", func(t *testing.T) {
+		cfg, err := LoadReader(Config{}, strings.NewReader("server: !include parts/server.yaml\n"), "yaml",
+			WithBaseDir(dir), WithYAMLInclude())
+		require.NoError(t, err)
+		assert.Equal(t, "http://included", cfg.Server.URL)
+	})
+}
+
+func TestLoadWithConfigPathsStopOnError(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	dir := t.TempDir()
+	corrupt := filepath.Join(dir, "corrupt.yaml")
+	require.NoError(t, os.WriteFile(corrupt, []byte("name: [unclosed\n"), 0o600))
+	valid := filepath.Join(dir, "valid.yaml")
+	require.NoError(t, os.WriteFile(valid, []byte("name: valid\n"), 0o600))
+	missing := filepath.Join(dir, "missing.yaml")
+
+	tests := []struct {
+		name    string
+		paths   []string
+		opts    []Option
+		want    string
+		errMsg  string
+		warning bool
+	}{
+		{name: "default skips missing", paths: []string{missing, valid}, want: "valid"},
+		{name: "default fails on parse", paths: []string{corrupt, valid}, errMsg: "parse config file"},
+		{
+			name:    "skip parse falls through",
+			paths:   []string{corrupt, valid},
+			opts:    []Option{WithConfigPathsStopOnError(ErrorModeSkip, ErrorModeSkip)},
+			want:    "valid",
+			warning: true,
+		},
+		{
+			name:   "fail missing",
+			paths:  []string{missing, valid},
+			opts:   []Option{WithConfigPathsStopOnError(ErrorModeFail, ErrorModeFail)},
+			errMsg: "missing.yaml",
+		},
+		{
+			name:    "merge all skips corrupt",
+			paths:   []string{valid, corrupt},
+			opts:    []Option{WithMergeAllPaths(), WithConfigPathsStopOnError(0, ErrorModeSkip)},
+			want:    "valid",
+			warning: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, warnings, err := LoadWithWarnings(Config{}, append([]Option{WithConfigPaths(tt.paths...)}, tt.opts...)...)
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.Name)
+			if !tt.warning {
+				assert.Empty(t, warnings)
+
+				return
+			}
+			require.Len(t, warnings, 1)
+			assert.Equal(t, WarnInvalidFile, warnings[0].Code)
+			assert.Equal(t, corrupt, warnings[0].Path)
+		})
+	}
+}
+
+func TestLoadWithoutHomeConfig(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	const app = "cfgm-home-test"
+	require.NoError(t, os.WriteFile(filepath.Join(home, "."+app+".yaml"), []byte("name: home\n"), 0o600))
+	baseDir := t.TempDir()
+
+	tests := []struct {
+		name string
+		opts []Option
+		want string
+	}{
+		{name: "included by default", want: "home"},
+		{name: "excluded", opts: []Option{WithoutHomeConfig()}, want: "default"},
+		{name: "re-included", opts: []Option{WithoutHomeConfig(), WithHomeConfig()}, want: "home"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithAppName(app), WithBaseDir(baseDir)}, tt.opts...)
+			cfg, err := Load(Config{Name: "default"}, opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.Name)
+		})
+	}
+}
+
+type fakeEtcd struct {
+	kvs map[string]string
+	err error
+}
+
+func (f fakeEtcd) GetPrefix(_ context.Context, prefix string) (map[string]string, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make(map[string]string)
+	for key, val := range f.kvs {
+		if strings.HasPrefix(key, prefix) {
+			out[key] = val
+		}
+	}
+
+	return out, nil
+}
+
+func TestLoadWithEtcdConfig(t *testing.T) {
+	type Server struct {
+		URL  string `json:"url"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Name   string `json:"name"`
+		Server Server `json:"server"`
+	}
+	path := writeTempConfig(t, "name: file\nserver:\n  url: http://file\n  port: 1\n")
+	client := fakeEtcd{kvs: map[string]string{
+		"/myapp":             `{"name": "blob", "server": {"port": 2}}`,
+		"/myapp/server/url":  "http://etcd",
+		"/myapp/server/port": "3",
+		"/other/name":        "ignored",
+		"/blob":              "name: yaml-blob\n",
+	}}
+
+	t.Run("flat keys override blob and file", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithEtcdConfig(client, "/myapp"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "blob", Server: Server{URL: "http://etcd", Port: 3}}, *cfg)
+	})
+
+	t.Run("yaml blob", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithEtcdConfig(client, "/blob"))
+		require.NoError(t, err)
+		assert.Equal(t, "yaml-blob", cfg.Name)
+	})
+
+	t.Run("env beats etcd", func(t *testing.T) {
+		t.Setenv("ETCDTEST_SERVER_PORT", "9")
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithEtcdConfig(client, "/myapp/"), WithEnvPrefix("ETCDTEST_"))
+		require.NoError(t, err)
+		assert.Equal(t, 9, cfg.Server.Port)
+		assert.Equal(t, "http://etcd", cfg.Server.URL)
+	})
+
+	t.Run("client error", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path), WithEtcdConfig(fakeEtcd{err: errors.New("permission denied")}, "/myapp"))
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "etcd config /myapp: read prefix: permission denied")
+	})
+}
+
+// fakeObjectStore 以 "bucket/key" 为键保存对象，同时实现 S3Client 与 GCSClient。
+type fakeObjectStore struct {
+	objects map[string]string
+	denied  map[string]bool
+}
+
+func (f fakeObjectStore) GetObject(_ context.Context, bucket, key string) ([]byte, error) {
+	return f.get(bucket + "/" + key)
+}
+
+func (f fakeObjectStore) ReadObject(_ context.Context, bucket, object string) ([]byte, error) {
+	return f.get(bucket + "/" + object)
+}
+
+func (f fakeObjectStore) get(name string) ([]byte, error) {
+	if f.denied[name] {
+		return nil, fmt.Errorf("%w: AccessDenied", fs.ErrPermission)
+	}
+	data, ok := f.objects[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: NoSuchKey", fs.ErrNotExist)
+	}
+
+	return []byte(data), nil
+}
+
+func TestLoadWithObjectStoreConfig(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+		Home  string `json:"home"`
+	}
+	t.Setenv("OBJSTORE_HOME", "/srv")
+	path := writeTempConfig(t, "name: file\nport: 1\n")
+	store := fakeObjectStore{
+		objects: map[string]string{
+			"cfg/app.yaml": "port: 2\nhome: '${OBJSTORE_HOME}'\n",
+			"cfg/app.json": `{"debug": true, "port": 3}`,
+		},
+		denied: map[string]bool{"cfg/secret.yaml": true},
+	}
+
+	t.Run("merged above files in declaration order", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path),
+			WithS3Config(store, "cfg", "app.yaml"), WithGCSConfig(store, "cfg", "app.json"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "file", Port: 3, Debug: true, Home: "/srv"}, *cfg)
+	})
+
+	t.Run("env beats object store", func(t *testing.T) {
+		t.Setenv("OBJSTORE_PORT", "9")
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithS3Config(store, "cfg", "app.yaml"), WithEnvPrefix("OBJSTORE_"))
+		require.NoError(t, err)
+		assert.Equal(t, 9, cfg.Port)
+	})
+
+	t.Run("missing object skipped", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithS3Config(store, "cfg", "missing.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "file", Port: 1}, *cfg)
+
+		_, err = Load(Config{}, WithConfigPaths(path), WithGCSConfig(store, "cfg", "missing.yaml"),
+			WithConfigPathsStopOnError(ErrorModeFail, ErrorModeFail))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+
+	t.Run("permission denied fails", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path), WithS3Config(store, "cfg", "secret.yaml"))
+		require.ErrorIs(t, err, fs.ErrPermission)
+		assert.Contains(t, err.Error(), "s3://cfg/secret.yaml")
+
+		_, err = Load(Config{}, WithConfigPaths(path), WithGCSConfig(store, "cfg", "secret.yaml"))
+		require.ErrorIs(t, err, fs.ErrPermission)
+		assert.Contains(t, err.Error(), "gs://cfg/secret.yaml")
+	})
+
+	t.Run("as config sources", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigSources(S3Source(store, "cfg", "app.yaml"), GCSSource(store, "cfg", "app.json")))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Port: 3, Debug: true, Home: "/srv"}, *cfg)
+	})
+}
+
+func TestLoadWithMapConfig(t *testing.T) {
+	type Cache struct {
+		Size int    `json:"size"`
+		TTL  string `json:"ttl"`
+	}
+	type Config struct {
+		Name  string `json:"name"`
+		Level string `json:"level"`
+		Cache Cache  `json:"cache"`
+	}
+
+	defaults := Config{Name: "default", Level: "info", Cache: Cache{Size: 1, TTL: "1m"}}
+	library := map[string]any{"level": "warn", "cache": map[string]any{"size": 128, "ttl": "5m"}}
+	app := map[string]any{"cache": map[string]any{"ttl": "10m"}}
+
+	t.Run("above defaults in declaration order", func(t *testing.T) {
+		cfg, err := Load(defaults, WithConfigPaths(), WithMapConfig(library), WithMapConfig(app))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "default", Level: "warn", Cache: Cache{Size: 128, TTL: "10m"}}, *cfg)
+	})
+
+	t.Run("below config files and env", func(t *testing.T) {
+		t.Setenv("MAPCFG_CACHE_SIZE", "256")
+		path := writeTempConfig(t, "level: debug\n")
+
+		cfg, err := Load(defaults, WithConfigPaths(path), WithEnvPrefix("MAPCFG_"), WithMapConfig(library))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "default", Level: "debug", Cache: Cache{Size: 256, TTL: "5m"}}, *cfg)
+	})
+
+	t.Run("copied at option time", func(t *testing.T) {
+		values := map[string]any{"cache": map[string]any{"size": 64}}
+		opt := WithMapConfig(values)
+		values["cache"].(map[string]any)["size"] = 32
+
+		cfg, err := Load(defaults, WithConfigPaths(), opt)
+		require.NoError(t, err)
+		assert.Equal(t, 64, cfg.Cache.Size)
+
+		// 合并后的配置不写回选项中保存的 map
+		cfg, err = Load(defaults, WithConfigPaths(), opt, WithMapConfig(map[string]any{"cache": map[string]any{"size": 16}}))
+		require.NoError(t, err)
+		assert.Equal(t, 16, cfg.Cache.Size)
+		cfg, err = Load(defaults, WithConfigPaths(), opt)
+		require.NoError(t, err)
+		assert.Equal(t, 64, cfg.Cache.Size)
+	})
+
+	t.Run("unknown keys warn", func(t *testing.T) {
+		_, warnings, err := LoadWithWarnings(defaults, WithConfigPaths(), WithMapConfig(map[string]any{"cahce": map[string]any{"size": 1}}))
+		require.NoError(t, err)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, "cahce.size", warnings[0].Path)
+	})
+}
+
+func TestLoadWithConfigPathsValidate(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	// unix socket 存在但无法作为文件打开（root 也一样），用于模拟不可读的配置文件
+	dir, err := os.MkdirTemp("", "cfgm")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = os.RemoveAll(dir) })
+	unreadable := filepath.Join(dir, "config.yaml")
+	listener, err := net.Listen("unix", unreadable)
+	if err != nil {
+		t.Skipf("unix socket unavailable: %v", err)
+	}
+	t.Cleanup(func() { _ = listener.Close() })
+	fallback := filepath.Join(dir, "fallback.yaml")
+	require.NoError(t, os.WriteFile(fallback, []byte("name: fallback\n"), 0o600))
+	missing := filepath.Join(dir, "missing.yaml")
+
+	t.Run("default skips unreadable", func(t *testing.T) {
+		cfg, err := Load(Config{Name: "default"}, WithConfigPaths(unreadable, fallback))
+		require.NoError(t, err)
+		assert.Equal(t, "fallback", cfg.Name)
+	})
+
+	t.Run("validate reports path", func(t *testing.T) {
+		_, err := Load(Config{Name: "default"}, WithConfigPaths(unreadable, fallback), WithConfigPathsValidate())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot read "+unreadable+": ")
+		assert.NotContains(t, err.Error(), "open ")
+	})
+
+	t.Run("missing paths are ignored", func(t *testing.T) {
+		cfg, err := Load(Config{Name: "default"}, WithConfigPaths(missing, fallback), WithConfigPathsValidate())
+		require.NoError(t, err)
+		assert.Equal(t, "fallback", cfg.Name)
+	})
+}
+
+func TestExportEnv(t *testing.T) {
+	type Server struct {
+		URL         string        `json:"url"`
+		IdleTimeout time.Duration `json:"idle-timeout"`
+	}
+	type Config struct {
+		Debug   bool              `json:"debug"`
+		Port    int               `json:"port"`
+		Ratio   float64           `json:"ratio"`
+		Server  Server            `json:"server"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels"`
+		Token   SecretRef         `json:"token"`
+		Started time.Time         `json:"started"`
+		Limit   *int              `json:"limit"`
+		Skipped string            `json:"-"`
+	}
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cfg := Config{
+		Debug:   true,
+		Port:    8080,
+		Ratio:   0.5,
+		Server:  Server{URL: "http://a:1", IdleTimeout: 90 * time.Second},
+		Tags:    []string{"a", "b,c"},
+		Labels:  map[string]string{"team": "core"},
+		Token:   NewSecretRef("api-token"),
+		Started: started,
+		Skipped: "x",
+	}
+
+	env := ExportEnv(&cfg, "APP_")
+	assert.Equal(t, []string{
+		"APP_DEBUG=true",
+		"APP_PORT=8080",
+		"APP_RATIO=0.5",
+		"APP_SERVER_URL=http://a:1",
+		"APP_SERVER_IDLE_TIMEOUT=1m30s",
+		`APP_TAGS=["a","b,c"]`,
+		`APP_LABELS={"team":"core"}`,
+		"APP_TOKEN=api-token",
+		"APP_STARTED=2024-05-01T12:00:00Z",
+	}, env)
+
+	snapshot := make(map[string]string, len(env))
+	for _, entry := range env {
+		key, value, _ := strings.Cut(entry, "=")
+		snapshot[key] = value
+	}
+	got, err := Load(Config{}, WithEnvPrefix("APP_"), WithEnvSnapshot(snapshot), WithConfigPaths())
+	require.NoError(t, err)
+	assert.Equal(t, cfg.Server, got.Server)
+	assert.Equal(t, cfg.Tags, got.Tags)
+	assert.Equal(t, cfg.Labels, got.Labels)
+	assert.Equal(t, "api-token", got.Token.Name())
+	assert.True(t, cfg.Started.Equal(got.Started))
+	assert.Equal(t, cfg.Debug, got.Debug)
+	assert.Equal(t, cfg.Port, got.Port)
+	assert.InDelta(t, cfg.Ratio, got.Ratio, 0)
+	assert.Nil(t, got.Limit)
+
+	assert.Nil(t, ExportEnv[Config](nil, "APP_"))
+
+	t.Run("bracketed values", func(t *testing.T) {
+		for _, tc := range []struct {
+			value string
+			want  []string
+		}{
+			{`["a","b,c"]`, []string{"a", "b,c"}},
+			{"[prod]", []string{"[prod]"}},
+			{"[a, b", []string{"[a, b"}},
+			{"[1,2]", []string{"1", "2"}},
+		} {
+			got, err := Load(Config{}, WithEnvPrefix("APP_"), WithEnvSnapshot(map[string]string{"APP_TAGS": tc.value}), WithConfigPaths())
+			require.NoError(t, err, tc.value)
+			assert.Equal(t, tc.want, got.Tags, tc.value)
+		}
+
+		// 不是 JSON 的 {...} 仍按字符串解码，字符串无法转换为 map
+		_, err := Load(Config{}, WithEnvPrefix("APP_"), WithEnvSnapshot(map[string]string{"APP_LABELS": "{team}"}), WithConfigPaths())
+		require.ErrorContains(t, err, "labels")
+	})
+}
+
+func TestResolveConfigPath(t *testing.T) {
+	dir := t.TempDir()
+	configDir := filepath.Join(dir, "config")
+	require.NoError(t, os.MkdirAll(configDir, 0o750))
+	second := filepath.Join(configDir, "config.yaml")
+	require.NoError(t, os.WriteFile(second, []byte("name: second\n"), 0o600))
+
+	t.Run("first existing default path", func(t *testing.T) {
+		path, ok, err := ResolveConfigPath(WithAppName("cfgm-resolve-test"), WithoutHomeConfig(), WithBaseDir(dir))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, second, path)
+	})
+
+	t.Run("earlier candidate wins", func(t *testing.T) {
+		first := filepath.Join(dir, "first.YAML")
+		require.NoError(t, os.WriteFile(first, []byte("name: first\n"), 0o600))
+		path, ok, err := ResolveConfigPath(WithConfigPaths("first.yaml", "config/config.yaml"), WithBaseDir(dir), WithConfigPathsCaseInsensitive())
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, first, path)
+	})
+
+	t.Run("glob", func(t *testing.T) {
+		path, ok, err := ResolveConfigPath(WithConfigPaths("config/*.yaml"), WithBaseDir(dir))
+		require.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, second, path)
+	})
+
+	t.Run("none found", func(t *testing.T) {
+		path, ok, err := ResolveConfigPath(WithConfigPaths("missing.yaml", "config"), WithBaseDir(dir))
+		require.NoError(t, err)
+		assert.False(t, ok)
+		assert.Empty(t, path)
+	})
+
+	t.Run("fail fast", func(t *testing.T) {
+		_, _, err := ResolveConfigPath(WithConfigPaths("missing.yaml"), WithBaseDir(dir), WithFailFastPaths())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "missing.yaml")
+	})
+}
+
+func TestLoadWithRequiredTags(t *testing.T) {
+	type Database struct {
+		Host     string `json:"host"`
+		Password string `json:"password" required:"true"`
+	}
+	type Config struct {
+		URL      string    `json:"url"      required:"true"`
+		Port     int       `json:"port"     required:"true"`
+		Optional string    `json:"optional"`
+		Database Database  `json:"database"`
+		Cache    *Database `json:"cache"`
+	}
+
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+
+	t.Run("disabled by default", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths())
+		require.NoError(t, err)
+	})
+
+	t.Run("all missing reported together", func(t *testing.T) {
+		path := write(t, "database:\n  host: db\n")
+		_, err := Load(Config{}, WithConfigPaths(path), WithRequiredTags())
+		require.Error(t, err)
+		assert.Equal(t, "missing required config: url, port, database.password, cache.password", err.Error())
+	})
+
+	t.Run("satisfied", func(t *testing.T) {
+		path := write(t, "url: http://a\nport: 80\ndatabase:\n  password: p\ncache:\n  password: c\n")
+		cfg, err := Load(Config{}, WithConfigPaths(path), WithRequiredTags())
+		require.NoError(t, err)
+		assert.Equal(t, "http://a", cfg.URL)
+	})
+
+	t.Run("defaults count", func(t *testing.T) {
+		defaults := Config{URL: "http://d", Port: 1, Database: Database{Password: "d"}, Cache: &Database{Password: "d"}}
+		_, err := Load(defaults, WithConfigPaths(), WithRequiredTags())
+		require.NoError(t, err)
+	})
+}
+
+func TestLoadCLIFlagOverwritesSubtree(t *testing.T) {
+	type Config struct {
+		Server string `json:"server"`
+		Name   string `json:"name"`
+	}
+	run := func(t *testing.T, content string, args ...string) (*Config, error) {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		var cfg *Config
+		cmd := &cli.Command{
+			Name:  "test",
+			Flags: []cli.Flag{&cli.StringFlag{Name: "server"}, &cli.StringFlag{Name: "name"}},
+			Action: func(_ context.Context, cmd *cli.Command) error {
+				var err error
+				cfg, err = Load(Config{}, WithConfigPaths(path), WithCommand(cmd))
+
+				return err
+			},
+		}
+		err := cmd.Run(context.Background(), append([]string{"test"}, args...))
+
+		return cfg, err
+	}
+
+	t.Run("flag named like a parent key", func(t *testing.T) {
+		_, err := run(t, "name: file\nserver:\n  host: a\n  port: 1\n", "--server", "x", "--name", "cli")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `cli flag --server would overwrite nested config under "server" (keys: host, port)`)
+	})
+
+	t.Run("leaf flag unaffected", func(t *testing.T) {
+		cfg, err := run(t, "name: file\nserver: s\n", "--server", "x", "--name", "cli")
+		require.NoError(t, err)
+		assert.Equal(t, "x", cfg.Server)
+		assert.Equal(t, "cli", cfg.Name)
+	})
+}
+
+func TestLoadWithK8sConfigMapDir(t *testing.T) {
+	type Database struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Database Database `json:"database"`
+		LogLevel string   `json:"log-level"`
+		Name     string   `json:"name"`
+	}
+
+	// 模拟 projected volume 布局：实际文件位于时间戳目录，..data 与各 key 均为符号链接
+	dir := t.TempDir()
+	dataDir := filepath.Join(dir, "..2024_01_01_00_00_00.000000001")
+	require.NoError(t, os.Mkdir(dataDir, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "database.yaml"), []byte("host: db\nport: 5432\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dataDir, "log-level"), []byte("debug\n"), 0o600))
+	require.NoError(t, os.Symlink(filepath.Base(dataDir), filepath.Join(dir, "..data")))
+	for _, name := range []string{"database.yaml", "log-level"} {
+		require.NoError(t, os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)))
+	}
+
+	cfgPath := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(cfgPath, []byte("name: file\nlog-level: info\ndatabase:\n  port: 3306\n"), 0o600))
+
+	cfg, err := Load(Config{}, WithConfigPaths(cfgPath), WithK8sConfigMapDir(dir))
+	require.NoError(t, err)
+	assert.Equal(t, Database{Host: "db", Port: 5432}, cfg.Database)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, "file", cfg.Name)
+
+	cfg, err = Load(Config{Name: "default"}, WithConfigPaths(), WithK8sConfigMapDir(filepath.Join(dir, "missing")))
+	require.NoError(t, err)
+	assert.Equal(t, "default", cfg.Name)
+}
+
+func TestLoadWithConfigPathsEnvExpand(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	xdg := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(xdg, "myapp"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(xdg, "myapp", "config.yaml"), []byte("name: xdg\n"), 0o600))
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	require.NoError(t, os.WriteFile(filepath.Join(home, ".myapp.yaml"), []byte("name: home\n"), 0o600))
+	baseDir := t.TempDir()
+
+	tests := []struct {
+		name string
+		path string
+		env  map[string]string
+		opts []Option
+		want string
+	}{
+		{name: "literal by default", path: "$XDG_CONFIG_HOME/myapp/config.yaml", env: map[string]string{"XDG_CONFIG_HOME": xdg}, want: "default"},
+		{name: "env var", path: "$XDG_CONFIG_HOME/myapp/config.yaml", env: map[string]string{"XDG_CONFIG_HOME": xdg}, opts: []Option{WithConfigPathsEnvExpand()}, want: "xdg"},
+		{name: "braced env var", path: "${XDG_CONFIG_HOME}/myapp/config.yaml", env: map[string]string{"XDG_CONFIG_HOME": xdg}, opts: []Option{WithConfigPathsEnvExpand()}, want: "xdg"},
+		{name: "tilde", path: "~/.myapp.yaml", opts: []Option{WithConfigPathsEnvExpand()}, want: "home"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithConfigPaths(tt.path), WithBaseDir(baseDir), WithEnvSnapshot(tt.env)}, tt.opts...)
+			cfg, err := Load(Config{Name: "default"}, opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.Name)
+		})
+	}
+}
+
+func TestLoadWithMetrics(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+		Host string `json:"host"`
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: ${APP_NAME:-file}\nport: 1\n"), 0o600))
+	fragments := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(fragments, 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(fragments, "10-host.yaml"), []byte("host: h\n"), 0o600))
+
+	var calls []LoadMetrics
+	opts := []Option{
+		WithConfigPaths(path),
+		WithConfigPathsDir(fragments),
+		WithEnvPrefix("APP_"),
+		WithEnvSnapshot(map[string]string{"APP_PORT": "2"}),
+		WithMetrics(func(m LoadMetrics) { calls = append(calls, m) }),
+	}
+
+	cfg, err := Load(Config{}, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, 2, cfg.Port)
+	require.Len(t, calls, 1)
+	m := calls[0]
+	assert.Equal(t, 2, m.Files)
+	assert.Equal(t, 1, m.EnvApplied)
+	assert.Positive(t, m.Total)
+	assert.Positive(t, m.Read)
+	assert.Positive(t, m.Parse)
+	sum := m.Discovery + m.Read + m.Template + m.Parse + m.Env + m.CLI + m.Unmarshal
+	assert.LessOrEqual(t, sum, m.Total)
+
+	t.Run("reload", func(t *testing.T) {
+		calls = nil
+		loader, err := NewLoader(Config{}, opts...)
+		require.NoError(t, err)
+		require.NoError(t, loader.Reload())
+		assert.Len(t, calls, 2)
+	})
+
+	t.Run("not called on failure", func(t *testing.T) {
+		calls = nil
+		_, err := Load(Config{}, append(opts, WithEnvSnapshot(map[string]string{"APP_PORT": "x"}), WithStrictEnvTypes())...)
+		require.Error(t, err)
+		assert.Empty(t, calls)
+	})
+}
+
+func TestLoadWithEnvBindingsCaseFold(t *testing.T) {
+	type Redis struct {
+		URL string `json:"url"`
+	}
+	type Config struct {
+		Redis Redis `json:"redis"`
+	}
+	env := WithEnvSnapshot(map[string]string{"REDIS_URL": "redis://env"})
+
+	t.Run("warns without folding", func(t *testing.T) {
+		cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(), env, WithEnvBinding("REDIS_URL", "Redis.URL"))
+		require.NoError(t, err)
+		assert.Empty(t, cfg.Redis.URL)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, WarnEnvBindingCase, warnings[0].Code)
+		assert.Equal(t, "Redis.URL", warnings[0].Path)
+		assert.Contains(t, warnings[0].Message, `"redis.url"`)
+	})
+
+	t.Run("folds to canonical key", func(t *testing.T) {
+		cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(), env,
+			WithEnvBinding("REDIS_URL", "Redis.URL"), WithEnvBindingsCaseFold(), WithEnvBindingsValidate())
+		require.NoError(t, err)
+		assert.Equal(t, "redis://env", cfg.Redis.URL)
+		assert.Empty(t, warnings)
+	})
+
+	t.Run("exact paths untouched", func(t *testing.T) {
+		_, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(), env, WithEnvBinding("REDIS_URL", "redis.url"))
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+}
+
+func TestEditFile(t *testing.T) {
+	const original = `# 应用配置
+name: "app" # 应用名称
+
+server:
+  # 监听端口
+  port: 8080
+  host: localhost
+tags: [a, b]
+`
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o640))
+
+		return path
+	}
+
+	t.Run("preserves comments and order", func(t *testing.T) {
+		path := write(t, original)
+		require.NoError(t, EditFile(path, "server.port", 9090))
+		require.NoError(t, EditFile(path, "name", "svc"))
+
+		content, err := os.ReadFile(path)
+		require.NoError(t, err)
+		assert.Equal(t, `# 应用配置
+name: "svc" # 应用名称
+server:
+  # 监听端口
+  port: 9090
+  host: localhost
+tags: [a, b]
+`, string(content))
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		assert.Equal(t, os.FileMode(0o640), info.Mode().Perm())
+	})
+
+	t.Run("creates missing keys", func(t *testing.T) {
+		path := write(t, original)
+		require.NoError(t, EditFile(path, "server.timeout", 90*time.Second))
+		require.NoError(t, EditFile(path, "tls.enabled", true))
+
+		type Config struct {
+			Server struct {
+				Port    int           `json:"port"`
+				Timeout time.Duration `json:"timeout"`
+			} `json:"server"`
+			TLS struct {
+				Enabled bool `json:"enabled"`
+			} `json:"tls"`
+		}
+		cfg, err := Load(Config{}, WithConfigPaths(path))
+		require.NoError(t, err)
+		assert.Equal(t, 8080, cfg.Server.Port)
+		assert.Equal(t, 90*time.Second, cfg.Server.Timeout)
+		assert.True(t, cfg.TLS.Enabled)
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		path := write(t, original)
+		require.ErrorContains(t, EditFile(path, "name.first", "x"), `key "name" is not an object`)
+		require.ErrorContains(t, EditFile(path, "server..port", 1), "invalid key")
+		require.ErrorContains(t, EditFile(filepath.Join(t.TempDir(), "config.json"), "name", "x"), "json files are not supported")
+		require.Error(t, EditFile(filepath.Join(t.TempDir(), "missing.yaml"), "name", "x"))
+	})
+}
+
+func TestLoadWithBaseAndOverlay(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	require.NoError(t, os.WriteFile(base, []byte("name: base\nport: 80\n"), 0o400))
+	overlay := filepath.Join(dir, "overlay.yaml")
+
+	t.Run("overlay missing", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithBaseAndOverlay(base, overlay))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "base", Port: 80}, *cfg)
+	})
+
+	t.Run("overlay wins", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(overlay, []byte("port: 8080\ndebug: true\n"), 0o600))
+		cfg, err := Load(Config{}, WithBaseAndOverlay(base, overlay))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "base", Port: 8080, Debug: true}, *cfg)
+	})
+}
+
+func TestLoadWithDotfileChain(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("USERPROFILE", home)
+	project := t.TempDir()
+	app := "cfgm-dotfile-chain-test"
+
+	t.Run("all scopes missing", func(t *testing.T) {
+		cfg, err := Load(Config{Name: "default"}, WithBaseDir(project), WithDotfileChain(app))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "default"}, *cfg)
+	})
+
+	t.Run("local wins over global", func(t *testing.T) {
+		require.NoError(t, os.WriteFile(filepath.Join(home, "."+app+".yaml"), []byte("name: global\nport: 80\n"), 0o600))
+		cfg, err := Load(Config{}, WithBaseDir(project), WithDotfileChain(app))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "global", Port: 80}, *cfg)
+
+		require.NoError(t, os.WriteFile(filepath.Join(project, "."+app+".yaml"), []byte("port: 8080\ndebug: true\n"), 0o600))
+		cfg, err = Load(Config{}, WithBaseDir(project), WithDotfileChain(app))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "global", Port: 8080, Debug: true}, *cfg)
+	})
+}
+
+func TestLoadWithUnmarshalMode(t *testing.T) {
+	type Config struct {
+		Port    int           `json:"port"`
+		Debug   bool          `json:"debug"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+	quoted := write(t, "port: \"8080\"\ndebug: \"true\"\n")
+	typed := write(t, "port: 8080\ndebug: true\ntimeout: 5s\n")
+
+	t.Run("weak by default", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(quoted))
+		require.NoError(t, err)
+		assert.Equal(t, 8080, cfg.Port)
+		assert.True(t, cfg.Debug)
+
+		cfg, err = Load(Config{}, WithConfigPaths(quoted), WithUnmarshalMode(UnmarshalWeak))
+		require.NoError(t, err)
+		assert.Equal(t, 8080, cfg.Port)
+	})
+
+	t.Run("strict rejects coercion", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(quoted), WithUnmarshalMode(UnmarshalStrict))
+		require.Error(t, err)
+
+		_, err = Load(Config{}, WithConfigPaths(typed), WithUnmarshalMode(UnmarshalStrict),
+			WithEnvPrefix("APP_"), WithEnvSnapshot(map[string]string{"APP_PORT": "9090"}))
+		require.Error(t, err)
+	})
+
+	t.Run("strict accepts exact types", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(typed), WithUnmarshalMode(UnmarshalStrict),
+			WithEnvPrefix("APP_"), WithEnvSnapshot(map[string]string{"APP_TIMEOUT": "1m"}))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Port: 8080, Debug: true, Timeout: time.Minute}, *cfg)
+	})
+}
+
+func TestLoadWithSearchUp(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	// root/.git  root/project/go.mod  root/project/a/b (工作目录)
+	root := t.TempDir()
+	project := filepath.Join(root, "project")
+	nested := filepath.Join(project, "a", "b")
+	require.NoError(t, os.MkdirAll(nested, 0o750))
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "go.mod"), []byte("module x\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "outer.yaml"), []byte("name: outer\n"), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(project, "config.yaml"), []byte("name: project\n"), 0o600))
+	t.Chdir(nested)
+
+	tests := []struct {
+		name string
+		path string
+		opts []Option
+		want string
+	}{
+		{name: "disabled", path: "config.yaml", want: "default"},
+		{name: "finds ancestor", path: "config.yaml", opts: []Option{WithSearchUp()}, want: "project"},
+		{name: "nearest wins", path: "config.yaml", opts: []Option{WithSearchUp()}, want: "nested"},
+		{name: "stops at go.mod", path: "outer.yaml", opts: []Option{WithSearchUp()}, want: "default"},
+		{name: "custom boundary", path: "outer.yaml", opts: []Option{WithSearchUpBoundary(".git")}, want: "outer"},
+		{name: "filesystem root", path: "outer.yaml", opts: []Option{WithSearchUpBoundary("")}, want: "outer"},
+		{name: "max depth", path: "config.yaml", opts: []Option{WithSearchUp(), WithMaxDepth(1)}, want: "default"},
+		{name: "within max depth", path: "config.yaml", opts: []Option{WithSearchUp(), WithMaxDepth(2)}, want: "project"},
+		{name: "max depth before boundary", path: "outer.yaml", opts: []Option{WithSearchUpBoundary(".git"), WithMaxDepth(2)}, want: "default"},
+		{name: "unlimited depth", path: "outer.yaml", opts: []Option{WithSearchUpBoundary(""), WithMaxDepth(-1)}, want: "outer"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.want == "nested" {
+				path := filepath.Join(nested, "..", "config.yaml")
+				require.NoError(t, os.WriteFile(path, []byte("name: nested\n"), 0o600))
+				t.Cleanup(func() { _ = os.Remove(path) })
+			}
+			opts := append([]Option{WithConfigPaths(tt.path), WithBaseDir(root)}, tt.opts...)
+			cfg, err := Load(Config{Name: "default"}, opts...)
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, cfg.Name)
+		})
+	}
+}
+
+func TestLoadWithEnvPrefixStrict(t *testing.T) {
+	type Server struct {
+		URL string `json:"url"`
+	}
+	type Config struct {
+		Server Server `json:"server"`
+		Token  string `json:"token" env:"APP_LEGACY_TOKEN"`
+	}
+	base := []Option{WithConfigPaths(), WithEnvPrefix("APP_")}
+
+	t.Run("unknown vars listed", func(t *testing.T) {
+		env := WithEnvSnapshot(map[string]string{
+			"APP_SERVER_URL": "http://ok",
+			"APP_SEVER_URL":  "typo",
+			"APP_TIMEOUTS":   "1s",
+			"APP_EMPTY":      "",
+			"OTHER_VALUE":    "x",
+		})
+		_, err := Load(Config{}, append(base, env, WithEnvPrefixStrict())...)
+		require.Error(t, err)
+		assert.Equal(t, "unknown env vars with prefix: APP_SEVER_URL, APP_TIMEOUTS", err.Error())
+
+		_, err = Load(Config{}, append(base, env)...)
+		require.NoError(t, err)
+	})
+
+	t.Run("explicit bindings are known", func(t *testing.T) {
+		env := WithEnvSnapshot(map[string]string{
+			"APP_SERVER_URL":   "http://ok",
+			"APP_LEGACY_TOKEN": "t",
+			"APP_REDIS":        "redis://",
+			"APP_CONFIG":       "token=x",
+		})
+		cfg, err := Load(Config{}, append(base, env, WithEnvPrefixStrict(),
+			WithEnvBindKey("env"), WithEnvBinding("APP_REDIS", "server.url"), WithFlatEnvKeys("APP_CONFIG"))...)
+		require.NoError(t, err)
+		assert.Equal(t, "redis://", cfg.Server.URL)
+		assert.Equal(t, "t", cfg.Token)
+	})
+
+	t.Run("top and flag bindings are known", func(t *testing.T) {
+		env := WithEnvSnapshot(map[string]string{"APP_LISTEN": "http://top"})
+		cfg, err := Load(Config{}, append(base, env, WithEnvPrefixStrict(), WithEnvBindingTop("APP_LISTEN", "server.url"))...)
+		require.NoError(t, err)
+		assert.Equal(t, "http://top", cfg.Server.URL)
+
+		cmd := &cli.Command{
+			Name:  "test",
+			Flags: []cli.Flag{&cli.StringFlag{Name: "server-url", Sources: cli.EnvVars("APP_FLAG_URL")}},
+			Action: func(_ context.Context, cmd *cli.Command) error {
+				_, err := Load(Config{}, append(base, WithEnvSnapshot(map[string]string{"APP_FLAG_URL": "http://flag"}),
+					WithEnvPrefixStrict(), WithCommand(cmd), WithEnvBindingsFromFlags())...)
+
+				return err
+			},
+		}
+		require.NoError(t, cmd.Run(context.Background(), []string{"test"}))
+	})
+}
+
+func TestLoadWithMigrations(t *testing.T) {
+	type Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Version int    `json:"version"`
+		Server  Server `json:"server"`
+		Debug   bool   `json:"debug"`
+	}
+
+	var migrations Migrations
+	migrations.
+		Migrate(0, func(cfg map[string]any) error {
+			cfg["server"] = map[string]any{"host": cfg["host"], "port": cfg["port"]}
+			delete(cfg, "host")
+			delete(cfg, "port")
+
+			return nil
+		}).
+		Migrate(1, func(cfg map[string]any) error {
+			if v, ok := cfg["verbose"]; ok {
+				cfg["debug"] = v
+				delete(cfg, "verbose")
+			}
+
+			return nil
+		})
+	assert.Equal(t, 2, migrations.Latest())
+
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		want    Config
+		wantErr string
+	}{
+		{name: "unversioned", content: "host: a\nport: 1\nverbose: true\n", want: Config{Version: 2, Server: Server{Host: "a", Port: 1}, Debug: true}},
+		{name: "partially migrated", content: "version: 1\nserver:\n  host: b\nverbose: true\n", want: Config{Version: 2, Server: Server{Host: "b"}, Debug: true}},
+		{name: "current", content: "version: 2\nserver:\n  port: 3\n", want: Config{Version: 2, Server: Server{Port: 3}}},
+		{name: "too new", content: "version: 3\n", wantErr: "version 3 is newer than supported version 2"},
+		{name: "invalid version", content: "version: x\n", wantErr: "invalid version x"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := Load(Config{}, WithConfigPaths(write(t, tt.content)), WithMigrations(&migrations))
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, *cfg)
+		})
+	}
+
+	t.Run("migration error", func(t *testing.T) {
+		var failing Migrations
+		failing.VersionKey = "schema"
+		failing.Migrate(0, func(map[string]any) error { return errors.New("boom") })
+		_, err := Load(Config{}, WithConfigPaths(write(t, "debug: true\n")), WithMigrations(&failing))
+		require.ErrorContains(t, err, "migrate from schema 0: boom")
+	})
+
+	t.Run("duplicate panics", func(t *testing.T) {
+		var dup Migrations
+		dup.Migrate(0, func(map[string]any) error { return nil })
+		assert.Panics(t, func() { dup.Migrate(0, func(map[string]any) error { return nil }) })
+	})
+}
+
+func TestLoadWithConfigVersion(t *testing.T) {
+	type Config struct {
+		Version string `json:"version"`
+		Schema  int    `json:"schema"`
+		Name    string `json:"name"`
+	}
+	write := func(t *testing.T, content string) string {
+		t.Helper()
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+	old := write(t, "version: 1\nschema: 3\nname: app\n")
+
+	t.Run("mismatch fails", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(old), WithConfigVersion("2", ErrorModeFail))
+		require.Error(t, err)
+		assert.Equal(t, "config "+old+`: version "1" does not match expected "2"; please upgrade the config file`, err.Error())
+
+		_, err = Load(Config{}, WithConfigPaths(old), WithConfigVersion("2", 0))
+		require.Error(t, err)
+	})
+
+	t.Run("mismatch warns", func(t *testing.T) {
+		cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(old), WithConfigVersion("2", ErrorModeSkip))
+		require.NoError(t, err)
+		assert.Equal(t, "app", cfg.Name)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, WarnConfigVersion, warnings[0].Code)
+	})
+
+	t.Run("match and missing pass", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(old), WithConfigVersion("1", ErrorModeFail))
+		require.NoError(t, err)
+		_, err = Load(Config{}, WithConfigPaths(write(t, "name: app\n")), WithConfigVersion("2", ErrorModeFail))
+		require.NoError(t, err)
+	})
+
+	t.Run("custom key", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(old), WithConfigVersionKey("schema"), WithConfigVersion("3", ErrorModeFail))
+		require.NoError(t, err)
+		_, err = Load(Config{}, WithConfigPaths(old), WithConfigVersion("4", ErrorModeFail), WithConfigVersionKey("schema"))
+		require.ErrorContains(t, err, `schema "3" does not match expected "4"`)
+	})
+}
+
+func TestLoadWithTemplateEnvDefault(t *testing.T) {
+	type Config struct {
+		Host   string `json:"host"`
+		Region string `json:"region"`
+		Zone   string `json:"zone"`
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("host: ${TED_HOST}\nregion: ${TED_REGION:-eu}\nzone: ${TED_ZONE}\n"), 0o600))
+
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithTemplateEnvDefault("unset"), WithTemplateStrictMissing(),
+		WithEnvSnapshot(map[string]string{"TED_ZONE": "z1"}))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Host: "unset", Region: "eu", Zone: "z1"}, *cfg)
+}
+
+func TestLoadWithConfigPathsLimit(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	dir := t.TempDir()
+	for i := range 5 {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%02d.yaml", i)), []byte(fmt.Sprintf("name: f%d\n", i)), 0o600))
+	}
+	pattern := filepath.Join(dir, "*.yaml")
+
+	cfg, err := Load(Config{}, WithConfigPaths(pattern))
+	require.NoError(t, err, "default limit is generous")
+	assert.Equal(t, "f4", cfg.Name)
+
+	_, err = Load(Config{}, WithConfigPaths(pattern), WithConfigPathsLimit(4))
+	require.ErrorContains(t, err, "more than 4 candidate files")
+
+	_, err = Load(Config{}, WithConfigPaths(pattern, "missing.yaml"), WithConfigPathsLimit(5))
+	require.Error(t, err, "plain paths count as candidates")
+
+	_, err = Load(Config{}, WithConfigPaths(pattern), WithConfigPathsLimit(0))
+	require.NoError(t, err)
+}
+
+func TestLoadWithEnvConfigFile(t *testing.T) {
+	type Config struct {
+		URL   string `json:"url"`
+		Debug bool   `json:"debug"`
+		Name  string `json:"name"`
+	}
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "myapp"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "myapp", "env"), []byte(`
+# per-user defaults
+ECF_URL = "https://file.example.com"
+ECF_DEBUG=true
+ECF_NAME='from-file'
+`), 0o600))
+
+	cfg, err := Load(Config{Name: "default"}, WithConfigPaths(), WithEnvPrefix("ECF_"), WithEnvConfigFile("myapp"),
+		WithEnvSnapshot(map[string]string{"XDG_CONFIG_HOME": dir, "ECF_NAME": "from-env"}))
+	require.NoError(t, err)
+	assert.Equal(t, Config{URL: "https://file.example.com", Debug: true, Name: "from-env"}, *cfg,
+		"process env wins over env file")
+
+	cfg, err = Load(Config{Name: "default"}, WithConfigPaths(), WithEnvPrefix("ECF_"), WithEnvConfigFile("other"),
+		WithEnvSnapshot(map[string]string{"XDG_CONFIG_HOME": dir}))
+	require.NoError(t, err, "missing env file is ignored")
+	assert.Equal(t, Config{Name: "default"}, *cfg)
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "myapp", "env"), []byte("ECF_URL\n"), 0o600))
+	_, err = Load(Config{}, WithConfigPaths(), WithEnvPrefix("ECF_"), WithEnvConfigFile("myapp"),
+		WithEnvSnapshot(map[string]string{"XDG_CONFIG_HOME": dir}))
+	require.ErrorContains(t, err, "line 1: want KEY=VALUE")
+}
+
+func TestLoadWithDurationUnit(t *testing.T) {
+	type Config struct {
+		Timeout  time.Duration `json:"timeout"`
+		Interval time.Duration `json:"interval"`
+		Grace    time.Duration `json:"grace"`
+		Raw      time.Duration `json:"raw"`
+	}
+	path := writeTempConfig(t, "timeout: 30\ninterval: 500ms\ngrace: 1.5\nraw: 30\n")
+
+	t.Setenv("DU_GRACE", "2")
+	cfg, err := Load(Config{}, WithConfigPaths(path),
+		WithDurationUnit("timeout", time.Second),
+		WithDurationUnit("interval", time.Second),
+		WithDurationUnit("grace", time.Minute),
+		WithEnvBinding("DU_GRACE", "grace"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, Config{
+		Timeout:  30 * time.Second,
+		Interval: 500 * time.Millisecond,
+		Grace:    2 * time.Minute,
+		Raw:      30,
+	}, *cfg)
+
+	_, err = Load(Config{}, WithConfigPaths(path), WithDurationUnit("timeout", 0))
+	require.ErrorContains(t, err, "duration unit for timeout")
+}
+
+func TestLoadWithGitRoot(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	root := t.TempDir()
+	sub := filepath.Join(root, "services", "api")
+	require.NoError(t, os.MkdirAll(sub, 0o755))
+	require.NoError(t, os.Mkdir(filepath.Join(root, ".git"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "config.yaml"), []byte("name: git-root\n"), 0o600))
+	t.Chdir(sub)
+
+	cfg, err := Load(Config{}, WithConfigPaths("config.yaml"), WithGitRoot(), WithBaseDir(sub))
+	require.NoError(t, err)
+	assert.Equal(t, "git-root", cfg.Name, "git root takes precedence over WithBaseDir")
+
+	require.NoError(t, os.Remove(filepath.Join(root, ".git")))
+	require.NoError(t, os.WriteFile(filepath.Join(sub, "config.yaml"), []byte("name: base-dir\n"), 0o600))
+	cfg, err = Load(Config{}, WithConfigPaths("config.yaml"), WithGitRoot(), WithBaseDir(sub))
+	require.NoError(t, err)
+	assert.Equal(t, "base-dir", cfg.Name, "falls back to WithBaseDir without .git")
+}
+
+func TestLoadWithConfigPathsEnv(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.yaml")
+	local := filepath.Join(dir, "local.yaml")
+	require.NoError(t, os.WriteFile(base, []byte("name: base\nport: 80\n"), 0o600))
+	require.NoError(t, os.WriteFile(local, []byte("port: 8080\n"), 0o600))
+
+	t.Setenv("CPE_CONFIG", local+string(os.PathListSeparator)+base)
+	cfg, err := Load(Config{}, WithConfigPaths("unused.yaml"), WithConfigPathsEnv("CPE_CONFIG"), WithMergeAllPaths())
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "base", Port: 8080}, *cfg)
+
+	t.Setenv("CPE_CONFIG", " "+base+" ,, "+local)
+	cfg, err = Load(Config{}, WithConfigPathsEnv("CPE_CONFIG"), WithConfigPathsSeparator(","))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "base", Port: 80}, *cfg, "first existing path wins")
+
+	t.Setenv("CPE_CONFIG", "")
+	cfg, err = Load(Config{}, WithConfigPaths(local), WithConfigPathsEnv("CPE_CONFIG"))
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port, "unset env keeps configured paths")
+}
+
+func TestLoadPreservesNilPointers(t *testing.T) {
+	type Limits struct {
+		Max *int `json:"max"`
+	}
+	type Config struct {
+		Port    *int    `json:"port"`
+		Debug   *bool   `json:"debug"`
+		Name    *string `json:"name"`
+		Limits  *Limits `json:"limits"`
+		Retries *int    `json:"retries"`
+		Verbose *bool   `json:"verbose"`
+	}
+	path := writeTempConfig(t, "debug: false\nname: null\nretries: 0\n")
+
+	t.Setenv("PNP_VERBOSE", "true")
+	t.Setenv("PNP_PORT", "")
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithEnvPrefix("PNP_"))
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Nil(cfg.Port, "empty env var is not a value")
+	a.Nil(cfg.Name, "null is not a value")
+	a.Nil(cfg.Limits)
+	if a.NotNil(cfg.Debug) {
+		a.False(*cfg.Debug, "explicit false is kept")
+	}
+	if a.NotNil(cfg.Retries) {
+		a.Zero(*cfg.Retries, "explicit zero is kept")
+	}
+	if a.NotNil(cfg.Verbose) {
+		a.True(*cfg.Verbose)
+	}
+}
+
+func TestLoadWithConfigPathsTrace(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	dir := t.TempDir()
+	missing := filepath.Join(dir, "missing.yaml")
+	invalid := filepath.Join(dir, "invalid.yaml")
+	subdir := filepath.Join(dir, "sub.yaml")
+	first := filepath.Join(dir, "first.yaml")
+	second := filepath.Join(dir, "second.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte("name: [\n"), 0o600))
+	require.NoError(t, os.Mkdir(subdir, 0o755))
+	require.NoError(t, os.WriteFile(first, []byte("name: first\n"), 0o600))
+	require.NoError(t, os.WriteFile(second, []byte("name: second\n"), 0o600))
+	glob := filepath.Join(dir, "conf.d", "*.yaml")
+
+	var probes []PathProbe
+	cfg, err := Load(Config{},
+		WithConfigPaths(missing, glob, invalid, subdir, first, second, filepath.Join(dir, "later.yaml")),
+		WithConfigPathsStopOnError(ErrorModeSkip, ErrorModeSkip),
+		WithConfigPathsTrace(func(p []PathProbe) { probes = p }),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "first", cfg.Name)
+	assert.Equal(t, []PathProbe{
+		{Path: missing, Reason: "not found"},
+		{Path: glob, Reason: "no files match pattern"},
+		{Path: invalid, Exists: true, Reason: "skipped: invalid config file"},
+		{Path: subdir, Exists: true, Reason: "is a directory"},
+		{Path: first, Exists: true, Selected: true, Reason: "selected: first existing path"},
+		{Path: second, Exists: true, Reason: "not read: shadowed by " + first + " (see WithMergeAllPaths)"},
+		{Path: filepath.Join(dir, "later.yaml"), Reason: "not read: " + first + " was selected first"},
+	}, probes)
+
+	probes = nil
+	_, err = Load(Config{}, WithConfigPaths(missing, invalid, first), WithConfigPathsTrace(func(p []PathProbe) { probes = p }))
+	require.Error(t, err)
+	require.Len(t, probes, 2, "trace is reported up to the failing file")
+	assert.Equal(t, invalid, probes[1].Path)
+	assert.False(t, probes[1].Selected)
+}
+
+func TestLoadWithTemplateMaxOutputSize(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	path := writeTempConfig(t, "name: '${TMO_A:=0123456789}${TMO_B:=${TMO_A}${TMO_A}}${TMO_B}${TMO_B}'\n")
+
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithTemplateMaxOutputSize(1024))
+	require.NoError(t, err)
+	assert.Len(t, cfg.Name, 70)
+
+	_, err = Load(Config{}, WithConfigPaths(path), WithTemplateMaxOutputSize(64))
+	require.ErrorIs(t, err, templexp.ErrOutputTooLarge)
+	assert.ErrorContains(t, err, "expand template in "+path)
+}
+
+func TestLoadWithConfigSources(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+		Mode  string `json:"mode"`
+	}
+	fsys := fstest.MapFS{"defaults.yaml": {Data: []byte("name: embedded\nport: 80\n")}}
+	local := filepath.Join(t.TempDir(), "local.json")
+	require.NoError(t, os.WriteFile(local, []byte(`{"debug": true}`), 0o600))
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/remote", func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		_, _ = w.Write([]byte(`{"port": 8080, "mode": "${CS_MODE:-remote}"}`))
+	})
+	mux.HandleFunc("/broken", func(w http.ResponseWriter, _ *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	cfg, err := Load(Config{}, WithConfigPaths("ignored.yaml"), WithConfigSources(
+		FSSource(fsys, "defaults.yaml"),
+		HTTPSource(srv.URL+"/remote"),
+		HTTPSource(srv.URL+"/missing"),
+		FileSource(local),
+		FileSource(filepath.Join(t.TempDir(), "missing.yaml")),
+	), WithConfigSources(BytesSource("inline", "yaml", []byte("name: inline\n"))))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "inline", Port: 8080, Debug: true, Mode: "remote"}, *cfg)
+
+	_, err = Load(Config{}, WithConfigSources(HTTPSource(srv.URL+"/broken")))
+	require.ErrorContains(t, err, "config source "+srv.URL+"/broken: http 500")
+
+	_, err = Load(Config{}, WithConfigSources(FileSource(filepath.Join(t.TempDir(), "missing.yaml"))),
+		WithConfigPathsStopOnError(ErrorModeFail, ErrorModeFail))
+	require.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = Load(Config{}, WithConfigSources(BytesSource("inline", "toml", nil)))
+	require.ErrorContains(t, err, `unsupported format "toml"`)
+}
+
+// stalledObjectStore 的读取阻塞到 ctx 取消，模拟无响应的对象存储。
+type stalledObjectStore struct{}
+
+func (stalledObjectStore) GetObject(ctx context.Context, _, _ string) ([]byte, error) {
+	<-ctx.Done()
+
+	return nil, ctx.Err()
+}
+
+// deadlineSource 记录读取时 ctx 的剩余时间。
+type deadlineSource struct {
+	remaining *time.Duration
+}
+
+func (s deadlineSource) Read(ctx context.Context) (string, string, []byte, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		*s.remaining = time.Until(deadline)
+	}
+
+	return "deadline", "yaml", []byte("name: ok\n"), nil
+}
+
+func TestLoadWithSourceTimeout(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+
+	t.Run("stalled http server", func(t *testing.T) {
+		start := time.Now()
+		_, err := Load(Config{}, WithConfigPaths(), WithConfigSources(HTTPSource(srv.URL)), WithSourceTimeout(50*time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 2*time.Second)
+	})
+
+	t.Run("custom http client", func(t *testing.T) {
+		client := &http.Client{Timeout: 50 * time.Millisecond}
+		_, err := Load(Config{}, WithConfigPaths(), WithConfigSources(HTTPClientSource(client, srv.URL)), WithSourceTimeout(-1))
+		require.ErrorContains(t, err, "Client.Timeout exceeded")
+	})
+
+	t.Run("stalled object store", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(), WithS3Config(stalledObjectStore{}, "cfg", "app.yaml"), WithSourceTimeout(50*time.Millisecond))
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.ErrorContains(t, err, "config source s3://cfg/app.yaml")
+	})
+
+	t.Run("default deadline", func(t *testing.T) {
+		var remaining time.Duration
+		cfg, err := Load(Config{}, WithConfigPaths(), WithConfigSources(deadlineSource{remaining: &remaining}))
+		require.NoError(t, err)
+		assert.Equal(t, "ok", cfg.Name)
+		assert.InDelta(t, defaultSourceTimeout, remaining, float64(time.Second))
+
+		remaining = 0
+		_, err = Load(Config{}, WithConfigPaths(), WithConfigSources(deadlineSource{remaining: &remaining}), WithSourceTimeout(-1))
+		require.NoError(t, err)
+		assert.Zero(t, remaining, "no deadline when disabled")
+	})
+}
+
+func TestLoadWithStrictDuplicateKeys(t *testing.T) {
+	type Config struct {
+		Port    int `json:"port"`
+		Servers []struct {
+			Host string `json:"host"`
+		} `json:"servers"`
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		return path
+	}
+
+	dup := write("dup.json", "{\n  \"port\": 1,\n  \"port\": 2\n}\n")
+	cfg, err := Load(Config{}, WithConfigPaths(dup))
+	require.NoError(t, err, "encoding/json keeps the last value by default")
+	assert.Equal(t, 2, cfg.Port)
+
+	_, err = Load(Config{}, WithConfigPaths(dup), WithStrictDuplicateKeys())
+	require.EqualError(t, err, "parse config file "+dup+`: duplicate key "port" at line 3`)
+
+	nested := write("nested.json", `{"servers": [{"host": "a"}, {"host": "b", "host": "c"}]}`)
+	_, err = Load(Config{}, WithConfigPaths(nested), WithStrictDuplicateKeys())
+	require.ErrorContains(t, err, `duplicate key "servers[1].host" at line 1`)
+
+	clean := write("clean.json", `{"port": 1, "servers": [{"host": "a"}, {"host": "b"}]}`)
+	_, err = Load(Config{}, WithConfigPaths(clean), WithStrictDuplicateKeys())
+	require.NoError(t, err, "same key in sibling objects is fine")
+
+	yamlDup := write("dup.yaml", "port: 1\nport: 2\n")
+	_, err = Load(Config{}, WithConfigPaths(yamlDup))
+	require.ErrorContains(t, err, `mapping key "port" already defined at line 1`, "YAML always rejects duplicates")
+}
+
+func TestLoadWithEnvBindingTop(t *testing.T) {
+	type Config struct {
+		ReadOnly bool   `json:"readonly"`
+		Name     string `json:"name"`
+	}
+	flags := []cli.Flag{
+		&cli.BoolFlag{Name: "readonly"},
+		&cli.StringFlag{Name: "name"},
+	}
+	args := []string{"test", "--readonly=false", "--name", "from-cli"}
+
+	t.Setenv("EBT_READONLY", "true")
+	t.Setenv("EBT_NAME", "from-env")
+	cfg := runCLITest(t, Config{}, flags, args,
+		WithConfigPaths(),
+		WithEnvBindingTop("EBT_READONLY", "readonly"),
+		WithEnvBinding("EBT_NAME", "name"),
+	)
+	assert.True(t, cfg.ReadOnly, "top binding beats CLI flag")
+	assert.Equal(t, "from-cli", cfg.Name, "regular binding stays below CLI")
+
+	cfg = runCLITest(t, Config{}, flags, args,
+		WithConfigPaths(),
+		WithEnvBindingTop("EBT_READONLY", "readonly"),
+		WithForcedValues(map[string]any{"readonly": false}),
+	)
+	assert.False(t, cfg.ReadOnly, "forced values stay on top")
+
+	_, bindings, err := LoadWithEnvReport(Config{}, WithConfigPaths(), WithEnvBindingTop("EBT_READONLY", "readonly"))
+	require.NoError(t, err)
+	assert.Equal(t, []EnvBindingResult{{EnvKey: "EBT_READONLY", ConfigPath: "readonly", Applied: true, Source: EnvSourceTop}}, bindings)
+}
+
+func TestLoadWithReadRetries(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: [partial\n"), 0o600))
+
+	_, err := Load(Config{}, WithConfigPaths(path))
+	require.Error(t, err, "torn file fails without retries")
+
+	// 模拟写入方在首次读取之后才完成写入
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		time.Sleep(10 * time.Millisecond)
+		tmp := path + ".tmp"
+		_ = os.WriteFile(tmp, []byte("name: complete\nport: 80\n"), 0o600)
+		_ = os.Rename(tmp, path)
+	}()
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithReadRetries(10, 20*time.Millisecond))
+	<-written
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "complete", Port: 80}, *cfg)
+
+	start := time.Now()
+	_, err = Load(Config{}, WithConfigPaths(filepath.Join(t.TempDir(), "missing.yaml")), WithReadRetries(3, time.Second))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), 500*time.Millisecond, "missing files are not retried")
+
+	require.NoError(t, os.WriteFile(path, []byte("name: [still broken\n"), 0o600))
+	_, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(path), WithReadRetries(2, time.Millisecond),
+		WithConfigPathsStopOnError(ErrorModeSkip, ErrorModeSkip))
+	require.NoError(t, err)
+	require.Len(t, warnings, 1, "skipped once after retries are exhausted")
+	assert.Equal(t, WarnInvalidFile, warnings[0].Code)
+}
+
+func TestLoadWithCLIFlagPrefix(t *testing.T) {
+	type Server struct {
+		URL string `json:"url"`
+	}
+	type Config struct {
+		Server Server `json:"server"`
+		Name   string `json:"name"`
+	}
+	defaults := Config{Server: Server{URL: "default"}, Name: "default"}
+	flags := []cli.Flag{
+		&cli.StringFlag{Name: "cfg.server-url"},
+		&cli.StringFlag{Name: "name"},
+	}
+
+	cfg := runCLITest(t, defaults, flags,
+		[]string{"test", "--cfg.server-url", "http://cli", "--name", "cli"},
+		WithCLIFlagPrefix("cfg."))
+
+	assert.Equal(t, "http://cli", cfg.Server.URL)
+	assert.Equal(t, "default", cfg.Name, "flags without the prefix are ignored")
+}
+
+func TestWithConfigPathsDeduplicate(t *testing.T) {
+	dir := t.TempDir()
+	target := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(target, []byte("name: target\n"), 0o600))
+	link := filepath.Join(dir, "link.yaml")
+	require.NoError(t, os.Symlink(target, link))
+	other := writeTempConfig(t, "name: other\n")
+
+	paths := WithConfigPaths(target, other, link, filepath.Join(dir, ".", "config.yaml"))
+
+	layers, err := MergePreview(paths, WithMergeAllPaths())
+	require.NoError(t, err)
+	require.Len(t, layers, 4, "without deduplication the same file is merged repeatedly")
+
+	var probes []PathProbe
+	layers, err = MergePreview(paths, WithMergeAllPaths(), WithConfigPathsDeduplicate(),
+		WithConfigPathsTrace(func(p []PathProbe) { probes = p }))
+	require.NoError(t, err)
+	require.Len(t, layers, 2)
+	assert.Equal(t, other, layers[0].Path)
+	assert.Equal(t, target, layers[1].Path, "first occurrence keeps its position")
+
+	require.Len(t, probes, 4)
+	assert.Equal(t, "skipped: same file as "+target, probes[2].Reason)
+	assert.False(t, probes[3].Selected)
+}
+
+func TestLoadWithConfigManifest(t *testing.T) {
+	type Config struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+		Debug  bool   `json:"debug"`
+	}
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+
+		return path
+	}
+	write("base.yaml", "name: base\nregion: us\n")
+	write("regions/eu.yaml", "region: eu\n")
+	write("overrides.json", `{"name": "override", "debug": true}`)
+	manifest := write("config.list", "# merge order\nbase.yaml\n\n  regions/eu.yaml\noverrides.json\n")
+
+	cfg, err := Load(Config{}, WithConfigManifest(manifest), WithConfigPaths(write("ignored.yaml", "name: ignored\n")))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "override", Region: "eu", Debug: true}, *cfg, "later entries win")
+
+	t.Run("relative to base dir", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithBaseDir(dir), WithConfigManifest("config.list"))
+		require.NoError(t, err)
+		assert.Equal(t, "eu", cfg.Region)
+	})
+
+	t.Run("missing fragment", func(t *testing.T) {
+		broken := write("broken.list", "base.yaml\nmissing.yaml\n")
+		_, err := Load(Config{}, WithConfigManifest(broken))
+		require.EqualError(t, err, "config manifest "+broken+": config file "+filepath.Join(dir, "missing.yaml")+" not found")
+	})
+
+	t.Run("missing manifest", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigManifest(filepath.Join(dir, "none.list")))
+		require.ErrorIs(t, err, fs.ErrNotExist)
+	})
+}
+
+type variantAuth interface{ kind() string }
+
+type variantOAuth struct {
+	ClientID string   `json:"client_id"`
+	Scopes   []string `json:"scopes"`
+}
+
+func (*variantOAuth) kind() string { return "oauth" }
+
+type variantAPIKey struct {
+	Key string `json:"key"`
+}
+
+func (variantAPIKey) kind() string { return "apikey" }
+
+func TestLoadWithRegisterVariant(t *testing.T) {
+	RegisterVariant("variant-test-oauth", &variantOAuth{Scopes: []string{"openid"}})
+	RegisterVariant("variant-test-apikey", variantAPIKey{})
+	RegisterVariant("variant-test-plain", struct{}{})
+
+	type Config struct {
+		Auth  variantAuth   `json:"auth"`
+		Extra any           `json:"extra"`
+		Chain []variantAuth `json:"chain"`
+	}
+
+	path := writeTempConfig(t, `
+auth:
+  type: variant-test-oauth
+  client_id: abc
+extra:
+  type: variant-test-oauth
+chain:
+  - type: variant-test-apikey
+    key: k1
+  - type: variant-test-oauth
+    scopes: [email]
+`)
+	cfg, err := Load(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal(&variantOAuth{ClientID: "abc", Scopes: []string{"openid"}}, cfg.Auth, "prototype supplies defaults")
+	a.Equal(map[string]any{"type": "variant-test-oauth"}, cfg.Extra, "empty interfaces are left alone")
+	require.Len(t, cfg.Chain, 2)
+	a.Equal(variantAPIKey{Key: "k1"}, cfg.Chain[0])
+	a.Equal(&variantOAuth{Scopes: []string{"email"}}, cfg.Chain[1])
+
+	t.Run("unknown discriminator", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(writeTempConfig(t, "auth: {type: variant-test-missing}\n")))
+		require.ErrorContains(t, err, `unknown variant "variant-test-missing" for cfgm.variantAuth`)
+	})
+
+	t.Run("type does not implement interface", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(writeTempConfig(t, "auth: {type: variant-test-plain}\n")))
+		require.ErrorContains(t, err, `variant "variant-test-plain" (struct {}) does not implement cfgm.variantAuth`)
+	})
+
+	t.Run("conflicting registration panics", func(t *testing.T) {
+		assert.Panics(t, func() { RegisterVariant("variant-test-oauth", variantAPIKey{}) })
+		assert.NotPanics(t, func() { RegisterVariant("variant-test-oauth", &variantOAuth{}) })
+	})
+}
+
+func TestLoadWithConfigExtensions(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Port  int    `json:"port"`
+		Debug bool   `json:"debug"`
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"10-base.yaml": "name: base\nport: 1\n",
+		"20-site.cfg":  "port: 2\n",
+		"30-over.CONF": `{"debug": true}`,
+		"40-skip.ini":  "name = skipped\n",
+	} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+
+	cfg, err := Load(Config{}, WithConfigPaths(), WithConfigPathsDir(dir))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "base", Port: 1}, *cfg, "custom extensions are skipped by default")
+
+	exts := WithConfigExtensions(map[string]string{"cfg": "yaml", ".conf": "json"})
+	cfg, err = Load(Config{}, WithConfigPaths(), WithConfigPathsDir(dir), exts)
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "base", Port: 2, Debug: true}, *cfg)
+
+	t.Run("explicit path uses declared format", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithConfigPaths(filepath.Join(dir, "30-over.CONF")), exts, WithStrictDuplicateKeys())
+		require.NoError(t, err)
+		assert.True(t, cfg.Debug)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPathsDir(dir), WithConfigExtensions(map[string]string{".ini": "ini"}))
+		require.EqualError(t, err, `config extension .ini: unsupported format "ini"`)
+	})
+}
+
+func TestLoadWithNoEnvForPaths(t *testing.T) {
+	type Security struct {
+		Mode string `json:"mode"`
+		TLS  struct {
+			Verify bool `json:"verify"`
+		} `json:"tls"`
+	}
+	type Config struct {
+		Security Security `json:"security"`
+		Name     string   `json:"name"`
+	}
+
+	path := writeTempConfig(t, "security:\n  mode: strict\n  tls:\n    verify: true\nname: file\n")
+	t.Setenv("NOENV_SECURITY_MODE", "off")
+	t.Setenv("NOENV_SECURITY_TLS_VERIFY", "false")
+	t.Setenv("NOENV_NAME", "env")
+
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithEnvPrefix("NOENV_"), WithNoEnvForPaths("security.*"))
+	require.NoError(t, err)
+	assert.Equal(t, "strict", cfg.Security.Mode)
+	assert.True(t, cfg.Security.TLS.Verify, "nested keys under the pattern are protected")
+	assert.Equal(t, "env", cfg.Name)
+
+	t.Run("explicit binding errors", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path),
+			WithEnvBinding("SEC_MODE", "security.mode"), WithNoEnvForPaths("security"))
+		require.EqualError(t, err, `env binding SEC_MODE: config path "security.mode" cannot be set from env (see WithNoEnvForPaths)`)
+
+		_, err = Load(Config{}, WithConfigPaths(path),
+			WithEnvBindingTop("SEC_MODE", "security.mode"), WithNoEnvForPaths("security.mode"))
+		require.ErrorContains(t, err, "cannot be set from env")
+	})
+
+	t.Run("strict prefix reports protected env", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPaths(path), WithEnvPrefix("NOENV_"), WithEnvPrefixStrict(),
+			WithNoEnvForPaths("security.mode"))
+		require.ErrorContains(t, err, "NOENV_SECURITY_MODE")
+	})
+}
+
+func TestConfigPathFromArgs(t *testing.T) {
+	names := []string{"config", "c"}
+	tests := []struct {
+		name string
+		args []string
+		want string
+		ok   bool
+	}{
+		{"separate value", []string{"serve", "--config", "a.yaml"}, "a.yaml", true},
+		{"equals", []string{"--config=b.yaml", "-v"}, "b.yaml", true},
+		{"short single dash", []string{"-c", "c.yaml"}, "c.yaml", true},
+		{"last wins", []string{"-c", "a.yaml", "--config=b.yaml"}, "b.yaml", true},
+		{"stops at double dash", []string{"--", "--config", "a.yaml"}, "", false},
+		{"missing value", []string{"--config"}, "", false},
+		{"other flags", []string{"--configs", "a.yaml", "config"}, "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := configPathFromArgs(tt.args, names)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadWithConfigFromArgs(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	path := writeTempConfig(t, "name: from-args\n")
+	other := writeTempConfig(t, "name: from-paths\n")
+
+	orig := os.Args
+	t.Cleanup(func() { os.Args = orig })
+	os.Args = []string{"tool", "--config=" + path}
+
+	cfg, err := Load(Config{}, WithConfigPaths(other), WithConfigFromArgs("config"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-args", cfg.Name)
+
+	os.Args = []string{"tool"}
+	cfg, err = Load(Config{}, WithConfigPaths(other), WithConfigFromArgs("config"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-paths", cfg.Name, "configured paths apply without the flag")
+
+	for _, args := range [][]string{
+		{"tool", "---config=" + path},
+		{"tool", "---config", path},
+		{"tool", "--config", "--verbose"},
+		{"tool", "--config"},
+	} {
+		os.Args = args
+		cfg, err = Load(Config{}, WithConfigPaths(other), WithConfigFromArgs("config"))
+		require.NoError(t, err, args)
+		assert.Equal(t, "from-paths", cfg.Name, args)
+	}
+	os.Args = []string{"tool", "--config", "--verbose", "-config", path}
+	cfg, err = Load(Config{}, WithConfigPaths(other), WithConfigFromArgs("config"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-args", cfg.Name, "missing value does not consume the next flag")
+
+	// os.Args 为空（如嵌入式运行时）时不扫描命令行
+	os.Args = nil
+	cfg, err = Load(Config{}, WithConfigPaths(other), WithConfigFromArgs("config"))
+	require.NoError(t, err)
+	assert.Equal(t, "from-paths", cfg.Name)
+	cfg, err = Load(Config{}, WithConfigPaths(other))
+	require.NoError(t, err)
+	assert.Equal(t, "from-paths", cfg.Name)
+}
+
+func TestLoadWithConfigDirRecursive(t *testing.T) {
+	type Config struct {
+		Name  string   `json:"name"`
+		Port  int      `json:"port"`
+		Order []string `json:"order"`
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"a.yaml":                  "name: a\norder: [a.yaml]\n",
+		"a/x.yaml":                "order: [a/x.yaml]\nport: 1\n",
+		"b/c/d.json":              `{"order": ["b/c/d.json"]}`,
+		"b/readme.md":             "# not config\n",
+		".git/config.yaml":        "name: git\n",
+		"node_modules/pkg/x.yaml": "name: node\n",
+	} {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o750))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	}
+
+	opts := []Option{WithConfigPaths(), WithConfigDirRecursive(dir), WithConfigDirExclude(".*", "node_modules")}
+	layers, err := MergePreview(opts...)
+	require.NoError(t, err)
+	files := make([]string, len(layers))
+	for i, layer := range layers {
+		files[i] = layer.Path
+	}
+
+	a := assert.New(t)
+	a.Equal([]string{
+		filepath.Join(dir, "a.yaml"),
+		filepath.Join(dir, "a", "x.yaml"),
+		filepath.Join(dir, "b", "c", "d.json"),
+	}, files, "lexical order of relative paths")
+
+	cfg, err := Load(Config{}, opts...)
+	require.NoError(t, err)
+	a.Equal(Config{Name: "a", Port: 1, Order: []string{"b/c/d.json"}}, *cfg)
+
+	cfg, err = Load(Config{}, WithConfigPaths(), WithConfigDirRecursive(dir))
+	require.NoError(t, err)
+	a.Equal("node", cfg.Name, "no directories excluded by default")
+
+	cfg, err = Load(Config{Name: "default"}, WithConfigPaths(), WithConfigDirRecursive(filepath.Join(dir, "missing")))
+	require.NoError(t, err)
+	a.Equal("default", cfg.Name)
+
+	for depth, want := range map[int][]string{
+		0:  {"a.yaml"},
+		1:  {"a.yaml", "a/x.yaml"},
+		2:  {"a.yaml", "a/x.yaml", "b/c/d.json"},
+		-1: {"a.yaml", "a/x.yaml", "b/c/d.json"},
+	} {
+		files, err := configDirRecursiveFiles(dir, newOptions(append(opts, WithMaxDepth(depth))...))
+		require.NoError(t, err)
+		a.Equal(want, files, "max depth %d", depth)
+	}
+}
+
+func TestLoadExplicitZeroValues(t *testing.T) {
+	type Config struct {
+		Replicas int    `json:"replicas"`
+		Enabled  bool   `json:"enabled"`
+		Name     string `json:"name"`
+	}
+	defaults := Config{Replicas: 3, Enabled: true, Name: "default"}
+	base := writeTempConfig(t, "replicas: 5\nenabled: true\nname: base\n")
+
+	t.Run("file zeros override lower layers", func(t *testing.T) {
+		override := writeTempConfig(t, "replicas: 0\nenabled: false\nname: \"\"\n")
+		cfg, err := Load(defaults, WithConfigPaths(override, base), WithMergeAllPaths())
+		require.NoError(t, err)
+		assert.Equal(t, Config{}, *cfg)
+	})
+
+	t.Run("env zeros", func(t *testing.T) {
+		t.Setenv("EZW_REPLICAS", "0")
+		t.Setenv("EZW_ENABLED", "false")
+		t.Setenv("EZW_NAME", "")
+
+		cfg, err := Load(defaults, WithConfigPaths(base), WithEnvPrefix("EZW_"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "base"}, *cfg, "empty env is unset by default")
+
+		cfg, err = Load(defaults, WithConfigPaths(base), WithEnvPrefix("EZW_"), WithExplicitZeroWins())
+		require.NoError(t, err)
+		assert.Equal(t, Config{}, *cfg)
+	})
+
+	t.Run("empty env coerced to zero for typed fields", func(t *testing.T) {
+		t.Setenv("EZW_REPLICAS", "")
+		t.Setenv("EZW_ENABLED", "")
+
+		cfg, err := Load(defaults, WithConfigPaths(base), WithExplicitZeroWins(), WithUnmarshalMode(UnmarshalStrict), WithStrictEnvTypes(),
+			WithEnvBinding("EZW_REPLICAS", "replicas"), WithEnvBinding("EZW_ENABLED", "enabled"), WithEnvBinding("EZW_UNSET", "name"))
+		require.NoError(t, err)
+		assert.Equal(t, Config{Name: "base"}, *cfg, "unset variables still do not override")
+	})
+
+	t.Run("cli zeros", func(t *testing.T) {
+		flags := []cli.Flag{
+			&cli.IntFlag{Name: "replicas"},
+			&cli.BoolFlag{Name: "enabled"},
+			&cli.StringFlag{Name: "name"},
+		}
+		cfg := runCLITest(t, defaults, flags, []string{"test", "--replicas", "0", "--enabled=false", "--name", ""},
+			WithConfigPaths(base))
+		assert.Equal(t, Config{}, *cfg)
+	})
+}
+
+func TestLoadWithConfigPathTyped(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+	dir := t.TempDir()
+	plain := filepath.Join(dir, "config")
+	require.NoError(t, os.WriteFile(plain, []byte(`{"name": "plain", "port": 1}`), 0o600))
+	yamlInJSON := filepath.Join(dir, "override.json")
+	require.NoError(t, os.WriteFile(yamlInJSON, []byte("port: 2\n"), 0o600))
+
+	cfg, err := Load(Config{}, WithConfigPathTyped(yamlInJSON, "yaml"), WithConfigPathTyped(plain, "json"), WithMergeAllPaths())
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "plain", Port: 2}, *cfg, "declared format replaces extension detection")
+
+	t.Run("declared json is parsed as json", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPathTyped(yamlInJSON, "json"))
+		require.ErrorContains(t, err, "invalid character")
+	})
+
+	t.Run("relative to base dir", func(t *testing.T) {
+		cfg, err := Load(Config{}, WithBaseDir(dir), WithConfigPathTyped("override.json", "yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, 2, cfg.Port)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		_, err := Load(Config{}, WithConfigPathTyped(plain, "toml"))
+		require.EqualError(t, err, "config path "+plain+`: unsupported format "toml"`)
+	})
+}
+
+func TestLoadWithOverrides(t *testing.T) {
+	type Server struct {
+		Host    string        `json:"host"`
+		Port    int           `json:"port"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	type Config struct {
+		Server Server            `json:"server"`
+		Tags   []string          `json:"tags"`
+		Labels map[string]string `json:"labels"`
+	}
+	defaults := Config{
+		Server: Server{Host: "localhost", Port: 80, Timeout: time.Second},
+		Tags:   []string{"a"},
+		Labels: map[string]string{"team": "core"},
+	}
+
+	path := writeTempConfig(t, "server:\n  port: 8080\n  timeout: 1s\ntags: [a, b]\nlabels:\n  env: prod\n")
+	t.Setenv("OVR_SERVER_HOST", "0.0.0.0")
+
+	cfg, diffs, err := LoadWithOverrides(defaults, WithConfigPaths(path), WithEnvPrefix("OVR_"))
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Server.Port)
+	assert.Equal(t, []FieldDiff{
+		{Path: "labels.env", Default: nil, Value: "prod"},
+		{Path: "server.host", Default: "localhost", Value: "0.0.0.0"},
+		{Path: "server.port", Default: 80, Value: 8080},
+		{Path: "tags", Default: []any{"a"}, Value: []any{"a", "b"}},
+	}, diffs, "unchanged keys such as server.timeout are omitted")
+
+	_, diffs, err = LoadWithOverrides(defaults, WithConfigPaths("nonexistent.yaml"))
+	require.NoError(t, err)
+	assert.Empty(t, diffs)
+}
+
+func TestLoadWithEnvBindingsTrimPrefix(t *testing.T) {
+	type AWSConfig struct {
+		Region  string `json:"region"`
+		Profile string `json:"profile"`
+	}
+	type Config struct {
+		Bar struct {
+			Baz string `json:"baz"`
+		} `json:"bar"`
+		AWS AWSConfig `json:"aws"`
+	}
+
+	t.Setenv("TRIMFOO_BAR_BAZ", "qux")
+	t.Setenv("TRIMAWS_TRIMAWS_REGION", "eu-west-1")
+	t.Setenv("TRIMAWS_PROFILE", "dev")
+	t.Setenv("TRIMPROFILE", "prod")
+
+	cfg, bindings, err := LoadWithEnvReport(Config{},
+		WithConfigPaths("nonexistent.yaml"),
+		WithEnvBindingsTrimPrefix(EnvTrimRule{Prefix: "TRIMFOO_"}),
+		WithEnvBindingsTrimPrefix(EnvTrimRule{Prefix: "TRIMAWS_", Repeat: true, Mount: "aws"}),
+		WithEnvBinding("TRIMPROFILE", "aws.profile"),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, "qux", cfg.Bar.Baz)
+	assert.Equal(t, "eu-west-1", cfg.AWS.Region)
+	assert.Equal(t, "prod", cfg.AWS.Profile, "explicit binding applied after trim rules")
+	assert.Contains(t, bindings, EnvBindingResult{EnvKey: "TRIMFOO_BAR_BAZ", ConfigPath: "bar.baz", Applied: true, Source: EnvSourceTrim})
+	assert.Contains(t, bindings, EnvBindingResult{EnvKey: "TRIMAWS_TRIMAWS_REGION", ConfigPath: "aws.region", Applied: true, Source: EnvSourceTrim})
+
+	t.Run("custom separator and recase", func(t *testing.T) {
+		cfg, err := Load(Config{},
+			WithConfigPaths("nonexistent.yaml"),
+			WithEnvSnapshot(map[string]string{"X--AWS__REGION": "us-east-1"}),
+			WithEnvBindingsTrimPrefix(EnvTrimRule{Prefix: "X--", Separator: "__", Recase: strings.ToLower}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, "us-east-1", cfg.AWS.Region)
+	})
+}
+
+func BenchmarkLoadStatCache(b *testing.B) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	dir := b.TempDir()
+	paths := make([]string, 0, 21)
+	for i := range 20 {
+		paths = append(paths, filepath.Join(dir, fmt.Sprintf("missing-%d.yaml", i)))
+	}
+	path := filepath.Join(dir, "config.yaml")
+	require.NoError(b, os.WriteFile(path, []byte("name: app\n"), 0o600))
+	paths = append(paths, path)
+
+	for _, bc := range []struct {
+		name string
+		opts []Option
+	}{
+		{"uncached", []Option{WithConfigPaths(paths...)}},
+		{"cached", []Option{WithConfigPaths(paths...), WithStatCache(time.Minute)}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			for b.Loop() {
+				if _, err := Load(Config{}, bc.opts...); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadWithAggregatePathErrors(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	dir := t.TempDir()
+	missingA := filepath.Join(dir, "a.yaml")
+	missingB := filepath.Join(dir, "b.yaml")
+	invalid := writeTempConfig(t, "name: [\n")
+	opts := []Option{WithConfigPaths(missingA, invalid, missingB), WithFailFastPaths()}
+
+	_, err := Load(Config{}, opts...)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), missingA)
+	assert.NotContains(t, err.Error(), missingB, "fails on the first error by default")
+
+	_, err = Load(Config{}, append(opts, WithAggregatePathErrors())...)
+	require.Error(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, "config file "+missingA)
+	assert.Contains(t, msg, "config file "+missingB)
+	assert.Contains(t, msg, invalid, "parse errors are collected with discovery errors")
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+
+	_, err = Load(Config{}, WithConfigPaths(invalid), WithAggregatePathErrors())
+	require.Error(t, err)
+}
+
+func TestLoadWithTemplateRequireEnv(t *testing.T) {
+	type Config struct {
+		Region string `json:"region"`
+	}
+	path := writeTempConfig(t, "region: ${REQENV_REGION:-us-east-1}\n")
+
+	_, err := Load(Config{}, WithConfigPaths(path), WithTemplateRequireEnv("REQENV_REGION", "REQENV_CLUSTER"))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "required variables not set: REQENV_REGION, REQENV_CLUSTER")
+	assert.Contains(t, err.Error(), path)
+
+	t.Setenv("REQENV_REGION", "eu-west-1")
+	cfg, err := Load(Config{}, WithConfigPaths(path), WithTemplateRequireEnv("REQENV_REGION"),
+		WithTemplateData(map[string]any{"REQENV_CLUSTER": "c1"}), WithTemplateRequireEnv("REQENV_CLUSTER"))
+	require.NoError(t, err)
+	assert.Equal(t, "eu-west-1", cfg.Region)
+}
+
+func TestWithConfigPathsForOS(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "myapp-os.yaml"), []byte("name: os\n"), 0o600))
+	other := "plan9"
+	if runtime.GOOS == other {
+		other = "linux"
+	}
+
+	path, ok, err := ResolveConfigPath(WithBaseDir(dir), WithAppName("myapp"), WithConfigPathsForOS(map[string][]string{
+		runtime.GOOS: {"missing.yaml", "{app}-os.yaml"},
+		other:        {"other.yaml"},
+	}))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "myapp-os.yaml"), path)
+
+	layers, err := MergePreview(WithBaseDir(dir), WithConfigPathsForOS(map[string][]string{other: {"myapp-os.yaml"}}))
+	require.NoError(t, err)
+	assert.Empty(t, layers, "unlisted OS falls back to DefaultPaths")
+
+	path, _, err = ResolveConfigPath(WithBaseDir(dir), WithConfigPaths("explicit.yaml"),
+		WithConfigPathsForOS(map[string][]string{runtime.GOOS: {"myapp-os.yaml"}}))
+	require.NoError(t, err)
+	assert.Empty(t, path, "explicit paths take precedence")
+}
+
+func TestProbeConfigPaths(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "conf.d"), 0o750))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "a.json"), []byte(`{"name":"a"}`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "conf.d", "b.conf"), []byte("name: b\n"), 0o600))
+	require.NoError(t, os.Mkdir(filepath.Join(dir, "dir.yaml"), 0o750))
+
+	opts := []Option{WithBaseDir(dir), WithConfigPaths("missing.yaml", "dir.yaml", "conf.d/*", "none/*.yaml")}
+	candidates, err := ProbeConfigPaths(opts...)
+	require.NoError(t, err)
+	assert.Equal(t, []CandidateResult{
+		{Pattern: filepath.Join(dir, "missing.yaml"), Path: filepath.Join(dir, "missing.yaml"), Format: "yaml"},
+		{Pattern: filepath.Join(dir, "dir.yaml"), Path: filepath.Join(dir, "dir.yaml"), Exists: true, Format: "yaml"},
+		{Pattern: filepath.Join(dir, "conf.d/*"), Path: filepath.Join(dir, "conf.d", "a.json"), Exists: true, Readable: true, Format: "json", Selected: true},
+		{Pattern: filepath.Join(dir, "conf.d/*"), Path: filepath.Join(dir, "conf.d", "b.conf"), Exists: true, Readable: true, Format: "yaml", Selected: true},
+		{Pattern: filepath.Join(dir, "none/*.yaml")},
+	}, candidates)
+
+	path, ok, err := ResolveConfigPath(opts...)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, candidates[2].Path, path, "first selected candidate matches ResolveConfigPath")
+
+	candidates, err = ProbeConfigPaths(WithBaseDir(dir), WithConfigPaths("conf.d/a.json", "conf.d/b.conf"))
+	require.NoError(t, err)
+	require.Len(t, candidates, 2)
+	assert.True(t, candidates[0].Selected)
+	assert.False(t, candidates[1].Selected, "later candidates are shadowed without WithMergeAllPaths")
+
+	_, err = ProbeConfigPaths(WithBaseDir(dir), WithConfigPaths("missing.yaml"), WithFailFastPaths())
+	require.Error(t, err)
+}
+
+func TestLoadWithMergeTrace(t *testing.T) {
+	type Config struct {
+		Port int    `json:"port"`
+		Host string `json:"host"`
+		Name string `json:"name"`
+	}
+	base := writeTempConfig(t, "port: 8080\nhost: base\n")
+	profile := writeTempConfig(t, "port: 9090\n")
+	t.Setenv("TRACE_PORT", "7070")
+
+	var audited []string
+	cfg, trace, err := LoadWithMergeTrace(Config{Port: 80, Name: "app"},
+		WithConfigPaths(profile, base), WithMergeAllPaths(), WithEnvPrefix("TRACE_"),
+		WithOnValueSet(func(source, path string, _ any) { audited = append(audited, path) }),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, 7070, cfg.Port)
+	assert.Equal(t, []SourceValue{
+		{Source: "default", Value: 80},
+		{Source: base, Value: 8080},
+		{Source: profile, Value: 9090},
+		{Source: "env TRACE_PORT", Value: "7070"},
+	}, trace["port"])
+	assert.Equal(t, []SourceValue{{Source: "default", Value: ""}, {Source: base, Value: "base"}}, trace["host"])
+	assert.Equal(t, []SourceValue{{Source: "default", Value: "app"}}, trace["name"])
+	assert.Contains(t, audited, "port", "existing WithOnValueSet callback still runs")
+}
+
+func TestWithConfigPathsFromXDGDirs(t *testing.T) {
+	type Config struct {
+		Name   string `json:"name"`
+		Region string `json:"region"`
+		Vendor string `json:"vendor"`
+	}
+	system := t.TempDir()
+	vendor := t.TempDir()
+	writeXDG := func(dir, content string) {
+		require.NoError(t, os.MkdirAll(filepath.Join(dir, "xdgapp"), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, "xdgapp", "config.yaml"), []byte(content), 0o600))
+	}
+	writeXDG(system, "name: system\nregion: system\nvendor: system\n")
+	writeXDG(vendor, "region: vendor\nvendor: vendor\n")
+	user := writeTempConfig(t, "name: user\n")
+	t.Setenv("XDG_CONFIG_DIRS", vendor+":relative:"+system)
+
+	cfg, err := Load(Config{}, WithAppName("xdgapp"), WithConfigPaths(user), WithConfigPathsFromXDGDirs())
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "user", Region: "vendor", Vendor: "vendor"}, *cfg)
+
+	layers, err := MergePreview(WithAppName("xdgapp"), WithConfigPaths(user), WithConfigPathsFromXDGDirs())
+	require.NoError(t, err)
+	require.Len(t, layers, 3)
+	assert.Equal(t, filepath.Join(system, "xdgapp", "config.yaml"), layers[0].Path)
+	assert.Equal(t, user, layers[2].Path)
+
+	cfg, err = Load(Config{}, WithConfigPaths(user), WithConfigPathsFromXDGDirs())
+	require.NoError(t, err)
+	assert.Empty(t, cfg.Region, "no app name, no XDG candidates")
+}
+
+func TestLoadWithUnmarshalTagChain(t *testing.T) {
+	type Backend struct {
+		Host string `koanf:"host" json:"hostname"`
+		Port int    `json:"port"`
+	}
+	type Server struct {
+		Addr     string             `koanf:"listen" json:"addr"`
+		Timeout  time.Duration      `json:"timeout"`
+		Internal string             `koanf:"-" json:"internal"`
+		Backends []Backend          `koanf:"backends"`
+		Routes   map[string]Backend `koanf:"routes"`
+	}
+	type Config struct {
+		Server Server `koanf:"srv" json:"server"`
+		Name   string `koanf:",omitempty" json:"name"`
+	}
+	defaults := Config{Server: Server{Addr: ":80", Timeout: time.Second}}
+	chain := WithUnmarshalTagChain("koanf", "json")
+
+	t.Run("koanf first with json fallback", func(t *testing.T) {
+		path := writeTempConfig(t, `
+srv:
+  listen: ":8080"
+  timeout: 5s
+  internal: ignored
+  backends:
+    - host: a
+      port: 1
+  routes:
+    api:
+      host: b
+      port: 2
+name: demo
+`)
+		cfg, err := Load(defaults, WithConfigPaths(path), chain)
+		require.NoError(t, err)
+		assert.Equal(t, Config{
+			Server: Server{
+				Addr:     ":8080",
+				Timeout:  5 * time.Second,
+				Backends: []Backend{{Host: "a", Port: 1}},
+				Routes:   map[string]Backend{"api": {Host: "b", Port: 2}},
+			},
+			Name: "demo",
+		}, *cfg)
+	})
+
+	t.Run("disagreeing names are not aliases", func(t *testing.T) {
+		path := writeTempConfig(t, "server:\n  addr: \":9090\"\nsrv:\n  backends:\n    - hostname: a\n")
+		cfg, err := Load(defaults, WithConfigPaths(path), chain)
+		require.NoError(t, err)
+		assert.Equal(t, ":80", cfg.Server.Addr)
+		assert.Equal(t, []Backend{{}}, cfg.Server.Backends)
+
+		_, warnings, err := LoadWithWarnings(defaults, WithConfigPaths(path), chain)
+		require.NoError(t, err)
+		var unknown []string
+		for _, w := range warnings {
+			if w.Code == WarnUnknownKey {
+				unknown = append(unknown, w.Path)
+			}
+		}
+		assert.Equal(t, []string{"server.addr"}, unknown)
+	})
+
+	t.Run("env and cli names follow the chain", func(t *testing.T) {
+		t.Setenv("CHAIN_SRV_LISTEN", ":7070")
+		t.Setenv("CHAIN_SERVER_ADDR", ":6060")
+		cfg, err := Load(defaults, WithConfigPaths("nonexistent.yaml"), chain, WithEnvPrefix("CHAIN_"))
+		require.NoError(t, err)
+		assert.Equal(t, ":7070", cfg.Server.Addr)
+
+		flags := []cli.Flag{&cli.StringFlag{Name: "srv-listen"}}
+		got := runCLITest(t, defaults, flags, []string{"test", "--srv-listen", ":5050"}, chain)
+		assert.Equal(t, ":5050", got.Server.Addr)
+	})
+
+	t.Run("decode error reports config path", func(t *testing.T) {
+		path := writeTempConfig(t, "srv:\n  backends:\n    - port: abc\n")
+		_, err := Load(defaults, WithConfigPaths(path), chain, WithUnmarshalErrorContext())
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot decode srv.backends[0].port")
+	})
+
+	t.Run("marshal round trip", func(t *testing.T) {
+		data, err := Marshal(&defaults, "yaml", chain)
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "srv:")
+		assert.Contains(t, string(data), "listen:")
+		assert.NotContains(t, string(data), "internal")
+
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, data, 0o600))
+		cfg, err := Load(Config{}, WithConfigPaths(path), chain)
+		require.NoError(t, err)
+		assert.Equal(t, defaults, *cfg)
+	})
+}
+
+func TestLoadWithUnmarshalErrorContext(t *testing.T) {
+	type Server struct {
+		Timeout time.Duration `json:"timeout"`
+		Port    int           `json:"port"`
+	}
+	type Config struct {
+		Server Server    `json:"server"`
+		Token  SecretRef `json:"token"`
+	}
+	path := writeTempConfig(t, "server:\n  timeout: abc\n  port: [1]\ntoken: [x]\n")
+
+	_, err := Load(Config{}, WithConfigPaths(path))
+	require.Error(t, err)
+	assert.NotContains(t, err.Error(), "cannot decode", "off by default")
+
+	_, err = Load(Config{}, WithConfigPaths(path), WithUnmarshalErrorContext())
+	require.Error(t, err)
+	msg := err.Error()
+	assert.Contains(t, msg, `cannot decode server.timeout: got string "abc", want time.Duration`)
+	assert.Contains(t, msg, `cannot decode server.port: got []interface {} [1], want int`)
+	assert.Contains(t, msg, "cannot decode token: got []interface {} (redacted)")
+	assert.NotContains(t, msg, "[x]")
+
+	var convErr *mapstructure.UnconvertibleTypeError
+	assert.ErrorAs(t, err, &convErr, "original error still reachable")
+}
+
+func TestWithAppNameFromExecutable(t *testing.T) {
+	for arg0, want := range map[string]string{
+		"/usr/local/bin/appfoo": "appfoo",
+		"appbar.exe":            "appbar",
+		"./AppBaz.EXE":          "AppBaz",
+		"app.v2":                "app.v2",
+	} {
+		assert.Equal(t, want, executableAppName(arg0), arg0)
+	}
+
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ".appfoo.yaml"), []byte("name: foo\n"), 0o600))
+	orig := os.Args
+	t.Cleanup(func() { os.Args = orig })
+	os.Args = []string{filepath.Join("/usr/bin", "appfoo")}
+
+	path, ok, err := ResolveConfigPath(WithAppNameFromExecutable(), WithBaseDir(dir), WithoutHomeConfig())
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, ".appfoo.yaml"), path)
+
+	_, ok, err = ResolveConfigPath(WithAppNameFromExecutable(), WithAppName("other"), WithBaseDir(dir), WithoutHomeConfig())
+	require.NoError(t, err)
+	assert.False(t, ok, "WithAppName takes precedence")
+}
+
+func TestLoadWithValueProcessor(t *testing.T) {
+	type Backend struct {
+		Host    string        `json:"host"`
+		Timeout time.Duration `json:"timeout"`
+	}
+	type Config struct {
+		Timeout  time.Duration      `json:"timeout"`
+		Retries  int64              `json:"retries"`
+		Backends []Backend          `json:"backends"`
+		Named    map[string]Backend `json:"named"`
+	}
+	path := writeTempConfig(t, "timeout: 10ms\nretries: 3\nbackends:\n  - host: A.example.com\n    timeout: 5s\nnamed:\n  x:\n    host: B\n")
+
+	minimum := WithValueProcessor(time.Duration(0), func(v any) (any, error) {
+		return max(v.(time.Duration), time.Second), nil
+	})
+	lower := WithValueProcessor("", func(v any) (any, error) {
+		return strings.ToLower(v.(string)), nil
+	})
+	cfg, err := Load(Config{}, WithConfigPaths(path), minimum, lower)
+	require.NoError(t, err)
+	assert.Equal(t, time.Second, cfg.Timeout)
+	assert.Equal(t, int64(3), cfg.Retries, "exact type match only")
+	assert.Equal(t, []Backend{{Host: "a.example.com", Timeout: 5 * time.Second}}, cfg.Backends)
+	assert.Equal(t, Backend{Host: "b", Timeout: time.Second}, cfg.Named["x"])
+
+	reject := WithValueProcessor("", func(v any) (any, error) {
+		if v == "B" {
+			return nil, errors.New("bad host")
+		}
+		return v, nil
+	})
+	_, err = Load(Config{}, WithConfigPaths(path), reject)
+	require.EqualError(t, err, "config named.x.host: bad host")
+
+	wrongType := WithValueProcessor(time.Duration(0), func(any) (any, error) { return 1, nil })
+	_, err = Load(Config{}, WithConfigPaths(path), wrongType)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "config timeout: value processor returned int, want time.Duration")
+}
+
+func TestLoadWithHostnameOverlay(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Level string `json:"level"`
+	}
+	orig := osHostname
+	t.Cleanup(func() { osHostname = orig })
+	osHostname = func() (string, error) { return "web-01.example.com", nil }
+
+	assert.Equal(t, filepath.Join("etc", "config.web-01.yaml"), hostnameOverlayPath(filepath.Join("etc", "config.yaml"), "web-01"))
+	assert.Equal(t, "config.web-01.yaml.gz", hostnameOverlayPath("config.yaml.gz", "web-01"))
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "config.yaml")
+	require.NoError(t, os.WriteFile(base, []byte("name: base\nlevel: info\n"), 0o600))
+
+	cfg, err := Load(Config{}, WithConfigPaths(base), WithHostnameOverlay())
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "base", Level: "info"}, *cfg, "overlay absent")
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.web-01.yaml"), []byte("level: debug\n"), 0o600))
+	cfg, err = Load(Config{}, WithConfigPaths(base), WithHostnameOverlay())
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "base", Level: "debug"}, *cfg)
+
+	cfg, err = Load(Config{}, WithConfigPaths(base))
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.Level, "off by default")
+
+	osHostname = func() (string, error) { return "", errors.New("no hostname") }
+	cfg, err = Load(Config{}, WithConfigPaths(base), WithHostnameOverlay())
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.Level, "hostname unavailable")
+}
+
+func TestValidateEnvBindings(t *testing.T) {
+	type DB struct {
+		Host string `json:"host" env:"DATABASE_HOST"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		DB     DB                `json:"db"`
+		Secret string            `json:"secret"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	require.NoError(t, ValidateEnvBindings(Config{},
+		WithEnvPrefix("MYAPP_"),
+		WithEnvBindKey("env"),
+		WithEnvBinding("DB_HOST", "db.host"),
+		WithEnvBinding("MYAPP_DB_PORT", "db.port"),
+		WithEnvBinding("REGION", "labels.region"),
+		WithEnvBindingTop("FORCE_PORT", "db.port"),
+	), "redundant and cross-tier bindings are fine")
+
+	err := ValidateEnvBindings(Config{},
+		WithEnvPrefix("MYAPP_"),
+		WithNoEnvForPaths("secret"),
+		WithEnvBinding("REDIS_URL", "redis.url"),
+		WithEnvBinding("DB_HOST", "db.host"),
+		WithEnvBinding("PGHOST", "db.host"),
+		WithEnvBinding("MYAPP_DB_HOST", "db.port"),
+		WithEnvBindingTop("SECRET", "secret"),
+	)
+	require.Error(t, err)
+	assert.Equal(t, strings.Join([]string{
+		`config path "db.host" bound by DB_HOST, PGHOST (binding): the last one set wins by declaration order`,
+		`env binding MYAPP_DB_HOST (binding): shadows prefix binding for "db.host", binds "db.port" instead`,
+		`env binding REDIS_URL (binding): unknown config path "redis.url"`,
+		`env binding SECRET (top): config path "secret" cannot be set from env (see WithNoEnvForPaths)`,
+	}, "\n"), err.Error())
+}
+
+func TestLoadWithSchemaFile(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+		Port int    `json:"port"`
+	}
+	dir := t.TempDir()
+	schema, err := GenerateSchema(Config{})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "config.schema.json"), schema, 0o600))
+	valid := writeTempConfig(t, "name: app\nport: 8080\n")
+	invalid := writeTempConfig(t, "port: abc\n")
+
+	cfg, err := Load(Config{}, WithBaseDir(dir), WithConfigPaths(valid), WithSchemaFile("config.schema.json"))
+	require.NoError(t, err)
+	assert.Equal(t, 8080, cfg.Port)
+
+	_, err = Load(Config{}, WithBaseDir(dir), WithConfigPaths(invalid), WithSchemaFile("config.schema.json"))
+	require.Error(t, err)
+	var schemaErr *SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	assert.Equal(t, "port", schemaErr.Path)
+
+	_, err = Load(Config{}, WithBaseDir(dir), WithConfigPaths(valid), WithSchemaFile("missing.schema.json"))
+	require.Error(t, err)
+	require.ErrorIs(t, err, fs.ErrNotExist)
+	assert.Contains(t, err.Error(), "schema file "+filepath.Join(dir, "missing.schema.json"))
+
+	t.Run("reloads changed schema", func(t *testing.T) {
+		schemaPath := filepath.Join(t.TempDir(), "schema.json")
+		require.NoError(t, os.WriteFile(schemaPath, []byte(`{"type": "object"}`), 0o600))
+		loader, err := NewLoader(Config{}, WithConfigPaths(valid), WithSchemaFile(schemaPath))
+		require.NoError(t, err)
+
+		require.NoError(t, os.WriteFile(schemaPath, []byte(`{"properties": {"port": {"maximum": 1024}}}`), 0o600))
+		err = loader.Reload()
+		require.ErrorAs(t, err, &schemaErr)
+		assert.Equal(t, "port", schemaErr.Path)
+
+		require.NoError(t, os.WriteFile(schemaPath, []byte(`{`), 0o600))
+		require.ErrorContains(t, loader.Reload(), "schema file "+schemaPath+": invalid schema")
+	})
+}
+
+func TestHTTPSourceContentType(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	for contentType, want := range map[string]string{
+		"application/json; charset=utf-8":   formatJSON,
+		"application/problem+json":          formatJSON,
+		"text/json":                         formatJSON,
+		"application/yaml":                  formatYAML,
+		"application/x-yaml; charset=UTF-8": formatYAML,
+		"text/yaml":                         formatYAML,
+		"application/toml":                  "toml",
+		"text/plain; charset=utf-8":         "",
+		"":                                  "",
+		"not a media type;;":                "",
+	} {
+		assert.Equal(t, want, mediaTypeFormat(contentType), contentType)
+	}
+
+	mux := http.NewServeMux()
+	serve := func(path, contentType, body string) {
+		mux.HandleFunc(path, func(w http.ResponseWriter, _ *http.Request) {
+			w.Header().Set("Content-Type", contentType)
+			_, _ = w.Write([]byte(body))
+		})
+	}
+	// "a: b" 形式的 YAML 不是合法 JSON，而 YAML 拒绝重复的 key，格式判断错误时解析失败
+	serve("/json", "application/json", `{"name": "dup", "name": "from-json"}`)
+	serve("/mislabeled.json", "application/yaml", "name: from-yaml\n")
+	serve("/plain.json", "text/plain", `{"name": "dup", "name": "from-ext"}`)
+	serve("/config", "application/octet-stream", "name: from-default\n")
+	serve("/toml", "application/toml", "name = \"x\"\n")
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	for path, want := range map[string]string{
+		"/json":            "from-json",
+		"/mislabeled.json": "from-yaml",
+		"/plain.json":      "from-ext",
+		"/config":          "from-default",
+	} {
+		cfg, err := Load(Config{}, WithConfigSources(HTTPSource(srv.URL+path)))
+		require.NoError(t, err, path)
+		assert.Equal(t, want, cfg.Name, path)
+	}
+
+	_, err := Load(Config{}, WithConfigSources(HTTPSource(srv.URL+"/toml")))
+	require.ErrorContains(t, err, `unsupported format "toml"`)
+}
+
+func TestLoadWithFieldInterceptor(t *testing.T) {
+	type DB struct {
+		User     string `json:"user"`
+		Password string `json:"password"`
+		Port     int    `json:"port"`
+	}
+	type Config struct {
+		DB   DB       `json:"db"`
+		Tags []string `json:"tags"`
+		Zone string   `json:"zone"`
+	}
+	path := writeTempConfig(t, "db:\n  user: admin\n  password: s3cret\n  port: \"5432\"\ntags: [a]\n")
+
+	type call struct {
+		Path string
+		Raw  any
+		Type reflect.Type
+	}
+	var calls []call
+	audit := WithFieldInterceptor(func(path string, raw any, typ reflect.Type) (any, error) {
+		calls = append(calls, call{path, raw, typ})
+		return raw, nil
+	})
+	adjust := WithFieldInterceptor(func(path string, raw any, _ reflect.Type) (any, error) {
+		switch path {
+		case "db.password":
+			return strings.ToUpper(raw.(string)), nil
+		case "zone":
+			return cmp.Or(raw.(string), "default-zone"), nil
+		}
+		return raw, nil
+	})
+	cfg, err := Load(Config{}, WithConfigPaths(path), audit, adjust)
+	require.NoError(t, err)
+	assert.Equal(t, Config{DB: DB{User: "admin", Password: "S3CRET", Port: 5432}, Tags: []string{"a"}, Zone: "default-zone"}, *cfg)
+	assert.Equal(t, []call{
+		{"db.user", "admin", reflect.TypeFor[string]()},
+		{"db.password", "s3cret", reflect.TypeFor[string]()},
+		{"db.port", "5432", reflect.TypeFor[int]()},
+		{"tags", []any{"a"}, reflect.TypeFor[[]string]()},
+		{"zone", "", reflect.TypeFor[string]()},
+	}, calls)
+
+	veto := WithFieldInterceptor(func(path string, raw any, _ reflect.Type) (any, error) {
+		if path == "db.user" && raw == "admin" {
+			return nil, errors.New("admin user not allowed")
+		}
+		return raw, nil
+	})
+	_, err = Load(Config{}, WithConfigPaths(path), veto)
+	require.EqualError(t, err, "config db.user: admin user not allowed")
+}
+
+func TestLoadWithFormatPreference(t *testing.T) {
+	type Config struct {
+		Name   string `json:"name"`
+		Format string `json:"format"`
+		Extra  string `json:"extra"`
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"config.json":  `{"name": "json", "format": "json", "extra": "json-only"}`,
+		"config.yaml":  "format: yaml\n",
+		"other.json":   `{"name": "other"}`,
+		"z/config.yml": "format: yml\n",
+	} {
+		require.NoError(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0o750))
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	pattern := filepath.Join(dir, "config.*")
+
+	cfg, err := Load(Config{}, WithConfigPaths(pattern))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "json", Format: "yaml", Extra: "json-only"}, *cfg, "default merges byte-wise, yaml last")
+
+	cfg, err = Load(Config{}, WithConfigPaths(pattern), WithFormatPreference("yaml", "json"))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Format: "yaml"}, *cfg)
+
+	cfg, err = Load(Config{}, WithConfigPaths(pattern), WithFormatPreference("json"))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "json", Format: "json", Extra: "json-only"}, *cfg)
+
+	cfg, err = Load(Config{}, WithConfigPaths("missing.yaml"), WithConfigPathsDir(dir), WithFormatPreference("json", "yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, Config{Name: "other", Format: "json", Extra: "json-only"}, *cfg, "different stems are all kept")
+
+	cfg, err = Load(Config{}, WithConfigPaths("missing.yaml"), WithConfigDirRecursive(dir), WithFormatPreference("yml"))
+	require.NoError(t, err)
+	assert.Equal(t, "yml", cfg.Format, "stems in different directories are independent")
+
+	path, ok, err := ResolveConfigPath(WithConfigPaths(pattern), WithFormatPreference("yaml"))
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(dir, "config.yaml"), path)
+
+	_, err = Load(Config{}, WithConfigPaths(pattern), WithFormatPreference("toml"))
+	require.EqualError(t, err, `format preference: unsupported format "toml"`)
+}
+
+func TestLoadWithEnvBindingsResolveOrder(t *testing.T) {
+	type Server struct {
+		URL string `json:"url" env:"ORD_TAG_URL"`
+	}
+	type Config struct {
+		Server Server `json:"server"`
+	}
+	flags := func() []cli.Flag {
+		return []cli.Flag{&cli.StringFlag{Name: "server-url", Sources: cli.EnvVars("ORD_FLAG_URL")}}
+	}
+	values := map[EnvSource]string{
+		EnvSourcePrefix:  "ORD_SERVER_URL",
+		EnvSourceTag:     "ORD_TAG_URL",
+		EnvSourceFlag:    "ORD_FLAG_URL",
+		EnvSourceTrim:    "ORDTRIM_SERVER_URL",
+		EnvSourceBinding: "ORD_BIND_URL",
+	}
+	for source, envKey := range values {
+		t.Setenv(envKey, string(source))
+	}
+	base := []Option{
+		WithEnvPrefix("ORD_"),
+		WithEnvBindKey("env"),
+		WithEnvBindingsFromFlags(),
+		WithEnvBindingsTrimPrefix(EnvTrimRule{Prefix: "ORDTRIM_"}),
+		WithEnvBinding("ORD_BIND_URL", "server.url"),
+	}
+
+	cfg := runCLITest(t, Config{}, flags(), []string{"test"}, base...)
+	assert.Equal(t, string(EnvSourceBinding), cfg.Server.URL, "default order")
+
+	// 全部 120 种排列中，最后应用的来源生效
+	var permutations func(rest, prefix []EnvSource)
+	permutations = func(rest, prefix []EnvSource) {
+		if len(rest) == 0 {
+			order := slices.Clone(prefix)
+			cfg := runCLITest(t, Config{}, flags(), []string{"test"}, append(slices.Clip(base), WithEnvBindingsResolveOrder(order...))...)
+			assert.Equal(t, string(order[len(order)-1]), cfg.Server.URL, "order %v", order)
+
+			return
+		}
+		for i, source := range rest {
+			others := slices.Concat(rest[:i], rest[i+1:])
+			permutations(others, append(slices.Clip(prefix), source))
+		}
+	}
+	permutations(slices.Clone(defaultEnvSourceOrder), nil)
+
+	cfg = runCLITest(t, Config{}, flags(), []string{"test"}, append(slices.Clip(base), WithEnvBindingsResolveOrder(EnvSourcePrefix))...)
+	assert.Equal(t, string(EnvSourcePrefix), cfg.Server.URL, "unlisted sources rank below listed ones")
+
+	for _, order := range [][]EnvSource{{EnvSourceTop}, {EnvSourceTag, EnvSourceTag}} {
+		_, err := Load(Config{}, WithConfigPaths("nonexistent.yaml"), WithEnvBindingsResolveOrder(order...))
+		require.ErrorContains(t, err, "env resolve order", "order %v", order)
+	}
+}
+
+func TestLoadWithEnvFileSuffix(t *testing.T) {
+	type DB struct {
+		Password string `json:"password"`
+		Port     int    `json:"port"`
+	}
+	type Config struct {
+		DB    DB     `json:"db"`
+		Token string `json:"token"`
+	}
+	dir := t.TempDir()
+	secret := filepath.Join(dir, "db_password")
+	require.NoError(t, os.WriteFile(secret, []byte("s3cret\n"), 0o600))
+	port := filepath.Join(dir, "db_port")
+	require.NoError(t, os.WriteFile(port, []byte("5432\r\n"), 0o600))
+	base := []Option{WithConfigPaths(), WithEnvPrefix("APP_"), WithEnvFileSuffix("_FILE")}
+
+	t.Run("file wins over direct var", func(t *testing.T) {
+		env := WithEnvSnapshot(map[string]string{
+			"APP_DB_PASSWORD":      "direct",
+			"APP_DB_PASSWORD_FILE": secret,
+			"APP_DB_PORT_FILE":     port,
+			"APP_TOKEN":            "plain",
+		})
+		cfg, err := Load(Config{}, append(base, env, WithEnvPrefixStrict())...)
+		require.NoError(t, err)
+		assert.Equal(t, Config{DB: DB{Password: "s3cret", Port: 5432}, Token: "plain"}, *cfg)
+	})
+
+	t.Run("explicit and trim bindings", func(t *testing.T) {
+		env := WithEnvSnapshot(map[string]string{
+			"LEGACY_SECRET_FILE":      secret,
+			"TRIMDB_DB_PASSWORD_FILE": secret,
+		})
+		cfg, err := Load(Config{}, WithConfigPaths(), env, WithEnvFileSuffix("_FILE"),
+			WithEnvBinding("LEGACY_SECRET", "token"), WithEnvBindingsTrimPrefix(EnvTrimRule{Prefix: "TRIMDB_"}))
+		require.NoError(t, err)
+		assert.Equal(t, Config{DB: DB{Password: "s3cret"}, Token: "s3cret"}, *cfg)
+	})
+
+	t.Run("unreadable file", func(t *testing.T) {
+		missing := filepath.Join(dir, "missing")
+		env := WithEnvSnapshot(map[string]string{"APP_DB_PASSWORD_FILE": missing})
+		_, err := Load(Config{}, append(base, env)...)
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "env APP_DB_PASSWORD_FILE")
+		assert.Contains(t, err.Error(), missing)
+	})
+
+	t.Run("suffix not configured", func(t *testing.T) {
+		env := WithEnvSnapshot(map[string]string{"APP_DB_PASSWORD_FILE": secret})
+		cfg, err := Load(Config{}, WithConfigPaths(), WithEnvPrefix("APP_"), env)
+		require.NoError(t, err)
+		assert.Empty(t, cfg.DB.Password)
+	})
+}
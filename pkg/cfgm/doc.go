@@ -8,7 +8,15 @@
 //  1. 默认值 - 通过 defaultConfig 参数传入
 //  2. 配置文件 - 通过 [WithConfigPaths] 或 [WithAppName] 设置
 //  3. 环境变量(前缀) - 通过 [WithEnvPrefix] 自动生成绑定
-//  4. CLI flags - 通过 [WithCommand] 选项设置，最高优先级
+//  4. CLI flags - 通过 [WithCommand] 选项设置
+//  5. 强制值 - 通过 [WithForcedValues] 设置，覆盖以上全部来源
+//
+// [WithMapConfig] 的配置树位于默认值之上、配置文件之下。
+// [WithEtcdConfig] 读取的配置位于配置文件之上、环境变量之下。
+// [WithEnvBindingTop] 声明的个别环境变量位于 CLI flags 之上、强制值之下。
+//
+// flag 通过 cli Sources 读取环境变量时默认也按 CLI 优先级处理；
+// 使用 [WithCLIEnvAware] 可将其降到环境变量(前缀)之下。
 //
 // # 快速开始
 //
@@ -46,6 +54,8 @@
 //   - /etc/myapp/config.yaml (系统配置)
 //   - config.yaml, config/config.yaml (通用路径)
 //
+// 不同操作系统需要不同的默认路径时，使用 [WithConfigPathsForOS] 按 GOOS 替代上述列表。
+//
 // 如需自定义路径，使用 [WithConfigPaths]：
 //
 //	cfgm.Load(config,
@@ -53,6 +63,18 @@
 //	    cfgm.WithConfigPaths("custom.yaml"), // 覆盖默认路径
 //	)
 //
+// glob 匹配与目录片段一律按逐字节字典序合并（靠后的优先），合并结果不随操作系统或文件系统变化；
+// 同一主名存在多种格式（如 config.yaml 与 config.json）时，可用 [WithFormatPreference] 只取其一。
+//
+// [WithHostnameOverlay] 在配置文件之上合并同目录下的 config.<hostname>.yaml，用于按主机微调配置。
+//
+// 以 .gz 结尾（如 config.yaml.gz）或带 gzip 文件头的配置会被自动解压，格式由去掉 .gz 后的扩展名决定。
+//
+// [WithK8sConfigMapDir] 读取 Kubernetes ConfigMap 挂载目录，每个文件按文件名作为一个顶层节点合并。
+//
+// [WithConfigSources] 以 [ConfigSource] 替代文件搜索，内置 [FileSource]、[FSSource]、[BytesSource] 与 [HTTPSource]，
+// 也可自行实现以接入对象存储或配置中心。单次读取受 [WithSourceTimeout] 限制（默认 30s）。
+//
 // # 环境变量(前缀)
 //
 // 通过 [WithEnvPrefix] 启用环境变量支持：
@@ -64,11 +86,40 @@
 //   - MYAPP_SERVER_URL → server.url
 //   - MYAPP_CLIENT_REV_AUTH_USER → client.rev-auth-user
 //
+// 切片、数组与 map 字段的值是以 "[" 或 "{" 开头的合法 JSON 时按 JSON 解析（如 MYAPP_TAGS='["a","b"]'），
+// 否则按原始字符串解码（MYAPP_TAGS=[prod] 得到 []string{"[prod]"}），详见 [WithEnvPrefix]。
+//
+// 前缀迁移期间可用 [WithEnvPrefixes] 同时启用多个前缀，后声明的前缀优先。
+// 不符合前缀规则的变量可用 [WithEnvBinding] 显式绑定，优先级高于前缀绑定。
+// [ExportEnv] 按相同规则将配置导出为环境变量，便于传递给子进程。
+// [WithEnvConfigFile] 从 $XDG_CONFIG_HOME/<app>/env 读取用户级的环境变量默认值，进程环境中的同名变量优先。
+//
+// # 环境变量优先级
+//
+// 多种绑定指向同一 key 时按以下顺序应用（从低到高），后应用的覆盖先应用的：
+//
+//  1. [WithEnvPrefix] / [WithEnvPrefixes] - 多个前缀按声明顺序，后声明的优先
+//  2. [WithEnvBindKey] - 字段 tag 中列出的多个变量，靠前的优先
+//  3. [WithEnvBindingsFromFlags] - flag 声明的多个变量，靠前的优先（与 cli 一致）
+//  4. [WithEnvBindingsTrimPrefix] - 按规则声明顺序，后声明的优先
+//  5. [WithEnvBinding] - 按注册顺序，后注册的优先
+//
+// 上述五类来源之间的顺序可通过 [WithEnvBindingsResolveOrder] 调整，各来源内部的顺序不变。
+// 只有已设置且非空的环境变量参与覆盖：优先级更高的变量未设置时，较低优先级中已设置的变量生效。
+// 以上任何一种绑定的环境变量一旦设置，都会覆盖配置文件中为同一 key 显式写出的值；
+// 未设置或为空时保留配置文件中的值。
+// 实际应用顺序可通过 [LoadWithEnvReport] 查看，[ValidateEnvBindings] 可在启动时检查绑定的 key 是否存在、是否互相冲突。
+//
+// [WithFlatEnvKeys] 读取的整段配置视为一层配置文件，优先级低于以上全部绑定。
+//
 // # 模板展开
 //
 // 读取配置文件前会进行字符串展开（YAML/JSON 均支持）。
 // 使用 [WithoutTemplateExpansion] 可禁用该行为。
 //
+// 展开作用于解析前的整个文件内容，因此 key 与 value 均可使用模板，
+// 例如 "${REGION}:" 可按环境生成分区名称。展开结果需要是合法的 YAML/JSON。
+//
 // 支持 Shell 参数展开：
 //   - 仅识别 ${...}（不解析 $VAR）
 //   - ${VAR} / ${VAR:-default} / ${VAR?msg} / ${VAR:=default}
@@ -81,12 +132,25 @@
 //	model: "${LLM_MODEL:-gpt-4}"
 //	base_url: "${PROD_URL:-${DEV_URL:-http://localhost:8080}}"
 //
+// 变量默认读取环境变量；[WithTemplateData] 提供的变量优先于同名环境变量。
+// 未设置的 ${VAR} 默认展开为空字符串，可用 [WithTemplateEnvDefault] 指定统一的默认值。
+//
 // # CLI Flag 映射
 //
 // 仅替换 "." 为 "-"：
 //   - server.url → --server-url
 //   - tls.skip_verify → --tls-skip_verify
 //
+// # 运行期重新加载
+//
+// [NewLoader] 返回的 [Loader] 支持 [Loader.Reload] 手动重新加载，
+// 以及 [Loader.Watch] 轮询配置文件变化（兼容 Kubernetes ConfigMap 的 ..data 链接替换）：
+//
+//	loader, err := cfgm.NewLoader(DefaultConfig(), cfgm.WithAppName("myapp"))
+//	go loader.Watch(ctx, func(cfg *Config, err error) { /* ... */ })
+//
+// 偏好由信号触发重新加载（如 SIGHUP）时使用 [WatchSignal]。
+//
 // # 生成配置示例
 //
 // 使用 [ExampleYAML] 生成带注释的 YAML：
@@ -99,6 +163,10 @@
 //	jsonBytes := cfgm.MarshalJSON(defaultConfig)
 //	os.WriteFile("config.json", jsonBytes, 0644)
 //
+// 使用 [GenerateSchema] 生成 JSON Schema，供编辑器补全或 [WithSchema] / [WithSchemaFile] 校验：
+//
+//	schema, err := cfgm.GenerateSchema(defaultConfig)
+//
 // # 测试辅助
 //
 // [ConfigTestHelper] 可用于校验配置项与示例文件的一致性：
@@ -0,0 +1,58 @@
+package cfgm
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// durationUnit 是一条 [WithDurationUnit] 声明的 key 与默认单位。
+type durationUnit struct {
+	path string
+	unit time.Duration
+}
+
+// applyDurationUnits 将 [WithDurationUnit] 指定 key 上不带单位的数字转换为带单位的时长字符串。
+//
+// 转换结果写回配置树（如 30 → "30s"），与 [Loader.GetDuration] 等按 key 读取的结果保持一致；
+// "30s" 等已带单位的字符串与无法解析为数字的值保持不变，交由解码阶段处理。
+func applyDurationUnits(configMap map[string]any, options *options) error {
+	for _, du := range options.durationUnits {
+		if du.unit <= 0 {
+			return fmt.Errorf("duration unit for %s: must be positive, got %s", du.path, du.unit)
+		}
+		parts := options.splitKey(du.path)
+		val, ok := getByPath(configMap, parts)
+		if !ok {
+			continue
+		}
+		n, ok := bareNumber(val)
+		if !ok {
+			continue
+		}
+		_ = setPathValue(configMap, parts, time.Duration(n*float64(du.unit)).String(), options.keyDelim()) // 已存在的路径不会越界
+	}
+
+	return nil
+}
+
+// bareNumber 返回数值或纯数字字符串（如环境变量值 "30"）表示的数字。
+func bareNumber(val any) (float64, bool) {
+	switch v := val.(type) {
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float64:
+		return v, true
+	case string:
+		n, err := strconv.ParseFloat(strings.TrimSpace(v), 64)
+
+		return n, err == nil
+	}
+
+	return 0, false
+}
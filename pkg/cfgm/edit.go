@@ -0,0 +1,145 @@
+package cfgm
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+)
+
+// EditFile 修改 YAML 配置文件中的单个 key，保留文件中的注释与 key 顺序。
+//
+// key 以 "." 分隔嵌套路径（如 "server.port"），不存在的中间节点会被创建；
+// 被替换的值保留原有注释，原值为带引号的字符串时新的字符串值沿用相同的引号样式。
+// value 按 YAML 规则编码，time.Duration 写为 "1m30s" 形式，time.Time 写为 RFC 3339。
+//
+// 与 [Marshal] 从结构体重新生成整个文件不同，适用于 "myapp config set key value" 之类的子命令：
+//
+//	err := cfgm.EditFile("config.yaml", "server.port", 9090)
+//
+// 文件需已存在；JSON 文件不支持注释，请使用 [Marshal] 重新生成。
+// 写回时缩进统一为 2 个空格，空行不保留。
+func EditFile(path, key string, value any) error {
+	if isJSONPath(path) {
+		return fmt.Errorf("edit %s: json files are not supported", path)
+	}
+	parts := strings.Split(key, ".")
+	if slices.Contains(parts, "") {
+		return fmt.Errorf("edit %s: invalid key %q", path, key)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return fmt.Errorf("edit %s: %w", path, err)
+	}
+	content, err := os.ReadFile(path) //nolint:gosec // path is from trusted config
+	if err != nil {
+		return fmt.Errorf("edit %s: %w", path, err)
+	}
+
+	var doc yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &doc); err != nil {
+		return fmt.Errorf("edit %s: parse yaml: %w", path, err)
+	}
+	if doc.Kind == 0 {
+		doc = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode}}}
+	}
+	root := doc.Content[0]
+	if root.Kind != yamlv3.MappingNode {
+		return fmt.Errorf("edit %s: config root must be object", path)
+	}
+
+	newNode, err := editValueNode(value)
+	if err != nil {
+		return fmt.Errorf("edit %s: encode %s: %w", path, key, err)
+	}
+	if err := setNodeByPath(root, parts, newNode); err != nil {
+		return fmt.Errorf("edit %s: %w", path, err)
+	}
+
+	var buf bytes.Buffer
+	enc := yamlv3.NewEncoder(&buf)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return fmt.Errorf("edit %s: marshal yaml: %w", path, err)
+	}
+	if err := enc.Close(); err != nil {
+		return fmt.Errorf("edit %s: marshal yaml: %w", path, err)
+	}
+
+	if err := os.WriteFile(path, buf.Bytes(), info.Mode().Perm()); err != nil {
+		return fmt.Errorf("edit %s: %w", path, err)
+	}
+
+	return nil
+}
+
+// editValueNode 将 value 编码为 yamlv3.Node，时间类型与 [Marshal] 的输出格式一致。
+func editValueNode(value any) (*yamlv3.Node, error) {
+	switch v := value.(type) {
+	case time.Duration:
+		value = v.String()
+	case time.Time:
+		value = v.Format(time.RFC3339)
+	}
+
+	var node yamlv3.Node
+	if err := node.Encode(value); err != nil {
+		return nil, err
+	}
+
+	return &node, nil
+}
+
+// setNodeByPath 将 mapping 节点中 parts 指向的值替换为 value，缺失的中间节点按 mapping 创建。
+func setNodeByPath(mapping *yamlv3.Node, parts []string, value *yamlv3.Node) error {
+	last := len(parts) - 1
+	for i, part := range parts[:last] {
+		next := mappingValue(mapping, part)
+		if next == nil {
+			next = &yamlv3.Node{Kind: yamlv3.MappingNode}
+			mapping.Content = append(mapping.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: part}, next)
+		}
+		if next.Kind != yamlv3.MappingNode {
+			return fmt.Errorf("key %q is not an object", strings.Join(parts[:i+1], "."))
+		}
+		mapping = next
+	}
+
+	if current := mappingValue(mapping, parts[last]); current != nil {
+		replaceNode(current, value)
+	} else {
+		mapping.Content = append(mapping.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: parts[last]}, value)
+	}
+
+	return nil
+}
+
+// mappingValue 返回 mapping 节点中 key 对应的值节点，不存在时返回 nil。
+func mappingValue(mapping *yamlv3.Node, key string) *yamlv3.Node {
+	for i := 0; i+1 < len(mapping.Content); i += 2 {
+		if mapping.Content[i].Value == key {
+			return mapping.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+// replaceNode 以 value 原地替换 dst，保留 dst 的注释与字符串引号样式。
+func replaceNode(dst, value *yamlv3.Node) {
+	head, line, foot := dst.HeadComment, dst.LineComment, dst.FootComment
+	style := dst.Style
+	wasString := dst.Kind == yamlv3.ScalarNode && dst.ShortTag() == "!!str"
+
+	*dst = *value
+	dst.HeadComment, dst.LineComment, dst.FootComment = head, line, foot
+	if wasString && dst.Kind == yamlv3.ScalarNode && dst.ShortTag() == "!!str" &&
+		style&(yamlv3.DoubleQuotedStyle|yamlv3.SingleQuotedStyle) != 0 {
+		dst.Style = style
+	}
+}
@@ -0,0 +1,76 @@
+package cfgm
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/encoding/ianaindex"
+)
+
+// lookupEncoding 按 WHATWG 名称（如 gbk、latin1）或 IANA 名称（如 ISO-8859-1）查找编码。
+func lookupEncoding(name string) (encoding.Encoding, error) {
+	if enc, err := htmlindex.Get(name); err == nil {
+		return enc, nil
+	}
+	enc, err := ianaindex.IANA.Encoding(name)
+	if err != nil || enc == nil {
+		return nil, fmt.Errorf("cfgm: unsupported config encoding %q", name)
+	}
+
+	return enc, nil
+}
+
+// isUTF8Encoding 判断编码名称是否表示 UTF-8（无需转换）。
+func isUTF8Encoding(name string) bool {
+	switch strings.ToLower(strings.ReplaceAll(name, "-", "")) {
+	case "", "utf8":
+		return true
+	default:
+		return false
+	}
+}
+
+// utf8BOM 是 Windows 编辑器常在 UTF-8 文件开头写入的字节顺序标记。
+var utf8BOM = []byte("\xef\xbb\xbf")
+
+// decodeConfigContent 将文件内容从 encName 指定的编码转换为 UTF-8，并去除开头的 UTF-8 BOM。
+//
+// BOM 会使 JSON 解析在第一个字符处报错，且在模板展开与解析之前去除，避免错误信息难以理解。
+// 解码器将非法字节序列替换为 U+FFFD，解码结果中的替换字符由 genuineReplacementChars 判断是否来自非法字节。
+func decodeConfigContent(path string, content []byte, encName string) ([]byte, error) {
+	if isUTF8Encoding(encName) {
+		return bytes.TrimPrefix(content, utf8BOM), nil
+	}
+
+	enc, err := lookupEncoding(encName)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := enc.NewDecoder().Bytes(content)
+	if err != nil {
+		return nil, fmt.Errorf("decode %s from %s: %w", path, encName, err)
+	}
+	if bytes.ContainsRune(decoded, utf8.RuneError) && !genuineReplacementChars(enc, content, decoded) {
+		return nil, fmt.Errorf("decode %s from %s: invalid byte sequence", path, encName)
+	}
+
+	return bytes.TrimPrefix(decoded, utf8BOM), nil
+}
+
+// genuineReplacementChars 报告 decoded 中的 U+FFFD 是否都原本就编码在 content 中，而非解码器替换的非法字节序列。
+//
+// GBK、Latin-1 等无法表示 U+FFFD 的编码中出现替换字符一定来自非法字节；
+// GB18030、UTF-16 等 Unicode 完备的编码则将解码结果重新编码，与原始内容一致时说明文件本身包含 U+FFFD。
+func genuineReplacementChars(enc encoding.Encoding, content, decoded []byte) bool {
+	if _, err := enc.NewEncoder().String(string(utf8.RuneError)); err != nil {
+		return false
+	}
+	encoded, err := enc.NewEncoder().Bytes(decoded)
+
+	return err == nil && bytes.Equal(encoded, content)
+}
@@ -0,0 +1,709 @@
+package cfgm
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+)
+
+// prefixEnvBindings 根据 [WithEnvPrefix] 为结构体的每个 key 生成绑定，按前缀声明顺序、再按环境变量名排序。
+//
+// 支持包含连字符的 key，例如 rev-auth-user。多个前缀按声明顺序应用，同一 key 由后声明的前缀覆盖。
+func prefixEnvBindings(typ reflect.Type, options *options) ([]envBinding, error) {
+	if len(options.envPrefixes) == 0 {
+		return nil, nil
+	}
+
+	delim := options.keyDelim()
+
+	var keys []string
+	collectConfigKeysRecursive(typ, "", delim, options.tagChain(), &keys)
+	// WithNoEnvForPaths: 不生成绑定，WithEnvPrefixStrict 时对应的环境变量视为未知
+	keys = slices.DeleteFunc(keys, options.noEnvForPath)
+
+	if options.envPrefixStrict {
+		if err := checkUnknownPrefixedEnv(keys, typ, options); err != nil {
+			return nil, err
+		}
+	}
+
+	var bindings []envBinding
+	for _, prefix := range options.envPrefixes {
+		autoBindings := generateEnvBindings(prefix, keys, delim)
+		slog.Debug("Generated auto env bindings", "prefix", prefix, "count", len(autoBindings))
+		for _, envKey := range slices.Sorted(maps.Keys(autoBindings)) {
+			bindings = append(bindings, envBinding{envKey: envKey, configPath: autoBindings[envKey], source: EnvSourcePrefix})
+		}
+	}
+
+	return bindings, nil
+}
+
+// checkUnknownPrefixedEnv 实现 [WithEnvPrefixStrict]：带前缀但不对应任何绑定的环境变量返回错误。
+func checkUnknownPrefixedEnv(keys []string, typ reflect.Type, options *options) error {
+	delim := options.keyDelim()
+
+	known := make(map[string]bool)
+	for _, prefix := range options.envPrefixes {
+		for envKey := range generateEnvBindings(prefix, keys, delim) {
+			known[envKey] = true
+		}
+	}
+	for _, binding := range slices.Concat(options.envBindings, options.topEnvBindings) {
+		known[binding.envKey] = true
+	}
+	if options.envBindingsFromFlags && options.cmd != nil {
+		for _, binding := range flagEnvBindings(options.cmd, typ, delim, options.tagChain(), options.cliFlagPrefix) {
+			known[binding.envKey] = true
+		}
+	}
+	if options.envBindKey != "" {
+		for _, binding := range tagEnvBindings(typ, delim, options.tagChain(), options.envBindKey) {
+			known[binding.envKey] = true
+		}
+	}
+	for _, envKey := range options.flatEnvKeys {
+		known[envKey] = true
+	}
+	for _, rule := range options.envTrimRules {
+		for _, binding := range trimEnvBindings(rule, options.envFileBaseNames(options.environNames()), delim) {
+			known[binding.envKey] = true
+		}
+	}
+
+	var unknown []string
+	for _, name := range options.environNames() {
+		if known[name] {
+			continue
+		}
+		// WithEnvFileSuffix: 已知变量的文件引用
+		if base, ok := strings.CutSuffix(name, options.envFileSuffix); ok && options.envFileSuffix != "" && known[base] {
+			continue
+		}
+		for _, prefix := range options.envPrefixes {
+			if strings.HasPrefix(name, prefix) {
+				unknown = append(unknown, name)
+
+				break
+			}
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	slices.Sort(unknown)
+
+	return fmt.Errorf("unknown env vars with prefix: %s", strings.Join(unknown, ", "))
+}
+
+// validateEnvValue 校验环境变量字符串能否解析为目标字段类型。
+//
+// 校验规则与解码阶段一致：整数支持 0x/0o/0b 前缀，布尔使用 strconv.ParseBool，
+// time.Duration 使用 time.ParseDuration，time.Time 使用 RFC 3339；
+// humanized 为 true 时（见 [WithHumanizedValues]）整数还接受字节大小字符串。
+func validateEnvValue(envKey, val string, typ reflect.Type, humanized bool) error {
+	if typ == nil {
+		return nil
+	}
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	var err error
+	switch {
+	case typ == durationType:
+		_, err = time.ParseDuration(val)
+	case typ == timeType:
+		_, err = time.Parse(time.RFC3339, val)
+	default:
+		switch typ.Kind() {
+		case reflect.Bool:
+			_, err = strconv.ParseBool(val)
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			_, err = strconv.ParseInt(val, 0, typ.Bits())
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			_, err = strconv.ParseUint(val, 0, typ.Bits())
+		case reflect.Float32, reflect.Float64:
+			_, err = strconv.ParseFloat(val, typ.Bits())
+		default:
+			// 字符串、切片等类型不做校验
+		}
+		if err != nil && humanized && isIntegerKind(typ.Kind()) {
+			_, err = parseByteSize(val)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("env %s=%q: expected %s", envKey, val, typ)
+	}
+
+	return nil
+}
+
+// decodeEnvValue 将切片、数组与 map 字段的 JSON 编码值（见 [ExportEnv]）解析为对应结构，其余值原样返回。
+//
+// 空值（仅在 [WithExplicitZeroWins] 时出现）转为字段类型的零值，避免严格解码时 "" 无法转换为数字等类型。
+func decodeEnvValue(val string, typ reflect.Type) any {
+	if val == "" && typ != nil {
+		return reflect.Zero(typ).Interface()
+	}
+	if typ == nil || !isJSONEncodedKind(typ) {
+		return val
+	}
+	trimmed := strings.TrimSpace(val)
+	if !strings.HasPrefix(trimmed, "[") && !strings.HasPrefix(trimmed, "{") {
+		return val
+	}
+
+	var decoded any
+	if err := json.Unmarshal([]byte(trimmed), &decoded); err != nil {
+		return val
+	}
+
+	return decoded
+}
+
+// splitEnvList 按 [WithEnvBindingList] 的分隔符拆分环境变量值，去除各项首尾空白并忽略空项。
+func splitEnvList(val, sep string) []any {
+	items := make([]any, 0, strings.Count(val, sep)+1)
+	for item := range strings.SplitSeq(val, sep) {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
+
+// isJSONEncodedKind 判断字段在环境变量中是否使用 JSON 编码。
+func isJSONEncodedKind(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return true
+	default:
+		return false
+	}
+}
+
+// defaultEnvSourceOrder 是 CLI flags 之下各类环境变量绑定的默认应用顺序，后应用的优先。
+var defaultEnvSourceOrder = []EnvSource{EnvSourcePrefix, EnvSourceTag, EnvSourceFlag, EnvSourceTrim, EnvSourceBinding}
+
+// envSourceOrder 返回生效的应用顺序：[WithEnvBindingsResolveOrder] 未列出的来源按默认顺序排在最前，其后为列出的来源。
+func (o *options) envSourceOrder() ([]EnvSource, error) {
+	if len(o.envResolveOrder) == 0 {
+		return defaultEnvSourceOrder, nil
+	}
+	for i, source := range o.envResolveOrder {
+		if !slices.Contains(defaultEnvSourceOrder, source) {
+			return nil, fmt.Errorf("env resolve order: unsupported source %q", source)
+		}
+		if slices.Contains(o.envResolveOrder[:i], source) {
+			return nil, fmt.Errorf("env resolve order: duplicate source %q", source)
+		}
+	}
+
+	order := slices.DeleteFunc(slices.Clone(defaultEnvSourceOrder), func(s EnvSource) bool {
+		return slices.Contains(o.envResolveOrder, s)
+	})
+
+	return append(order, o.envResolveOrder...), nil
+}
+
+// applyEnvBindings 将 [WithEnvPrefix]、[WithEnvBindKey]、[WithEnvBindingsFromFlags]、[WithEnvBindingsTrimPrefix]
+// 与 [WithEnvBinding] 声明的环境变量写入配置 map。
+//
+// 各类绑定按 envSourceOrder 分组依次应用，默认为前缀绑定、tag 绑定、flag 推导的绑定、去前缀规则、显式绑定；
+// 同一 key 以最后应用且已设置的环境变量为准，详见 doc.go 中的「环境变量优先级」。
+func applyEnvBindings(configMap map[string]any, typ reflect.Type, options *options, report *loadReport) error {
+	delim := options.keyDelim()
+	order, err := options.envSourceOrder()
+	if err != nil {
+		return err
+	}
+
+	groups := make(map[EnvSource][]envBinding, len(order))
+	if groups[EnvSourcePrefix], err = prefixEnvBindings(typ, options); err != nil {
+		return err
+	}
+	if options.envBindKey != "" {
+		groups[EnvSourceTag] = tagEnvBindings(typ, delim, options.tagChain(), options.envBindKey)
+	}
+	if options.envBindingsFromFlags && options.cmd != nil {
+		groups[EnvSourceFlag] = flagEnvBindings(options.cmd, typ, delim, options.tagChain(), options.cliFlagPrefix)
+	}
+	for _, rule := range options.envTrimRules {
+		groups[EnvSourceTrim] = append(groups[EnvSourceTrim], trimEnvBindings(rule, options.envFileBaseNames(options.environNames()), delim)...)
+	}
+	if len(groups[EnvSourcePrefix]) == 0 && len(groups[EnvSourceTag]) == 0 && len(groups[EnvSourceFlag]) == 0 &&
+		len(groups[EnvSourceTrim]) == 0 && len(options.envBindings) == 0 {
+		return nil
+	}
+	// WithNoEnvForPaths: tag 与 flag 推导的绑定跳过受保护的 key，前缀绑定不生成，显式绑定见 checkNoEnvBindings
+	for _, source := range []EnvSource{EnvSourceTag, EnvSourceFlag, EnvSourceTrim} {
+		groups[source] = slices.DeleteFunc(groups[source], func(b envBinding) bool { return options.noEnvForPath(b.configPath) })
+	}
+
+	keyTypes := collectConfigKeyTypes(typ, delim, options.tagChain())
+	explicit := foldEnvBindingPaths(options.envBindings, keyTypes, options, report)
+	if err := checkNoEnvBindings(explicit, options); err != nil {
+		return err
+	}
+	groups[EnvSourceBinding] = explicit
+
+	if options.validateEnvBindings {
+		for _, binding := range explicit {
+			if !isBindableConfigPath(binding.configPath, keyTypes, delim) {
+				return fmt.Errorf("env binding %s: unknown config path %q", binding.envKey, binding.configPath)
+			}
+		}
+	}
+
+	var bindings []envBinding
+	for _, source := range order {
+		bindings = append(bindings, groups[source]...)
+	}
+
+	return applyEnvBindingList(configMap, bindings, keyTypes, options, report)
+}
+
+// trimEnvBindings 按 [WithEnvBindingsTrimPrefix] 的规则为匹配的环境变量生成绑定，按环境变量名排序。
+func trimEnvBindings(rule EnvTrimRule, names []string, delim string) []envBinding {
+	sep := rule.Separator
+	if sep == "" {
+		sep = "_"
+	}
+	recase := rule.Recase
+	if recase == nil {
+		recase = strings.ToLower
+	}
+
+	var bindings []envBinding
+	for _, name := range slices.Sorted(slices.Values(names)) {
+		rest, ok := strings.CutPrefix(name, rule.Prefix)
+		if !ok {
+			continue
+		}
+		for rule.Repeat && strings.HasPrefix(rest, rule.Prefix) {
+			rest = rest[len(rule.Prefix):]
+		}
+
+		var parts []string
+		for part := range strings.SplitSeq(rest, sep) {
+			if part != "" {
+				parts = append(parts, recase(part))
+			}
+		}
+		if len(parts) == 0 {
+			continue
+		}
+		if rule.Mount != "" {
+			parts = append([]string{rule.Mount}, parts...)
+		}
+		bindings = append(bindings, envBinding{envKey: name, configPath: strings.Join(parts, delim), source: EnvSourceTrim})
+	}
+
+	return bindings
+}
+
+// applyTopEnvBindings 在 CLI flags 之后应用 [WithEnvBindingTop] 声明的绑定。
+func applyTopEnvBindings(configMap map[string]any, typ reflect.Type, options *options, report *loadReport) error {
+	if len(options.topEnvBindings) == 0 {
+		return nil
+	}
+	delim := options.keyDelim()
+	keyTypes := collectConfigKeyTypes(typ, delim, options.tagChain())
+	bindings := foldEnvBindingPaths(options.topEnvBindings, keyTypes, options, report)
+	if err := checkNoEnvBindings(bindings, options); err != nil {
+		return err
+	}
+	if options.validateEnvBindings {
+		for _, binding := range bindings {
+			if !isBindableConfigPath(binding.configPath, keyTypes, delim) {
+				return fmt.Errorf("env binding %s: unknown config path %q", binding.envKey, binding.configPath)
+			}
+		}
+	}
+
+	return applyEnvBindingList(configMap, bindings, keyTypes, options, report)
+}
+
+// checkNoEnvBindings 检查显式绑定没有指向 [WithNoEnvForPaths] 保护的 key。
+func checkNoEnvBindings(bindings []envBinding, options *options) error {
+	for _, binding := range bindings {
+		if options.noEnvForPath(binding.configPath) {
+			return fmt.Errorf("env binding %s: config path %q cannot be set from env (see WithNoEnvForPaths)", binding.envKey, binding.configPath)
+		}
+	}
+
+	return nil
+}
+
+// applyEnvBindingList 按顺序将已设置的环境变量写入配置 map，并记录到 report。
+func applyEnvBindingList(configMap map[string]any, bindings []envBinding, keyTypes map[string]reflect.Type, options *options, report *loadReport) error {
+	for _, binding := range bindings {
+		val, err := options.envValue(binding.envKey)
+		if err != nil {
+			return err
+		}
+		result := EnvBindingResult{EnvKey: binding.envKey, ConfigPath: binding.configPath, Source: binding.source}
+		explicitEmpty := val == "" && options.explicitEmptyEnv(binding.envKey)
+		if val == "" && !explicitEmpty {
+			report.envBindings = append(report.envBindings, result)
+			if options.envBindingsReport && (binding.source == EnvSourceBinding || binding.source == EnvSourceTop) {
+				report.addWarning(WarnUnusedEnvBinding, binding.configPath, "env binding %s is not set", binding.envKey)
+			}
+
+			continue
+		}
+		if options.strictEnvTypes && !explicitEmpty {
+			if err := validateEnvValue(binding.envKey, val, keyTypes[binding.configPath], options.humanizedValues); err != nil {
+				return err
+			}
+		}
+		parts := options.splitKey(binding.configPath)
+		value := decodeEnvValue(val, keyTypes[binding.configPath])
+		if _, ok := value.(string); ok && binding.listSep != "" {
+			value = splitEnvList(val, binding.listSep)
+		}
+		setByPath(configMap, parts, value)
+		options.notifyValueSet("env "+binding.envKey, parts, value)
+		result.Applied = true
+		report.envBindings = append(report.envBindings, result)
+		report.checkFileOverride(binding.envKey, binding.configPath)
+		slog.Debug("Loaded env binding", "env", binding.envKey, "path", binding.configPath)
+	}
+
+	return nil
+}
+
+// foldEnvBindingPaths 检查 [WithEnvBinding] 的 config path 与结构体 key 的大小写是否一致。
+//
+// 仅大小写不同的 path 在启用 [WithEnvBindingsCaseFold] 时改写为结构体中的规范 key，
+// 否则记录 [WarnEnvBindingCase]。[WithNormalizeKeys] 下 key 统一为小写，不做检查。
+func foldEnvBindingPaths(bindings []envBinding, keyTypes map[string]reflect.Type, options *options, report *loadReport) []envBinding {
+	if len(bindings) == 0 || options.normalizeKeys {
+		return bindings
+	}
+
+	canonical := make(map[string]string, len(keyTypes))
+	for key := range keyTypes {
+		canonical[strings.ToLower(key)] = key
+	}
+
+	folded := slices.Clone(bindings)
+	for i, binding := range folded {
+		if _, ok := keyTypes[binding.configPath]; ok {
+			continue
+		}
+		key, ok := canonical[strings.ToLower(binding.configPath)]
+		if !ok {
+			continue
+		}
+		if options.envBindingsCaseFold {
+			folded[i].configPath = key
+
+			continue
+		}
+		report.addWarning(WarnEnvBindingCase, binding.configPath,
+			"env binding %s: config path %q differs only in case from %q (see WithEnvBindingsCaseFold)", binding.envKey, binding.configPath, key)
+	}
+
+	return folded
+}
+
+// isBindableConfigPath 判断 path 是否为结构体的叶子 key，或位于 map 等动态字段之下。
+func isBindableConfigPath(path string, keyTypes map[string]reflect.Type, delim string) bool {
+	if _, ok := keyTypes[path]; ok {
+		return true
+	}
+	for key, typ := range keyTypes {
+		if strings.HasPrefix(path, key+delim) && isDynamicType(typ) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// isDynamicType 判断字段类型下是否允许任意子 key（map 与 interface）。
+func isDynamicType(typ reflect.Type) bool {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	return typ.Kind() == reflect.Map || typ.Kind() == reflect.Interface
+}
+
+// flagEnvBindings 根据 CLI flag 声明的环境变量（cli Sources）生成绑定，见 [WithEnvBindingsFromFlags]。
+//
+// 按结构体字段顺序遍历叶子 key，flag 名称与 CLI 映射规则一致（key 各段以 "-" 拼接）；
+// 一个 flag 声明多个环境变量时与 cli 一致，靠前的环境变量优先。flagPrefix 见 [WithCLIFlagPrefix]。
+func flagEnvBindings(cmd *cli.Command, typ reflect.Type, delim string, tags []string, flagPrefix string) []envBinding {
+	var bindings []envBinding
+	walkConfigFields(typ, "", delim, tags, func(key string, _ reflect.StructField) {
+		flag := lookupCLIFlag(cmd, flagPrefix+strings.ReplaceAll(key, delim, "-"))
+		envFlag, ok := flag.(interface{ GetEnvVars() []string })
+		if !ok {
+			return
+		}
+		// cli 取第一个存在的环境变量，逆序应用使靠前的变量最终生效
+		for _, envKey := range slices.Backward(envFlag.GetEnvVars()) {
+			bindings = append(bindings, envBinding{envKey: envKey, configPath: key, source: EnvSourceFlag})
+		}
+	})
+
+	return bindings
+}
+
+// tagEnvBindings 根据字段的 tag 声明环境变量绑定，见 [WithEnvBindKey]。
+//
+// tag 值可用逗号分隔多个环境变量名，靠前的优先。
+func tagEnvBindings(typ reflect.Type, delim string, tags []string, tag string) []envBinding {
+	var bindings []envBinding
+	walkConfigFields(typ, "", delim, tags, func(key string, field reflect.StructField) {
+		value := field.Tag.Get(tag)
+		if value == "" || value == "-" {
+			return
+		}
+		envKeys := strings.Split(value, ",")
+		for _, envKey := range slices.Backward(envKeys) {
+			if envKey = strings.TrimSpace(envKey); envKey != "" {
+				bindings = append(bindings, envBinding{envKey: envKey, configPath: key, source: EnvSourceTag})
+			}
+		}
+	})
+
+	return bindings
+}
+
+// readFlatEnvLayer 将 [WithFlatEnvKeys] 指定的环境变量解析为配置层；变量未设置或为空时返回 false。
+func readFlatEnvLayer(envKey string, options *options) (configLayer, bool, error) {
+	val, err := options.envValue(envKey)
+	if err != nil {
+		return configLayer{}, false, err
+	}
+	val = strings.TrimSpace(val)
+	if val == "" {
+		return configLayer{}, false, nil
+	}
+
+	layer := configLayer{path: "env " + envKey}
+	if strings.HasPrefix(val, "{") {
+		data, err := parseConfigBytes(".json", []byte(val))
+		if err != nil {
+			return configLayer{}, false, fmt.Errorf("env %s: parse json: %w", envKey, err)
+		}
+		if options.normalizeKeys {
+			data = normalizeKeyCase(data)
+		}
+		layer.data = data
+		slog.Debug("Loaded flat env config", "env", envKey, "format", formatJSON)
+
+		return layer, true, nil
+	}
+
+	layer.data = make(map[string]any)
+	for i, line := range strings.Split(val, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return configLayer{}, false, fmt.Errorf("env %s: line %d: want key=value, got %q", envKey, i+1, line)
+		}
+		setByPath(layer.data, options.splitKey(key), strings.TrimSpace(value))
+	}
+	slog.Debug("Loaded flat env config", "env", envKey, "keys", len(layer.data))
+
+	return layer, true, nil
+}
+
+// envConfigFilePath 返回 [WithEnvConfigFile] 的 env 文件路径，无法确定配置目录时返回空字符串。
+func envConfigFilePath(appName string, getenv func(string) string) string {
+	dir := getenv("XDG_CONFIG_HOME")
+	if dir == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(home, ".config")
+	}
+
+	return filepath.Join(dir, appName, "env")
+}
+
+// withEnvConfigFile 返回附带 [WithEnvConfigFile] 变量的选项副本，未启用或文件不存在时返回 options 本身。
+//
+// 每次加载单独读取并写入副本，避免 [Loader.Reload] 之间共享上一次读取的结果。
+func (o *options) withEnvConfigFile() (*options, error) {
+	if o.envConfigFileApp == "" {
+		return o, nil
+	}
+	path := envConfigFilePath(o.envConfigFileApp, o.getenv)
+	if path == "" {
+		return o, nil
+	}
+	content, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return o, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("env config file: %w", err)
+	}
+
+	vars := make(map[string]string)
+	for i, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("env config file %s: line %d: want KEY=VALUE, got %q", path, i+1, line)
+		}
+		vars[key] = unquoteEnvValue(strings.TrimSpace(value))
+	}
+	slog.Debug("Loaded env config file", "path", path, "vars", len(vars))
+
+	copied := *o
+	copied.envFileVars = vars
+
+	return &copied, nil
+}
+
+// unquoteEnvValue 去除 env 文件中值两侧成对的单引号或双引号，双引号内支持 Go 风格的转义。
+func unquoteEnvValue(value string) string {
+	if len(value) < 2 {
+		return value
+	}
+	switch {
+	case value[0] == '"' && value[len(value)-1] == '"':
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	case value[0] == '\'' && value[len(value)-1] == '\'':
+		return value[1 : len(value)-1]
+	}
+
+	return value
+}
+
+// ExportEnv 将配置展开为 "KEY=value" 形式的环境变量列表，是 [WithEnvPrefix] 的逆操作。
+//
+// 变量名与 [WithEnvPrefix] 的生成规则一致（key 中的 "." 与 "-" 转为 "_" 并大写，加上 prefix），
+// 按结构体字段顺序输出，可直接追加到 exec.Cmd.Env 交给子进程，子进程以相同前缀加载即可还原配置。
+//
+// 值的编码：
+//   - 字符串原样输出，布尔与数字使用 Go 的默认格式
+//   - time.Duration 输出为 "1m30s" 形式，实现 encoding.TextMarshaler 的类型（time.Time、[SecretRef] 等）使用其文本形式
+//   - 切片、数组与 map 输出为 JSON（如 ["a","b"]、{"k":"v"}），加载时按字段类型解析回原结构
+//
+// nil 指针、切片与 map 不输出，子进程保留其默认值。
+func ExportEnv[T any](cfg *T, prefix string) []string {
+	if cfg == nil {
+		return nil
+	}
+
+	var env []string
+	exportEnvFields(reflect.ValueOf(cfg).Elem(), prefix, "", &env)
+
+	return env
+}
+
+// exportEnvFields 按 walkConfigFields 的遍历规则递归输出结构体叶子字段。
+func exportEnvFields(val reflect.Value, prefix, keyPrefix string, env *[]string) {
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			return
+		}
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return
+	}
+
+	typ := val.Type()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		key := configTagName(field, defaultConfigTags)
+		if key == "" {
+			continue
+		}
+		if keyPrefix != "" {
+			key = keyPrefix + "." + key
+		}
+
+		if isStructType(field.Type) {
+			exportEnvFields(val.Field(i), prefix, key, env)
+
+			continue
+		}
+
+		value, ok := encodeEnvValue(val.Field(i))
+		if !ok {
+			continue
+		}
+		*env = append(*env, envKeyName(prefix, key, ".")+"="+value)
+	}
+}
+
+// encodeEnvValue 按 [ExportEnv] 的编码规则格式化字段值；nil 值返回 false。
+func encodeEnvValue(val reflect.Value) (string, bool) {
+	for val.Kind() == reflect.Pointer || val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return "", false
+		}
+		val = val.Elem()
+	}
+
+	if val.Type() == durationType {
+		return time.Duration(val.Int()).String(), true
+	}
+	if marshaler, ok := val.Interface().(encoding.TextMarshaler); ok {
+		text, err := marshaler.MarshalText()
+		if err != nil {
+			return "", false
+		}
+
+		return string(text), true
+	}
+
+	switch val.Kind() {
+	case reflect.Slice, reflect.Map:
+		if val.IsNil() {
+			return "", false
+		}
+		fallthrough
+	case reflect.Array:
+		data, err := json.Marshal(valueToAny(val, val.Type(), defaultConfigTags))
+		if err != nil {
+			return "", false
+		}
+
+		return string(data), true
+	default:
+		return fmt.Sprint(val.Interface()), true
+	}
+}
@@ -0,0 +1,115 @@
+package cfgm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ValidateEnvBindings 检查 opts 中声明的环境变量绑定与 defaultConfig 的结构体是否一致，
+// 用于在启动时或测试中发现环境变量配置的错误，不读取配置文件，也不要求环境变量已设置。
+//
+// 检查的绑定包括 [WithEnvBinding]、[WithEnvBindingTop]、[WithEnvBindingsNamespace]、[WithEnvBindKey] 的字段 tag
+// 与 [WithEnvBindingsFromFlags] 的 flag 声明（需同时使用 [WithCommand]），发现以下问题时返回错误：
+//   - 绑定的配置 key 不存在于结构体中（map 与 interface 字段之下的动态 key 除外）
+//   - 绑定指向 [WithNoEnvForPaths] 保护的 key
+//   - 同一优先级的多个显式绑定以不同的环境变量指向同一 key，实际生效的变量取决于声明顺序
+//   - 绑定的环境变量名与 [WithEnvPrefix] 为另一个 key 生成的名称相同，抢占了前缀绑定
+//
+// 全部问题按 key 排序后以 errors.Join 汇总返回，没有问题时返回 nil。
+// [WithEnvBindingsTrimPrefix] 的绑定取决于运行时的环境变量，不在检查范围内。
+func ValidateEnvBindings[T any](defaultConfig T, opts ...Option) error {
+	options := newOptions(opts...)
+	options.resolve(0)
+
+	typ := reflect.TypeOf(defaultConfig)
+	delim := options.keyDelim()
+	tags := options.tagChain()
+	keyTypes := collectConfigKeyTypes(typ, delim, tags)
+	report := &loadReport{}
+
+	var bindings []envBinding
+	if options.envBindKey != "" {
+		bindings = append(bindings, tagEnvBindings(typ, delim, tags, options.envBindKey)...)
+	}
+	if options.envBindingsFromFlags && options.cmd != nil {
+		bindings = append(bindings, flagEnvBindings(options.cmd, typ, delim, tags, options.cliFlagPrefix)...)
+	}
+	explicit := foldEnvBindingPaths(options.envBindings, keyTypes, options, report)
+	top := foldEnvBindingPaths(options.topEnvBindings, keyTypes, options, report)
+	bindings = slices.Concat(bindings, explicit, top)
+
+	// 前缀绑定：环境变量名 → 配置 key
+	var keys []string
+	collectConfigKeysRecursive(typ, "", delim, tags, &keys)
+	keys = slices.DeleteFunc(keys, options.noEnvForPath)
+	prefixed := make(map[string]string)
+	for _, prefix := range options.envPrefixes {
+		for envKey, key := range generateEnvBindings(prefix, keys, delim) {
+			prefixed[envKey] = key
+		}
+	}
+
+	var issues []envBindingIssue
+	for _, binding := range bindings {
+		switch {
+		case !isBindableConfigPath(binding.configPath, keyTypes, delim):
+			issues = append(issues, envBindingIssue{binding.configPath,
+				fmt.Sprintf("env binding %s (%s): unknown config path %q", binding.envKey, binding.source, binding.configPath)})
+		case options.noEnvForPath(binding.configPath):
+			issues = append(issues, envBindingIssue{binding.configPath,
+				fmt.Sprintf("env binding %s (%s): config path %q cannot be set from env (see WithNoEnvForPaths)", binding.envKey, binding.source, binding.configPath)})
+		}
+		if key, ok := prefixed[binding.envKey]; ok && key != binding.configPath {
+			issues = append(issues, envBindingIssue{binding.configPath,
+				fmt.Sprintf("env binding %s (%s): shadows prefix binding for %q, binds %q instead", binding.envKey, binding.source, key, binding.configPath)})
+		}
+	}
+	issues = append(issues, envBindingCollisions(explicit)...)
+	issues = append(issues, envBindingCollisions(top)...)
+
+	if len(issues) == 0 {
+		return nil
+	}
+	slices.SortStableFunc(issues, func(a, b envBindingIssue) int { return strings.Compare(a.path, b.path) })
+	errs := make([]error, len(issues))
+	for i, issue := range issues {
+		errs[i] = errors.New(issue.msg)
+	}
+
+	return errors.Join(errs...)
+}
+
+// envBindingIssue 是 [ValidateEnvBindings] 发现的一个问题，path 用于排序。
+type envBindingIssue struct {
+	path string
+	msg  string
+}
+
+// envBindingCollisions 报告同一组显式绑定中以不同环境变量指向同一 key 的情况，按声明顺序列出变量名。
+func envBindingCollisions(bindings []envBinding) []envBindingIssue {
+	byPath := make(map[string][]string)
+	var paths []string
+	for _, binding := range bindings {
+		envKeys, seen := byPath[binding.configPath]
+		if !seen {
+			paths = append(paths, binding.configPath)
+		}
+		if !slices.Contains(envKeys, binding.envKey) {
+			byPath[binding.configPath] = append(envKeys, binding.envKey)
+		}
+	}
+
+	var issues []envBindingIssue
+	for _, path := range paths {
+		if envKeys := byPath[path]; len(envKeys) > 1 {
+			issues = append(issues, envBindingIssue{path,
+				fmt.Sprintf("config path %q bound by %s (%s): the last one set wins by declaration order",
+					path, strings.Join(envKeys, ", "), bindings[0].source)})
+		}
+	}
+
+	return issues
+}
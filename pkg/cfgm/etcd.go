@@ -0,0 +1,73 @@
+package cfgm
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// EtcdClient 是 [WithEtcdConfig] 读取 etcd 所需的最小接口，使 cfgm 不直接依赖 etcd 客户端。
+//
+// GetPrefix 返回 key 以 prefix 开头的全部键值。基于 go.etcd.io/etcd/client/v3 的适配示例：
+//
+//	type etcdKV struct{ cli *clientv3.Client }
+//
+//	func (e etcdKV) GetPrefix(ctx context.Context, prefix string) (map[string]string, error) {
+//	    resp, err := e.cli.Get(ctx, prefix, clientv3.WithPrefix())
+//	    if err != nil {
+//	        return nil, err
+//	    }
+//	    out := make(map[string]string, len(resp.Kvs))
+//	    for _, kv := range resp.Kvs {
+//	        out[string(kv.Key)] = string(kv.Value)
+//	    }
+//	    return out, nil
+//	}
+type EtcdClient interface {
+	GetPrefix(ctx context.Context, prefix string) (map[string]string, error)
+}
+
+// etcdSource 是一个 [WithEtcdConfig] 声明的 etcd 配置来源。
+type etcdSource struct {
+	client EtcdClient
+	prefix string
+}
+
+// readEtcdLayer 读取 prefix 下的键值并转换为配置层。
+//
+// key 恰好等于 prefix 时其值按 JSON/YAML 整体解析；其余 key 去掉 prefix 后按 "/" 拆分为 key 路径，
+// 值为字符串。同时存在时逐 key 的值覆盖整体解析的结果。
+func readEtcdLayer(src etcdSource, options *options) (configLayer, error) {
+	ctx, cancel := options.sourceContext()
+	kvs, err := src.client.GetPrefix(ctx, src.prefix)
+	cancel()
+	if err != nil {
+		return configLayer{}, fmt.Errorf("etcd config %s: read prefix: %w", src.prefix, err)
+	}
+
+	layer := configLayer{path: "etcd " + src.prefix, data: make(map[string]any)}
+	if blob, ok := kvs[src.prefix]; ok && strings.TrimSpace(blob) != "" {
+		data, err := parseConfigBytes(".yaml", []byte(blob))
+		if err != nil {
+			return configLayer{}, fmt.Errorf("etcd config %s: parse value: %w", src.prefix, err)
+		}
+		layer.data = data
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(kvs)) {
+		rel := strings.Trim(strings.TrimPrefix(key, src.prefix), "/")
+		if key == src.prefix || rel == "" {
+			continue
+		}
+		setByPath(layer.data, strings.Split(rel, "/"), kvs[key])
+	}
+	if options.normalizeKeys {
+		layer.data = normalizeKeyCase(layer.data)
+	}
+	slog.Debug("Loaded config from etcd", "prefix", src.prefix, "keys", len(kvs))
+
+	return layer, nil
+}
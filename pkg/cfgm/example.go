@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
@@ -26,7 +27,7 @@ import (
 //	yaml := cfgm.ExampleYAML(DefaultConfig())
 //	os.WriteFile("config/config.example.yaml", yaml, 0644)
 func ExampleYAML[T any](cfg T) []byte {
-	node := structToNode(reflect.ValueOf(cfg), reflect.TypeOf(cfg))
+	node := structToNode(reflect.ValueOf(cfg), reflect.TypeOf(cfg), defaultConfigTags)
 	node.HeadComment = "配置示例文件, 复制此文件为 config.yaml 并根据需要修改"
 
 	var buf bytes.Buffer
@@ -45,7 +46,7 @@ func ExampleYAML[T any](cfg T) []byte {
 //	yaml := cfgm.MarshalYAML(cfg)
 //	os.WriteFile("config/config.yaml", yaml, 0644)
 func MarshalYAML[T any](cfg T) []byte {
-	data, _ := yamlv3.Marshal(structToMap(cfg))
+	data, _ := yamlv3.Marshal(structToMap(cfg, defaultConfigTags))
 
 	return data
 }
@@ -65,8 +66,67 @@ func MarshalJSON[T any](cfg T) []byte {
 	return buf.Bytes()
 }
 
+// Marshal 将配置结构体序列化为指定格式，适用于 `myapp init > config.yaml` 等场景。
+//
+// format 支持 "yaml"/"yml" 与 "json"（不区分大小写，可带前导点号）。
+// key 与 [Load] 使用同一套 json tag（或 [WithUnmarshalTagChain] 指定的 tag），输出可直接作为配置文件读回。
+// 可配合 [WithCommentedDefaults] 为 YAML 输出附加字段注释，其他格式忽略注释。
+//
+// 使用示例：
+//
+//	cfg := DefaultConfig()
+//	data, err := cfgm.Marshal(&cfg, "yaml", cfgm.WithCommentedDefaults())
+func Marshal[T any](cfg *T, format string, opts ...Option) ([]byte, error) {
+	if cfg == nil {
+		return nil, errors.New("cfgm: marshal nil config")
+	}
+
+	options := &options{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	switch normalizeFormat(format) {
+	case formatYAML:
+		var doc any = structToMap(*cfg, options.tagChain())
+		if options.commentedDefaults {
+			node := structToNode(reflect.ValueOf(*cfg), reflect.TypeOf(*cfg), options.tagChain())
+			hoistLineComments(node)
+			doc = node
+		}
+
+		var buf bytes.Buffer
+		enc := yamlv3.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("marshal yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("marshal yaml: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	case formatJSON:
+		var doc any = cfg
+		if tags := options.tagChain(); !slices.Equal(tags, defaultConfigTags) {
+			doc = structToMap(*cfg, tags)
+		}
+
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("marshal json: %w", err)
+		}
+
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("cfgm: unsupported format %q", format)
+	}
+}
+
 // structToNode 将结构体转换为带注释的 yamlv3.Node。
-func structToNode(val reflect.Value, typ reflect.Type) *yamlv3.Node {
+func structToNode(val reflect.Value, typ reflect.Type, tags []string) *yamlv3.Node {
 	// 处理指针类型
 	if val.Kind() == reflect.Ptr {
 		if val.IsNil() {
@@ -82,11 +142,11 @@ func structToNode(val reflect.Value, typ reflect.Type) *yamlv3.Node {
 		field := typ.Field(i)
 		fieldVal := val.Field(i)
 
-		key := configTagName(field)
+		key := configTagName(field, tags)
 		if key == "" {
 			continue
 		}
-		comment := field.Tag.Get("desc")
+		comment := fieldComment(field)
 
 		// Key node
 		keyNode := &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: key}
@@ -95,14 +155,12 @@ func structToNode(val reflect.Value, typ reflect.Type) *yamlv3.Node {
 		var valNode *yamlv3.Node
 
 		// 判断是否为复杂类型（结构体或数组）
-		isStruct := field.Type.Kind() == reflect.Struct &&
-			field.Type != reflect.TypeFor[time.Duration]() &&
-			field.Type != reflect.TypeFor[time.Time]()
+		isStruct := field.Type.Kind() == reflect.Struct && isStructType(field.Type)
 		isSlice := field.Type.Kind() == reflect.Slice
 
 		switch {
 		case isStruct:
-			valNode = structToNode(fieldVal, field.Type)
+			valNode = structToNode(fieldVal, field.Type, tags)
 			keyNode.HeadComment = "\n" + comment // 复杂类型注释放在 key 上方，前面加空行
 		case isSlice:
 			valNode = valueToNode(fieldVal, field.Type)
@@ -119,6 +177,32 @@ func structToNode(val reflect.Value, typ reflect.Type) *yamlv3.Node {
 	return node
 }
 
+// fieldComment 返回字段注释，依次读取 desc、comment、usage tag。
+func fieldComment(field reflect.StructField) string {
+	for _, tag := range []string{"desc", "comment", "usage"} {
+		if comment := field.Tag.Get(tag); comment != "" {
+			return comment
+		}
+	}
+
+	return ""
+}
+
+// hoistLineComments 将行尾注释移动到 key 上方，使每个字段的注释都以 "# ..." 行出现在 key 之前。
+func hoistLineComments(node *yamlv3.Node) {
+	if node.Kind != yamlv3.MappingNode {
+		return
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		keyNode, valNode := node.Content[i], node.Content[i+1]
+		if valNode.LineComment != "" {
+			keyNode.HeadComment = valNode.LineComment
+			valNode.LineComment = ""
+		}
+		hoistLineComments(valNode)
+	}
+}
+
 // setSimpleFieldComment 设置简单字段的注释。
 // 多行注释放在 key 上方（HeadComment），单行注释放在行尾（LineComment）。
 func setSimpleFieldComment(keyNode, valNode *yamlv3.Node, comment string) {
@@ -342,5 +426,5 @@ func loadConfigKeys(path string) ([]string, error) {
 		return nil, fmt.Errorf("解析文件失败: %w", err)
 	}
 
-	return flattenMapKeys(configMap), nil
+	return flattenMapKeys(configMap, defaultKeyDelim), nil
 }
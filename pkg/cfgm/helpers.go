@@ -1,11 +1,17 @@
 package cfgm
 
 import (
+	"bytes"
+	"cmp"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"maps"
+	"os"
 	"path/filepath"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
@@ -18,20 +24,27 @@ var (
 	timeType     = reflect.TypeFor[time.Time]()
 )
 
-func configTagName(field reflect.StructField) string {
-	return parseTagName(field.Tag.Get("json"))
-}
+// defaultConfigTags 是未设置 [WithUnmarshalTagChain] 时决定配置 key 的 struct tag。
+var defaultConfigTags = []string{"json"}
+
+// configTagName 按 tags 的顺序返回字段的配置 key，字段不参与配置时返回空字符串。
+//
+// 第一个声明了名称的 tag 生效（如 `koanf:"name"`，只有选项的 `koanf:",omitempty"` 视为未声明）；
+// 该名称为 "-" 时字段不参与配置，不再读取后续的 tag。
+func configTagName(field reflect.StructField, tags []string) string {
+	for _, tag := range tags {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		switch name {
+		case "":
+			continue
+		case "-":
+			return ""
+		}
 
-func parseTagName(tag string) string {
-	if tag == "" {
-		return ""
-	}
-	parts := strings.Split(tag, ",")
-	if len(parts) == 0 || parts[0] == "" || parts[0] == "-" {
-		return ""
+		return name
 	}
 
-	return parts[0]
+	return ""
 }
 
 func isStructType(typ reflect.Type) bool {
@@ -39,15 +52,15 @@ func isStructType(typ reflect.Type) bool {
 		typ = typ.Elem()
 	}
 
-	return typ.Kind() == reflect.Struct && typ != durationType && typ != timeType
+	return typ.Kind() == reflect.Struct && typ != durationType && typ != timeType && typ != secretRefType
 }
 
-func structToMap(cfg any) map[string]any {
+func structToMap(cfg any, tags []string) map[string]any {
 	val := reflect.ValueOf(cfg)
-	return structValueToMap(val, val.Type())
+	return structValueToMap(val, val.Type(), tags)
 }
 
-func structValueToMap(val reflect.Value, typ reflect.Type) map[string]any {
+func structValueToMap(val reflect.Value, typ reflect.Type, tags []string) map[string]any {
 	if val.Kind() == reflect.Pointer {
 		if val.IsNil() {
 			return map[string]any{}
@@ -66,19 +79,19 @@ func structValueToMap(val reflect.Value, typ reflect.Type) map[string]any {
 			continue
 		}
 
-		key := configTagName(field)
+		key := configTagName(field, tags)
 		if key == "" {
 			continue
 		}
 
 		fieldVal := val.Field(i)
-		out[key] = valueToAny(fieldVal, field.Type)
+		out[key] = valueToAny(fieldVal, field.Type, tags)
 	}
 
 	return out
 }
 
-func valueToAny(val reflect.Value, typ reflect.Type) any {
+func valueToAny(val reflect.Value, typ reflect.Type, tags []string) any {
 	if val.Kind() == reflect.Pointer {
 		if val.IsNil() {
 			return nil
@@ -88,7 +101,7 @@ func valueToAny(val reflect.Value, typ reflect.Type) any {
 	}
 
 	if isStructType(typ) {
-		return structValueToMap(val, typ)
+		return structValueToMap(val, typ, tags)
 	}
 
 	switch val.Kind() {
@@ -99,7 +112,7 @@ func valueToAny(val reflect.Value, typ reflect.Type) any {
 		out := make([]any, val.Len())
 		for i := range val.Len() {
 			elem := val.Index(i)
-			out[i] = valueToAny(elem, elem.Type())
+			out[i] = valueToAny(elem, elem.Type(), tags)
 		}
 
 		return out
@@ -111,7 +124,7 @@ func valueToAny(val reflect.Value, typ reflect.Type) any {
 		iter := val.MapRange()
 		for iter.Next() {
 			key := fmt.Sprintf("%v", iter.Key().Interface())
-			out[key] = valueToAny(iter.Value(), iter.Value().Type())
+			out[key] = valueToAny(iter.Value(), iter.Value().Type(), tags)
 		}
 
 		return out
@@ -121,9 +134,19 @@ func valueToAny(val reflect.Value, typ reflect.Type) any {
 }
 
 func parseConfigBytes(path string, content []byte) (map[string]any, error) {
+	format := formatYAML
+	if isJSONPath(path) {
+		format = formatJSON
+	}
+
+	return parseConfigFormat(format, content)
+}
+
+// parseConfigFormat 按 format 解析配置内容，formatJSON 以外均按 YAML 解析。
+func parseConfigFormat(format string, content []byte) (map[string]any, error) {
 	var raw any
 	var err error
-	if isJSONPath(path) {
+	if format == formatJSON {
 		err = json.Unmarshal(content, &raw)
 	} else {
 		err = yamlv3.Unmarshal(content, &raw)
@@ -132,11 +155,15 @@ func parseConfigBytes(path string, content []byte) (map[string]any, error) {
 		return nil, err
 	}
 
-	normalized := normalizeMapKeys(raw)
-	if normalized == nil {
+	return configRoot(normalizeMapKeys(raw))
+}
+
+// configRoot 校验解析结果的根节点为对象，空文档视为空对象。
+func configRoot(val any) (map[string]any, error) {
+	if val == nil {
 		return map[string]any{}, nil
 	}
-	configMap, ok := normalized.(map[string]any)
+	configMap, ok := val.(map[string]any)
 	if !ok {
 		return nil, errors.New("config root must be object")
 	}
@@ -144,8 +171,89 @@ func parseConfigBytes(path string, content []byte) (map[string]any, error) {
 	return configMap, nil
 }
 
+const (
+	formatYAML = "yaml"
+	formatJSON = "json"
+)
+
+// normalizeFormat 将格式名称规范化为 formatYAML / formatJSON，无法识别时返回空字符串。
+func normalizeFormat(format string) string {
+	switch strings.ToLower(strings.TrimPrefix(format, ".")) {
+	case "yaml", "yml":
+		return formatYAML
+	case "json":
+		return formatJSON
+	default:
+		return ""
+	}
+}
+
+// checkDuplicateJSONKeys 检查 JSON 文档的同一对象中是否出现重复的 key，错误中包含 key 路径与行号。
+//
+// 语法错误留给后续的 json.Unmarshal 报告。
+func checkDuplicateJSONKeys(content []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(content))
+	err := checkDuplicateJSONValue(dec, content, "")
+	if errors.Is(err, errJSONSyntax) {
+		return nil
+	}
+
+	return err
+}
+
+// errJSONSyntax 表示 checkDuplicateJSONValue 遇到了无法继续的语法错误。
+var errJSONSyntax = errors.New("json syntax error")
+
+// checkDuplicateJSONValue 读取 dec 中的下一个值并递归检查其中的对象，path 为该值的 key 路径。
+func checkDuplicateJSONValue(dec *json.Decoder, content []byte, path string) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return errJSONSyntax
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		seen := make(map[string]bool)
+		for dec.More() {
+			tok, err := dec.Token()
+			key, ok := tok.(string)
+			if err != nil || !ok {
+				return errJSONSyntax
+			}
+			child := key
+			if path != "" {
+				child = path + "." + key
+			}
+			if seen[key] {
+				line := 1 + bytes.Count(content[:dec.InputOffset()], []byte("\n"))
+
+				return fmt.Errorf("duplicate key %q at line %d", child, line)
+			}
+			seen[key] = true
+			if err := checkDuplicateJSONValue(dec, content, child); err != nil {
+				return err
+			}
+		}
+	case json.Delim('['):
+		for i := 0; dec.More(); i++ {
+			if err := checkDuplicateJSONValue(dec, content, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	default:
+		return nil
+	}
+
+	// 读取对应的结束符
+	if _, err := dec.Token(); err != nil {
+		return errJSONSyntax
+	}
+
+	return nil
+}
+
 func isJSONPath(path string) bool {
-	return strings.EqualFold(filepath.Ext(path), ".json")
+	return strings.EqualFold(filepath.Ext(trimGzipExt(path)), ".json")
 }
 
 func normalizeMapKeys(val any) any {
@@ -175,10 +283,29 @@ func normalizeMapKeys(val any) any {
 }
 
 func mergeMaps(dst, src map[string]any) {
+	mergeMapsFunc(dst, src, "", "", nil)
+}
+
+// mergeMapsFunc 与 mergeMaps 相同，fn 非 nil 时对每个 key 先调用 fn（见 [WithMergeFunc]）。
+//
+// prefix 为 dst 在配置树中的 key 路径，各段以 delim 拼接。
+func mergeMapsFunc(dst, src map[string]any, prefix, delim string, fn MergeFunc) {
 	for key, value := range src {
+		path := key
+		if prefix != "" {
+			path = prefix + delim + key
+		}
+		if fn != nil {
+			if merged, ok := fn(path, dst[key], value); ok {
+				dst[key] = merged
+
+				continue
+			}
+		}
+
 		if valueMap, ok := value.(map[string]any); ok {
 			if dstMap, ok := dst[key].(map[string]any); ok {
-				mergeMaps(dstMap, valueMap)
+				mergeMapsFunc(dstMap, valueMap, path, delim, fn)
 				continue
 			}
 		}
@@ -187,8 +314,211 @@ func mergeMaps(dst, src map[string]any) {
 	}
 }
 
-func setByPath(dst map[string]any, path string, value any) {
-	parts := strings.Split(path, ".")
+// normalizeKeyCase 返回所有 key 转为小写后的配置树（见 [WithNormalizeKeys]）。
+//
+// 同一层中仅大小写不同的 key 按原始 key 的字典序处理：map 值深度合并，其余后者覆盖前者。
+// 由于大写字母排在小写字母之前，全小写的 key 通常最后写入而胜出。
+func normalizeKeyCase(data map[string]any) map[string]any {
+	out := make(map[string]any, len(data))
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		value := data[key]
+		if child, ok := value.(map[string]any); ok {
+			value = normalizeKeyCase(child)
+		}
+
+		lower := strings.ToLower(key)
+		if valueMap, ok := value.(map[string]any); ok {
+			if existing, ok := out[lower].(map[string]any); ok {
+				mergeMaps(existing, valueMap)
+
+				continue
+			}
+		}
+		out[lower] = value
+	}
+
+	return out
+}
+
+// trimStrings 递归去除字符串值（含 map 与切片中的字符串）的首尾空白。
+func trimStrings(val any) any {
+	switch typed := val.(type) {
+	case string:
+		return strings.TrimSpace(typed)
+	case map[string]any:
+		for key, value := range typed {
+			typed[key] = trimStrings(value)
+		}
+
+		return typed
+	case []any:
+		for i := range typed {
+			typed[i] = trimStrings(typed[i])
+		}
+
+		return typed
+	case []string:
+		out := make([]string, len(typed))
+		for i, s := range typed {
+			out[i] = strings.TrimSpace(s)
+		}
+
+		return out
+	default:
+		return val
+	}
+}
+
+// cloneConfigValue 深拷贝配置树中的 map[string]any 与 []any，其余值原样返回。
+func cloneConfigValue(val any) any {
+	switch typed := val.(type) {
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, value := range typed {
+			out[key] = cloneConfigValue(value)
+		}
+
+		return out
+	case []any:
+		out := make([]any, len(typed))
+		for i, value := range typed {
+			out[i] = cloneConfigValue(value)
+		}
+
+		return out
+	default:
+		return val
+	}
+}
+
+// splitIndexParts 将 "servers[0]"、"matrix[1][2]" 等带下标的段拆为 key 与各 "[N]" 段。
+//
+// 方括号内不是非负整数时整段视为普通 key。
+func splitIndexParts(part string) []string {
+	var indexes []string
+	for strings.HasSuffix(part, "]") {
+		open := strings.LastIndexByte(part, '[')
+		if open <= 0 {
+			break
+		}
+		if _, ok := indexPart(part[open:]); !ok {
+			break
+		}
+		indexes = append(indexes, part[open:])
+		part = part[:open]
+	}
+	if len(indexes) == 0 {
+		return []string{part}
+	}
+	slices.Reverse(indexes)
+
+	return append([]string{part}, indexes...)
+}
+
+// joinKey 是 splitKey 的逆操作，"[N]" 段直接拼接在前一段之后。
+func joinKey(parts []string, delim string) string {
+	var b strings.Builder
+	for i, part := range parts {
+		if _, ok := indexPart(part); !ok && i > 0 {
+			b.WriteString(delim)
+		}
+		b.WriteString(part)
+	}
+
+	return b.String()
+}
+
+// indexPart 判断 splitKey 拆出的段是否为数组下标 "[N]"，并返回下标。
+func indexPart(part string) (int, bool) {
+	if len(part) < 3 || part[0] != '[' || part[len(part)-1] != ']' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(part[1 : len(part)-1])
+	if err != nil || n < 0 || strings.HasPrefix(part, "[+") {
+		return 0, false
+	}
+
+	return n, true
+}
+
+// hasIndexPart 判断 key 路径中是否包含数组下标段。
+func hasIndexPart(parts []string) bool {
+	return slices.ContainsFunc(parts, func(part string) bool {
+		_, ok := indexPart(part)
+		return ok
+	})
+}
+
+// setPathValue 按 key 路径写入值，支持 "[N]" 下标段修改已有数组的元素。
+//
+// map 段的行为与 setByPath 相同；下标段要求该位置已是切片且下标在范围内，否则返回错误。
+// 非 []any 的切片（如 CLI flag 写入的 []string）会转换为 []any 后写入。
+func setPathValue(dst map[string]any, parts []string, value any, delim string) error {
+	_, err := setPathValueAt(dst, parts, value, "", delim)
+
+	return err
+}
+
+// setPathValueAt 将 value 写入 node 中 parts 对应的位置并返回更新后的节点，prefix 为已经过的路径，用于错误信息。
+func setPathValueAt(node any, parts []string, value any, prefix, delim string) (any, error) {
+	if len(parts) == 0 {
+		return value, nil
+	}
+	part := parts[0]
+
+	if idx, ok := indexPart(part); ok {
+		items, ok := toAnySlice(node)
+		if !ok {
+			return nil, fmt.Errorf("config key %q: not an array", prefix)
+		}
+		if idx >= len(items) {
+			return nil, fmt.Errorf("config key %q: index %d out of range (len %d)", prefix+part, idx, len(items))
+		}
+		elem, err := setPathValueAt(items[idx], parts[1:], value, prefix+part, delim)
+		if err != nil {
+			return nil, err
+		}
+		items[idx] = elem
+
+		return items, nil
+	}
+
+	current, ok := node.(map[string]any)
+	if !ok {
+		current = make(map[string]any)
+	}
+	path := part
+	if prefix != "" {
+		path = prefix + delim + part
+	}
+	elem, err := setPathValueAt(current[part], parts[1:], value, path, delim)
+	if err != nil {
+		return nil, err
+	}
+	current[part] = elem
+
+	return current, nil
+}
+
+// toAnySlice 将任意切片转换为 []any，[]any 原样返回。
+func toAnySlice(val any) ([]any, bool) {
+	if items, ok := val.([]any); ok {
+		return items, true
+	}
+	rv := reflect.ValueOf(val)
+	if rv.Kind() != reflect.Slice {
+		return nil, false
+	}
+	items := make([]any, rv.Len())
+	for i := range rv.Len() {
+		items[i] = rv.Index(i).Interface()
+	}
+
+	return items, true
+}
+
+// setByPath 按 key 路径各段写入值，沿途缺失或非 map 的节点会被替换为 map。
+func setByPath(dst map[string]any, parts []string, value any) {
 	current := dst
 	for i, part := range parts {
 		if i == len(parts)-1 {
@@ -206,17 +536,174 @@ func setByPath(dst map[string]any, path string, value any) {
 	}
 }
 
-func decodeConfigMap(data map[string]any, out any) error {
+// getByPath 按 key 路径各段读取值，"[N]" 段读取数组元素，下标越界视为不存在。
+func getByPath(src map[string]any, parts []string) (any, bool) {
+	if len(parts) == 0 {
+		return nil, false
+	}
+
+	var current any = src
+	for _, part := range parts {
+		if idx, ok := indexPart(part); ok {
+			if rv := reflect.ValueOf(current); rv.Kind() == reflect.Slice && idx < rv.Len() {
+				current = rv.Index(idx).Interface()
+
+				continue
+			}
+		}
+
+		node, ok := current.(map[string]any)
+		if !ok {
+			return nil, false
+		}
+		if current, ok = node[part]; !ok {
+			return nil, false
+		}
+	}
+
+	return current, true
+}
+
+func decodeConfigMap(data map[string]any, out any, o *options) error {
+	err := decodeConfigValue(data, out, o)
+	if err != nil && o.decodeErrContext {
+		return describeDecodeError(err, data, reflect.TypeOf(out), o)
+	}
+
+	return err
+}
+
+// fieldDecodeError 是 [WithUnmarshalErrorContext] 改写后的单个字段解码错误。
+type fieldDecodeError struct {
+	path  string
+	value any
+	found bool         // 配置树中找到了该 key 的值
+	want  reflect.Type // 目标字段类型，未知时为 nil
+	err   error
+}
+
+func (e *fieldDecodeError) Error() string {
+	var b strings.Builder
+	b.WriteString("cannot decode " + e.path)
+	switch {
+	case !e.found:
+	case e.want == secretRefType:
+		fmt.Fprintf(&b, ": got %T (redacted)", e.value)
+	case isString(e.value):
+		fmt.Fprintf(&b, ": got %T %q", e.value, e.value)
+	default:
+		fmt.Fprintf(&b, ": got %T %v", e.value, e.value)
+	}
+	if e.want != nil {
+		fmt.Fprintf(&b, ", want %s", e.want)
+	} else {
+		b.WriteString(": " + e.err.Error())
+	}
+
+	return b.String()
+}
+
+func (e *fieldDecodeError) Unwrap() error { return e.err }
+
+// isString 判断 v 是否为字符串。
+func isString(v any) bool {
+	_, ok := v.(string)
+
+	return ok
+}
+
+// describeDecodeError 为 mapstructure 的每个字段错误补充 key 路径、实际值及其类型与目标类型。
+//
+// 值与类型优先取自错误本身，解码钩子返回的错误则按路径从配置树与结构体中查找；
+// 目标为 [SecretRef] 的值不写入错误信息。
+func describeDecodeError(err error, data map[string]any, typ reflect.Type, o *options) error {
+	delim := o.keyDelim()
+	tags := o.tagChain()
+	keyTypes := collectConfigKeyTypes(typ, delim, tags)
+
+	var errs []error
+	for _, leaf := range decodeErrorLeaves(err) {
+		var decodeErr *mapstructure.DecodeError
+		if !errors.As(leaf, &decodeErr) {
+			errs = append(errs, leaf)
+
+			continue
+		}
+		var parts []string
+		for part := range strings.SplitSeq(decodeErr.Name(), ".") {
+			parts = append(parts, splitIndexParts(part)...)
+		}
+		if !slices.Equal(tags, defaultConfigTags) {
+			parts = taggedKeyParts(typ, parts, tags)
+		}
+		field := &fieldDecodeError{path: joinKey(parts, delim), err: decodeErr.Unwrap()}
+		field.value, field.found = getByPath(data, parts)
+		field.want = keyTypes[field.path]
+
+		var parseErr *mapstructure.ParseError
+		var convErr *mapstructure.UnconvertibleTypeError
+		switch {
+		case errors.As(leaf, &parseErr):
+			field.value, field.found, field.want = parseErr.Value, true, parseErr.Expected.Type()
+		case errors.As(leaf, &convErr):
+			field.value, field.found, field.want = convErr.Value, true, convErr.Expected.Type()
+		}
+		errs = append(errs, field)
+	}
+
+	return errors.Join(errs...)
+}
+
+// decodeErrorLeaves 展开 errors.Join 与嵌套的 mapstructure.DecodeError，返回最内层的字段错误。
+func decodeErrorLeaves(err error) []error {
+	var inner error
+	switch e := err.(type) {
+	case interface{ Unwrap() []error }:
+		var leaves []error
+		for _, child := range e.Unwrap() {
+			leaves = append(leaves, decodeErrorLeaves(child)...)
+		}
+
+		return leaves
+	case *mapstructure.DecodeError:
+		inner = e.Unwrap()
+	default:
+		inner = errors.Unwrap(err)
+	}
+
+	var nested *mapstructure.DecodeError
+	if inner == nil || !errors.As(inner, &nested) {
+		return []error{err}
+	}
+
+	return decodeErrorLeaves(inner)
+}
+
+// decodeConfigValue 使用与主配置一致的解码规则将任意配置值解码到 out。
+func decodeConfigValue(data any, out any, o *options) error {
+	hooks := []mapstructure.DecodeHookFunc{
+		secretRefHookFunc(o.secretResolver),
+		variantHookFunc(o),
+		mapstructure.StringToTimeDurationHookFunc(),
+		mapstructure.TextUnmarshallerHookFunc(),
+	}
+	if o.humanizedValues {
+		hooks = append(hooks, byteSizeHookFunc())
+	}
+
 	conf := &mapstructure.DecoderConfig{
-		DecodeHook: mapstructure.ComposeDecodeHookFunc(
-			mapstructure.StringToTimeDurationHookFunc(),
-			mapstructure.TextUnmarshallerHookFunc(),
-		),
+		DecodeHook:       mapstructure.ComposeDecodeHookFunc(hooks...),
 		Metadata:         nil,
 		Result:           out,
-		WeaklyTypedInput: true,
+		WeaklyTypedInput: o.unmarshalMode != UnmarshalStrict,
 		TagName:          "json",
 	}
+	// WithUnmarshalTagChain: mapstructure 只读取单个 tag，先将配置 key 改写为 Go 字段名，再按字段名精确匹配
+	if tags := o.tagChain(); !slices.Equal(tags, defaultConfigTags) {
+		data = renameTaggedKeys(data, reflect.TypeOf(out), tags)
+		conf.TagName = tagChainDecodeTag
+		conf.MatchName = func(key, field string) bool { return key == field }
+	}
 	decoder, err := mapstructure.NewDecoder(conf)
 	if err != nil {
 		return err
@@ -225,18 +712,116 @@ func decodeConfigMap(data map[string]any, out any) error {
 	return decoder.Decode(data)
 }
 
-func flattenMapKeys(data map[string]any) []string {
+// tagChainDecodeTag 是设置 [WithUnmarshalTagChain] 时交给 mapstructure 的 tag 名称。
+//
+// 结构体不会声明该 tag，mapstructure 因此按 Go 字段名匹配由 renameTaggedKeys 改写后的 key。
+const tagChainDecodeTag = "cfgm-tag-chain"
+
+// renameTaggedKeys 按 typ 的结构返回 data 的副本，其中由 tags 决定的配置 key 改写为对应的 Go 字段名。
+//
+// 不对应任何字段的 key 保持原样，严格解码模式下仍会报告为未使用的 key。
+func renameTaggedKeys(data any, typ reflect.Type, tags []string) any {
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		obj, ok := data.(map[string]any)
+		if !ok || !isStructType(typ) {
+			return data
+		}
+		renamed := make(map[string]any)
+		used := make(map[string]bool)
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			key := configTagName(field, tags)
+			if key == "" || field.PkgPath != "" {
+				continue
+			}
+			if value, ok := obj[key]; ok {
+				renamed[field.Name] = renameTaggedKeys(value, field.Type, tags)
+				used[key] = true
+			}
+		}
+		out := make(map[string]any, len(obj))
+		for key, value := range obj {
+			if !used[key] {
+				out[key] = value
+			}
+		}
+		maps.Copy(out, renamed)
+
+		return out
+	case reflect.Slice, reflect.Array:
+		items, ok := data.([]any)
+		if !ok {
+			return data
+		}
+		out := make([]any, len(items))
+		for i, item := range items {
+			out[i] = renameTaggedKeys(item, typ.Elem(), tags)
+		}
+
+		return out
+	case reflect.Map:
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return data
+		}
+		out := make(map[string]any, len(obj))
+		for key, value := range obj {
+			out[key] = renameTaggedKeys(value, typ.Elem(), tags)
+		}
+
+		return out
+	default:
+		return data
+	}
+}
+
+// taggedKeyParts 将 mapstructure 错误中以 Go 字段名表示的路径段还原为由 tags 决定的配置 key，见 renameTaggedKeys。
+func taggedKeyParts(typ reflect.Type, parts []string, tags []string) []string {
+	out := slices.Clone(parts)
+	for i, part := range out {
+		for typ != nil && typ.Kind() == reflect.Pointer {
+			typ = typ.Elem()
+		}
+		if typ == nil {
+			break
+		}
+		switch typ.Kind() {
+		case reflect.Struct:
+			field, ok := typ.FieldByName(part)
+			if !ok {
+				return out
+			}
+			if key := configTagName(field, tags); key != "" {
+				out[i] = key
+			}
+			typ = field.Type
+		case reflect.Slice, reflect.Array, reflect.Map:
+			typ = typ.Elem()
+		default:
+			return out
+		}
+	}
+
+	return out
+}
+
+func flattenMapKeys(data map[string]any, delim string) []string {
 	var keys []string
-	flattenMapKeysRecursive(data, "", &keys)
+	flattenMapKeysRecursive(data, "", delim, &keys)
 
 	return keys
 }
 
-func flattenMapKeysRecursive(data map[string]any, prefix string, keys *[]string) {
+func flattenMapKeysRecursive(data map[string]any, prefix, delim string, keys *[]string) {
 	for key, value := range data {
 		fullKey := key
 		if prefix != "" {
-			fullKey = prefix + "." + key
+			fullKey = prefix + delim + key
 		}
 		if child, ok := value.(map[string]any); ok {
 			if len(child) == 0 {
@@ -244,7 +829,7 @@ func flattenMapKeysRecursive(data map[string]any, prefix string, keys *[]string)
 
 				continue
 			}
-			flattenMapKeysRecursive(child, fullKey, keys)
+			flattenMapKeysRecursive(child, fullKey, delim, keys)
 
 			continue
 		}
@@ -252,3 +837,44 @@ func flattenMapKeysRecursive(data map[string]any, prefix string, keys *[]string)
 		*keys = append(*keys, fullKey)
 	}
 }
+
+// matchFileCase 在 path 所在目录中按大小写不敏感查找文件名，返回磁盘上的实际路径。
+//
+// 存在大小写完全一致的条目时优先使用；目录不可读或没有匹配时原样返回 path。
+func matchFileCase(path string) string {
+	dir, base := filepath.Split(path)
+	entries, err := os.ReadDir(cmp.Or(dir, "."))
+	if err != nil {
+		return path
+	}
+
+	match := ""
+	for _, entry := range entries {
+		name := entry.Name()
+		if name == base {
+			return path
+		}
+		if match == "" && strings.EqualFold(name, base) {
+			match = name
+		}
+	}
+	if match == "" {
+		return path
+	}
+
+	return filepath.Join(dir, match)
+}
+
+// canonicalPath 返回用于判断是否为同一文件的规范化路径：绝对路径、解析符号链接并 Clean。
+//
+// 符号链接无法解析（如文件不存在）时退回 Clean 后的绝对路径。
+func canonicalPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if realPath, err := filepath.EvalSymlinks(path); err == nil {
+		path = realPath
+	}
+
+	return filepath.Clean(path)
+}
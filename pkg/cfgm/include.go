@@ -0,0 +1,123 @@
+package cfgm
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	yamlv3 "go.yaml.in/yaml/v3"
+
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/templexp"
+)
+
+// includeTag 是 [WithYAMLInclude] 识别的 YAML 标签。
+const includeTag = "!include"
+
+// parseYAMLWithIncludes 解析 YAML 内容并将 !include 节点替换为被引用文件的内容。
+//
+// dir 为相对路径的解析基准；stack 为当前包含链上各文件的真实路径，用于检测循环包含。
+func parseYAMLWithIncludes(path string, content []byte, dir string, options *options, stack []string) (any, error) {
+	var node yamlv3.Node
+	if err := yamlv3.Unmarshal(content, &node); err != nil {
+		return nil, err
+	}
+	if err := resolveIncludes(&node, dir, options, stack); err != nil {
+		return nil, err
+	}
+
+	var raw any
+	if err := node.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", path, err)
+	}
+
+	return normalizeMapKeys(raw), nil
+}
+
+// resolveIncludes 递归查找 !include 标量节点并原地替换为被引用文件解析后的节点。
+func resolveIncludes(node *yamlv3.Node, dir string, options *options, stack []string) error {
+	if node.Kind == yamlv3.ScalarNode && node.Tag == includeTag {
+		val, err := loadIncludedFile(node.Value, dir, options, stack)
+		if err != nil {
+			return fmt.Errorf("line %d: %w", node.Line, err)
+		}
+
+		var replacement yamlv3.Node
+		if err := replacement.Encode(val); err != nil {
+			return fmt.Errorf("line %d: include %s: %w", node.Line, node.Value, err)
+		}
+		*node = replacement
+
+		return nil
+	}
+
+	for _, child := range node.Content {
+		if err := resolveIncludes(child, dir, options, stack); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// loadIncludedFile 读取并解析被 !include 引用的文件，YAML 文件中的 !include 继续递归展开。
+//
+// 被引用文件同样执行编码转换、解压与模板展开，但不做 [WithConfigChecksum] 校验。
+func loadIncludedFile(target, dir string, options *options, stack []string) (any, error) {
+	path := target
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(dir, path)
+	}
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", target, err)
+	}
+	if slices.Contains(stack, realPath) {
+		return nil, fmt.Errorf("include %s: cycle detected: %v", target, append(slices.Clip(stack), realPath))
+	}
+	// WithConfigPathsResolveSymlinks: 嵌套包含以链接目标所在目录为基准
+	if options.resolveSymlinks {
+		path = realPath
+	}
+
+	file, err := os.Open(path) //nolint:gosec // path is from trusted config
+	if err != nil {
+		return nil, fmt.Errorf("include %s: %w", target, err)
+	}
+	defer func() { _ = file.Close() }()
+
+	content, err := readLimited(file, path, options.maxFileSize)
+	if err != nil {
+		return nil, err
+	}
+	if content, err = decompressConfig(path, content, options.maxFileSize); err != nil {
+		return nil, err
+	}
+	if content, err = decodeConfigContent(path, content, options.configEncoding); err != nil {
+		return nil, err
+	}
+	if !options.noTemplateExpansion {
+		expanded, err := templexp.ExpandTemplate(string(content), options.templateOptions()...)
+		if err != nil {
+			return nil, fmt.Errorf("expand template in %s: %w", path, err)
+		}
+		content = []byte(expanded)
+	}
+
+	if options.fileFormat(path) == formatJSON {
+		var raw any
+		if err := json.Unmarshal(content, &raw); err != nil {
+			return nil, fmt.Errorf("parse included file %s: %w", path, err)
+		}
+
+		return normalizeMapKeys(raw), nil
+	}
+
+	val, err := parseYAMLWithIncludes(path, content, filepath.Dir(path), options, append(slices.Clip(stack), realPath))
+	if err != nil {
+		return nil, fmt.Errorf("parse included file %s: %w", path, err)
+	}
+
+	return val, nil
+}
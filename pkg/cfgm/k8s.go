@@ -0,0 +1,89 @@
+package cfgm
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// loadK8sConfigMapDir 读取 Kubernetes ConfigMap 挂载目录，每个文件对应一个顶层配置节点。
+//
+// 文件名去掉 .gz 与 .yaml/.yml/.json 扩展名后作为节点名，带扩展名的文件按格式解析为对象，
+// 其余文件的内容（去掉首尾空白）作为字符串值。以 "." 开头的条目（..data、..2024_xx 等
+// 投影卷内部链接）与子目录被忽略；目录不存在时返回空结果。
+func loadK8sConfigMapDir(dir string, options *options, report *loadReport) ([]configLayer, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("read configmap dir %s: %w", dir, err)
+	}
+
+	var layers []configLayer
+	for _, entry := range entries {
+		name := entry.Name()
+		if strings.HasPrefix(name, ".") {
+			continue
+		}
+		path := filepath.Join(dir, name)
+		if info, err := os.Stat(path); err != nil || info.IsDir() {
+			continue // 投影卷中的文件为符号链接，按链接目标判断
+		}
+
+		base := trimGzipExt(name)
+		ext := filepath.Ext(base)
+		section := strings.TrimSuffix(base, ext)
+		format := options.fileFormat(name)
+		if format == "" {
+			section = name
+		}
+		if options.normalizeKeys {
+			section = strings.ToLower(section)
+		}
+		if format == "" {
+			layer, ok, err := readK8sScalarLayer(path, section, options)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				layers = append(layers, layer)
+			}
+
+			continue
+		}
+
+		layer, ok, err := readConfigLayer(path, options, report)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			layer.data = map[string]any{section: layer.data}
+			layers = append(layers, layer)
+		}
+	}
+	slog.Debug("Loaded configmap dir", "dir", dir, "keys", len(layers))
+
+	return layers, nil
+}
+
+// readK8sScalarLayer 将无扩展名的 ConfigMap 文件读取为 {section: 内容} 配置层。
+func readK8sScalarLayer(path, section string, options *options) (configLayer, bool, error) {
+	file, err := os.Open(path) //nolint:gosec // path is from trusted config
+	if err != nil {
+		return configLayer{}, false, nil
+	}
+	defer func() { _ = file.Close() }()
+
+	content, err := readLimited(file, path, options.maxFileSize)
+	if err != nil {
+		return configLayer{}, false, err
+	}
+
+	return configLayer{path: path, data: map[string]any{section: strings.TrimSpace(string(content))}}, true, nil
+}
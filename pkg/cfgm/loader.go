@@ -0,0 +1,398 @@
+package cfgm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/templexp"
+)
+
+// ErrClosed 表示 [Loader] 已通过 [Loader.Close] 关闭。
+var ErrClosed = errors.New("cfgm: loader closed")
+
+// Loader 持有一次加载的结果与合并后的配置树。
+//
+// 与 [Load] 使用同一套选项与优先级，额外提供按 key 的动态读取，
+// 适合需要在运行期访问配置树的场景（如 [WithLazyKeys]）。
+// 通过 [Loader.Reload] 或 [Loader.Watch] 可在运行期重新加载，所有方法并发安全。
+type Loader[T any] struct {
+	options        *options
+	defaults       T
+	validateReload func(prev, next *T) error // WithConfigPathsReloadValidation 的校验函数，类型在 NewLoader 时检查
+
+	reloadMu sync.Mutex // 串行化 Reload，保证校验对比的正是被替换的配置，且较早开始的加载不会覆盖较新的结果
+
+	mu          sync.RWMutex
+	data        map[string]any
+	cfg         atomic.Pointer[T] // 每次加载生成新的结构体后整体替换，见 Snapshot
+	files       []fileState       // 最近一次加载时各候选配置路径的状态，供 Watch 比较
+	fingerprint string            // 合并后配置树的摘要，见 Fingerprint
+
+	closeMu  sync.Mutex
+	closed   chan struct{}  // Close 时关闭，通知 Watch 退出
+	watchers sync.WaitGroup // 运行中的 Watch
+
+	listenersMu sync.Mutex
+	listeners   []*reloadListener[T] // OnReload 注册的回调，按注册顺序调用
+}
+
+// reloadListener 是一个 [Loader.OnReload] 订阅，removed 在取消订阅后置位。
+type reloadListener[T any] struct {
+	fn      func(prev, next *T)
+	removed atomic.Bool
+}
+
+// NewLoader 按 [Load] 的规则加载配置并返回 [Loader]。
+//
+// 示例：
+//
+//	loader, err := cfgm.NewLoader(DefaultConfig(),
+//	    cfgm.WithAppName("myapp"),
+//	    cfgm.WithLazyKeys("db.password"),
+//	)
+//	cfg := loader.Config()
+func NewLoader[T any](defaultConfig T, opts ...Option) (*Loader[T], error) {
+	options := newOptions(opts...)
+	options.resolve(0)
+
+	l := &Loader[T]{options: options, defaults: defaultConfig, closed: make(chan struct{})}
+	if options.reloadValidator != nil {
+		validate, ok := options.reloadValidator.(func(prev, next *T) error)
+		if !ok {
+			return nil, fmt.Errorf("reload validation func: got %T, want func(prev, next *%T) error",
+				options.reloadValidator, defaultConfig)
+		}
+		l.validateReload = validate
+	}
+	if err := l.Reload(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// Reload 按创建时的选项重新加载配置。
+//
+// 新配置通过全部校验（含 [WithConfigPathsReloadValidation]）后才替换当前配置，
+// 加载或校验失败时返回错误并保留当前配置；[Loader.Close] 之后返回 [ErrClosed]。
+// 并发调用（包括 [Loader.Watch] 触发的重新加载）依次执行，不会以较旧的结果覆盖较新的配置。
+func (l *Loader[T]) Reload() error {
+	_, err := l.reload()
+
+	return err
+}
+
+// reload 实现 [Loader.Reload]，并在替换配置的同一临界区内报告合并后的配置树是否与替换前不同，
+// 供 [WithOnConfigReload] 的 [ReloadEvent].Changed 使用。
+func (l *Loader[T]) reload() (changed bool, err error) {
+	if l.isClosed() {
+		return false, ErrClosed
+	}
+	l.reloadMu.Lock()
+	defer l.reloadMu.Unlock()
+
+	start := time.Now()
+
+	// 先记录文件状态再读取，读取期间发生的修改会在下一轮 Watch 中被发现
+	files := snapshotFiles(l.options.watchedPaths())
+
+	configMap, report, err := buildConfigMap(l.defaults, l.options)
+	if err != nil {
+		return false, err
+	}
+
+	cfg, err := finishConfig[T](configMap, l.options, report)
+	if err != nil {
+		return false, err
+	}
+	// WithConfigPathsReloadValidation: 全部校验通过后再对比当前配置，失败时不替换
+	if prev := l.cfg.Load(); prev != nil && l.validateReload != nil {
+		if err := l.validateReload(prev, &cfg); err != nil {
+			return false, fmt.Errorf("reload validation failed: %w", err)
+		}
+	}
+	report.finishMetrics(l.options, start)
+	fingerprint := configFingerprint(configMap)
+
+	l.mu.Lock()
+	changed = !reflect.DeepEqual(l.data, configMap)
+	l.data, l.files, l.fingerprint = configMap, files, fingerprint
+	old := l.cfg.Swap(&cfg)
+	l.mu.Unlock()
+
+	if old != nil {
+		l.notifyReload(old, &cfg)
+	}
+
+	return changed, nil
+}
+
+// OnReload 注册在每次成功重新加载后调用的回调，返回取消订阅的函数。
+//
+// [Loader.Reload] 与 [Loader.Watch] 触发的重新加载都会按注册顺序调用全部回调，参数为替换前后的配置快照；
+// 加载失败时不调用。可注册多个回调，使各子系统分别响应配置变化，而不必共用 Watch 的 onChange。
+// 回调在执行重新加载的 goroutine 中同步调用，可以在其中（包括回调自身）取消订阅，
+// 已取消的回调不会再被调用；取消订阅函数可重复调用。
+// 回调执行期间下一次重新加载会等待，因此回调中不能调用 [Loader.Reload]。
+//
+// 示例：
+//
+//	unsubscribe := loader.OnReload(func(prev, next *Config) {
+//	    if prev.LogLevel != next.LogLevel {
+//	        setLogLevel(next.LogLevel)
+//	    }
+//	})
+//	defer unsubscribe()
+func (l *Loader[T]) OnReload(fn func(prev, next *T)) (unsubscribe func()) {
+	listener := &reloadListener[T]{fn: fn}
+	l.listenersMu.Lock()
+	l.listeners = append(l.listeners, listener)
+	l.listenersMu.Unlock()
+
+	return func() {
+		listener.removed.Store(true)
+		l.listenersMu.Lock()
+		l.listeners = slices.DeleteFunc(l.listeners, func(r *reloadListener[T]) bool { return r == listener })
+		l.listenersMu.Unlock()
+	}
+}
+
+// notifyReload 依次调用 OnReload 注册的回调；调用期间不持有锁，回调中可以注册或取消订阅。
+func (l *Loader[T]) notifyReload(old, cfg *T) {
+	l.listenersMu.Lock()
+	listeners := slices.Clone(l.listeners)
+	l.listenersMu.Unlock()
+
+	for _, listener := range listeners {
+		if !listener.removed.Load() {
+			listener.fn(old, cfg)
+		}
+	}
+}
+
+// Close 停止全部运行中的 [Loader.Watch] 并等待其返回，之后的 [Loader.Reload] 返回 [ErrClosed]。
+//
+// 关闭后 [Loader.Config] 与按 key 读取的方法仍返回最后一次加载的结果。
+// 可重复调用，始终返回 nil；不要在 Watch 的 onChange 回调中调用，否则会等待自身返回而阻塞。
+func (l *Loader[T]) Close() error {
+	l.closeMu.Lock()
+	if !l.isClosed() {
+		close(l.closed)
+	}
+	l.closeMu.Unlock()
+	l.watchers.Wait()
+
+	return nil
+}
+
+// isClosed 判断是否已调用 [Loader.Close]。
+func (l *Loader[T]) isClosed() bool {
+	select {
+	case <-l.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// Fingerprint 返回当前生效配置的摘要（SHA-256 十六进制字符串），每次成功的 [Loader.Reload] 后重新计算。
+//
+// 摘要基于合并后的完整配置树，涵盖默认值、配置文件、环境变量与 CLI flags 等全部来源：
+// 内容相同的配置得到相同的摘要，与来源的顺序、文件中的注释与格式无关。
+// 可用于判断一次重新加载是否需要处理，或作为缓存的 key，比逐项比较配置更廉价。
+//
+// 摘要反映真实内容，包括 password、token 等敏感值的明文（[SecretRef] 为引用本身），
+// 敏感值变化同样会改变摘要；摘要不可逆，但低熵的值可被穷举，不要将其作为公开信息输出。
+func (l *Loader[T]) Fingerprint() string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	return l.fingerprint
+}
+
+// configFingerprint 计算配置树的 SHA-256 摘要。
+//
+// encoding/json 按 key 排序输出 map，因此结果与合并顺序无关；无法编码为 JSON 的值（如 func）退回 %#v 表示。
+func configFingerprint(data map[string]any) string {
+	content, err := json.Marshal(data)
+	if err != nil {
+		content = fmt.Appendf(nil, "%#v", data)
+	}
+	sum := sha256.Sum256(content)
+
+	return hex.EncodeToString(sum[:])
+}
+
+// Config 返回当前的配置结构体，与 [Loader.Snapshot] 相同。
+func (l *Loader[T]) Config() *T {
+	return l.Snapshot()
+}
+
+// Snapshot 无锁返回当前配置的快照。
+//
+// 每次 [Loader.Reload]（包括 [Loader.Watch] 触发的重新加载）都会生成新的结构体并原子替换，
+// 已取得的快照不会被修改，并发读取时不会看到更新到一半的配置。
+// 快照在多个 goroutine 间共享，调用方不应修改其内容；需要一致视图时在一次处理中只取一次快照：
+//
+//	cfg := loader.Snapshot()
+//	serve(cfg.Server.Host, cfg.Server.Port)
+func (l *Loader[T]) Snapshot() *T {
+	return l.cfg.Load()
+}
+
+// UnmarshalKey 将 key 对应的子树解码为 V，适合插件只读取自己的配置片段。
+//
+// 解码规则与主配置一致（json tag、弱类型转换、time.Duration 等解码钩子）。
+// key 不存在或不是对象（子树）时返回错误。
+//
+// 示例：
+//
+//	db, err := cfgm.UnmarshalKey[DBConfig](loader, "plugins.db")
+func UnmarshalKey[V, T any](l *Loader[T], key string) (*V, error) {
+	l.mu.RLock()
+	val, ok := getByPath(l.data, l.options.splitKey(key))
+	l.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("config key %q not found", key)
+	}
+	section, ok := val.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("config key %q is not a section", key)
+	}
+
+	var out V
+	if err := decodeConfigMap(section, &out, l.options); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config key %q: %w", key, err)
+	}
+
+	return &out, nil
+}
+
+// Lookup 返回 path 对应的值并按主配置的解码规则转换为 V（如 "30s" → time.Duration，"8080" → int）。
+//
+// path 不存在或无法转换时返回 V 的零值与 false。[WithLazyKeys] 声明的 key 会在读取时重新展开。
+// 适合 key 在运行期才确定的插件式配置。path 可以用 "[N]" 读取数组元素（如 "servers[0].url"），下标越界视为不存在；
+// [UnmarshalKey] 与 Get* 系列方法同样支持该写法。
+//
+// 示例：
+//
+//	timeout, ok := cfgm.Lookup[time.Duration](loader, "plugins.cache.timeout")
+func Lookup[V, T any](l *Loader[T], path string) (V, bool) {
+	var out V
+	val, ok := l.value(path)
+	if !ok {
+		return out, false
+	}
+	if err := decodeConfigValue(val, &out, l.options); err != nil {
+		return out, false
+	}
+
+	return out, true
+}
+
+// Bind 返回读取 path 当前值的函数，值按 [Lookup] 的规则转换为 V。
+//
+// 返回的函数每次调用都读取最新一次加载的配置树，[Loader.Reload] 与 [Loader.Watch] 的更新立即可见，
+// 适合在组件中持有热更新字段的实时视图。绑定时 path 必须存在且能转换为 V，否则返回错误；
+// 之后的重新加载使 path 不存在或无法转换时，函数返回 V 的零值。
+//
+// 示例：
+//
+//	timeout, err := cfgm.Bind[time.Duration](loader, "server.timeout")
+//	ctx, cancel := context.WithTimeout(ctx, timeout())
+func Bind[V, T any](l *Loader[T], path string) (func() V, error) {
+	val, ok := l.value(path)
+	if !ok {
+		return nil, fmt.Errorf("config key %q not found", path)
+	}
+	var out V
+	if err := decodeConfigValue(val, &out, l.options); err != nil {
+		return nil, fmt.Errorf("config key %q cannot be bound as %T: %w", path, out, err)
+	}
+
+	return func() V {
+		v, _ := Lookup[V](l, path)
+
+		return v
+	}, nil
+}
+
+// GetString 返回 path 对应的字符串值，path 不存在时返回空字符串。
+//
+// 若 path 由 [WithLazyKeys] 声明，每次调用都会重新执行模板展开；展开失败时返回空字符串。
+func (l *Loader[T]) GetString(path string) string {
+	val, ok := l.value(path)
+	if !ok {
+		return ""
+	}
+
+	return fmt.Sprintf("%v", val)
+}
+
+// GetInt 返回 path 对应的整数值，path 不存在或无法转换时返回 0，见 [Lookup]。
+func (l *Loader[T]) GetInt(path string) int {
+	v, _ := Lookup[int](l, path)
+
+	return v
+}
+
+// GetBool 返回 path 对应的布尔值，path 不存在或无法转换时返回 false，见 [Lookup]。
+func (l *Loader[T]) GetBool(path string) bool {
+	v, _ := Lookup[bool](l, path)
+
+	return v
+}
+
+// GetDuration 返回 path 对应的 time.Duration，path 不存在或无法转换时返回 0，见 [Lookup]。
+func (l *Loader[T]) GetDuration(path string) time.Duration {
+	v, _ := Lookup[time.Duration](l, path)
+
+	return v
+}
+
+// GetStringSlice 返回 path 对应的字符串切片，path 不存在或无法转换时返回 nil，见 [Lookup]。
+func (l *Loader[T]) GetStringSlice(path string) []string {
+	v, _ := Lookup[[]string](l, path)
+
+	return v
+}
+
+// value 返回 path 对应的原始值，lazy key 会重新展开；展开失败视为不存在。
+func (l *Loader[T]) value(path string) (any, bool) {
+	l.mu.RLock()
+	val, ok := getByPath(l.data, l.options.splitKey(path))
+	l.mu.RUnlock()
+	if !ok || !l.isLazyKey(path) {
+		return val, ok
+	}
+
+	expanded, err := templexp.ExpandTemplate(fmt.Sprintf("%v", val), l.options.templateOptions()...)
+	if err != nil {
+		return nil, false
+	}
+
+	return expanded, true
+}
+
+// isLazyKey 判断 path 是否声明为延迟展开，按 splitKey 的规则比较（[WithNormalizeKeys] 时忽略大小写）。
+func (l *Loader[T]) isLazyKey(path string) bool {
+	if l.options.noTemplateExpansion {
+		return false
+	}
+	parts := l.options.splitKey(path)
+	for _, key := range l.options.lazyKeys {
+		if slices.Equal(l.options.splitKey(key), parts) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,432 @@
+package cfgm
+
+import (
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderLazyKeys(t *testing.T) {
+	type DBConfig struct {
+		Host     string `json:"host"`
+		Password string `json:"password"`
+	}
+	type Config struct {
+		DB DBConfig `json:"db"`
+	}
+
+	path := writeTempConfig(t, `
+db:
+  host: '${LAZY_DB_HOST:-localhost}'
+  password: '${LAZY_DB_PASSWORD:-none}'
+`)
+
+	t.Setenv("LAZY_DB_PASSWORD", "v1")
+	loader, err := NewLoader(Config{}, WithConfigPaths(path), WithLazyKeys("db.password"))
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("localhost", loader.Config().DB.Host, "non-lazy key expanded at load")
+	a.Equal("${LAZY_DB_PASSWORD:-none}", loader.Config().DB.Password, "lazy key keeps raw template")
+	a.Equal("v1", loader.GetString("db.password"))
+
+	t.Setenv("LAZY_DB_PASSWORD", "v2")
+	a.Equal("v2", loader.GetString("db.password"), "lazy key re-expanded on access")
+	a.Equal("localhost", loader.GetString("db.host"))
+	a.Empty(loader.GetString("db.missing"))
+
+	t.Run("normalized keys and custom delimiter", func(t *testing.T) {
+		loader, err := NewLoader(Config{},
+			WithConfigPaths(path),
+			WithNormalizeKeys(),
+			WithKeyDelim("/"),
+			WithLazyKeys("DB/Password"),
+		)
+		require.NoError(t, err)
+
+		a := assert.New(t)
+		a.Equal("${LAZY_DB_PASSWORD:-none}", loader.Config().DB.Password)
+		a.Equal("v2", loader.GetString("db/password"))
+		a.Equal("v2", loader.GetString("DB/Password"), "lookup with different case still expands")
+		a.Equal("localhost", loader.GetString("DB/HOST"))
+	})
+}
+
+func TestWithKeyDelim(t *testing.T) {
+	type Host struct {
+		Endpoint string `json:"endpoint"`
+	}
+	//nolint:tagliatelle
+	type Config struct {
+		Hosts   map[string]Host `json:"hosts"`
+		Primary string          `json:"primary.host"`
+	}
+
+	path := writeTempConfig(t, `
+hosts:
+  api.example.com:
+    endpoint: "${DELIM_ENDPOINT:-https://api.example.com}"
+primary.host: "from-file"
+`)
+	t.Setenv("DELIM_ENDPOINT", "https://v1")
+	t.Setenv("DELIM_PRIMARY_HOST", "from-env")
+
+	loader, err := NewLoader(Config{},
+		WithConfigPaths(path),
+		WithKeyDelim("/"),
+		WithEnvPrefix("DELIM_"),
+		WithLazyKeys("hosts/api.example.com/endpoint"),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal("from-env", loader.Config().Primary, "dotted struct key bound from env")
+	a.Equal("https://v1", loader.GetString("hosts/api.example.com/endpoint"))
+	a.Equal("from-env", loader.GetString("primary.host"))
+
+	layers, err := MergePreview(WithConfigPaths(path), WithKeyDelim("/"))
+	require.NoError(t, err)
+	require.Len(t, layers, 1)
+	a.Equal([]string{"hosts/api.example.com/endpoint", "primary.host"}, layers[0].Keys)
+}
+
+func TestUnmarshalKey(t *testing.T) {
+	type PluginConfig struct {
+		Endpoint string        `json:"endpoint"`
+		Timeout  time.Duration `json:"timeout"`
+		Retries  int           `json:"retries"`
+	}
+	type Config struct {
+		Name    string         `json:"name"`
+		Plugins map[string]any `json:"plugins"`
+	}
+
+	path := writeTempConfig(t, `
+name: app
+plugins:
+  cache:
+    endpoint: redis://localhost
+    timeout: 3s
+    retries: "2"
+`)
+	loader, err := NewLoader(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	plugin, err := UnmarshalKey[PluginConfig](loader, "plugins.cache")
+	require.NoError(t, err)
+	assert.Equal(t, PluginConfig{Endpoint: "redis://localhost", Timeout: 3 * time.Second, Retries: 2}, *plugin)
+
+	_, err = UnmarshalKey[PluginConfig](loader, "plugins.missing")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+
+	_, err = UnmarshalKey[PluginConfig](loader, "name")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "not a section")
+}
+
+func TestLoaderTypedGetters(t *testing.T) {
+	type Config struct {
+		Plugins map[string]any `json:"plugins"`
+	}
+
+	path := writeTempConfig(t, `
+plugins:
+  cache:
+    port: "6379"
+    enabled: "true"
+    timeout: 1m30s
+    hosts: [a, b]
+    ratio: 0.5
+`)
+	loader, err := NewLoader(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal(6379, loader.GetInt("plugins.cache.port"))
+	a.True(loader.GetBool("plugins.cache.enabled"))
+	a.Equal(90*time.Second, loader.GetDuration("plugins.cache.timeout"))
+	a.Equal([]string{"a", "b"}, loader.GetStringSlice("plugins.cache.hosts"))
+	a.Equal("6379", loader.GetString("plugins.cache.port"))
+
+	a.Zero(loader.GetInt("plugins.cache.missing"))
+	a.Nil(loader.GetStringSlice("plugins.cache.missing"))
+
+	ratio, ok := Lookup[float64](loader, "plugins.cache.ratio")
+	a.True(ok)
+	a.InDelta(0.5, ratio, 1e-9)
+
+	_, ok = Lookup[int](loader, "plugins.cache.missing")
+	a.False(ok, "missing key")
+
+	_, ok = Lookup[int](loader, "plugins.cache.hosts")
+	a.False(ok, "unconvertible value")
+}
+
+func TestLoaderOnReload(t *testing.T) {
+	type Config struct {
+		Version int `json:"version"`
+	}
+
+	path := writeTempConfig(t, "version: 1\n")
+	loader, err := NewLoader(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	var calls []string
+	loader.OnReload(func(prev, next *Config) {
+		calls = append(calls, fmt.Sprintf("a:%d->%d", prev.Version, next.Version))
+	})
+	var unsubscribeB func()
+	unsubscribeB = loader.OnReload(func(_, next *Config) {
+		calls = append(calls, fmt.Sprintf("b:%d", next.Version))
+		unsubscribeB() // 回调中取消订阅
+	})
+	unsubscribeC := loader.OnReload(func(_, next *Config) {
+		calls = append(calls, fmt.Sprintf("c:%d", next.Version))
+	})
+
+	require.NoError(t, os.WriteFile(path, []byte("version: 2\n"), 0o600))
+	require.NoError(t, loader.Reload())
+	unsubscribeC()
+	unsubscribeC()
+
+	require.NoError(t, os.WriteFile(path, []byte("version: [\n"), 0o600))
+	require.Error(t, loader.Reload())
+
+	require.NoError(t, os.WriteFile(path, []byte("version: 3\n"), 0o600))
+	require.NoError(t, loader.Reload())
+
+	assert.Equal(t, []string{"a:1->2", "b:2", "c:2", "a:2->3"}, calls, "failed reloads do not notify")
+}
+
+func TestLoaderSnapshot(t *testing.T) {
+	type Config struct {
+		Version int `json:"version"`
+	}
+
+	path := writeTempConfig(t, "version: 1\n")
+	loader, err := NewLoader(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	first := loader.Snapshot()
+	require.Equal(t, 1, first.Version)
+
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 100 {
+				if cfg := loader.Snapshot(); cfg.Version < 1 || cfg.Version > 2 {
+					t.Errorf("unexpected version %d", cfg.Version)
+				}
+			}
+		}()
+	}
+
+	require.NoError(t, os.WriteFile(path, []byte("version: 2\n"), 0o600))
+	require.NoError(t, loader.Reload())
+	wg.Wait()
+
+	a := assert.New(t)
+	a.Equal(1, first.Version, "earlier snapshot is not modified by reload")
+	a.Equal(2, loader.Snapshot().Version)
+	a.Same(loader.Snapshot(), loader.Config())
+}
+
+func TestLoaderConcurrentReload(t *testing.T) {
+	type Config struct {
+		Generation int64 `json:"generation"`
+	}
+
+	// 每次加载生成递增的代数，串行化后每次替换都应恰好比当前配置新一代
+	var generation, inFlight atomic.Int64
+	var loader *Loader[Config]
+	loader, err := NewLoader(Config{},
+		WithConfigPaths(),
+		WithDefaultConfigFunc(func(LoadContext) (Config, error) {
+			return Config{Generation: generation.Add(1)}, nil
+		}),
+		WithConfigPathsReloadValidation(func(prev, next *Config) error {
+			if n := inFlight.Add(1); n != 1 {
+				t.Errorf("%d reloads validating at once", n)
+			}
+			defer inFlight.Add(-1)
+			time.Sleep(100 * time.Microsecond) // 拉长窗口，未串行化时重叠的加载会在此交错
+			if prev != loader.Snapshot() {
+				t.Error("validator prev is not the current snapshot")
+			}
+			if next.Generation != prev.Generation+1 {
+				return fmt.Errorf("generation %d after %d", next.Generation, prev.Generation)
+			}
+
+			return nil
+		}),
+	)
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 25 {
+				assert.NoError(t, loader.Reload())
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(201), loader.Snapshot().Generation)
+}
+
+func TestLoaderReloadChanged(t *testing.T) {
+	type Config struct {
+		Version int `json:"version"`
+	}
+
+	path := writeTempConfig(t, "version: 1\n")
+	loader, err := NewLoader(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	changed, err := loader.reload()
+	require.NoError(t, err)
+	assert.False(t, changed)
+
+	// 并发的重新加载中只有最先替换配置的一次报告变化
+	require.NoError(t, os.WriteFile(path, []byte("version: 2\n"), 0o600))
+	var changes atomic.Int32
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			changed, err := loader.reload()
+			assert.NoError(t, err)
+			if changed {
+				changes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), changes.Load())
+	assert.Equal(t, 2, loader.Config().Version)
+}
+
+func TestBind(t *testing.T) {
+	type Config struct {
+		Timeout string `json:"timeout"`
+		Name    string `json:"name"`
+	}
+
+	path := writeTempConfig(t, "timeout: 5s\nname: app\n")
+	loader, err := NewLoader(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+
+	timeout, err := Bind[time.Duration](loader, "timeout")
+	require.NoError(t, err)
+	assert.Equal(t, 5*time.Second, timeout())
+
+	require.NoError(t, os.WriteFile(path, []byte("timeout: 10s\nname: app\n"), 0o600))
+	require.NoError(t, loader.Reload())
+	assert.Equal(t, 10*time.Second, timeout(), "reload is visible through the bound getter")
+
+	_, err = Bind[time.Duration](loader, "name")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), `config key "name" cannot be bound as time.Duration`)
+
+	_, err = Bind[string](loader, "missing")
+	require.EqualError(t, err, `config key "missing" not found`)
+}
+
+func TestKeyPathIndex(t *testing.T) {
+	type Server struct {
+		URL  string   `json:"url"`
+		Tags []string `json:"tags"`
+	}
+	type Config struct {
+		Servers []Server `json:"servers"`
+		Matrix  [][]int  `json:"matrix"`
+	}
+
+	path := writeTempConfig(t, `
+servers:
+  - url: http://a
+    tags: [x, y]
+  - url: http://b
+matrix:
+  - [1, 2]
+  - [3, 4]
+`)
+
+	var sets []string
+	loader, err := NewLoader(Config{}, WithConfigPaths(path),
+		WithForcedValues(map[string]any{
+			"servers[1].url":     "http://forced",
+			"servers[0].tags[1]": "z",
+			"matrix[1][0]":       30,
+		}),
+		WithOnValueSet(func(source, path string, _ any) {
+			if source == "forced values" {
+				sets = append(sets, path)
+			}
+		}),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	cfg := loader.Config()
+	a.Equal([]Server{{URL: "http://a", Tags: []string{"x", "z"}}, {URL: "http://forced"}}, cfg.Servers)
+	a.Equal([][]int{{1, 2}, {30, 4}}, cfg.Matrix)
+	a.Equal([]string{"matrix[1][0]", "servers[0].tags[1]", "servers[1].url"}, sets)
+
+	a.Equal("http://forced", loader.GetString("servers[1].url"))
+	a.Equal(4, loader.GetInt("matrix[1][1]"))
+	a.Empty(loader.GetString("servers[2].url"), "out of range index reads as missing")
+	server, err := UnmarshalKey[Server](loader, "servers[0]")
+	require.NoError(t, err)
+	a.Equal("http://a", server.URL)
+
+	_, err = Load(Config{}, WithConfigPaths(path), WithForcedValues(map[string]any{"servers[2].url": "x"}))
+	require.EqualError(t, err, `forced value servers[2].url: config key "servers[2]": index 2 out of range (len 2)`)
+
+	_, err = Load(Config{}, WithConfigPaths(path), WithForcedValues(map[string]any{"servers[0].url[0]": "x"}))
+	require.ErrorContains(t, err, `config key "servers[0].url": not an array`)
+}
+
+func TestLoaderFingerprint(t *testing.T) {
+	type Config struct {
+		Name  string `json:"name"`
+		Token string `json:"token"`
+	}
+	path := writeTempConfig(t, "name: app\ntoken: a\n")
+	t.Setenv("FP_TOKEN", "")
+
+	loader, err := NewLoader(Config{}, WithConfigPaths(path), WithEnvBinding("FP_TOKEN", "token"))
+	require.NoError(t, err)
+	first := loader.Fingerprint()
+	assert.Len(t, first, 64)
+
+	require.NoError(t, os.WriteFile(path, []byte("# comment\ntoken: a\nname:   app\n"), 0o600))
+	require.NoError(t, loader.Reload())
+	assert.Equal(t, first, loader.Fingerprint(), "comments, order and formatting do not matter")
+
+	t.Setenv("FP_TOKEN", "b")
+	require.NoError(t, loader.Reload())
+	changed := loader.Fingerprint()
+	assert.NotEqual(t, first, changed, "env value changes the fingerprint")
+
+	other, err := NewLoader(Config{}, WithConfigPaths(path), WithEnvBinding("FP_TOKEN", "token"))
+	require.NoError(t, err)
+	assert.Equal(t, changed, other.Fingerprint(), "stable across loaders")
+
+	require.NoError(t, os.WriteFile(path, []byte("name: [\n"), 0o600))
+	require.Error(t, loader.Reload())
+	assert.Equal(t, changed, loader.Fingerprint(), "failed reload keeps the fingerprint")
+}
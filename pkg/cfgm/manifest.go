@@ -0,0 +1,73 @@
+package cfgm
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// resolvedManifest 返回基于 baseDir 解析后的 [WithConfigManifest] 清单路径，未设置时返回空字符串。
+func (o *options) resolvedManifest() string {
+	if o.manifest == "" {
+		return ""
+	}
+
+	return o.resolveDirs([]string{o.manifest})[0]
+}
+
+// readManifest 读取清单文件，返回按声明顺序排列的配置片段路径。
+//
+// 每行一个路径，忽略空行与 "#" 开头的注释；相对路径基于清单文件所在目录解析。
+func readManifest(path string) ([]string, error) {
+	content, err := os.ReadFile(path) //nolint:gosec // path is from trusted config
+	if err != nil {
+		return nil, fmt.Errorf("config manifest %s: %w", path, err)
+	}
+
+	dir := filepath.Dir(path)
+	var files []string
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if !filepath.IsAbs(line) {
+			line = filepath.Join(dir, line)
+		}
+		files = append(files, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("config manifest %s: %w", path, err)
+	}
+
+	return files, nil
+}
+
+// readManifestLayers 按 [WithConfigManifest] 清单中的顺序读取配置片段（靠后的优先）。
+//
+// 清单显式列出的片段必须存在，缺失时返回错误。
+func readManifestLayers(options *options, report *loadReport) ([]configLayer, error) {
+	manifest := options.resolvedManifest()
+	files, err := readManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+
+	layers := make([]configLayer, 0, len(files))
+	for _, file := range files {
+		layer, ok, err := readConfigLayer(file, options, report)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			return nil, fmt.Errorf("config manifest %s: config file %s not found", manifest, file)
+		}
+		layers = append(layers, layer)
+	}
+
+	return layers, nil
+}
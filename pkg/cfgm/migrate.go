@@ -0,0 +1,131 @@
+package cfgm
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strconv"
+)
+
+// defaultVersionKey 是 [Migrations] 未设置 VersionKey 时使用的版本 key。
+const defaultVersionKey = "version"
+
+// MigrationFunc 将配置树从某个版本升级到下一个版本，直接修改 cfg，见 [Migrations.Migrate]。
+type MigrationFunc func(cfg map[string]any) error
+
+// Migrations 是按版本排列的配置迁移，通过 [WithMigrations] 在加载时应用。
+//
+// 每个配置文件按其中的版本 key（默认 "version"）执行尚未应用的迁移，
+// 缺少版本 key 的文件视为版本 0；迁移完成后版本 key 更新为 [Migrations.Latest]。
+// 迁移在合并之前对每个文件单独执行，因此旧版本与新版本的文件可以混合使用。
+//
+// 示例（v0 的 host/port 在 v1 移入 server 节点）：
+//
+//	var migrations cfgm.Migrations
+//	migrations.Migrate(0, func(cfg map[string]any) error {
+//	    server := map[string]any{"host": cfg["host"], "port": cfg["port"]}
+//	    delete(cfg, "host")
+//	    delete(cfg, "port")
+//	    cfg["server"] = server
+//	    return nil
+//	})
+//
+//	cfg, err := cfgm.Load(DefaultConfig(), cfgm.WithMigrations(&migrations))
+type Migrations struct {
+	// VersionKey 是记录配置版本的顶层 key，为空时使用 "version"。
+	VersionKey string
+
+	steps map[int]MigrationFunc
+}
+
+// Migrate 注册从 fromVersion 升级到 fromVersion+1 的迁移，返回 m 以便链式调用。
+//
+// 同一版本重复注册会 panic。
+func (m *Migrations) Migrate(fromVersion int, fn MigrationFunc) *Migrations {
+	if m.steps == nil {
+		m.steps = make(map[int]MigrationFunc)
+	}
+	if _, ok := m.steps[fromVersion]; ok {
+		panic(fmt.Sprintf("cfgm: duplicate migration from version %d", fromVersion))
+	}
+	m.steps[fromVersion] = fn
+
+	return m
+}
+
+// Latest 返回应用全部迁移后的版本号，即最大的 fromVersion + 1；未注册迁移时为 0。
+func (m *Migrations) Latest() int {
+	if len(m.steps) == 0 {
+		return 0
+	}
+
+	return slices.Max(slices.Collect(maps.Keys(m.steps))) + 1
+}
+
+func (m *Migrations) versionKey() string {
+	if m.VersionKey == "" {
+		return defaultVersionKey
+	}
+
+	return m.VersionKey
+}
+
+// apply 将单个配置文件的配置树升级到最新版本。
+func (m *Migrations) apply(layer configLayer) error {
+	if layer.data == nil {
+		return nil // 空文件没有需要迁移的内容
+	}
+
+	key := m.versionKey()
+	version := 0
+	if raw, ok := layer.data[key]; ok && raw != nil {
+		v, err := strconv.Atoi(fmt.Sprint(raw))
+		if err != nil {
+			return fmt.Errorf("config %s: invalid %s %v", layer.path, key, raw)
+		}
+		version = v
+	}
+
+	latest := m.Latest()
+	if version > latest {
+		return fmt.Errorf("config %s: %s %d is newer than supported version %d", layer.path, key, version, latest)
+	}
+	for ; version < latest; version++ {
+		fn, ok := m.steps[version]
+		if !ok {
+			return fmt.Errorf("config %s: no migration from %s %d", layer.path, key, version)
+		}
+		if err := fn(layer.data); err != nil {
+			return fmt.Errorf("config %s: migrate from %s %d: %w", layer.path, key, version, err)
+		}
+	}
+	if latest > 0 {
+		layer.data[key] = latest
+	}
+
+	return nil
+}
+
+// checkConfigVersion 实现 [WithConfigVersion]：检查单个配置文件的版本 key。
+func checkConfigVersion(layer configLayer, options *options, report *loadReport) error {
+	key := options.configVersionKey
+	if key == "" {
+		key = defaultVersionKey
+	}
+	raw, ok := layer.data[key]
+	if !ok || raw == nil {
+		return nil
+	}
+
+	version, expected := fmt.Sprint(raw), *options.configVersion
+	if version == expected {
+		return nil
+	}
+	if options.configVersionMode == ErrorModeSkip {
+		report.addWarning(WarnConfigVersion, key, "config %s: %s %q does not match expected %q", layer.path, key, version, expected)
+
+		return nil
+	}
+
+	return fmt.Errorf("config %s: %s %q does not match expected %q; please upgrade the config file", layer.path, key, version, expected)
+}
@@ -0,0 +1,112 @@
+package cfgm
+
+import (
+	"context"
+)
+
+// S3Client 是 [S3Source] 与 [WithS3Config] 读取对象所需的最小接口，使 cfgm 不直接依赖 AWS SDK。
+//
+// GetObject 返回对象的完整内容。对象或 bucket 不存在时应返回包装了 [fs.ErrNotExist] 的错误，
+// 无权访问时应返回包装了 [fs.ErrPermission] 的错误，以便加载时区分处理。ctx 的截止时间由 [WithSourceTimeout] 决定。
+// 基于 github.com/aws/aws-sdk-go-v2/service/s3 的适配示例：
+//
+//	type s3Objects struct{ client *s3.Client }
+//
+//	func (c s3Objects) GetObject(ctx context.Context, bucket, key string) ([]byte, error) {
+//	    out, err := c.client.GetObject(ctx, &s3.GetObjectInput{Bucket: &bucket, Key: &key})
+//	    var noKey *types.NoSuchKey
+//	    var apiErr smithy.APIError
+//	    switch {
+//	    case errors.As(err, &noKey):
+//	        return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+//	    case errors.As(err, &apiErr) && apiErr.ErrorCode() == "AccessDenied":
+//	        return nil, fmt.Errorf("%w: %w", fs.ErrPermission, err)
+//	    case err != nil:
+//	        return nil, err
+//	    }
+//	    defer out.Body.Close()
+//	    return io.ReadAll(out.Body)
+//	}
+type S3Client interface {
+	GetObject(ctx context.Context, bucket, key string) ([]byte, error)
+}
+
+// GCSClient 是 [GCSSource] 与 [WithGCSConfig] 读取对象所需的最小接口，使 cfgm 不直接依赖 Google Cloud SDK。
+//
+// 错误与 ctx 的约定与 [S3Client] 相同。基于 cloud.google.com/go/storage 的适配示例：
+//
+//	type gcsObjects struct{ client *storage.Client }
+//
+//	func (c gcsObjects) ReadObject(ctx context.Context, bucket, object string) ([]byte, error) {
+//	    r, err := c.client.Bucket(bucket).Object(object).NewReader(ctx)
+//	    var apiErr *googleapi.Error
+//	    switch {
+//	    case errors.Is(err, storage.ErrObjectNotExist), errors.Is(err, storage.ErrBucketNotExist):
+//	        return nil, fmt.Errorf("%w: %w", fs.ErrNotExist, err)
+//	    case errors.As(err, &apiErr) && apiErr.Code == http.StatusForbidden:
+//	        return nil, fmt.Errorf("%w: %w", fs.ErrPermission, err)
+//	    case err != nil:
+//	        return nil, err
+//	    }
+//	    defer r.Close()
+//	    return io.ReadAll(r)
+//	}
+type GCSClient interface {
+	ReadObject(ctx context.Context, bucket, object string) ([]byte, error)
+}
+
+// s3Source 是 [S3Source] 返回的 S3 对象来源。
+type s3Source struct {
+	client S3Client
+	bucket string
+	key    string
+}
+
+// S3Source 返回通过 client 读取 s3://bucket/key 的 [ConfigSource]，格式按 key 的扩展名判断。
+//
+// 对象不存在时按 [ConfigSource] 的约定跳过；无权访问等其他错误使加载失败，错误信息包含对象地址。
+func S3Source(client S3Client, bucket, key string) ConfigSource {
+	return s3Source{client: client, bucket: bucket, key: key}
+}
+
+func (s s3Source) Read(ctx context.Context) (string, string, []byte, error) {
+	data, err := s.client.GetObject(ctx, s.bucket, s.key)
+
+	return "s3://" + s.bucket + "/" + s.key, "", data, err
+}
+
+// gcsSource 是 [GCSSource] 返回的 GCS 对象来源。
+type gcsSource struct {
+	client GCSClient
+	bucket string
+	object string
+}
+
+// GCSSource 返回通过 client 读取 gs://bucket/object 的 [ConfigSource]，格式按 object 的扩展名判断。
+//
+// 对象不存在时按 [ConfigSource] 的约定跳过；无权访问等其他错误使加载失败，错误信息包含对象地址。
+func GCSSource(client GCSClient, bucket, object string) ConfigSource {
+	return gcsSource{client: client, bucket: bucket, object: object}
+}
+
+func (s gcsSource) Read(ctx context.Context) (string, string, []byte, error) {
+	data, err := s.client.ReadObject(ctx, s.bucket, s.object)
+
+	return "gs://" + s.bucket + "/" + s.object, "", data, err
+}
+
+// readObjectLayers 依次读取 [WithS3Config] 与 [WithGCSConfig] 声明的对象，按声明顺序返回（靠后的优先）。
+func readObjectLayers(options *options, report *loadReport) ([]configLayer, error) {
+	var layers []configLayer
+	for _, src := range options.objectSources {
+		layer, ok, err := readSourceLayer(src, options, report)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			layers = append(layers, layer)
+		}
+	}
+
+	return layers, nil
+}
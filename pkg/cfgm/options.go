@@ -1,17 +1,905 @@
 package cfgm
 
-import "github.com/urfave/cli/v3"
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v3"
+
+	"github.com/lwmacct/251207-go-pkg-cfgm/pkg/templexp"
+)
 
 // options 配置加载选项。
 type options struct {
-	appName             string // 应用名称，用于生成默认配置路径
-	cmd                 *cli.Command
-	configPaths         []string
-	baseDir             string // 路径基准目录，用于将相对路径转换为绝对路径
-	baseDirSet          bool   // 是否显式设置了 baseDir（区分空字符串和未设置）
-	envPrefix           string
-	noTemplateExpansion bool // 是否禁用配置文件模板展开（默认启用）
-	callerSkip          int  // FindProjectRoot 的调用栈跳过层数（0 表示使用默认值）
+	appName              string // 应用名称，用于生成默认配置路径
+	cmd                  *cli.Command
+	configPaths          []string
+	baseDir              string   // 路径基准目录，用于将相对路径转换为绝对路径
+	baseDirSet           bool     // 是否显式设置了 baseDir（区分空字符串和未设置）
+	envPrefixes          []string // 环境变量前缀，按声明顺序应用
+	noTemplateExpansion  bool     // 是否禁用配置文件模板展开（默认启用）
+	callerSkip           int      // FindProjectRoot 的调用栈跳过层数（0 表示使用默认值）
+	commentedDefaults    bool     // Marshal 输出 YAML 时是否附加字段注释
+	lazyKeys             []string // 延迟展开模板的 key
+	strictEnvTypes       bool     // 绑定环境变量前校验值能否解析为字段类型
+	secretResolver       SecretResolver
+	configEncoding       string            // 配置文件编码，空字符串表示 UTF-8
+	mergeAllPaths        bool              // 合并全部存在的配置文件，而非命中首个即停止
+	configPathsSet       bool              // 是否通过 WithConfigPaths 显式指定了路径
+	failFastPaths        bool              // 显式指定的路径不存在时直接报错
+	delim                string            // key 路径分隔符，空字符串表示 "."
+	templateStrict       bool              // 未设置变量的 ${VAR} 展开时报错
+	cliEnvAware          bool              // flag 自身环境变量来源的值按环境变量优先级处理
+	resolveSymlinks      bool              // 读取前解析配置文件路径中的符号链接
+	watchInterval        time.Duration     // Loader.Watch 的轮询间隔，0 表示默认值
+	defaultConfigFunc    any               // WithDefaultConfigFunc 设置的 func(LoadContext) (T, error)
+	caseInsensitive      bool              // 配置文件名按大小写不敏感匹配
+	checksum             string            // 配置文件内容期望的 SHA-256 (hex)
+	checksums            map[string]string // 按路径指定期望的 SHA-256，设置后优先于 checksum
+	configDirs           []string          // 配置片段目录 (conf.d)
+	k8sConfigMapDirs     []string          // Kubernetes ConfigMap 挂载目录
+	mergeFunc            MergeFunc         // 自定义合并逻辑
+	envBindings          []envBinding      // 显式声明的环境变量绑定，按声明顺序
+	validateEnvBindings  bool              // 校验显式绑定的配置路径存在
+	maxFileSize          int64             // 单个配置文件的最大字节数，0 表示不限制
+	reader               *readerSource     // LoadReader 传入的数据流，替代配置文件搜索
+	normalizeKeys        bool              // 所有 key 统一转为小写
+	envBindingsFromFlags bool              // 从 CLI flag 声明的环境变量推导绑定
+	envBindKey           string            // 声明环境变量绑定的字段 tag 名称
+	embedded             []embeddedSource  // 内嵌的默认配置文件，优先级最低
+	trimKeys             []string          // 需要去除首尾空白的 key
+	trimAllStrings       bool              // 去除全部字符串值的首尾空白
+	flatEnvKeys          []string          // 以 key=value 行或 JSON 承载配置子树的环境变量
+	schema               []byte            // 校验合并后配置树的 JSON Schema 文档
+	relativeToExecutable bool              // 相对路径以可执行文件所在目录为基准
+	forcedValues         []map[string]any  // 在 CLI flags 之后写入的强制值，按声明顺序
+	templateData         map[string]string // 模板展开时优先于环境变量的变量
+	envSnapshot          map[string]string // 替代进程环境的环境变量快照
+	envSnapshotSet       bool              // 是否设置了 envSnapshot（nil 视为空环境）
+	onValueSet           ValueSetFunc      // 每次写入配置 key 时的回调
+	humanizedValues      bool              // 整数字段接受 "10MB" 等字节大小字符串
+	reloadThrottle       time.Duration     // Loader.Watch 两次重新加载之间的最小间隔
+	envTransform         EnvValueTransform // 写入前处理每个环境变量值
+	yamlInclude          bool              // 展开 YAML 中的 !include 标签
+	onMissingFile        ErrorMode         // 配置文件不存在时的处理方式，零值表示跳过
+	onParseError         ErrorMode         // 配置文件存在但无法解析时的处理方式，零值表示失败
+	noHomeConfig         bool              // 默认搜索路径不包含用户主目录
+	etcdSources          []etcdSource      // etcd 配置来源，按声明顺序合并
+	validatePaths        bool              // 解析前检查已存在的候选文件均可读取
+	requiredTags         bool              // 解码后检查 required tag 标记的字段
+	pathsEnvExpand       bool              // 配置路径展开环境变量与 ~
+	onMetrics            func(LoadMetrics) // 加载完成后的耗时统计回调
+	envBindingsCaseFold  bool              // 显式绑定的 config path 按大小写不敏感匹配结构体 key
+	unmarshalMode        UnmarshalMode     // 解码时的类型转换规则，零值为 UnmarshalWeak
+	searchUp             bool              // 相对路径从工作目录向上逐级查找
+	searchUpBoundary     *string           // 向上查找的停止标记文件，nil 表示 go.mod
+	envPrefixStrict      bool              // 带前缀但无法映射到配置 key 的环境变量使加载失败
+	migrations           *Migrations       // 合并前对每个配置文件执行的版本迁移
+	configVersion        *string           // 期望的配置文件版本，nil 表示不检查
+	configVersionMode    ErrorMode         // 版本不一致时的处理方式
+	configVersionKey     string            // 版本 key，为空时使用 "version"
+	templateEnvDefault   *string           // 模板中未设置变量的全局默认值
+	maxPaths             *int              // 候选文件数上限，nil 表示 defaultPathsLimit
+	envConfigFileApp     string            // WithEnvConfigFile 的应用名称
+	envFileVars          map[string]string // 本次加载从 env 文件读取的变量，进程环境未设置时生效
+	durationUnits        []durationUnit    // 不带单位的数字时长按 key 指定的单位解析
+	gitRoot              bool              // 以工作目录所在的 git 仓库根目录作为 baseDir
+	envBindingsReport    bool              // 未设置的显式绑定记录为警告
+	configPathsEnv       string            // 以路径列表形式提供配置文件路径的环境变量
+	configPathsSep       string            // 拆分 configPathsEnv 的分隔符，空字符串表示系统路径分隔符
+	onProbe              func([]PathProbe) // 配置文件发现结束后按检查顺序报告全部候选路径
+	templateMaxOutput    int               // 单个文件模板展开结果的最大字节数，0 表示不限制
+	sources              []ConfigSource    // WithConfigSources 声明的配置来源，替代配置文件搜索
+	onReload             func(ReloadEvent) // Loader.Watch 每次重新加载后的回调
+	strictDuplicateKeys  bool              // JSON 配置文件中的重复 key 使解析失败
+	newerThan            time.Time         // 修改时间早于该时刻的配置文件被跳过，零值表示不检查
+	topEnvBindings       []envBinding      // 在 CLI flags 之后应用的环境变量绑定，按声明顺序
+	readRetries          int               // 配置文件读取或解析失败后的重试次数
+	readRetryDelay       time.Duration     // 首次重试前的等待时间，之后按次数线性增加
+	cliFlagPrefix        string            // CLI flag 名称的命名空间前缀，映射到配置路径前去除
+	dedupePaths          bool              // 候选配置文件按规范化路径去重，同一文件只合并一次
+	statTimeout          time.Duration     // 配置文件发现时单次 stat 的时限，0 表示不限制
+	manifest             string            // 列出配置片段及合并顺序的清单文件，替代配置文件搜索
+	envBindingsInverse   bool              // 环境变量绑定覆盖配置文件中的值时记录为警告
+	configExtensions     map[string]string // 自定义扩展名（小写，含 "."）→ 格式名称
+	noEnvPaths           []string          // 不允许由环境变量设置的 key 模式
+	configArgFlags       []string          // 从 os.Args 读取配置文件路径的 flag 名称（不含 "-"）
+	configDirsRecursive  []string          // 递归读取的配置片段目录
+	configDirExclude     []string          // 递归读取时跳过的子目录名称模式
+	explicitZeroWins     bool              // 已设置但为空的环境变量按零值生效
+	pathFormats          map[string]string // WithConfigPathTyped 声明的路径 → 格式
+	typedPaths           map[string]string // pathFormats 解析为候选路径后的结果，resolve 时生成
+	envTrimRules         []EnvTrimRule     // 按环境变量名去除前缀后映射为配置路径的规则
+	statCache            *statCache        // 配置文件发现的 stat 缓存，nil 表示不缓存
+	aggregatePathErrors  bool              // 收集全部配置路径错误后以 errors.Join 一并返回
+	templateRequired     []string          // 模板展开时必须已设置的变量
+	osConfigPaths        []string          // WithConfigPathsForOS 为当前系统声明的默认搜索路径，nil 表示使用 DefaultPaths
+	xdgConfigDirs        bool              // 合并 $XDG_CONFIG_DIRS 下的系统级配置作为低优先级层
+	decodeErrContext     bool              // 解码错误中附带 key 路径、实际值与目标类型
+	appNameFromExe       bool              // 未设置 appName 时由 os.Args[0] 推导
+	watchDebounce        time.Duration     // Watch 发现变化后等待文件稳定的时间，0 表示立即重新加载
+	valueProcessors      []valueProcessor  // 解码后按字段类型调用的处理函数
+	hostnameOverlay      bool              // 合并配置文件同目录下以短主机名命名的覆盖文件
+	schemaFile           *schemaFile       // WithSchemaFile 的 schema 文件及其解析缓存
+	interceptors         []interceptFunc   // 解码前按字段路径调用的拦截函数
+	formatPreference     []string          // 主名相同的多个文件按格式择一，靠前的格式优先
+	maxDepth             *int              // 向上查找与递归扫描的最大层数，nil 表示 defaultMaxDepth
+	envResolveOrder      []EnvSource       // WithEnvBindingsResolveOrder 声明的来源顺序，后者优先
+	securePermissions    bool              // 拒绝加载组用户或其他用户可读的配置文件
+	reloadGracePeriod    time.Duration     // Watch 遇到空白文件时的重新检查间隔，0 表示默认值，负数表示不等待
+	configTags           []string          // 按顺序决定配置 key 的 struct tag，nil 表示 defaultConfigTags
+	envFileSuffix        string            // 环境变量名加上该后缀的变量指向值所在的文件
+	objectSources        []ConfigSource    // WithS3Config / WithGCSConfig 的对象存储来源，与配置文件同一优先级
+	mapConfigs           []map[string]any  // WithMapConfig 的配置树，位于默认值之上、配置文件之下
+	reloadValidator      any               // WithConfigPathsReloadValidation 设置的 func(prev, next *T) error
+	sourceTimeout        time.Duration     // WithSourceTimeout 的单次来源读取超时，0 为默认值，负数不限制
+}
+
+// embeddedSource 是 [WithEmbeddedDefault] 指定的内嵌配置文件。
+type embeddedSource struct {
+	fsys fs.FS
+	path string
+}
+
+// readerSource 是 [LoadReader] 传入的配置数据流。
+type readerSource struct {
+	name string // 用于格式识别与错误信息的名称，如 "<reader>.yaml"
+	r    io.Reader
+}
+
+// envBinding 是一条 [WithEnvBinding] 声明的环境变量到配置 key 的绑定。
+type envBinding struct {
+	envKey     string
+	configPath string
+	source     EnvSource
+	namespace  string // WithEnvBindingsNamespace 的挂载前缀，resolve 时拼接到 configPath 之前
+	listSep    string // WithEnvBindingList 的列表分隔符，非空时按其拆分为切片
+}
+
+// defaultKeyDelim 默认的 key 路径分隔符。
+const defaultKeyDelim = "."
+
+// keyDelim 返回生效的 key 路径分隔符。
+func (o *options) keyDelim() string {
+	if o.delim == "" {
+		return defaultKeyDelim
+	}
+
+	return o.delim
+}
+
+// templateOptions 返回模板展开所用的 templexp 选项。
+func (o *options) templateOptions() []templexp.Option {
+	var opts []templexp.Option
+	if o.templateStrict {
+		opts = append(opts, templexp.WithStrictMissing())
+	}
+	if o.envSnapshotSet {
+		opts = append(opts, templexp.WithEnv(o.envSnapshot))
+	}
+	if len(o.templateData) > 0 {
+		opts = append(opts, templexp.WithVars(o.templateData))
+	}
+	if o.templateEnvDefault != nil {
+		opts = append(opts, templexp.WithMissingDefault(*o.templateEnvDefault))
+	}
+	if o.templateMaxOutput > 0 {
+		opts = append(opts, templexp.WithMaxOutputSize(o.templateMaxOutput))
+	}
+	if len(o.templateRequired) > 0 {
+		opts = append(opts, templexp.WithRequiredVars(o.templateRequired...))
+	}
+
+	return opts
+}
+
+// getenv 读取环境变量，设置了 [WithEnvSnapshot] 时从快照中读取。
+//
+// 变量未设置或为空时回退到 [WithEnvConfigFile] 读取的值。
+func (o *options) getenv(key string) string {
+	var val string
+	if o.envSnapshotSet {
+		val = o.envSnapshot[key]
+	} else {
+		val = os.Getenv(key)
+	}
+	if val == "" {
+		return o.envFileVars[key]
+	}
+
+	return val
+}
+
+// envFileBaseNames 将以 [WithEnvFileSuffix] 后缀结尾的变量名替换为去掉后缀的名称，用于按变量名扫描的绑定。
+func (o *options) envFileBaseNames(names []string) []string {
+	if o.envFileSuffix == "" {
+		return names
+	}
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		if base, ok := strings.CutSuffix(name, o.envFileSuffix); ok && base != "" {
+			name = base
+		}
+		if !slices.Contains(out, name) {
+			out = append(out, name)
+		}
+	}
+
+	return out
+}
+
+// explicitEmptyEnv 判断空值的环境变量是否按 [WithExplicitZeroWins] 视为已设置。
+//
+// 仅当变量确实存在（os.LookupEnv、[WithEnvSnapshot] 或 [WithEnvConfigFile] 中有该 key）时成立。
+func (o *options) explicitEmptyEnv(key string) bool {
+	if !o.explicitZeroWins {
+		return false
+	}
+	var ok bool
+	if o.envSnapshotSet {
+		_, ok = o.envSnapshot[key]
+	} else {
+		_, ok = os.LookupEnv(key)
+	}
+	if !ok {
+		_, ok = o.envFileVars[key]
+	}
+
+	return ok
+}
+
+// defaultPathsLimit 是 [WithConfigPathsLimit] 未设置时的候选文件数上限。
+const defaultPathsLimit = 1000
+
+// pathsLimit 返回候选文件数上限，0 表示不限制。
+func (o *options) pathsLimit() int {
+	if o.maxPaths == nil {
+		return defaultPathsLimit
+	}
+
+	return max(*o.maxPaths, 0)
+}
+
+// environNames 返回全部已设置且非空的环境变量名，[WithEnvSnapshot] 时为快照中的变量。
+func (o *options) environNames() []string {
+	var names []string
+	if o.envSnapshotSet {
+		for key, val := range o.envSnapshot {
+			if val != "" {
+				names = append(names, key)
+			}
+		}
+	} else {
+		for _, kv := range os.Environ() {
+			if key, val, ok := strings.Cut(kv, "="); ok && key != "" && val != "" {
+				names = append(names, key)
+			}
+		}
+	}
+	for key, val := range o.envFileVars {
+		if val != "" && !slices.Contains(names, key) {
+			names = append(names, key)
+		}
+	}
+
+	return names
+}
+
+// envValue 读取环境变量并应用 [WithEnvValueTransform]，未设置或为空的变量不经过转换。
+//
+// 设置了 [WithEnvFileSuffix] 且带后缀的变量非空时，改为读取其指向的文件内容。
+func (o *options) envValue(key string) (string, error) {
+	val := o.getenv(key)
+	if o.envFileSuffix != "" {
+		if path := o.getenv(key + o.envFileSuffix); path != "" {
+			content, err := os.ReadFile(path) //nolint:gosec // path is provided by the environment
+			if err != nil {
+				return "", fmt.Errorf("env %s: %w", key+o.envFileSuffix, err)
+			}
+			val = strings.TrimRight(string(content), "\r\n")
+			slog.Debug("Loaded env value from file", "env", key+o.envFileSuffix, "path", path)
+		}
+	}
+	if val == "" || o.envTransform == nil {
+		return val, nil
+	}
+
+	transformed, err := o.envTransform(key, val)
+	if err != nil {
+		return "", fmt.Errorf("env %s: transform value: %w", key, err)
+	}
+
+	return transformed, nil
+}
+
+// notifyValueSet 在设置了 [WithOnValueSet] 时报告一次写入。
+func (o *options) notifyValueSet(source string, parts []string, value any) {
+	if o.onValueSet == nil {
+		return
+	}
+	o.onValueSet(source, joinKey(parts, o.keyDelim()), value)
+}
+
+// notifyLayer 按 key 的字典序报告 data 中的每个叶子值，非空 map 逐层展开。
+func (o *options) notifyLayer(source string, data map[string]any) {
+	if o.onValueSet == nil {
+		return
+	}
+	o.notifyLayerRecursive(source, data, nil)
+}
+
+func (o *options) notifyLayerRecursive(source string, data map[string]any, prefix []string) {
+	for _, key := range slices.Sorted(maps.Keys(data)) {
+		parts := append(slices.Clip(prefix), key)
+		if child, ok := data[key].(map[string]any); ok && len(child) > 0 {
+			o.notifyLayerRecursive(source, child, parts)
+
+			continue
+		}
+		o.notifyValueSet(source, parts, data[key])
+	}
+}
+
+// resolvedDirs 返回基于 baseDir 解析后的配置片段目录。
+func (o *options) resolvedDirs() []string {
+	return o.resolveDirs(o.configDirs)
+}
+
+// resolvedK8sDirs 返回基于 baseDir 解析后的 ConfigMap 挂载目录。
+func (o *options) resolvedK8sDirs() []string {
+	return o.resolveDirs(o.k8sConfigMapDirs)
+}
+
+func (o *options) resolveDirs(dirs []string) []string {
+	resolved := make([]string, len(dirs))
+	for i, dir := range dirs {
+		if o.baseDir != "" && !filepath.IsAbs(dir) {
+			dir = filepath.Join(o.baseDir, dir)
+		}
+		resolved[i] = dir
+	}
+
+	return resolved
+}
+
+// watchedPaths 返回 [Loader.Watch] 需要监视的路径：配置文件候选路径与 ConfigMap 目录中的全部条目。
+func (o *options) watchedPaths() []string {
+	paths := o.resolvedPaths()
+	// WithConfigManifest: 清单本身与其中列出的片段，清单无法读取时仅监听清单
+	if manifest := o.resolvedManifest(); manifest != "" {
+		paths = append(paths, manifest)
+		if files, err := readManifest(manifest); err == nil {
+			paths = append(paths, files...)
+		}
+	}
+	for _, dir := range o.resolvedK8sDirs() {
+		paths = append(paths, filepath.Join(dir, "*"))
+	}
+	// WithConfigPathsDir / WithConfigDirRecursive: 片段的新增、删除与修改同样触发重新加载
+	for _, dir := range o.resolvedDirs() {
+		paths = append(paths, filepath.Join(dir, "*"))
+	}
+	for _, dir := range o.resolveDirs(o.configDirsRecursive) {
+		files, _ := configDirRecursiveFiles(dir, o)
+		if len(files) == 0 {
+			paths = append(paths, dir)
+		}
+		for _, rel := range files {
+			paths = append(paths, filepath.Join(dir, filepath.FromSlash(rel)))
+		}
+	}
+	paths = append(paths, o.xdgConfigPaths()...)
+	if host := o.overlayHostname(); host != "" {
+		for _, path := range o.resolvedPaths() {
+			paths = append(paths, hostnameOverlayPath(path, host))
+		}
+	}
+
+	return paths
+}
+
+// defaultXDGConfigDirs 是未设置 XDG_CONFIG_DIRS 时的系统配置目录。
+const defaultXDGConfigDirs = "/etc/xdg"
+
+// xdgConfigPaths 返回 [WithConfigPathsFromXDGDirs] 的候选文件，按优先级从低到高排列。
+//
+// XDG_CONFIG_DIRS 中靠前的目录更重要，因此返回顺序与其相反；未启用或未设置 appName 时返回 nil。
+func (o *options) xdgConfigPaths() []string {
+	if !o.xdgConfigDirs || o.appName == "" {
+		return nil
+	}
+	dirs := o.getenv("XDG_CONFIG_DIRS")
+	if dirs == "" {
+		dirs = defaultXDGConfigDirs
+	}
+
+	var paths []string
+	for dir := range strings.SplitSeq(dirs, ":") {
+		// 规范要求使用绝对路径，相对路径忽略
+		if filepath.IsAbs(dir) {
+			paths = append(paths, filepath.Join(dir, o.appName, "config.yaml"))
+		}
+	}
+	slices.Reverse(paths)
+
+	return paths
+}
+
+// loadContext 返回传给 [WithDefaultConfigFunc] 的加载上下文。
+func (o *options) loadContext() LoadContext {
+	return LoadContext{
+		AppName:     o.appName,
+		BaseDir:     o.baseDir,
+		ConfigPaths: o.resolvedPaths(),
+		EnvPrefixes: slices.Clone(o.envPrefixes),
+	}
+}
+
+// fileFormat 按扩展名（忽略 .gz 后缀）返回配置文件的格式，无法识别时返回空字符串。
+//
+// [WithConfigPathTyped] 为该路径（或匹配该路径的 glob）声明的格式最优先，
+// 其次为 [WithConfigExtensions] 声明的扩展名，最后为内置的 .yaml/.yml/.json。
+func (o *options) fileFormat(path string) string {
+	if format, ok := o.typedPaths[path]; ok {
+		return normalizeFormat(format)
+	}
+	for _, pattern := range slices.Sorted(maps.Keys(o.typedPaths)) {
+		if matched, _ := filepath.Match(pattern, path); matched && isGlobPattern(pattern) {
+			return normalizeFormat(o.typedPaths[pattern])
+		}
+	}
+
+	ext := strings.ToLower(filepath.Ext(trimGzipExt(path)))
+	if format, ok := o.configExtensions[ext]; ok {
+		return normalizeFormat(format)
+	}
+
+	return normalizeFormat(ext)
+}
+
+// checkConfigFormats 校验 [WithConfigExtensions] 与 [WithConfigPathTyped] 声明的格式均可识别。
+func (o *options) checkConfigFormats() error {
+	for _, ext := range slices.Sorted(maps.Keys(o.configExtensions)) {
+		if format := o.configExtensions[ext]; normalizeFormat(format) == "" {
+			return fmt.Errorf("config extension %s: unsupported format %q", ext, format)
+		}
+	}
+	for _, path := range slices.Sorted(maps.Keys(o.pathFormats)) {
+		if format := o.pathFormats[path]; normalizeFormat(format) == "" {
+			return fmt.Errorf("config path %s: unsupported format %q", path, format)
+		}
+	}
+	for _, format := range o.formatPreference {
+		if normalizeFormat(format) == "" {
+			return fmt.Errorf("format preference: unsupported format %q", format)
+		}
+	}
+
+	return nil
+}
+
+// preferFormats 实现 [WithFormatPreference]：同一目录下主名相同、格式不同的文件只保留最优先格式的文件，其余顺序不变。
+//
+// 主名为去掉 .gz 与格式扩展名后的路径。未设置时原样返回。
+func (o *options) preferFormats(files []string) []string {
+	if len(o.formatPreference) == 0 || len(files) < 2 {
+		return files
+	}
+	rank := func(file string) int {
+		format := o.fileFormat(file)
+		for i, preferred := range o.formatPreference {
+			if normalizeFormat(preferred) == format {
+				return i
+			}
+		}
+
+		return len(o.formatPreference)
+	}
+	stem := func(file string) string {
+		inner := trimGzipExt(file)
+
+		return strings.TrimSuffix(inner, filepath.Ext(inner))
+	}
+
+	best := make(map[string]int, len(files))
+	for _, file := range files {
+		key, r := stem(file), rank(file)
+		if current, ok := best[key]; !ok || r < current {
+			best[key] = r
+		}
+	}
+
+	return slices.DeleteFunc(slices.Clone(files), func(file string) bool { return rank(file) > best[stem(file)] })
+}
+
+// excludedConfigDir 判断子目录名称是否匹配 [WithConfigDirExclude] 的模式。
+func (o *options) excludedConfigDir(name string) bool {
+	for _, pattern := range o.configDirExclude {
+		if matched, _ := filepath.Match(pattern, name); matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// noEnvForPath 判断 configPath 是否受 [WithNoEnvForPaths] 保护。
+//
+// 模式按分隔符逐段比较，"*" 匹配任意一段；匹配到的 key 及其下的全部子 key 均受保护。
+func (o *options) noEnvForPath(configPath string) bool {
+	if len(o.noEnvPaths) == 0 {
+		return false
+	}
+	delim := o.keyDelim()
+	parts := strings.Split(configPath, delim)
+	for _, pattern := range o.noEnvPaths {
+		segments := strings.Split(pattern, delim)
+		if len(segments) > len(parts) {
+			continue
+		}
+		matched := true
+		for i, seg := range segments {
+			if seg != "*" && !strings.EqualFold(seg, parts[i]) {
+				matched = false
+
+				break
+			}
+		}
+		if matched {
+			return true
+		}
+	}
+
+	return false
+}
+
+// splitKey 按生效的分隔符拆分用户传入的 key 路径。
+//
+// 段末尾的数组下标拆为单独的 "[N]" 段，如 "servers[0].url" → servers、[0]、url，见 [indexPart]。
+// [WithNormalizeKeys] 时 path 会先转为小写，与规范化后的配置树保持一致。
+func (o *options) splitKey(path string) []string {
+	if o.normalizeKeys {
+		path = strings.ToLower(path)
+	}
+
+	var parts []string
+	for _, part := range strings.Split(path, o.keyDelim()) {
+		parts = append(parts, splitIndexParts(part)...)
+	}
+
+	return parts
+}
+
+// newOptions 依次应用选项函数。
+func newOptions(opts ...Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	return o
+}
+
+// resolve 补全未显式设置的默认值（baseDir、configPaths）。
+//
+// callerSkip 为相对 resolve 调用者的跳过层数，[WithCallerSkip] 显式设置时优先使用。
+func (o *options) resolve(callerSkip int) {
+	// 如果用户显式设置了 callerSkip，则优先使用
+	if o.callerSkip > 0 {
+		callerSkip = o.callerSkip
+	}
+
+	// WithAppNameFromExecutable: WithAppName 未设置时使用调用时的程序名
+	if o.appNameFromExe && o.appName == "" && len(os.Args) > 0 {
+		o.appName = executableAppName(os.Args[0])
+	}
+
+	// WithConfigPathsRelativeToExecutable 优先于 WithGitRoot、WithBaseDir 与项目根目录
+	exeResolved := false
+	if o.relativeToExecutable {
+		if dir, err := executableDir(); err == nil {
+			o.baseDir = dir
+			o.baseDirSet = true
+			exeResolved = true
+		} else {
+			slog.Debug("Failed to resolve executable dir", "error", err)
+		}
+	}
+
+	// WithGitRoot 优先于 WithBaseDir，未找到 .git 时保持原有基准
+	if o.gitRoot && !exeResolved {
+		if root, err := findGitRoot(); err == nil {
+			o.baseDir = root
+			o.baseDirSet = true
+		} else {
+			slog.Debug("Failed to find git root", "error", err)
+		}
+	}
+
+	// 默认使用项目根目录作为相对路径基准
+	if !o.baseDirSet {
+		if root, err := FindProjectRoot(callerSkip + 1); err == nil {
+			o.baseDir = root
+		}
+	}
+
+	// WithConfigPathsEnv: 环境变量提供的路径列表替代代码中设置的路径
+	if paths := o.envConfigPaths(); len(paths) > 0 {
+		o.configPaths = paths
+		o.configPathsSet = true
+	}
+
+	// WithConfigFromArgs: 命令行中的路径优先于环境变量与代码中设置的路径
+	if len(o.configArgFlags) > 0 && len(os.Args) > 0 {
+		if path, ok := configPathFromArgs(os.Args[1:], o.configArgFlags); ok {
+			if abs, err := filepath.Abs(path); err == nil {
+				path = abs
+			}
+			o.configPaths = []string{path}
+			o.configPathsSet = true
+		}
+	}
+
+	// 默认使用 DefaultPaths 作为配置文件搜索路径
+	// 如果设置了 appName，使用 DefaultPaths(appName) 生成应用专属路径
+	// WithConfigPathsForOS 为当前系统声明了路径时替代 DefaultPaths
+	if len(o.configPaths) == 0 {
+		if o.osConfigPaths != nil {
+			for _, path := range o.osConfigPaths {
+				o.configPaths = append(o.configPaths, strings.ReplaceAll(path, appNamePlaceholder, o.appName))
+			}
+		} else {
+			o.configPaths = defaultPaths(o.appName, !o.noHomeConfig)
+		}
+	}
+
+	// WithConfigPathTyped: 按解析后的候选路径记录声明的格式
+	if len(o.pathFormats) > 0 {
+		var searchDirs []string
+		if o.searchUp {
+			searchDirs = o.searchUpDirs()
+		}
+		o.typedPaths = make(map[string]string)
+		for _, raw := range slices.Sorted(maps.Keys(o.pathFormats)) {
+			for _, candidate := range o.resolvePath(raw, searchDirs) {
+				o.typedPaths[candidate] = o.pathFormats[raw]
+			}
+		}
+	}
+
+	// WithEnvBindingsNamespace: 分隔符可能由之后的选项设置，此处再拼接挂载前缀
+	for i, b := range o.envBindings {
+		if b.namespace != "" {
+			o.envBindings[i].configPath = b.namespace + o.keyDelim() + b.configPath
+			o.envBindings[i].namespace = ""
+		}
+	}
+}
+
+// configPathFromArgs 在 args 中查找 [WithConfigFromArgs] 的 flag，返回最后一次出现的值。
+//
+// 支持 "--name value"、"--name=value" 及单个 "-" 的写法，遇到 "--" 后停止扫描。
+// "---name" 等三个及以上 "-" 的参数不视为 flag；"--name" 之后的参数以 "-" 开头时视为缺少值，不作为路径。
+func configPathFromArgs(args, names []string) (string, bool) {
+	if len(names) == 0 {
+		return "", false
+	}
+
+	var path string
+	found := false
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		trimmed := strings.TrimPrefix(strings.TrimPrefix(arg, "-"), "-")
+		if strings.HasPrefix(trimmed, "-") {
+			continue
+		}
+		name, value, hasValue := strings.Cut(trimmed, "=")
+		if !slices.Contains(names, name) {
+			continue
+		}
+		if !hasValue {
+			if i+1 >= len(args) || strings.HasPrefix(args[i+1], "-") {
+				continue
+			}
+			i++
+			value = args[i]
+		}
+		path, found = value, true
+	}
+
+	return path, found && path != ""
+}
+
+// executableDir 返回当前可执行文件（解析符号链接后）所在的目录。
+func executableDir() (string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return "", err
+	}
+	if exe, err = filepath.EvalSymlinks(exe); err != nil {
+		return "", err
+	}
+
+	return filepath.Dir(exe), nil
+}
+
+// envConfigPaths 按 [WithConfigPathsSeparator] 拆分 [WithConfigPathsEnv] 环境变量中的路径列表，忽略空项。
+func (o *options) envConfigPaths() []string {
+	if o.configPathsEnv == "" {
+		return nil
+	}
+	sep := o.configPathsSep
+	if sep == "" {
+		sep = string(os.PathListSeparator)
+	}
+
+	var paths []string
+	for _, p := range strings.Split(o.getenv(o.configPathsEnv), sep) {
+		if p = strings.TrimSpace(p); p != "" {
+			paths = append(paths, p)
+		}
+	}
+
+	return paths
+}
+
+// isStale 判断文件是否早于 [WithConfigPathsNewerThan] 设置的时刻。
+func (o *options) isStale(info fs.FileInfo) bool {
+	return !o.newerThan.IsZero() && info.ModTime().Before(o.newerThan)
+}
+
+// findGitRoot 从工作目录向上查找包含 .git 的目录（.git 为目录或 worktree 的文件均可）。
+func findGitRoot() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			return dir, nil
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", errors.New("no .git found")
+		}
+		dir = parent
+	}
+}
+
+// resolvedPaths 返回基于 baseDir 解析后的配置文件路径。
+//
+// [WithConfigPathsCaseInsensitive] 时文件名替换为磁盘上的实际大小写。
+func (o *options) resolvedPaths() []string {
+	var searchDirs []string
+	if o.searchUp {
+		searchDirs = o.searchUpDirs()
+	}
+
+	paths := make([]string, 0, len(o.configPaths))
+	for _, p := range o.configPaths {
+		paths = append(paths, o.resolvePath(p, searchDirs)...)
+	}
+
+	return paths
+}
+
+// resolvePath 将单个配置路径解析为候选路径，规则见 [options.resolvedPaths]。
+func (o *options) resolvePath(p string, searchDirs []string) []string {
+	if o.pathsEnvExpand {
+		p = o.expandPath(p)
+	}
+
+	candidates := []string{p}
+	switch {
+	case filepath.IsAbs(p):
+	case len(searchDirs) > 0:
+		// WithSearchUp: 由近及远展开为各级祖先目录中的同名路径
+		candidates = make([]string, len(searchDirs))
+		for i, dir := range searchDirs {
+			candidates[i] = filepath.Join(dir, p)
+		}
+	case o.baseDir != "":
+		candidates[0] = filepath.Join(o.baseDir, p)
+	}
+
+	for i, candidate := range candidates {
+		if o.caseInsensitive && !isGlobPattern(candidate) {
+			candidates[i] = matchFileCase(candidate)
+		}
+	}
+
+	return candidates
+}
+
+// searchUpDirs 返回 [WithSearchUp] 查找的目录：从工作目录开始逐级向上，
+// 到包含停止标记文件的目录（含）或文件系统根目录为止。
+func (o *options) searchUpDirs() []string {
+	dir, err := os.Getwd()
+	if err != nil {
+		slog.Debug("Failed to get working dir for search up", "error", err)
+
+		return nil
+	}
+
+	boundary := "go.mod"
+	if o.searchUpBoundary != nil {
+		boundary = *o.searchUpBoundary
+	}
+
+	maxDepth := o.walkDepth()
+	var dirs []string
+	for {
+		dirs = append(dirs, dir)
+		if maxDepth >= 0 && len(dirs) > maxDepth {
+			slog.Debug("Search up stopped at max depth", "dir", dir, "max_depth", maxDepth)
+
+			return dirs
+		}
+		if boundary != "" {
+			if _, err := os.Stat(filepath.Join(dir, boundary)); err == nil {
+				return dirs
+			}
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return dirs
+		}
+		dir = parent
+	}
+}
+
+// defaultMaxDepth 是未设置 [WithMaxDepth] 时向上查找与递归扫描的最大层数。
+const defaultMaxDepth = 32
+
+// tagChain 返回按顺序决定配置 key 的 struct tag，见 [WithUnmarshalTagChain]。
+func (o *options) tagChain() []string {
+	if len(o.configTags) == 0 {
+		return defaultConfigTags
+	}
+
+	return o.configTags
+}
+
+// walkDepth 返回生效的最大层数，负数表示不限制。
+func (o *options) walkDepth() int {
+	if o.maxDepth != nil {
+		return *o.maxDepth
+	}
+
+	return defaultMaxDepth
+}
+
+// expandPath 展开路径中的 $VAR / ${VAR} 与开头的 ~，见 [WithConfigPathsEnvExpand]。
+func (o *options) expandPath(path string) string {
+	path = os.Expand(path, o.getenv)
+	if path == "~" || strings.HasPrefix(path, "~/") || strings.HasPrefix(path, "~"+string(filepath.Separator)) {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			slog.Debug("Failed to expand ~ in config path", "path", path, "error", err)
+
+			return path
+		}
+		path = filepath.Join(home, path[1:])
+	}
+
+	return path
 }
 
 // Option 配置加载选项函数。
@@ -41,9 +929,31 @@ func WithAppName(name string) Option {
 // WithConfigPaths 设置配置文件搜索路径。
 //
 // 按顺序查找，命中首个文件即停止；相对路径会基于 [WithBaseDir] 解析。
+//
+// 路径可以是 glob 模式（如 "conf.d/*.yaml"，语法见 [filepath.Match]）：
+// 匹配的全部文件按完整路径的逐字节字典序合并（靠后的优先，与操作系统无关），整体视为一次命中；
+// 未匹配任何文件时跳过该路径，启用 [WithFailFastPaths] 时则加载失败。
 func WithConfigPaths(paths ...string) Option {
 	return func(o *options) {
 		o.configPaths = paths
+		o.configPathsSet = len(paths) > 0
+	}
+}
+
+// WithBaseAndOverlay 以 basePath 为基础配置、overlayPath 为覆盖层加载配置文件。
+//
+// 适用于「只读挂载的基础配置 + 可写的用户覆盖」：overlay 中的 key 覆盖 base 中的同名 key，
+// overlay 不存在时仅使用 base。等价于 WithConfigPaths(overlayPath, basePath) 加 [WithMergeAllPaths]，
+// 因此会替换此前设置的搜索路径；相对路径同样基于 [WithBaseDir] 解析。
+//
+//	cfgm.Load(config,
+//	    cfgm.WithBaseAndOverlay("/usr/share/myapp/config.yaml", "/var/lib/myapp/config.yaml"),
+//	)
+func WithBaseAndOverlay(basePath, overlayPath string) Option {
+	return func(o *options) {
+		o.configPaths = []string{overlayPath, basePath}
+		o.configPathsSet = true
+		o.mergeAllPaths = true
 	}
 }
 
@@ -94,9 +1004,33 @@ func WithCallerSkip(skip int) Option {
 //   - MYAPP_CLIENT_REV_AUTH_USER → client.rev-auth-user
 //
 // 注意：通过反射自动生成配置 key 的绑定，只匹配结构体中定义的 key。
+// 可多次调用以同时启用多个前缀，优先级规则见 [WithEnvPrefixes]；空前缀会被忽略。
+//
+// 切片、数组与 map 字段的值以 "[" 或 "{" 开头且是合法 JSON 时按 JSON 解析（与 [ExportEnv] 的输出对应），
+// 如 MYAPP_TAGS='["a","b,c"]' 得到 []string{"a", "b,c"}；JSON 中的数字先解析为 float64 再按字段类型转换，
+// 因此 []string 字段的 [1,2] 得到 {"1", "2"}，超出 float64 精度的整数会丢失精度，此类值应写为 JSON 字符串。
+// 不是合法 JSON 的值（如 MYAPP_TAGS=[prod]）仍按原始字符串解码，得到 []string{"[prod]"}。
 func WithEnvPrefix(prefix string) Option {
+	return WithEnvPrefixes(prefix)
+}
+
+// WithEnvPrefixes 同时启用多个环境变量前缀，适合前缀重命名的过渡期。
+//
+// 每个前缀都按 [WithEnvPrefix] 的规则生成绑定。
+// 多个前缀命中同一 key 时，后声明的前缀优先（与多次调用 [WithEnvPrefix] 的顺序一致）。
+//
+// 示例（OLD_ 迁移到 NEW_，两者同时设置时 NEW_ 生效）：
+//
+//	cfgm.Load(defaultConfig,
+//	    cfgm.WithEnvPrefixes("OLD_", "NEW_"),
+//	)
+func WithEnvPrefixes(prefixes ...string) Option {
 	return func(o *options) {
-		o.envPrefix = prefix
+		for _, prefix := range prefixes {
+			if prefix != "" {
+				o.envPrefixes = append(o.envPrefixes, prefix)
+			}
+		}
 	}
 }
 
@@ -109,3 +1043,1693 @@ func WithoutTemplateExpansion() Option {
 		o.noTemplateExpansion = true
 	}
 }
+
+// WithCommentedDefaults 让 [Marshal] 在输出 YAML 时附加字段注释。
+//
+// 注释依次读取 desc、comment、usage tag，以 "# ..." 行写在对应 key 上方，
+// 用于生成自带说明的初始配置文件。非 YAML 格式会忽略该选项。
+//
+// 示例：
+//
+//	cfg := DefaultConfig()
+//	data, err := cfgm.Marshal(&cfg, "yaml", cfgm.WithCommentedDefaults())
+func WithCommentedDefaults() Option {
+	return func(o *options) {
+		o.commentedDefaults = true
+	}
+}
+
+// WithLazyKeys 指定延迟展开模板的 key（如 "db.password"），适合轮换的密钥。
+//
+// 这些 key 在结构体中保留原始模板字符串（如 "${DB_PASSWORD}"），
+// 通过 [Loader.GetString] 读取时才执行展开，每次读取都会重新解析环境变量。
+// 加载时仍会对整个文件执行一次展开以校验模板，但结果不会写入配置。
+//
+// 示例：
+//
+//	loader, err := cfgm.NewLoader(DefaultConfig(),
+//	    cfgm.WithLazyKeys("db.password"),
+//	)
+//	password := loader.GetString("db.password") // 每次读取时展开
+func WithLazyKeys(keys ...string) Option {
+	return func(o *options) {
+		o.lazyKeys = append(o.lazyKeys, keys...)
+	}
+}
+
+// WithStrictEnvTypes 在绑定环境变量时按字段类型校验取值。
+//
+// 默认情况下，MYAPP_PORT=abc 这类错误直到解码阶段才以通用的类型错误暴露。
+// 启用后会在绑定时校验 int/uint/float/bool/time.Duration/time.Time 字段，
+// 失败时返回包含环境变量名与期望类型的错误，例如：
+//
+//	env MYAPP_PORT="abc": expected int
+func WithStrictEnvTypes() Option {
+	return func(o *options) {
+		o.strictEnvTypes = true
+	}
+}
+
+// WithSecretRefResolver 注册 [SecretRef] 字段的解析函数。
+//
+// 配置中 [SecretRef] 字段只保存密钥名称，加载时不会解析；
+// 应用在真正需要时调用 [SecretRef.Resolve]，由 fn 按名称取回密钥，
+// 从而避免密钥在整个进程生命周期内常驻内存。
+//
+// 示例：
+//
+//	type DBConfig struct {
+//	    Password cfgm.SecretRef `json:"password"` // 配置文件中写 password: "db/password"
+//	}
+//
+//	cfg, err := cfgm.Load(DefaultConfig(),
+//	    cfgm.WithSecretRefResolver(vault.Read),
+//	)
+//	password, err := cfg.DB.Password.Resolve()
+func WithSecretRefResolver(fn SecretResolver) Option {
+	return func(o *options) {
+		o.secretResolver = fn
+	}
+}
+
+// WithConfigEncoding 设置配置文件的字符编码（如 "gbk"、"latin1"、"ISO-8859-1"）。
+//
+// 文件内容会在模板展开与解析之前转换为 UTF-8；默认 UTF-8，不做任何转换。
+// 编码名称无法识别或文件包含非法字节序列时，返回包含文件路径的错误。
+func WithConfigEncoding(enc string) Option {
+	return func(o *options) {
+		o.configEncoding = enc
+	}
+}
+
+// WithMergeAllPaths 合并所有存在的配置文件，而不是命中首个文件即停止。
+//
+// 路径列表的顺序仍表示优先级：靠前的文件覆盖靠后的文件。
+// 例如 [DefaultPaths] 下，./.myapp.yaml 会覆盖 /etc/myapp/config.yaml 中的同名 key。
+// 可使用 [MergePreview] 查看每个文件贡献的 key。
+func WithMergeAllPaths() Option {
+	return func(o *options) {
+		o.mergeAllPaths = true
+	}
+}
+
+// WithFailFastPaths 要求 [WithConfigPaths] 显式列出的路径全部存在，否则 Load 直接报错。
+//
+// 默认每个路径都只是候选，拼写错误会悄悄回退到下一个路径或默认值。
+// 启用后可区分「依次尝试这些路径」与「必须使用这些文件」。
+// 由 [WithAppName] / [DefaultPaths] 自动生成的路径不受影响，仍按候选处理。
+func WithFailFastPaths() Option {
+	return func(o *options) {
+		o.failFastPaths = true
+	}
+}
+
+// WithKeyDelim 设置 key 路径分隔符，默认 "."。
+//
+// 当配置 key 本身包含点号（如以主机名作为 map key）时，可改用 "/" 等分隔符。
+// 分隔符作用于所有接受 key 路径字符串的位置，例如 [WithLazyKeys]、[Loader.GetString]
+// 以及 [MergePreview] 返回的 key。
+//
+// 对环境变量的影响：[WithEnvPrefix] 生成绑定时分隔符同样转为 "_"，
+// 例如分隔符为 "/" 时 hosts/example.com → MYAPP_HOSTS_EXAMPLE_COM。
+// CLI flag 名称始终以 "-" 拼接各段，不受影响。
+//
+// 示例：
+//
+//	loader, err := cfgm.NewLoader(DefaultConfig(), cfgm.WithKeyDelim("/"))
+//	endpoint := loader.GetString("hosts/api.example.com/endpoint")
+func WithKeyDelim(delim string) Option {
+	return func(o *options) {
+		o.delim = delim
+	}
+}
+
+// WithTemplateStrictMissing 让模板中引用未设置变量的 ${VAR} 直接报错。
+//
+// 默认 ${VAR} 在变量未设置时展开为空字符串，变量名拼写错误会被悄悄忽略。
+// 启用后加载失败并返回包含变量名的错误；${VAR:-default} 等带默认值的写法不受影响。
+// 见 [templexp.WithStrictMissing]。
+func WithTemplateStrictMissing() Option {
+	return func(o *options) {
+		o.templateStrict = true
+	}
+}
+
+// WithCLIEnvAware 让来自 flag 自身环境变量 (cli Sources) 的值按环境变量优先级处理。
+//
+// 默认情况下，urfave/cli 从 flag 的 Sources 读到的值与命令行传入的值无法区分，
+// 都按 CLI 优先级覆盖，因此会压过 [WithEnvPrefix] 绑定的同一配置项。启用后优先级为：
+//
+//  1. 默认值
+//  2. 配置文件
+//  3. flag 自身环境变量 (cli Sources)
+//  4. 环境变量(前缀)
+//  5. 命令行显式传入的 flags
+//
+// urfave/cli 不记录值的来源：当 flag 的某个环境变量存在且与 flag 当前值相同时，
+// 即视为来自环境变量。
+func WithCLIEnvAware() Option {
+	return func(o *options) {
+		o.cliEnvAware = true
+	}
+}
+
+// WithConfigPathsResolveSymlinks 在读取配置文件前解析路径中的符号链接。
+//
+// 适用于 Kubernetes ConfigMap 等以符号链接挂载配置的场景（如 ..data 目录）。
+// 启用后 [MergePreview] 返回的 [LayerInfo].Path 为链接指向的真实路径，
+// 错误信息中的路径同样使用真实路径。
+func WithConfigPathsResolveSymlinks() Option {
+	return func(o *options) {
+		o.resolveSymlinks = true
+	}
+}
+
+// WithWatchInterval 设置 [Loader.Watch] 检查配置文件变化的轮询间隔，默认 1s。
+func WithWatchInterval(d time.Duration) Option {
+	return func(o *options) {
+		o.watchInterval = d
+	}
+}
+
+// LoadContext 描述选项解析完成后的加载环境，见 [WithDefaultConfigFunc]。
+type LoadContext struct {
+	AppName     string   // [WithAppName] 设置的应用名称
+	BaseDir     string   // 相对路径基准目录（项目根目录或 [WithBaseDir]）
+	ConfigPaths []string // 按 BaseDir 解析后的配置文件候选路径
+	EnvPrefixes []string // 生效的环境变量前缀，按声明顺序
+}
+
+// WithDefaultConfigFunc 在加载时调用 fn 生成默认配置，替代传入的 defaultConfig。
+//
+// fn 在 baseDir 与配置路径解析完成后、读取配置文件前调用，
+// 因此默认值可以依赖加载环境，例如数据目录默认为 BaseDir/data：
+//
+//	cfg, err := cfgm.Load(Config{},
+//	    cfgm.WithDefaultConfigFunc(func(ctx cfgm.LoadContext) (Config, error) {
+//	        return Config{DataDir: filepath.Join(ctx.BaseDir, "data")}, nil
+//	    }),
+//	)
+//
+// fn 返回错误时加载失败；T 必须与 Load 的配置类型一致，否则加载返回错误。
+// 用于 [Loader] 时每次 [Loader.Reload] 都会重新调用。
+func WithDefaultConfigFunc[T any](fn func(ctx LoadContext) (T, error)) Option {
+	return func(o *options) {
+		o.defaultConfigFunc = fn
+	}
+}
+
+// WithConfigPathsCaseInsensitive 让配置文件名按大小写不敏感的方式匹配。
+//
+// 默认按路径原样查找：macOS/Windows 的文件系统不区分大小写，config.yaml 能读到 Config.yaml，
+// 而 Linux 容器中却找不到，造成环境间行为不一致。启用后在各平台上统一按大小写不敏感匹配，
+// 并使用磁盘上的实际文件名（体现在 [MergePreview] 与错误信息中）。
+// 优先选择大小写完全一致的文件；仅匹配文件名，目录部分保持原样。
+func WithConfigPathsCaseInsensitive() Option {
+	return func(o *options) {
+		o.caseInsensitive = true
+	}
+}
+
+// WithConfigChecksum 校验读取到的配置文件内容与期望的 SHA-256 (hex) 一致，不一致时加载失败。
+//
+// 校验针对模板展开前的原始文件字节，用于锁定部署中发现意外修改。
+// 多个文件合并时（[WithMergeAllPaths]）请使用 [WithConfigChecksums] 按路径分别指定。
+func WithConfigChecksum(hexDigest string) Option {
+	return func(o *options) {
+		o.checksum = hexDigest
+	}
+}
+
+// WithConfigChecksums 按路径指定配置文件期望的 SHA-256 (hex)。
+//
+// key 为按 baseDir 解析后的路径（与 [MergePreview] 的 [LayerInfo].Path 一致）。
+// 读取到未列出的配置文件时加载失败，避免高优先级路径上的文件绕过校验。
+func WithConfigChecksums(digests map[string]string) Option {
+	return func(o *options) {
+		o.checksums = maps.Clone(digests)
+	}
+}
+
+// WithConfigPathsDir 合并目录中的全部配置片段（如 /etc/myapp/conf.d）。
+//
+// 按文件名字典序读取 .yaml/.yml/.json 文件，后读取的覆盖先读取的；
+// 片段整体优先级高于 [WithConfigPaths] 的配置文件、低于环境变量。
+// 其他扩展名的文件与子目录被忽略，目录为空或不存在时不做任何处理。
+// 相对路径基于 baseDir 解析；多次调用按声明顺序合并。
+func WithConfigPathsDir(dir string) Option {
+	return func(o *options) {
+		o.configDirs = append(o.configDirs, dir)
+	}
+}
+
+// MergeFunc 自定义配置文件合并时单个 key 的合并结果，见 [WithMergeFunc]。
+//
+// path 为完整 key 路径，existing 为已合并的低优先级值（不存在时为 nil），incoming 为当前文件的值。
+// 返回 ok=true 时使用返回值作为合并结果；ok=false 时回退到默认策略（map 深度合并，其余覆盖）。
+type MergeFunc func(path string, existing, incoming any) (merged any, ok bool)
+
+// WithMergeFunc 设置配置文件逐层合并时的自定义合并逻辑，用于取最大值、集合并集等特殊语义。
+//
+// fn 在默认值之上合并每个配置文件（含 [WithConfigPathsDir] 片段）时，对文件中的每个 key 调用，
+// 包括 map 类型的中间节点：对中间节点返回 ok=true 会整体替换该子树，不再递归。
+// 环境变量与 CLI flags 按路径直接覆盖，不经过 fn。
+//
+// fn 按 key 逐个调用，开销与配置树大小成正比：应保持轻量、无阻塞，
+// 并尽快对不关心的 path 返回 ok=false。
+//
+// 示例（端口列表取并集）：
+//
+//	cfgm.WithMergeFunc(func(path string, existing, incoming any) (any, bool) {
+//	    if path != "server.ports" {
+//	        return nil, false
+//	    }
+//	    old, _ := existing.([]any)
+//	    add, _ := incoming.([]any)
+//	    return append(slices.Clone(old), add...), true
+//	})
+func WithMergeFunc(fn MergeFunc) Option {
+	return func(o *options) {
+		o.mergeFunc = fn
+	}
+}
+
+// WithEnvBinding 将环境变量 envKey 显式绑定到配置 key configPath（如 "redis.url"）。
+//
+// 适用于无法按前缀规则命名的变量（如平台注入的 REDIS_URL）。显式绑定在前缀绑定之后应用，
+// 同一 key 以显式绑定为准；可多次调用，后声明的绑定覆盖先声明的。
+// 环境变量未设置或为空时不生效。
+func WithEnvBinding(envKey, configPath string) Option {
+	return func(o *options) {
+		o.envBindings = append(o.envBindings, envBinding{envKey: envKey, configPath: configPath, source: EnvSourceBinding})
+	}
+}
+
+// WithEnvBindingsValidate 在加载时校验 [WithEnvBinding] 的配置 key 均存在于配置结构体中。
+//
+// 用于在启动时发现 "reids.url" 之类的拼写错误：无论环境变量是否设置，
+// 绑定到不存在 key 的声明都会使加载失败。map 与 interface 字段之下的动态 key 不做校验。
+func WithEnvBindingsValidate() Option {
+	return func(o *options) {
+		o.validateEnvBindings = true
+	}
+}
+
+// WithMaxFileSize 限制单个配置文件（或 [LoadReader] 数据流）的最大字节数，超过时加载失败。
+//
+// 用于防止意外读取超大文件；gzip 压缩的配置同时限制解压后的大小，防止解压炸弹。
+// n <= 0 表示不限制（默认）。
+func WithMaxFileSize(n int64) Option {
+	return func(o *options) {
+		o.maxFileSize = n
+	}
+}
+
+// WithNormalizeKeys 将默认值、配置文件、环境变量与 CLI flags 中的 key 统一转为小写后再合并。
+//
+// 用于避免一个来源写 Server.URL、另一个写 server.url 时各自成为独立的 key。
+// 每个来源先规范化再按优先级合并，因此优先级规则不变；
+// 同一来源中仅大小写不同的 key 按原始 key 的字典序后者覆盖前者（map 值深度合并）。
+//
+// 解码时 json tag 与 key 按大小写不敏感匹配，因此含大写字母的 tag（如 "apiKey"）仍可正常解码；
+// 但若两个字段的 tag 仅大小写不同，规范化后会合并为同一个 key，应避免这种写法。
+// [Loader.GetString] 等按 key 读取的方法同样按小写查找。
+func WithNormalizeKeys() Option {
+	return func(o *options) {
+		o.normalizeKeys = true
+	}
+}
+
+// WithEnvBindingsFromFlags 从 [WithCommand] 命令的 flag 声明的环境变量（cli Sources）推导环境变量绑定。
+//
+// 例如 flag --server-url 声明了 Sources: cli.EnvVars("SERVER_URL")，则 SERVER_URL 绑定到 server.url，
+// 环境变量映射只需在 CLI 定义中维护一份。推导的绑定在前缀绑定之后、[WithEnvBinding] 之前应用，
+// 并出现在 [LoadWithEnvReport] 的结果中（来源为 [EnvSourceFlag]）。
+//
+// 启用后，flag 从自身环境变量读到的值不再按 CLI 优先级覆盖，仅命令行显式传入的值保持最高优先级。
+// 未设置 [WithCommand] 时不生效。
+func WithEnvBindingsFromFlags() Option {
+	return func(o *options) {
+		o.envBindingsFromFlags = true
+	}
+}
+
+// WithEnvBindKey 通过字段 tag 声明环境变量绑定，tag 值为环境变量名（可用逗号分隔多个，靠前的优先）。
+//
+// 示例：
+//
+//	type Config struct {
+//	    RedisURL string `json:"redis_url" env:"REDIS_URL,CACHE_URL"`
+//	}
+//
+//	cfg, err := cfgm.Load(DefaultConfig(), cfgm.WithEnvBindKey("env"))
+//
+// tag 绑定在前缀绑定之后、[WithEnvBindingsFromFlags] 与 [WithEnvBinding] 之前应用，
+// 优先级规则见包文档「环境变量优先级」。
+func WithEnvBindKey(tag string) Option {
+	return func(o *options) {
+		o.envBindKey = tag
+	}
+}
+
+// WithEmbeddedDefault 将 fsys 中的 path 文件作为最低优先级的基础配置，磁盘上找到的配置文件覆盖其中的值。
+//
+// 通常与 go:embed 搭配，实现“内置默认配置 + 可选的磁盘覆盖”：
+//
+//	//go:embed config.default.yaml
+//	var defaultConfigFS embed.FS
+//
+//	cfg, err := cfgm.Load(Config{},
+//	    cfgm.WithAppName("myapp"),
+//	    cfgm.WithEmbeddedDefault(defaultConfigFS, "config.default.yaml"),
+//	)
+//
+// 内嵌文件同样执行模板展开，格式由扩展名决定；文件不存在时加载失败。
+// 优先级位于结构体默认值之上、配置文件之下；多次调用按声明顺序合并，后者优先。
+// 展开后内容相同的内嵌文件只解析一次，结果在多次加载间复用（每次加载得到独立的副本），磁盘上的配置文件仍每次重新读取。
+func WithEmbeddedDefault(fsys fs.FS, path string) Option {
+	return func(o *options) {
+		o.embedded = append(o.embedded, embeddedSource{fsys: fsys, path: path})
+	}
+}
+
+// WithTrimWhitespace 在合并完成后去除指定 key 字符串值的首尾空白（含换行）。
+//
+// 常用于 YAML 多行块（如 "url: |"）带来的末尾换行。key 对应对象或列表时，
+// 其中的全部字符串都会被处理。可多次调用，不存在的 key 会被忽略。
+func WithTrimWhitespace(keys ...string) Option {
+	return func(o *options) {
+		o.trimKeys = append(o.trimKeys, keys...)
+	}
+}
+
+// WithTrimAllStrings 在合并完成后去除全部字符串值的首尾空白，见 [WithTrimWhitespace]。
+//
+// 需显式启用：依赖有意义空白（如多行证书、缩进文本）的配置不应使用该选项。
+func WithTrimAllStrings() Option {
+	return func(o *options) {
+		o.trimAllStrings = true
+	}
+}
+
+// WithFlatEnvKeys 读取环境变量 envKey 中的整段配置，作为一层配置合并。
+//
+// 变量值为 JSON 对象时按 JSON 解析；否则按行解析 key=value，key 为完整路径（如 "server.url"），
+// 空行与 # 开头的行被忽略：
+//
+//	APP_CONFIG='server.url=http://example.com
+//	server.timeout=5s'
+//
+// 适用于只能注入少量环境变量的受限环境。该层优先级位于配置文件之上、[WithEnvPrefix] 等环境变量绑定之下；
+// 可多次调用，按声明顺序合并，后者优先。环境变量未设置或为空时不生效，解析失败的错误信息包含变量名。
+func WithFlatEnvKeys(envKey string) Option {
+	return func(o *options) {
+		o.flatEnvKeys = append(o.flatEnvKeys, envKey)
+	}
+}
+
+// WithSchema 在解码到结构体之前，使用 JSON Schema 文档 schema 校验合并后的配置树。
+//
+// 用于表达结构体类型无法约束的规则，如枚举、取值范围与字符串格式：
+//
+//	//go:embed config.schema.json
+//	var configSchema []byte
+//
+//	cfg, err := cfgm.Load(DefaultConfig(), cfgm.WithSchema(configSchema))
+//
+// 校验失败时返回的错误包含全部不满足的 key（每条均为 [*SchemaError]，以 errors.Join 合并）。
+// 内置实现不引入额外依赖，支持常用关键字：type、enum、const、properties、required、
+// additionalProperties、items、minItems、maxItems、minimum、maximum、exclusiveMinimum、
+// exclusiveMaximum、minLength、maxLength、pattern、allOf、anyOf；$ref 等其他关键字会被忽略。
+//
+// 与解码规则一致，环境变量等来源写入的字符串在可解析时满足 number / integer / boolean，
+// time.Duration 按字符串形式（如 "5s"）校验；值为 null 的 key（如未设置的切片）视为不存在。
+func WithSchema(schema []byte) Option {
+	return func(o *options) {
+		o.schema = schema
+	}
+}
+
+// WithConfigPathsRelativeToExecutable 以可执行文件所在目录作为相对路径的解析基准，
+// 适用于二进制与配置文件一同分发的部署方式。
+//
+// 可执行文件为符号链接（如 /usr/local/bin/myapp -> /opt/myapp/bin/myapp）时使用链接目标所在的目录。
+// 该选项优先于 [WithBaseDir] 与默认的项目根目录，同时影响 [WithConfigPathsDir] 等相对路径；
+// 无法确定可执行文件路径时回退到 [WithBaseDir] 或默认基准。绝对路径不受影响。
+func WithConfigPathsRelativeToExecutable() Option {
+	return func(o *options) {
+		o.relativeToExecutable = true
+	}
+}
+
+// WithForcedValues 在 CLI flags 之后写入 values，使其覆盖其他全部来源，位于优先级的最顶层。
+//
+// 适用于必须生效的程序化设置（如测试中强制关闭外部依赖）。key 可以是嵌套 map，
+// 也可以是以分隔符表示的完整路径（如 "server.url"）：
+//
+//	cfg, err := cfgm.Load(DefaultConfig(),
+//	    cfgm.WithCommand(cmd),
+//	    cfgm.WithForcedValues(map[string]any{"server.url": "http://127.0.0.1:0", "debug": true}),
+//	)
+//
+// 路径可以用 "[N]" 指定数组元素（如 "servers[0].url"、"matrix[1][2]"），修改合并后已有的数组，
+// 下标越界或该位置不是数组时加载失败。
+//
+// 值按与配置文件相同的规则解码；结构体中不存在的 key 不会使加载失败，
+// 但会出现在 [LoadWithWarnings] 的警告中。可多次调用，后声明的优先。
+// values 在调用时被复制，之后修改传入的 map 不影响加载结果。
+func WithForcedValues(values map[string]any) Option {
+	forced := cloneConfigValue(values).(map[string]any)
+
+	return func(o *options) {
+		o.forcedValues = append(o.forcedValues, forced)
+	}
+}
+
+// WithTemplateData 为配置文件的模板展开提供变量，${NAME} 优先读取 data 中的值，未提供时读取环境变量。
+//
+// 用于由代码注入模板数据而无需修改进程环境：
+//
+//	cfg, err := cfgm.Load(DefaultConfig(),
+//	    cfgm.WithTemplateData(map[string]any{"CLUSTER_NAME": cluster.Name, "REPLICAS": 3}),
+//	)
+//
+// 值按 fmt 的 %v 格式转为字符串。变量名需符合 ${...} 的命名规则（字母、数字与下划线）。
+// 可多次调用，同名变量后者覆盖前者；同样作用于 [WithLazyKeys] 的延迟展开。
+func WithTemplateData(data map[string]any) Option {
+	return func(o *options) {
+		if o.templateData == nil {
+			o.templateData = make(map[string]string, len(data))
+		}
+		for name, val := range data {
+			o.templateData[name] = fmt.Sprintf("%v", val)
+		}
+	}
+}
+
+// WithEnvSnapshot 让加载过程中的全部环境变量读取（前缀与显式绑定、[WithFlatEnvKeys]、模板展开）
+// 都使用 env，而不是进程环境，使依赖环境变量的加载结果完全可复现。
+//
+// env 在调用时被复制；传入 nil 表示空环境，等同于忽略全部环境变量。
+// CLI flags 通过 cli Sources 读取的环境变量由 urfave/cli 在解析命令行时处理，不受该选项影响。
+func WithEnvSnapshot(env map[string]string) Option {
+	snapshot := maps.Clone(env)
+
+	return func(o *options) {
+		o.envSnapshot = snapshot
+		o.envSnapshotSet = true
+	}
+}
+
+// ValueSetFunc 是 [WithOnValueSet] 的回调，path 为以分隔符拼接的完整 key 路径。
+type ValueSetFunc func(source, path string, value any)
+
+// WithOnValueSet 在构建配置树的过程中，每当某个来源写入一个 key 时按写入顺序调用 fn，用于审计。
+//
+// 与最终值不同，被后续来源覆盖的中间值同样会被报告。source 取值：
+//   - "default" - 结构体默认值（含 [WithDefaultConfigFunc]）
+//   - 配置文件路径 - 包括内嵌文件、配置片段与 [LoadReader] 的数据流名称
+//   - "env NAME" - 环境变量 NAME（前缀与各类绑定、[WithFlatEnvKeys]）
+//   - "cli" - CLI flags
+//   - "forced values" - [WithForcedValues]
+//
+// 来自文件等整层来源的值按叶子 key 的字典序逐个报告。未设置时不产生额外开销。
+func WithOnValueSet(fn ValueSetFunc) Option {
+	return func(o *options) {
+		o.onValueSet = fn
+	}
+}
+
+// WithHumanizedValues 允许整数字段使用 "10MB"、"512KiB"、"1.5 GB" 等字节大小字符串，解码为字节数。
+//
+// 单位不区分大小写：B、KB、MB、GB、TB、PB 为十进制（1 KB = 1000 B），
+// KiB、MiB、GiB、TiB、PiB 为二进制（1 KiB = 1024 B）；不带单位时为字节数。
+// 纯整数字符串仍按原规则解析，超出字段类型范围时解码失败。
+// time.Duration 字段默认即支持 "30s"、"5m" 等字符串，无需该选项。
+func WithHumanizedValues() Option {
+	return func(o *options) {
+		o.humanizedValues = true
+	}
+}
+
+// reloadGrace 返回 [Loader.Watch] 遇到空白文件时的重新检查间隔，0 表示不等待。
+func (o *options) reloadGrace() time.Duration {
+	switch {
+	case o.reloadGracePeriod < 0:
+		return 0
+	case o.reloadGracePeriod == 0:
+		return defaultReloadGracePeriod
+	default:
+		return o.reloadGracePeriod
+	}
+}
+
+// WithConfigReloadThrottle 限制 [Loader.Watch] 两次重新加载之间的最小间隔为 min，防止文件频繁改写时反复重新加载。
+//
+// 节流窗口内发生的多次变化会合并为窗口结束时的一次重新加载，并读取届时的最新内容，
+// 因此最终状态不会被丢弃。min <= 0 表示不节流（默认），此时频率仅受 [WithWatchInterval] 限制。
+func WithConfigReloadThrottle(min time.Duration) Option {
+	return func(o *options) {
+		o.reloadThrottle = min
+	}
+}
+
+// EnvValueTransform 是 [WithEnvValueTransform] 的转换函数，envKey 为环境变量名，raw 为原始值。
+type EnvValueTransform func(envKey, raw string) (string, error)
+
+// WithEnvValueTransform 在写入配置之前，用 fn 处理每个来自环境变量的值，作为统一的清洗入口。
+//
+// 作用于前缀绑定、[WithEnvBindKey]、[WithEnvBindingsFromFlags]、[WithEnvBinding] 与 [WithFlatEnvKeys]，
+// 例如去掉部分 CI 系统在值两侧添加的引号：
+//
+//	cfgm.WithEnvValueTransform(func(_, raw string) (string, error) {
+//	    return strings.Trim(raw, `"'`), nil
+//	})
+//
+// 未设置或为空的变量不会传给 fn；fn 返回空字符串时视为未设置。
+// fn 返回错误时加载失败，错误信息包含变量名。模板展开中的 ${VAR} 不受影响。
+func WithEnvValueTransform(fn EnvValueTransform) Option {
+	return func(o *options) {
+		o.envTransform = fn
+	}
+}
+
+// WithYAMLInclude 启用 YAML 自定义标签 !include，将被引用文件的内容内联到标签所在位置（任意层级）：
+//
+//	# config.yaml
+//	server: !include server.yaml
+//	users: !include users.json
+//
+// 相对路径以包含它的文件所在目录为基准；[WithEmbeddedDefault] 与 [LoadReader] 中的相对路径以
+// [WithBaseDir] 的基准目录为准。启用 [WithConfigPathsResolveSymlinks] 时，基准为符号链接目标所在目录。
+// 被引用文件按扩展名解析（JSON 或 YAML，YAML 中可继续 !include），同样执行模板展开；
+// 循环包含与文件不存在均使加载失败。需显式启用，避免不可信的配置读取任意文件。
+func WithYAMLInclude() Option {
+	return func(o *options) {
+		o.yamlInclude = true
+	}
+}
+
+// ErrorMode 指定配置文件出错时的处理方式，见 [WithConfigPathsStopOnError]。
+type ErrorMode int
+
+const (
+	// ErrorModeSkip 跳过该文件，继续处理其余路径。
+	ErrorModeSkip ErrorMode = iota + 1
+	// ErrorModeFail 使加载失败。
+	ErrorModeFail
+)
+
+// WithConfigPathsStopOnError 分别指定「文件不存在」与「文件存在但无法解析」两种情况的处理方式。
+//
+// 默认 onMissing 为 [ErrorModeSkip]、onParse 为 [ErrorModeFail]：缺失的文件按候选路径跳过，
+// 损坏的文件使加载失败。onMissing 为 [ErrorModeFail] 时等同于 [WithFailFastPaths]，
+// 仅作用于 [WithConfigPaths] 显式列出的路径；onParse 为 [ErrorModeSkip] 时，无法解压、
+// 模板展开或解析失败的文件（含 [WithConfigPathsDir] 中的片段）被跳过并记录 [WarnInvalidFile] 警告，
+// 见 [LoadWithWarnings]，搜索继续尝试下一个路径。超过 [WithMaxFileSize] 与校验和不匹配仍使加载失败。
+// 传入零值表示保持该情况的默认处理方式。
+func WithConfigPathsStopOnError(onMissing, onParse ErrorMode) Option {
+	return func(o *options) {
+		if onMissing != 0 {
+			o.onMissingFile = onMissing
+		}
+		if onParse != 0 {
+			o.onParseError = onParse
+		}
+	}
+}
+
+// WithoutHomeConfig 从默认搜索路径（见 [DefaultPaths]）中排除用户主目录下的 ~/.appname.yaml。
+//
+// 适用于以服务账号运行、没有可用主目录的部署，避免多余的文件探测或误读残留文件。
+// 对 [WithConfigPaths] 显式指定的路径无效。
+func WithoutHomeConfig() Option {
+	return func(o *options) {
+		o.noHomeConfig = true
+	}
+}
+
+// WithHomeConfig 在默认搜索路径中包含用户主目录下的 ~/.appname.yaml，用于覆盖之前的 [WithoutHomeConfig]。
+//
+// 这是所有平台上的默认行为；用户主目录无法确定时（见 [DefaultPaths]）仍会省略该路径。
+func WithHomeConfig() Option {
+	return func(o *options) {
+		o.noHomeConfig = false
+	}
+}
+
+// WithEtcdConfig 读取 etcd 中 prefix 下的键值，作为一层配置合并，优先级位于配置文件之上、环境变量之下。
+//
+// 支持两种布局，可同时使用：
+//   - 逐 key 存储：/myapp/server/url = http://... 映射为 server.url（去掉 prefix 后按 "/" 拆分）
+//   - 整体存储：key 恰好为 prefix，值为 JSON 或 YAML 文档
+//
+// client 通过 [EtcdClient] 接口传入，连接地址、认证与超时由调用方创建客户端时配置，
+// 因此 cfgm 不引入 etcd 依赖。读取失败（连接、认证等）时加载失败，错误信息包含 prefix。
+// 可多次调用，按声明顺序合并，后者优先。
+func WithEtcdConfig(client EtcdClient, prefix string) Option {
+	return func(o *options) {
+		o.etcdSources = append(o.etcdSources, etcdSource{client: client, prefix: prefix})
+	}
+}
+
+// WithConfigPathsValidate 在解析任何配置文件之前，打开每个已存在的候选路径以确认可读取。
+//
+// 默认无法打开的文件（如权限不足）与不存在的文件一样被跳过，容易悄悄回退到其他路径或默认值。
+// 启用后存在但无法读取的文件使加载失败，错误信息形如：
+//
+//	cannot read /etc/myapp/config.yaml: permission denied
+//
+// 不存在的路径不受影响（需要时配合 [WithFailFastPaths] 使用）。
+func WithConfigPathsValidate() Option {
+	return func(o *options) {
+		o.validatePaths = true
+	}
+}
+
+// WithRequiredTags 在解码后检查带 required:"true" tag 的字段，任一字段为零值时加载失败。
+//
+// 必填约束与类型定义放在一起，全部缺失的 key 汇总在同一个错误中：
+//
+//	type Config struct {
+//	    URL      string `json:"url"      required:"true"`
+//	    Password string `json:"password" required:"true"`
+//	}
+//
+//	// missing required config: url, password
+//
+// 嵌套结构体中的字段按完整 key 报告；结构体字段本身也可标记 required。
+// 注意 false、0 与空字符串同样是零值，需要区分「未设置」时请使用指针类型。
+func WithRequiredTags() Option {
+	return func(o *options) {
+		o.requiredTags = true
+	}
+}
+
+// WithK8sConfigMapDir 读取 Kubernetes ConfigMap 挂载目录，每个 key（文件）作为一个顶层配置节点。
+//
+// 文件名去掉 .yaml/.yml/.json（及 .gz）扩展名后作为节点名，内容按格式解析为对象；
+// 没有这些扩展名的文件内容（去掉首尾空白）作为字符串值：
+//
+//	/etc/myapp/config/
+//	├── database.yaml   → database: {...}
+//	├── log-level       → log-level: "debug"
+//	└── ..data -> ..2024_01_01_00_00_00.123
+//
+// "..data" 等以 "." 开头的投影卷内部条目与子目录被忽略，目录不存在时不做任何处理。
+// 优先级高于 [WithConfigPathsDir] 片段、低于环境变量；相对路径基于 baseDir 解析。
+// [Loader.Watch] 会监视目录中的条目，ConfigMap 更新替换 ..data 链接后触发重新加载。
+func WithK8sConfigMapDir(dir string) Option {
+	return func(o *options) {
+		o.k8sConfigMapDirs = append(o.k8sConfigMapDirs, dir)
+	}
+}
+
+// WithConfigPathsEnvExpand 在查找前展开配置文件路径中的环境变量（$VAR / ${VAR}）与开头的 ~。
+//
+// 默认路径按字面处理，例如 "$XDG_CONFIG_HOME/myapp/config.yaml" 会被当作相对路径查找。
+// 启用后先展开再按 baseDir 解析，未设置的变量展开为空字符串：
+//
+//	cfgm.Load(config,
+//	    cfgm.WithConfigPaths("$XDG_CONFIG_HOME/myapp/config.yaml", "~/.myapp.yaml"),
+//	    cfgm.WithConfigPathsEnvExpand(),
+//	)
+//
+// 环境变量通过 [WithEnvSnapshot] 设置时使用快照中的值。
+func WithConfigPathsEnvExpand() Option {
+	return func(o *options) {
+		o.pathsEnvExpand = true
+	}
+}
+
+// WithMetrics 在每次加载成功后以本次的耗时分解与来源统计调用 fn，可用于导出到 Prometheus 等监控系统。
+//
+// [Load] 等入口在返回前调用一次；[Loader] 的每次 [Loader.Reload] 同样调用。
+// 加载失败时不调用。fn 在加载所在的 goroutine 中同步执行，应保持轻量：
+//
+//	cfgm.WithMetrics(func(m cfgm.LoadMetrics) {
+//	    loadSeconds.Observe(m.Total.Seconds())
+//	})
+func WithMetrics(fn func(m LoadMetrics)) Option {
+	return func(o *options) {
+		o.onMetrics = fn
+	}
+}
+
+// WithEnvBindingsCaseFold 将 [WithEnvBinding] 中仅大小写与结构体 key 不同的 config path 改写为规范 key。
+//
+// 例如结构体 key 为 redis.url 时，WithEnvBinding("REDIS_URL", "Redis.URL") 默认写入不存在的
+// "Redis.URL" 节点，值被静默丢弃；启用后绑定到 redis.url。
+//
+// 未启用时此类 path 会记录 [WarnEnvBindingCase] 警告（见 [LoadWithWarnings]），便于发现拼写问题。
+func WithEnvBindingsCaseFold() Option {
+	return func(o *options) {
+		o.envBindingsCaseFold = true
+	}
+}
+
+// UnmarshalMode 指定解码到结构体时的类型转换规则，见 [WithUnmarshalMode]。
+type UnmarshalMode int
+
+const (
+	// UnmarshalWeak 允许字符串与数字、布尔之间的宽松转换（如 "5" → 5、"true" → true、1 → "1"），默认模式。
+	UnmarshalWeak UnmarshalMode = iota + 1
+	// UnmarshalStrict 要求值的类型与字段一致，拒绝上述转换。
+	UnmarshalStrict
+)
+
+// WithUnmarshalMode 设置解码到结构体时的类型转换规则。
+//
+// 默认 [UnmarshalWeak]：环境变量等字符串来源无需额外处理即可解码到数字、布尔字段。
+// [UnmarshalStrict] 拒绝这些转换，配置文件中 port: "8080" 之类的写法会使加载失败；
+// 注意此时环境变量只能绑定到字符串字段（time.Duration 等由解码 hook 处理的类型除外）。
+func WithUnmarshalMode(mode UnmarshalMode) Option {
+	return func(o *options) {
+		o.unmarshalMode = mode
+	}
+}
+
+// WithSearchUp 从当前工作目录开始逐级向上查找相对配置路径，类似 .editorconfig 的查找方式。
+//
+// 每个相对路径展开为各级目录中的同名路径，由近及远排列，默认命中最近的一个
+// （[WithMergeAllPaths] 时全部合并，近处的优先）。查找在包含 go.mod 的目录（含）处停止，
+// 未找到 go.mod 时一直到文件系统根目录，但最多向上 32 层；可用 [WithSearchUpBoundary] 修改停止条件，[WithMaxDepth] 修改层数上限。
+// 启用后相对路径不再基于 [WithBaseDir] 解析，绝对路径不受影响。
+// [WithFailFastPaths] 会要求每一级目录中的候选文件都存在，通常不应与本选项同时使用。
+func WithSearchUp() Option {
+	return func(o *options) {
+		o.searchUp = true
+	}
+}
+
+// WithSearchUpBoundary 设置 [WithSearchUp] 的停止标记文件（如 ".git"），
+// 查找在包含该文件的目录（含）处停止；空字符串表示一直查找到文件系统根目录。隐含 [WithSearchUp]。
+func WithSearchUpBoundary(marker string) Option {
+	return func(o *options) {
+		o.searchUp = true
+		o.searchUpBoundary = &marker
+	}
+}
+
+// WithEnvPrefixStrict 使带 [WithEnvPrefix] 前缀、但无法映射到任何配置 key 的环境变量导致加载失败。
+//
+// 用于在 CI 或部署前发现 MYAPP_SEVER_URL 之类的拼写错误，错误中列出全部此类变量：
+//
+//	unknown env vars with prefix: MYAPP_SEVER_URL, MYAPP_TIMEOUTS
+//
+// 只检查前缀（含 [WithEnvPrefixes] 声明的全部前缀）下的变量；[WithEnvBinding]、[WithFlatEnvKeys]
+// 等显式使用的变量视为已知，其他不带前缀的第三方变量不受影响。未设置或为空的变量不检查。
+func WithEnvPrefixStrict() Option {
+	return func(o *options) {
+		o.envPrefixStrict = true
+	}
+}
+
+// WithMigrations 在合并前对每个配置文件执行 m 中尚未应用的版本迁移，详见 [Migrations]。
+//
+// 迁移作用于配置文件、内嵌配置与目录片段，不影响默认值、环境变量与 CLI flags。
+// 迁移只在内存中进行，不会改写磁盘上的文件；需要持久化时可配合 [EditFile] 更新版本 key。
+func WithMigrations(m *Migrations) Option {
+	return func(o *options) {
+		o.migrations = m
+	}
+}
+
+// WithConfigVersion 检查配置文件顶层的版本 key（默认 "version"）是否等于 expected。
+//
+// 不一致时 mode 为 [ErrorModeFail] 使加载失败，为 [ErrorModeSkip] 时记录 [WarnConfigVersion] 警告后继续；
+// 零值等同于 [ErrorModeFail]。未写版本 key 的文件不检查。版本按字符串比较，1 与 "1" 视为相同：
+//
+//	config /etc/myapp/config.yaml: version "1" does not match expected "2"; please upgrade the config file
+//
+// 版本 key 可用 [WithConfigVersionKey] 修改；与 [WithMigrations] 同时使用时检查迁移后的版本。
+func WithConfigVersion(expected string, mode ErrorMode) Option {
+	return func(o *options) {
+		o.configVersion = &expected
+		o.configVersionMode = mode
+	}
+}
+
+// WithConfigVersionKey 设置 [WithConfigVersion] 检查的顶层 key，默认 "version"。
+func WithConfigVersionKey(key string) Option {
+	return func(o *options) {
+		o.configVersionKey = key
+	}
+}
+
+// WithTemplateEnvDefault 为配置文件模板中未设置的 ${VAR} 提供统一的默认值，免去逐处书写 ${VAR:-value}。
+//
+// 仅影响不带操作符的 ${VAR}，${VAR:-default} 等写法中的默认值仍优先；已设置但为空的变量保持为空。
+// 同时使用 [WithTemplateStrictMissing] 时未设置的变量取该默认值，不再报错。
+func WithTemplateEnvDefault(value string) Option {
+	return func(o *options) {
+		o.templateEnvDefault = &value
+	}
+}
+
+// WithConfigPathsLimit 限制配置发现阶段探测的候选文件数（glob 匹配结果与 [WithSearchUp] 展开后计数）。
+//
+// 超过 n 时加载失败，通常意味着 glob 写得过宽等配置错误，避免在网络文件系统上逐个探测大量路径。
+// 默认上限为 1000；n <= 0 表示不限制。
+func WithConfigPathsLimit(n int) Option {
+	return func(o *options) {
+		o.maxPaths = &n
+	}
+}
+
+// WithEnvConfigFile 从应用的 env 文件读取环境变量默认值，类似 go env -w 写入的 go.env。
+//
+// 文件位于 $XDG_CONFIG_HOME/<appName>/env（未设置 XDG_CONFIG_HOME 时为 ~/.config/<appName>/env），
+// 每行一个 KEY=VALUE，忽略空行与 # 开头的注释，值两侧的引号会被去除。
+// 文件中的变量仅在进程环境未设置（或为空）时生效，参与 [WithEnvPrefix]、[WithEnvBinding] 等全部环境变量绑定，
+// 但不参与配置文件的模板展开。文件不存在时忽略，每次加载（含 [Loader.Reload]）都会重新读取。
+//
+// 示例：
+//
+//	# ~/.config/myapp/env
+//	MYAPP_SERVER_URL=https://api.example.com
+//
+//	cfgm.Load(DefaultConfig(),
+//	    cfgm.WithEnvPrefix("MYAPP_"),
+//	    cfgm.WithEnvConfigFile("myapp"),
+//	)
+func WithEnvConfigFile(appName string) Option {
+	return func(o *options) {
+		o.envConfigFileApp = appName
+	}
+}
+
+// WithDurationUnit 指定 key 上不带单位的数字按 unit 解析为时长，用于约定以秒等为单位的配置。
+//
+// 默认情况下 time.Duration 字段中的数字按纳秒解析（如 timeout: 30 为 30ns）。
+// 设置后 path 上的数字（含环境变量等来源的 "30"、"1.5" 字符串）乘以 unit，
+// 显式带单位的字符串（如 "30s"、"500ms"）不受影响，始终按其自身单位解析。可多次调用为不同 key 指定单位。
+//
+// 示例：
+//
+//	cfgm.Load(DefaultConfig(),
+//	    cfgm.WithDurationUnit("server.timeout", time.Second), // timeout: 30 → 30s
+//	)
+func WithDurationUnit(path string, unit time.Duration) Option {
+	return func(o *options) {
+		o.durationUnits = append(o.durationUnits, durationUnit{path: path, unit: unit})
+	}
+}
+
+// WithGitRoot 以工作目录所在的 git 仓库根目录（向上查找 .git）作为相对路径的解析基准，
+// 适用于包含多种语言、根目录不一定有 go.mod 的 monorepo。
+//
+// 基准的优先级（从高到低）：[WithConfigPathsRelativeToExecutable]、WithGitRoot、[WithBaseDir]、
+// 默认的项目根目录（go.mod 所在目录）。未找到 .git 时回退到 [WithBaseDir] 或默认基准。
+// 与默认基准从源文件位置查找 go.mod 不同，WithGitRoot 从运行时的工作目录开始查找。
+func WithGitRoot() Option {
+	return func(o *options) {
+		o.gitRoot = true
+	}
+}
+
+// WithEnvBindingsReport 将加载时未设置（或为空）的 [WithEnvBinding] / [WithEnvBindingTop] 绑定记录为 [WarnUnusedEnvBinding] 警告，
+// 便于发现从未生效、可以清理的绑定。
+//
+// 仅提示，不影响加载结果；警告通过 [LoadWithWarnings] 获取，[Load] 时以 slog Debug 级别输出。
+// 由 [WithEnvBindKey]、[WithEnvBindingsFromFlags] 推导的绑定通常声明了多个备选变量，不在检查范围内。
+// 需要全部绑定的应用情况时使用 [LoadWithEnvReport]。
+func WithEnvBindingsReport() Option {
+	return func(o *options) {
+		o.envBindingsReport = true
+	}
+}
+
+// WithConfigPathsEnv 从环境变量 envKey 读取配置文件路径列表，设置且非空时替代 [WithConfigPaths] 与默认路径。
+//
+// 列表默认以系统路径分隔符拆分（Unix 为 ":"，Windows 为 ";"，与 PATH 相同），可用 [WithConfigPathsSeparator] 修改；
+// 空项被忽略。路径的解析规则与 [WithConfigPaths] 相同，同样视为显式指定（影响 [WithFailFastPaths] 等）。
+//
+//	// MYAPP_CONFIG=/etc/myapp/base.yaml:/etc/myapp/local.yaml
+//	cfgm.Load(config, cfgm.WithAppName("myapp"), cfgm.WithConfigPathsEnv("MYAPP_CONFIG"))
+//
+// 环境变量在 [Load] 调用或 [NewLoader] 创建时读取，[Loader.Reload] 不会重新读取。
+func WithConfigPathsEnv(envKey string) Option {
+	return func(o *options) {
+		o.configPathsEnv = envKey
+	}
+}
+
+// WithConfigPathsSeparator 设置拆分 [WithConfigPathsEnv] 路径列表的分隔符，默认为系统路径分隔符。
+//
+// 同一环境变量在 Linux 与 Windows 间共享时可使用固定的分隔符（如 ","），避免 ":" 与盘符冲突。
+func WithConfigPathsSeparator(sep string) Option {
+	return func(o *options) {
+		o.configPathsSep = sep
+	}
+}
+
+// WithConfigPathsTrace 在配置文件发现结束后，按检查顺序以全部候选路径调用 fn，用于排查「配置文件没有生效」。
+//
+// 每个 [PathProbe] 说明该路径是否存在、是否被选中及原因：不存在、glob 未匹配任何文件、
+// 是目录或无法打开、内容无效被跳过（见 [WithConfigPathsStopOnError]），
+// 以及命中首个文件后未读取的路径（存在时标明被哪个路径遮蔽，见 [WithMergeAllPaths]）。
+// 加载因读取或解析失败而中止时，以已检查的部分调用 fn。fn 在加载所在的 goroutine 中同步执行：
+//
+//	cfgm.WithConfigPathsTrace(func(probes []cfgm.PathProbe) {
+//	    for _, p := range probes {
+//	        slog.Info("config probe", "path", p.Path, "exists", p.Exists, "selected", p.Selected, "reason", p.Reason)
+//	    }
+//	})
+//
+// 仅覆盖 [WithConfigPaths] / [WithAppName] 的搜索路径，不含内嵌默认值、配置片段目录等其他来源。
+func WithConfigPathsTrace(fn func(probes []PathProbe)) Option {
+	return func(o *options) {
+		o.onProbe = fn
+	}
+}
+
+// WithTemplateMaxOutputSize 限制每个配置文件模板展开后的大小（字节），超过时加载失败，错误中包含文件路径。
+//
+// 反复引用体积较大的变量或 ":=" 赋值后层层引用时，展开结果可能远大于文件本身；
+// 加载来自远程或不可信来源的配置时建议设置，避免异常模板耗尽内存。
+// 错误可用 errors.Is(err, templexp.ErrOutputTooLarge) 判断。n <= 0 表示不限制（默认）。
+// 同样作用于 !include 引入的文件与 [WithLazyKeys] 的重新展开。
+func WithTemplateMaxOutputSize(n int) Option {
+	return func(o *options) {
+		o.templateMaxOutput = n
+	}
+}
+
+// WithConfigSources 从 sources 读取配置，按声明顺序合并（靠后的优先），替代 [WithConfigPaths] / [WithAppName] 的文件搜索。
+//
+// 每个来源的内容按配置文件的规则处理：模板展开、[WithMaxFileSize]、[WithConfigChecksum]、gzip 解压等均适用。
+// [WithEmbeddedDefault] 仍位于来源之下，[WithConfigPathsDir] 等目录片段位于来源之上；可多次调用，来源依次追加。
+// 不存在的来源被跳过（见 [ConfigSource]）。[Loader.Watch] 不监听来源的变化，可按需调用 [Loader.Reload]。
+//
+// 示例：
+//
+//	cfgm.Load(DefaultConfig(),
+//	    cfgm.WithConfigSources(
+//	        cfgm.FSSource(defaults, "config.yaml"),
+//	        cfgm.HTTPSource("https://config.internal/myapp.yaml"),
+//	        cfgm.FileSource("/etc/myapp/local.yaml"),
+//	    ),
+//	)
+func WithConfigSources(sources ...ConfigSource) Option {
+	return func(o *options) {
+		o.sources = append(o.sources, sources...)
+	}
+}
+
+// WithOnConfigReload 在 [Loader.Watch] 每次因文件变化重新加载后调用 fn，用于统计重新加载的频率与成败。
+//
+// 与每次加载都会触发的 [WithMetrics] 不同，fn 只在 Watch 的重新加载时调用（手动 [Loader.Reload] 不触发），
+// 反映长期运行的服务中配置的变动情况。fn 在 onChange 之前、Watch 所在的 goroutine 中同步调用：
+//
+//	cfgm.WithOnConfigReload(func(e cfgm.ReloadEvent) {
+//	    reloads.WithLabelValues(strconv.FormatBool(e.Err == nil), strconv.FormatBool(e.Changed)).Inc()
+//	    reloadSeconds.Observe(e.Duration.Seconds())
+//	})
+func WithOnConfigReload(fn func(e ReloadEvent)) Option {
+	return func(o *options) {
+		o.onReload = fn
+	}
+}
+
+// WithStrictDuplicateKeys 使 JSON 配置文件中同一对象内的重复 key 解析失败，错误包含文件、key 路径与行号。
+//
+// encoding/json 默认静默保留最后一个值，复制粘贴产生的两个 "port" 难以察觉。
+// YAML 文件无需该选项：解析器始终拒绝同一映射中的重复 key（错误同样包含行号）。
+// 检查在每个文件解析时进行、早于合并，不同文件之间同名的 key 仍按优先级正常覆盖。
+func WithStrictDuplicateKeys() Option {
+	return func(o *options) {
+		o.strictDuplicateKeys = true
+	}
+}
+
+// WithConfigPathsNewerThan 跳过修改时间早于 t 的候选配置文件，防止遗留在工作目录中的旧配置遮蔽预期的文件。
+//
+// 典型用法是以二进制的构建时间为界。被跳过的文件视为不存在（继续尝试下一个路径），
+// 并记录为 [WarnStaleFile] 警告；[WithFailFastPaths] 等要求显式路径必须存在时，过旧的文件使加载失败。
+// 同时作用于 [WithConfigPathsDir] 与 [WithK8sConfigMapDir] 中的文件。t 为零值表示不检查（默认）。
+//
+//	cfgm.Load(config, cfgm.WithAppName("myapp"), cfgm.WithConfigPathsNewerThan(buildTime))
+func WithConfigPathsNewerThan(t time.Time) Option {
+	return func(o *options) {
+		o.newerThan = t
+	}
+}
+
+// WithEnvBindingTop 将环境变量 envKey 绑定到 configPath，并在 CLI flags 之后应用，使其覆盖命令行参数。
+//
+// 用于少数运维层面的紧急开关（如 FORCE_READONLY），即使启动命令中写死了相反的 flag 也能通过环境变量强制生效。
+// 仅影响该绑定，其余环境变量仍低于 CLI flags；[WithForcedValues] 仍位于其上。
+// 环境变量未设置或为空时不生效，可多次调用，后声明的优先。[LoadWithEnvReport] 中的来源为 [EnvSourceTop]。
+//
+// 安全提示：能够设置进程环境变量的一方即可覆盖对应配置，且不会在命令行中留下痕迹；
+// 只应为确有需要的 key 使用，不要绑定到鉴权、TLS 校验等安全相关的配置，并在变量生效时记录日志以便审计。
+func WithEnvBindingTop(envKey, configPath string) Option {
+	return func(o *options) {
+		o.topEnvBindings = append(o.topEnvBindings, envBinding{envKey: envKey, configPath: configPath, source: EnvSourceTop})
+	}
+}
+
+// WithReadRetries 在配置文件读取或解析失败时最多重试 n 次，第 i 次重试前等待 i*delay。
+//
+// 用于配置文件被频繁改写或位于网络挂载的场景：读取时写入尚未完成会得到不完整的内容，
+// 短暂等待后重新读取通常即可成功，避免部署期间的偶发加载失败（[Loader.Watch] 的重新加载同样受益）。
+// 仅对已存在文件的读取、校验和、解压与解析错误重试，文件不存在时不重试。n <= 0 表示不重试（默认）。
+//
+//	cfgm.WithReadRetries(3, 20*time.Millisecond) // 最多额外等待 20+40+60ms
+func WithReadRetries(n int, delay time.Duration) Option {
+	return func(o *options) {
+		o.readRetries = n
+		o.readRetryDelay = delay
+	}
+}
+
+// WithCLIFlagPrefix 为配置对应的 CLI flag 名称加上命名空间前缀，映射到配置路径前去除该前缀。
+//
+// 用于将配置 flag 与命令自身的 flag 分组，如前缀 "cfg." 时 --cfg.server-url 对应 server.url。
+// 其余部分仍按 key 各段以 "-" 拼接的规则生成；不带该前缀的 flag 不参与配置覆盖，
+// [WithEnvBindingsFromFlags] 同样按带前缀的名称查找 flag。
+//
+//	&cli.StringFlag{Name: "cfg.server-url"}
+//	cfgm.WithCLIFlagPrefix("cfg.")
+func WithCLIFlagPrefix(prefix string) Option {
+	return func(o *options) {
+		o.cliFlagPrefix = prefix
+	}
+}
+
+// WithConfigPathsDeduplicate 按规范化路径（解析符号链接并 Clean）对候选配置文件去重，同一文件最多合并一次。
+//
+// 默认路径、[WithConfigPaths] 与环境变量提供的路径可能指向同一文件（尤其经由符号链接），
+// 配合 [WithMergeAllPaths] 时会被重复合并。去重保留首次出现的位置，之后的重复项不再读取，
+// [WithConfigPathsTrace] 中记录为 "skipped: same file as ..."。
+func WithConfigPathsDeduplicate() Option {
+	return func(o *options) {
+		o.dedupePaths = true
+	}
+}
+
+// WithEnvBindingsNamespace 批量声明环境变量绑定（环境变量名 → 配置 key），并将 prefix 挂载到每个 key 之前。
+//
+// 用于可复用的配置模块：库只声明相对自身配置片段的绑定，由应用决定挂载位置，避免与应用的 key 冲突。
+// 绑定按环境变量名排序后应用，其余规则与 [WithEnvBinding] 相同。
+//
+//	// 库：redis.EnvBindings = map[string]string{"REDIS_URL": "url"}
+//	cfgm.WithEnvBindingsNamespace("cache.redis", redis.EnvBindings) // REDIS_URL → cache.redis.url
+func WithEnvBindingsNamespace(prefix string, bindings map[string]string) Option {
+	return func(o *options) {
+		for _, envKey := range slices.Sorted(maps.Keys(bindings)) {
+			o.envBindings = append(o.envBindings, envBinding{
+				envKey: envKey, configPath: bindings[envKey], source: EnvSourceBinding, namespace: prefix,
+			})
+		}
+	}
+}
+
+// WithStatTimeout 限制配置文件发现时每次文件系统访问的耗时，超时的文件按不存在处理。
+//
+// 失效的 NFS/SMB 挂载上 stat、open 与 read 都可能阻塞数分钟，使 Load 长时间无响应。
+// 每个候选文件的 stat、读取（包括符号链接解析、打开与读取内容）以及 glob 候选路径的展开各自受 d 约束。
+// 超时会记录日志和 [WarnStatTimeout] 警告（见 [LoadWithWarnings]），并继续检查下一个路径；
+// 解析不受该时限约束。d <= 0 表示不限制（默认）。
+func WithStatTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.statTimeout = d
+	}
+}
+
+// WithConfigManifest 从清单文件读取要加载的配置片段，按清单中的顺序合并（靠后的优先），替代配置文件搜索。
+//
+// 清单每行一个路径，忽略空行与 "#" 开头的注释；相对路径基于清单所在目录解析。
+// 清单本身的相对路径基于 baseDir 解析。
+// 清单或其中列出的片段不存在时加载失败；[Loader.Watch] 会同时监听清单与片段。
+//
+//	# config.list
+//	base.yaml
+//	regions/eu.yaml
+//	overrides.yaml
+func WithConfigManifest(path string) Option {
+	return func(o *options) {
+		o.manifest = path
+	}
+}
+
+// WithEnvBindingsInverse 将环境变量已设置、且配置文件也设置了同一 key 的绑定记录为 [WarnEnvOverridesFile] 警告。
+//
+// 用于安全审查时确认优先级符合预期：同一 key 同时来自文件与环境变量时，最终生效的是环境变量，
+// 审查文件中的值并不代表运行时配置。覆盖前缀规则、显式绑定与 [WithEnvBindingTop] 等全部环境变量绑定，
+// 警告中包含最后设置该 key 的配置文件；仅提示，不影响加载结果，通过 [LoadWithWarnings] 获取。
+func WithEnvBindingsInverse() Option {
+	return func(o *options) {
+		o.envBindingsInverse = true
+	}
+}
+
+// WithConfigExtensions 声明额外的配置文件扩展名及其格式（"yaml" 或 "json"），如 {".cfg": "yaml"}。
+//
+// 扩展名不区分大小写，可省略开头的 "."，并可带 .gz 后缀。[WithConfigPathsDir] 与 [WithK8sConfigMapDir]
+// 扫描目录时除内置的 .yaml/.yml/.json 外也读取这些文件，未声明的扩展名仍被跳过；
+// 解析显式路径、glob 匹配到的文件与 !include 时同样按声明的格式解析。格式无法识别时加载失败。
+// 可多次调用，同一扩展名以后声明的为准。
+func WithConfigExtensions(extensions map[string]string) Option {
+	return func(o *options) {
+		if o.configExtensions == nil {
+			o.configExtensions = make(map[string]string, len(extensions))
+		}
+		for ext, format := range extensions {
+			o.configExtensions["."+strings.ToLower(strings.TrimPrefix(ext, "."))] = format
+		}
+	}
+}
+
+// WithNoEnvForPaths 禁止由环境变量设置 keys 对应的配置（如安全模式开关），使其只能来自经过审查的配置文件。
+//
+// [WithEnvPrefix] 不再为这些 key 生成绑定（[WithEnvPrefixStrict] 时对应的环境变量视为未知），
+// [WithEnvBindKey] 与 [WithEnvBindingsFromFlags] 推导的绑定被跳过；[WithEnvBinding] 等显式绑定指向这些 key 时加载失败。
+// key 可以使用 "*" 匹配任意一段（如 "security.*"），匹配到的 key 之下的子 key 同样受保护；比较不区分大小写。
+// 不影响 [WithFlatEnvKeys] 等以整个配置片段形式提供的环境变量。可多次调用，效果累加。
+func WithNoEnvForPaths(keys ...string) Option {
+	return func(o *options) {
+		o.noEnvPaths = append(o.noEnvPaths, keys...)
+	}
+}
+
+// WithConfigFromArgs 从 os.Args 中读取 flagNames 指定的 flag（如 "config"、"c"）作为配置文件路径。
+//
+// 适合未使用 urfave/cli 的小工具：无需 [WithCommand]，只做最简单的扫描，
+// 支持 --config path、--config=path 及单个 "-" 的写法，多次出现时以最后一次为准，"--" 之后的参数不扫描。
+// --config 之后的参数以 "-" 开头时视为未提供值（不会把 --verbose 当作路径），需要此类路径时使用 --config=path。
+// 找到时替代 [WithConfigPaths]、[WithConfigPathsEnv] 与默认路径；相对路径基于当前工作目录解析。
+// flag 名称可带或省略前导 "-"；其他命令行参数不做校验。
+func WithConfigFromArgs(flagNames ...string) Option {
+	return func(o *options) {
+		for _, name := range flagNames {
+			o.configArgFlags = append(o.configArgFlags, strings.TrimLeft(name, "-"))
+		}
+	}
+}
+
+// WithConfigDirRecursive 递归合并目录树中的全部配置片段，是 [WithConfigPathsDir] 的多级版本。
+//
+// 收集各级子目录中可识别的配置文件（.yaml/.yml/.json 及 [WithConfigExtensions] 声明的扩展名），
+// 按相对路径字典序合并，后读取的覆盖先读取的，如 base.yaml 先于 services/api.yaml。
+// 优先级位于 [WithConfigPathsDir] 的片段之上；目录不存在时不做任何处理，符号链接目录不跟随。
+// 默认最多进入 32 层子目录，可用 [WithMaxDepth] 修改。
+// 用 [WithConfigDirExclude] 跳过 .git、node_modules 等目录。相对路径基于 baseDir 解析；多次调用按声明顺序合并。
+func WithConfigDirRecursive(dir string) Option {
+	return func(o *options) {
+		o.configDirsRecursive = append(o.configDirsRecursive, dir)
+	}
+}
+
+// WithConfigDirExclude 设置 [WithConfigDirRecursive] 遍历时跳过的子目录，按目录名称匹配（语法见 [filepath.Match]）。
+//
+//	cfgm.WithConfigDirExclude(".*", "node_modules")
+func WithConfigDirExclude(patterns ...string) Option {
+	return func(o *options) {
+		o.configDirExclude = append(o.configDirExclude, patterns...)
+	}
+}
+
+// WithExplicitZeroWins 让已设置但为空的环境变量（如 APP_NAME=）同样生效，以字段类型的零值覆盖低优先级来源。
+//
+// 各来源对零值的默认处理：
+//   - 配置文件：显式写出的零值（replicas: 0、enabled: false、name: ""）总是覆盖低优先级文件与默认值
+//   - CLI flags：显式传入的零值（--replicas 0、--enabled=false）总是生效，未传入的 flag 不参与覆盖
+//   - 环境变量："0"、"false" 按字符串转换后生效；空值默认视为未设置，与变量不存在相同
+//
+// 启用后，空值的环境变量按变量是否存在判断（而非值是否为空），写入字段类型的零值，
+// 不受 [WithStrictEnvTypes] 的格式校验；未设置的变量仍不参与覆盖。
+func WithExplicitZeroWins() Option {
+	return func(o *options) {
+		o.explicitZeroWins = true
+	}
+}
+
+// WithConfigPathTyped 追加配置文件路径 path，并声明按 format（"yaml" 或 "json"）解析，不再根据扩展名判断。
+//
+// 用于混合使用无扩展名文件（如 /etc/myapp/config）与其他格式的文件：可多次调用，
+// 路径按调用顺序追加到 [WithConfigPaths] 的列表之后，查找与合并规则不变（之后调用 WithConfigPaths 会替换整个列表）。
+// path 可以是 glob 模式，匹配到的文件均按 format 解析。格式无法识别时加载失败。
+func WithConfigPathTyped(path, format string) Option {
+	return func(o *options) {
+		o.configPaths = append(slices.Clip(o.configPaths), path)
+		o.configPathsSet = true
+		if o.pathFormats == nil {
+			o.pathFormats = make(map[string]string)
+		}
+		o.pathFormats[path] = format
+	}
+}
+
+// WithEnvBindingList 将环境变量 envKey 绑定到切片类型的 configPath，值按 sep 拆分为列表（sep 为空时使用 ","）。
+//
+// 如 WithEnvBindingList("HOSTS", "server.hosts", ",") 时 HOSTS="a, b,c" 得到 ["a", "b", "c"]：
+// 各项去除首尾空白、忽略空项，再按字段的元素类型转换（如 []int）。以 "[" 开头的值仍按 JSON 数组解析。
+// 其余规则与 [WithEnvBinding] 相同。
+func WithEnvBindingList(envKey, configPath, sep string) Option {
+	if sep == "" {
+		sep = ","
+	}
+
+	return func(o *options) {
+		o.envBindings = append(o.envBindings, envBinding{envKey: envKey, configPath: configPath, source: EnvSourceBinding, listSep: sep})
+	}
+}
+
+// EnvTrimRule 描述 [WithEnvBindingsTrimPrefix] 如何由环境变量名得到配置路径。
+type EnvTrimRule struct {
+	Prefix    string              // 要匹配并去除的环境变量名前缀，如 "FOO_"
+	Repeat    bool                // 重复去除前缀，用于 "AWS_AWS_REGION" 这类重复前缀
+	Separator string              // 去除前缀后各段的分隔符，空表示 "_"
+	Mount     string              // 挂载到的配置路径前缀，空表示根
+	Recase    func(string) string // 每段的大小写转换，nil 表示 strings.ToLower
+}
+
+// WithEnvBindingsTrimPrefix 将名称以 rule.Prefix 开头的环境变量按名称映射为配置路径，用于不规范的第三方命名。
+//
+// 与按结构体 key 生成绑定的 [WithEnvPrefix] 不同，规则从环境变量名出发：去除前缀（Repeat 时重复去除），
+// 其余部分按 Separator 拆分、经 Recase 转换后以 key 分隔符连接，再拼接 Mount。
+// 如 Prefix "FOO_" 时 FOO_BAR_BAZ → bar.baz；由于 "_" 被视为层级分隔，含下划线的 key 需使用 [WithEnvBinding]。
+// 绑定在 flag 推导的绑定之后、显式绑定之前应用，可多次调用。
+//
+//	cfgm.WithEnvBindingsTrimPrefix(cfgm.EnvTrimRule{Prefix: "AWS_", Repeat: true, Mount: "aws"})
+//	// AWS_AWS_REGION → aws.region
+func WithEnvBindingsTrimPrefix(rule EnvTrimRule) Option {
+	return func(o *options) {
+		if rule.Prefix != "" {
+			o.envTrimRules = append(o.envTrimRules, rule)
+		}
+	}
+}
+
+// WithStatCache 在 ttl 内缓存配置文件发现时确认不存在的候选路径，重复加载时不再 stat 这些路径。
+//
+// 用于频繁调用 Load / [Loader.Reload] 的场景（如测试或 Watch），默认路径列表中多数候选文件并不存在。
+// 缓存随选项保存：同一个 Option 值（或同一个 [Loader]）的多次加载共享缓存。
+// ttl 内新建的文件可能被忽略，直到缓存过期；[Loader.Watch] 发现监听路径变化时会先清除对应的缓存再重新加载。
+// 单次 Load 无需启用；ttl <= 0 表示不缓存（默认）。
+func WithStatCache(ttl time.Duration) Option {
+	if ttl <= 0 {
+		return func(o *options) { o.statCache = nil }
+	}
+	cache := &statCache{ttl: ttl, missing: make(map[string]time.Time)}
+
+	return func(o *options) {
+		o.statCache = cache
+	}
+}
+
+// WithAggregatePathErrors 收集配置文件发现、读取与解析中的全部错误，以 errors.Join 一并返回。
+//
+// 默认遇到第一个错误即停止，多个显式路径同时缺失或无效时需要逐个修复、反复运行。
+// 启用后 [WithFailFastPaths]、[WithConfigPathsValidate] 的检查覆盖全部路径，
+// 读取或解析失败的文件被记录后继续处理后续路径，最后返回所有路径相关的错误。
+// 有错误时不返回配置；[WithConfigPathsLimit] 超限仍立即失败。
+func WithAggregatePathErrors() Option {
+	return func(o *options) {
+		o.aggregatePathErrors = true
+	}
+}
+
+// WithTemplateRequireEnv 要求 vars 中的变量在展开配置文件模板时均已设置，否则加载失败，错误中列出全部缺失的变量。
+//
+// 比 [WithTemplateStrictMissing] 更严格：即使模板以 ${VAR:-default} 引用或未引用该变量，未设置也报错，
+// 用于部署校验，缺少约定的环境变量即视为部署配置错误。变量按模板的规则查找（[WithTemplateData] 优先于环境变量），
+// 已设置但为空的变量视为已设置。可多次调用，变量列表累加；见 [templexp.WithRequiredVars]。
+func WithTemplateRequireEnv(vars ...string) Option {
+	return func(o *options) {
+		o.templateRequired = append(o.templateRequired, vars...)
+	}
+}
+
+// appNamePlaceholder 是 [WithConfigPathsForOS] 路径中替换为应用名的占位符。
+const appNamePlaceholder = "{app}"
+
+// WithConfigPathsForOS 按 runtime.GOOS 选择默认的配置文件搜索路径，替代 [DefaultPaths]。
+//
+// paths 以 GOOS（如 "linux"、"darwin"、"windows"）为 key；当前系统未列出时仍使用 DefaultPaths。
+// 路径中的 "{app}" 替换为 [WithAppName] 设置的应用名，便于与应用名派生的路径组合。
+// 与 DefaultPaths 一样只是默认值：[WithConfigPaths] 等显式路径优先，也不受 [WithFailFastPaths] 约束。
+//
+//	cfgm.WithConfigPathsForOS(map[string][]string{
+//	    "darwin":  {"/Library/Application Support/{app}/config.yaml", ".{app}.yaml"},
+//	    "windows": {`C:\ProgramData\{app}\config.yaml`},
+//	})
+func WithConfigPathsForOS(paths map[string][]string) Option {
+	return func(o *options) {
+		if list, ok := paths[runtime.GOOS]; ok {
+			o.osConfigPaths = append([]string{}, list...)
+		}
+	}
+}
+
+// WithConfigPathsFromXDGDirs 读取 $XDG_CONFIG_DIRS（默认 /etc/xdg）各目录下的 <app>/config.yaml 作为系统级配置。
+//
+// 应用名来自 [WithAppName]。系统级配置作为低优先级的基础层全部合并，用户配置（常规的配置文件搜索结果）覆盖其中的值；
+// 按 XDG 规范，XDG_CONFIG_DIRS 中靠前的目录优先。不存在的文件被忽略，[Loader.Watch] 同样监听这些路径。
+//
+//	// XDG_CONFIG_DIRS=/etc/xdg/vendor:/etc/xdg
+//	// 合并顺序: /etc/xdg/myapp/config.yaml → /etc/xdg/vendor/myapp/config.yaml → ~/.myapp.yaml 等用户配置
+//	cfgm.Load(cfg, cfgm.WithAppName("myapp"), cfgm.WithConfigPathsFromXDGDirs())
+func WithConfigPathsFromXDGDirs() Option {
+	return func(o *options) {
+		o.xdgConfigDirs = true
+	}
+}
+
+// WithUnmarshalErrorContext 改写解码到结构体时的错误，附带出错的 key 路径、实际值及其类型与目标类型。
+//
+// mapstructure 的原始错误多为 "'server.timeout' time: invalid duration" 之类，看不出配置中的实际值。
+// 启用后每个字段的错误形如：
+//
+//	cannot decode server.timeout: got string "abc", want time.Duration
+//
+// 多个字段出错时逐个列出；目标为 [SecretRef] 的字段只显示值的类型。原始错误仍可通过 errors.As 取得。
+func WithUnmarshalErrorContext() Option {
+	return func(o *options) {
+		o.decodeErrContext = true
+	}
+}
+
+// WithAppNameFromExecutable 在未通过 [WithAppName] 设置应用名时，以调用时的程序名（os.Args[0] 的文件名）作为应用名。
+//
+// 用于通过不同名称的符号链接调用的多功能程序（busybox 风格）：以 appfoo 调用时搜索 .appfoo.yaml 等路径。
+// 使用 os.Args[0] 而非 os.Executable，因此符号链接本身的名称生效；Windows 上的 .exe 后缀（不区分大小写）会被去除。
+// [WithAppName] 显式设置的名称优先，与选项顺序无关。
+func WithAppNameFromExecutable() Option {
+	return func(o *options) {
+		o.appNameFromExe = true
+	}
+}
+
+// executableAppName 返回程序路径的文件名，去除 .exe 后缀。
+func executableAppName(arg0 string) string {
+	name := filepath.Base(arg0)
+	if ext := filepath.Ext(name); strings.EqualFold(ext, ".exe") {
+		name = strings.TrimSuffix(name, ext)
+	}
+
+	return name
+}
+
+// WithWatchDebounce 让 [Loader.Watch] 发现变化后等待文件稳定 window 时长再重新加载，将一批变化合并为一次。
+//
+// 用于 [WithConfigPathsDir] 等 drop-in 片段目录：部署工具通常连续新增、删除、修改多个片段，
+// 逐个重新加载既浪费，又可能短暂应用只更新了一半的配置。启用后每次轮询仍检查变化，
+// 直到连续 window 时长内没有新的变化才重新加载一次，读取届时全部片段合并的结果。
+// 与 [WithConfigReloadThrottle] 的区别在于等待的是变化停止，而不是两次重新加载的间隔。
+// window <= 0 表示不等待（默认）。
+func WithWatchDebounce(window time.Duration) Option {
+	return func(o *options) {
+		o.watchDebounce = window
+	}
+}
+
+// WithValueProcessor 注册按目标类型生效的处理函数：解码完成后，类型与 proto 相同的每个配置字段都会以当前值调用 fn，
+// 并以返回值替换字段值，用于在多个配置间复用按类型的校验与规范化策略。
+//
+// 例如 WithValueProcessor((*url.URL)(nil), fn) 处理所有 *url.URL 字段，WithValueProcessor(time.Duration(0), fn) 处理所有 time.Duration 字段。
+// 类型须完全一致，time.Duration 的处理函数不会作用于 int64 字段。遍历规则与 json tag 一致，并进入切片元素与 map 值；
+// nil 指针字段不调用。同一类型注册多个处理函数时按注册顺序依次调用。
+// fn 返回的值须可赋值给字段类型；fn 返回错误或结果类型不匹配时加载失败，错误中包含字段路径（如 "config server.url: ..."）。
+func WithValueProcessor(proto any, fn func(v any) (any, error)) Option {
+	return func(o *options) {
+		o.valueProcessors = append(o.valueProcessors, valueProcessor{typ: reflect.TypeOf(proto), fn: fn})
+	}
+}
+
+// WithHostnameOverlay 在加载配置文件后，合并同目录下以短主机名命名的覆盖文件，其中的 key 优先于基础文件。
+//
+// 例如主机 web-01.example.com 加载 /etc/myapp/config.yaml 时，/etc/myapp/config.web-01.yaml 存在即合并于其上，
+// 用于同一份部署产物中按主机微调配置。覆盖文件不存在时仅使用基础文件；[WithMergeAllPaths] 时每个命中的文件各自查找覆盖文件。
+// 主机名取 os.Hostname 第一个 "." 之前的部分，无法获取时不合并覆盖文件。
+func WithHostnameOverlay() Option {
+	return func(o *options) {
+		o.hostnameOverlay = true
+	}
+}
+
+// WithSchemaFile 与 [WithSchema] 相同，但从磁盘读取 JSON Schema 文档，便于 schema 与配置文件一同版本管理。
+//
+// 相对路径基于 baseDir 解析。文件在首次加载时读取并解析，之后文件修改时间与大小不变时复用解析结果，
+// 因此 [Loader.Reload] 与 [Loader.Watch] 每次重新加载只需一次 stat。
+// 既然显式要求校验，schema 文件不存在、无法读取或不是合法的 schema 时加载失败，错误中包含文件路径。
+// 可与 [WithSchema] 同时使用，两份 schema 均需满足。schema 文件可由 [GenerateSchema] 生成：
+//
+//	schema, _ := cfgm.GenerateSchema(DefaultConfig())
+//	os.WriteFile("config/config.schema.json", schema, 0o644)
+//
+//	cfg, err := cfgm.Load(DefaultConfig(), cfgm.WithSchemaFile("config/config.schema.json"))
+func WithSchemaFile(path string) Option {
+	file := &schemaFile{path: path}
+
+	return func(o *options) {
+		o.schemaFile = file
+	}
+}
+
+// WithFieldInterceptor 在解码前对配置结构体的每个叶子字段调用 fn，参数为字段路径（如 "db.password"）、
+// 合并后的原始值与字段的 Go 类型，返回值替换原始值，返回错误则拒绝该值并使加载失败，错误中包含字段路径。
+//
+// 与按目标类型生效的 [WithValueProcessor] 不同，fn 可以按路径实施策略，如审计指定字段的来源值、
+// 先脱敏再解码、为未配置的字段计算默认值。原始值为包括 defaultConfig 在内的各来源合并后的结果
+// （配置文件中的字符串、数字、map 等，环境变量与 CLI flag 多为字符串），尚未转换为字段类型；返回值按正常的解码规则转换。
+// 合并结果中不存在的字段（如值为 nil 的指针结构体之下的字段）以 nil 调用，fn 返回 nil 时保持不存在。嵌套结构体按其叶子字段逐个调用，
+// map 与切片字段作为整体调用一次。可多次调用，按注册顺序依次处理，前者的结果作为后者的输入。
+//
+// 示例：
+//
+//	cfgm.WithFieldInterceptor(func(path string, raw any, _ reflect.Type) (any, error) {
+//	    if path == "db.password" && raw == "changeme" {
+//	        return nil, errors.New("default password not allowed")
+//	    }
+//	    return raw, nil
+//	})
+func WithFieldInterceptor(fn func(path string, raw any, typ reflect.Type) (any, error)) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, fn)
+	}
+}
+
+// WithFormatPreference 在 glob 匹配或配置片段目录中出现主名相同、格式不同的文件（如 config.yaml 与 config.json）时，
+// 只使用 order 中最靠前的格式的文件，例如 WithFormatPreference("yaml", "json") 时忽略 config.json。
+//
+// 未设置时不做取舍：这些文件全部参与合并，按完整路径的逐字节字典序，靠后的优先（config.json 先于 config.yaml 合并，
+// 因此同名 key 以 config.yaml 为准）。order 中的格式为 "yaml"/"yml"/"json"，.yml 与 .yaml 视为同一格式；
+// 未列出的格式排在全部列出的格式之后。影响 [WithConfigPaths] 的 glob 候选、[WithConfigPathsDir] 与 [WithConfigDirRecursive]；
+// 明确写出的单个文件路径不受影响。格式无法识别时加载失败。
+func WithFormatPreference(order ...string) Option {
+	return func(o *options) {
+		o.formatPreference = order
+	}
+}
+
+// WithMaxDepth 限制 [WithSearchUp] 向上查找与 [WithConfigDirRecursive] 向下扫描的层数，避免在配置异常的系统上
+// 一直遍历到文件系统根目录或深入庞大的目录树。
+//
+// [WithSearchUp] 最多查找工作目录及其上 n 级目录，先于 [WithSearchUpBoundary] 的标记文件到达上限时同样停止；
+// [WithConfigDirRecursive] 最多读取根目录之下 n 层子目录中的文件，更深的子目录整体跳过。
+// n 为 0 时只使用起始目录，负数表示不限制。未设置时两者均为 32 层。
+func WithMaxDepth(n int) Option {
+	return func(o *options) {
+		o.maxDepth = &n
+	}
+}
+
+// WithEnvBindingsResolveOrder 指定 CLI flags 之下各类环境变量绑定之间的应用顺序，靠后的来源优先。
+//
+// 多种绑定指向同一 key 时，默认按 [EnvSourcePrefix] < [EnvSourceTag] < [EnvSourceFlag] < [EnvSourceTrim] < [EnvSourceBinding]
+// 应用（见 doc.go 中的「环境变量优先级」）。例如希望前缀变量覆盖显式绑定的平台变量：
+//
+//	cfgm.WithEnvBindingsResolveOrder(cfgm.EnvSourceBinding, cfgm.EnvSourcePrefix)
+//
+// 未列出的来源按默认顺序排在列出的来源之前（优先级最低）；同一来源内部的顺序不变。
+// [EnvSourceTop] 始终位于 CLI flags 之上，不能出现在 sources 中；出现不支持或重复的来源时加载失败。
+func WithEnvBindingsResolveOrder(sources ...EnvSource) Option {
+	return func(o *options) {
+		o.envResolveOrder = sources
+	}
+}
+
+// WithSecureFilePermissions 拒绝加载组用户或其他用户可读的配置文件（类似 ssh 对私钥的检查），
+// 适用于包含密钥等敏感信息的配置。
+//
+// 检查在读取文件内容之前进行，权限位包含 0o044 中任意一位时加载失败，错误信息包含文件路径与其权限（如 0644），
+// 应将文件权限收紧为 0600 或 0400。检查覆盖从磁盘读取的全部配置文件，包括 [WithConfigPathsDir] 等目录中的片段；
+// 符号链接按其指向的文件检查。[WithConfigSources] 中的 [FileSource] 同样检查；
+// [WithEmbeddedDefault]、[LoadReader] 与其他配置来源的数据不涉及文件权限，不做检查。
+//
+// 仅在 Unix 类系统上生效：Windows 的访问控制由 ACL 决定，文件权限位不反映实际的可读性，该选项不做任何检查。
+func WithSecureFilePermissions() Option {
+	return func(o *options) {
+		o.securePermissions = true
+	}
+}
+
+// WithConfigReloadGracePeriod 设置 [Loader.Watch] 遇到空白配置文件时的重新检查间隔。
+//
+// 部分工具替换配置文件时会先将其截断为 0 字节再写入新内容，恰好在此时重新加载会得到空配置并清空全部设置。
+// Watch 发现变化后，若发生变化的文件为空或只含空白字符，则视为写入中途的状态：不重新加载、不调用 onChange，
+// 经过 d 后重新检查；仍为空白时以 slog.Warn 记录一次，之后按 [WithWatchInterval] 的间隔检查，直到文件出现内容后才重新加载。
+// 因此有意清空的配置文件不会被 Watch 加载，需显式调用 [Loader.Reload]。
+//
+// d 为 0 时使用默认的 100ms，d < 0 时不做等待，空白文件与其他内容一样立即重新加载。
+func WithConfigReloadGracePeriod(d time.Duration) Option {
+	return func(o *options) {
+		o.reloadGracePeriod = d
+	}
+}
+
+// WithDotfileChain 按 git 配置的三级作用域依次合并 appName 的配置文件，靠后的作用域优先：
+//
+//	/etc/<app>.yaml   系统级
+//	~/.<app>.yaml     用户级，主目录由 [os.UserHomeDir] 确定，无法确定时跳过
+//	./.<app>.yaml     项目级，相对路径，与 [DefaultPaths] 一致基于 [WithBaseDir] 解析
+//
+// 每个作用域都是可选的，不存在的文件直接跳过。等价于以上路径（按优先级从高到低）的 [WithConfigPaths] 加 [WithMergeAllPaths]，
+// 因此会替换此前设置的搜索路径。
+//
+//	cfgm.Load(config, cfgm.WithDotfileChain("myapp"))
+func WithDotfileChain(appName string) Option {
+	return func(o *options) {
+		paths := []string{"." + appName + ".yaml"}
+		if home, err := os.UserHomeDir(); err == nil {
+			paths = append(paths, filepath.Join(home, "."+appName+".yaml"))
+		}
+		paths = append(paths, "/etc/"+appName+".yaml")
+
+		o.configPaths = paths
+		o.configPathsSet = true
+		o.mergeAllPaths = true
+	}
+}
+
+// WithUnmarshalTagChain 按 tags 的顺序读取字段的 struct tag 确定配置 key，替代默认的 json tag，
+// 便于直接复用已有 json tag 的 API 结构体：
+//
+//	type Server struct {
+//	    Addr    string `koanf:"listen" json:"addr"` // 配置 key 为 listen
+//	    Timeout int    `json:"timeout"`             // 没有 koanf tag，配置 key 为 timeout
+//	}
+//
+//	cfgm.Load(config, cfgm.WithUnmarshalTagChain("koanf", "json"))
+//
+// 每个字段使用第一个声明了名称的 tag，只写了选项的 tag（如 `koanf:",omitempty"`）视为未声明；
+// tag 之间不一致时以靠前的为准，后续 tag 中的名称不作为别名，配置文件中写 addr 视为未知 key。
+// 第一个声明名称的 tag 为 "-" 时字段不参与配置，即使后续 tag 声明了名称；全部 tag 都未声明名称的字段同样忽略。
+//
+// 配置 key 决定了加载流程中的全部 key 路径：配置文件、默认值、[WithEnvPrefix] 生成的环境变量名、CLI flag 名称、
+// [WithRequiredTags] 与 [LoadWithOverrides] 报告的路径，以及 [Marshal] 的输出。
+// 不接受选项的 [ExampleYAML]、[MarshalYAML]、[MarshalJSON]、[GenerateSchema] 与 [ExportEnv] 仍使用 json tag。
+// 解码错误中的路径为 Go 字段名，启用 [WithUnmarshalErrorContext] 时还原为配置 key。
+func WithUnmarshalTagChain(tags ...string) Option {
+	return func(o *options) {
+		o.configTags = tags
+	}
+}
+
+// WithEnvFileSuffix 支持 Docker / Kubernetes 的 secrets 约定：绑定的环境变量 NAME 之外，
+// 若 NAME 加上 suffix 的变量（如 MYAPP_DB_PASSWORD_FILE=/run/secrets/db_password）已设置且非空，
+// 则读取该变量指向的文件内容作为 NAME 的值，优先于 NAME 本身。
+//
+//	cfgm.Load(config, cfgm.WithEnvPrefix("MYAPP_"), cfgm.WithEnvFileSuffix("_FILE"))
+//
+// 适用于全部环境变量绑定（[WithEnvPrefix]、[WithEnvBindKey]、[WithEnvBinding] 等）与 [WithFlatEnvKeys]。
+// 文件内容去掉末尾的换行符后按普通环境变量的值处理（如 [WithEnvValueTransform] 与按字段类型解析）；
+// 文件无法读取时加载失败。相对路径基于工作目录解析。
+// [WithEnvBindingsTrimPrefix] 按变量名扫描时，以 suffix 结尾的变量视为去掉后缀的变量的文件引用；
+// [WithEnvPrefixStrict] 同样接受这些变量。
+func WithEnvFileSuffix(suffix string) Option {
+	return func(o *options) {
+		o.envFileSuffix = suffix
+	}
+}
+
+// WithS3Config 通过 client 读取 S3 对象 s3://bucket/key，作为一层配置文件合并。
+//
+// 对象内容与配置文件一样经过模板展开、[WithMaxFileSize]、[WithConfigChecksum]、gzip 解压与解析，
+// 格式按 key 的扩展名判断。对象位于配置文件这一优先级：在 [WithConfigPaths] 等搜索到的文件之上、
+// [WithConfigPathsDir] 等目录片段与环境变量之下；可多次调用，与 [WithGCSConfig] 一起按声明顺序合并，后者优先。
+//
+// client 通过 [S3Client] 接口传入，区域、凭证与超时由调用方创建客户端时配置，因此 cfgm 不引入 AWS SDK 依赖。
+// 对象不存在（client 返回包装了 [fs.ErrNotExist] 的错误）时跳过，[WithConfigPathsStopOnError] 的 onMissing 为
+// [ErrorModeFail] 时加载失败；无权访问（[fs.ErrPermission]）等其他错误总是使加载失败。
+// [Loader.Watch] 不监听对象的变化，可按需调用 [Loader.Reload]。
+func WithS3Config(client S3Client, bucket, key string) Option {
+	return func(o *options) {
+		o.objectSources = append(o.objectSources, S3Source(client, bucket, key))
+	}
+}
+
+// WithGCSConfig 通过 client 读取 GCS 对象 gs://bucket/object，作为一层配置文件合并。
+//
+// 处理方式与优先级同 [WithS3Config]，client 通过 [GCSClient] 接口传入，cfgm 不引入 Google Cloud SDK 依赖。
+func WithGCSConfig(client GCSClient, bucket, object string) Option {
+	return func(o *options) {
+		o.objectSources = append(o.objectSources, GCSSource(client, bucket, object))
+	}
+}
+
+// WithMapConfig 将嵌套的配置树 m 作为一层配置合并，适用于库以编程方式提供默认配置，
+// 比逐个 key 的 [WithForcedValues] 更适合整棵子树：
+//
+//	cfgm.Load(config, cfgm.WithMapConfig(map[string]any{
+//	    "cache": map[string]any{"size": 128, "ttl": "5m"},
+//	}))
+//
+// 优先级位于结构体默认值（含 [WithDefaultConfigFunc]）之上、全部配置文件之下，
+// 配置文件（包括 [WithEmbeddedDefault]）、环境变量与 CLI flags 都会覆盖其中的值。
+// 可多次调用，按声明顺序合并，后者优先；与配置文件一样逐 key 深度合并（见 [WithMergeFunc]）。
+//
+// m 的 key 按层级嵌套，不按 [WithKeyDelim] 拆分；值按与配置文件相同的规则解码，不做模板展开与 [WithMigrations] 迁移。
+// 结构体中不存在的 key 不会使加载失败，但会出现在 [LoadWithWarnings] 的警告中。
+// m 在调用时被复制，之后修改传入的 map 不影响加载结果。
+func WithMapConfig(m map[string]any) Option {
+	values, _ := cloneConfigValue(m).(map[string]any)
+
+	return func(o *options) {
+		if values != nil {
+			o.mapConfigs = append(o.mapConfigs, values)
+		}
+	}
+}
+
+// WithConfigPathsReloadValidation 在 [Loader] 每次重新加载时，用 fn 校验新配置，返回错误则拒绝本次重新加载。
+//
+// [Loader.Reload] 与 [Loader.Watch] 的重新加载先完成全部解析与校验（[WithSchema]、[WithSchemaFile]、
+// [WithRequiredTags] 与 [WithValueProcessor] 等），最后调用 fn，全部通过后才替换当前配置；
+// 任一步骤失败时保留上一份有效配置，Reload 返回错误，Watch 将错误传给 onChange 与 [WithOnConfigReload]，
+// 且不调用 [Loader.OnReload] 的回调。因此运行中的服务不会被写坏的配置文件替换为不可用的配置。
+//
+// fn 的参数为当前配置与候选配置，适合表达只对运行期变更有意义的约束，例如禁止修改监听地址：
+//
+//	loader, err := cfgm.NewLoader(DefaultConfig(),
+//	    cfgm.WithAppName("myapp"),
+//	    cfgm.WithConfigPathsReloadValidation(func(prev, next *Config) error {
+//	        if next.Server.Addr != prev.Server.Addr {
+//	            return errors.New("server.addr cannot change without restart")
+//	        }
+//	        return nil
+//	    }),
+//	)
+//
+// [NewLoader] 的首次加载不调用 fn，[Load] 忽略此选项。T 必须与 Loader 的配置类型一致，否则 [NewLoader] 返回错误。
+func WithConfigPathsReloadValidation[T any](fn func(prev, next *T) error) Option {
+	return func(o *options) {
+		o.reloadValidator = fn
+	}
+}
+
+// WithSourceTimeout 限制单次读取 [WithConfigSources]、[WithS3Config]、[WithGCSConfig] 与 [WithEtcdConfig] 来源的时长。
+//
+// 每次读取传给 [ConfigSource].Read 等方法的 context 在 d 后取消，因此无响应的远程服务使
+// [Load]、[NewLoader] 与 [Loader.Reload] 返回错误，而不会使加载（以及 [Loader.Watch]）一直阻塞。
+// 自定义来源应遵守传入的 context。
+//
+// d 为 0 时使用默认的 30s，d < 0 时不限制。
+func WithSourceTimeout(d time.Duration) Option {
+	return func(o *options) {
+		o.sourceTimeout = d
+	}
+}
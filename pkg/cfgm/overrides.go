@@ -0,0 +1,115 @@
+package cfgm
+
+import (
+	"maps"
+	"reflect"
+	"slices"
+)
+
+// FieldDiff 描述最终配置中与默认配置不同的一个叶子 key，见 [LoadWithOverrides]。
+type FieldDiff struct {
+	Path    string // 以分隔符连接的 key 路径，如 "server.port"
+	Default any    // 默认配置中的值，默认配置中不存在时为 nil
+	Value   any    // 最终配置中的值，最终配置中不存在时为 nil
+}
+
+// LoadWithOverrides 与 [Load] 相同，额外返回最终配置中与 defaultConfig 不同的全部叶子 key。
+//
+// 结果按路径排序，只包含被配置文件、环境变量、CLI 等来源改变的 key，便于运维确认相对发布默认值的改动。
+// key 路径由 json tag（或 [WithUnmarshalTagChain] 指定的 tag）生成，结构体与 map 逐层展开，切片作为整体比较。
+//
+// 示例：
+//
+//	cfg, diffs, err := cfgm.LoadWithOverrides(DefaultConfig(), cfgm.WithAppName("myapp"))
+//	for _, d := range diffs {
+//	    fmt.Printf("%s: %v -> %v\n", d.Path, d.Default, d.Value)
+//	}
+func LoadWithOverrides[T any](defaultConfig T, opts ...Option) (*T, []FieldDiff, error) {
+	cfg, _, err := load(defaultConfig, 1, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	options := newOptions(opts...)
+
+	return cfg, diffConfigs(defaultConfig, *cfg, options.keyDelim(), options.tagChain()), nil
+}
+
+// diffConfigs 比较两个配置结构体展开后的叶子值。
+func diffConfigs[T any](defaults, cfg T, delim string, tags []string) []FieldDiff {
+	before := make(map[string]any)
+	flattenLeafValues(structToMap(defaults, tags), "", delim, before)
+	after := make(map[string]any)
+	flattenLeafValues(structToMap(cfg, tags), "", delim, after)
+
+	keys := slices.Collect(maps.Keys(before))
+	for key := range after {
+		if _, ok := before[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var diffs []FieldDiff
+	for _, key := range keys {
+		if !reflect.DeepEqual(before[key], after[key]) {
+			diffs = append(diffs, FieldDiff{Path: key, Default: before[key], Value: after[key]})
+		}
+	}
+
+	return diffs
+}
+
+// flattenLeafValues 将配置树展开为 key 路径 → 叶子值，空 map 与切片视为叶子。
+func flattenLeafValues(data map[string]any, prefix, delim string, out map[string]any) {
+	for key, value := range data {
+		path := key
+		if prefix != "" {
+			path = prefix + delim + key
+		}
+		if child, ok := value.(map[string]any); ok && len(child) > 0 {
+			flattenLeafValues(child, path, delim, out)
+
+			continue
+		}
+		out[path] = value
+	}
+}
+
+// SourceValue 是某个来源为 key 写入的一个值，见 [LoadWithMergeTrace]。
+type SourceValue struct {
+	Source string // 来源，取值同 [WithOnValueSet] 的 source，如 "default"、文件路径、"env NAME"、"cli"
+	Value  any    // 该来源写入的值（环境变量为解码前的字符串）
+}
+
+// LoadWithMergeTrace 与 [Load] 相同，额外返回每个 key 的完整覆盖历史：按写入顺序排列的 (来源, 值)，最后一项为生效的值。
+//
+// 用于排查多层配置（默认值 → 基础文件 → profile → 环境变量 → CLI）中某个值的来龙去脉。
+// 记录基于 [WithOnValueSet]，已设置的回调仍会被调用；被覆盖的中间值全部保留，
+// 内存开销与写入次数成正比，因此仅在调用本函数时记录。
+//
+// 示例：
+//
+//	cfg, trace, err := cfgm.LoadWithMergeTrace(DefaultConfig(), cfgm.WithAppName("myapp"))
+//	for _, sv := range trace["server.port"] {
+//	    fmt.Printf("%s = %v\n", sv.Source, sv.Value)
+//	}
+func LoadWithMergeTrace[T any](defaultConfig T, opts ...Option) (*T, map[string][]SourceValue, error) {
+	trace := make(map[string][]SourceValue)
+	opts = append(slices.Clip(opts), func(o *options) {
+		next := o.onValueSet
+		o.onValueSet = func(source, path string, value any) {
+			trace[path] = append(trace[path], SourceValue{Source: source, Value: value})
+			if next != nil {
+				next(source, path, value)
+			}
+		}
+	})
+
+	cfg, _, err := load(defaultConfig, 1, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, trace, nil
+}
@@ -0,0 +1,61 @@
+package cfgm
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// maxParsedLayers 是 parsedLayers 最多保留的解析结果数，超出时整体清空。
+//
+// 内嵌文件与 [BytesSource] 的内容通常固定，条目数接近来源数；模板展开结果随环境变化时
+// 才会持续产生新的条目，上限避免缓存无限增长。
+const maxParsedLayers = 64
+
+// parsedLayers 缓存内嵌文件与 [BytesSource] 的解析结果，供反复调用 Load 时复用。
+var parsedLayers = &parseCache{entries: make(map[parseCacheKey]map[string]any)}
+
+// parseCacheKey 标识一次解析：模板展开后内容的摘要与影响解析结果的选项。
+type parseCacheKey struct {
+	sum       [sha256.Size]byte
+	format    string
+	strictDup bool
+}
+
+// parseCache 是以内容摘要为 key 的解析结果缓存，并发安全。
+//
+// 缓存的 map 不会被修改：写入与读取时均返回深拷贝，调用方可以自由修改得到的配置层。
+type parseCache struct {
+	mu      sync.Mutex
+	entries map[parseCacheKey]map[string]any
+}
+
+// parse 返回 content 的解析结果，命中缓存时跳过解析。
+//
+// [WithYAMLInclude] 的结果还取决于被引用的文件，不做缓存；解析失败的结果同样不缓存。
+func (c *parseCache) parse(path, dir string, content []byte, options *options) (map[string]any, error) {
+	format := options.fileFormat(path)
+	if options.yamlInclude && format != formatJSON {
+		return parseConfigContent(path, dir, content, options)
+	}
+
+	key := parseCacheKey{sum: sha256.Sum256(content), format: format, strictDup: options.strictDuplicateKeys}
+	c.mu.Lock()
+	cached, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return cloneConfigValue(cached).(map[string]any), nil
+	}
+
+	data, err := parseConfigContent(path, dir, content, options)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	if len(c.entries) >= maxParsedLayers {
+		clear(c.entries)
+	}
+	c.entries[key] = cloneConfigValue(data).(map[string]any)
+	c.mu.Unlock()
+
+	return data, nil
+}
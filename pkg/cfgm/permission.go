@@ -0,0 +1,24 @@
+package cfgm
+
+import (
+	"fmt"
+	"io/fs"
+	"runtime"
+)
+
+// insecurePermBits 是 [WithSecureFilePermissions] 拒绝的权限位：组用户与其他用户可读。
+const insecurePermBits fs.FileMode = 0o044
+
+// checkFilePermissions 检查配置文件的权限位，见 [WithSecureFilePermissions]。
+//
+// Windows 的文件权限由 ACL 决定，Go 返回的权限位不反映实际的访问控制，因此不做检查。
+func checkFilePermissions(path string, info fs.FileInfo) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	if mode := info.Mode().Perm(); mode&insecurePermBits != 0 {
+		return fmt.Errorf("config file %s: permissions %#o are too open: must not be readable by group or others (e.g. chmod 600)", path, mode)
+	}
+
+	return nil
+}
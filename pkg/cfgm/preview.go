@@ -0,0 +1,254 @@
+package cfgm
+
+import (
+	"errors"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// LayerInfo 描述合并链中的一个配置文件，见 [MergePreview]。
+type LayerInfo struct {
+	Path       string   // 配置文件路径（已按 baseDir 解析）
+	Keys       []string // 该文件设置的全部叶子 key（已排序）
+	Overridden []string // 其中覆盖了更低优先级文件的 key（已排序）
+}
+
+// PathProbe 描述配置文件发现过程中检查的一个候选路径，见 [WithConfigPathsTrace]。
+type PathProbe struct {
+	Path     string // 候选路径（已按 baseDir 解析；glob 为匹配到的文件，未匹配时为模式本身）
+	Exists   bool   // 路径是否存在
+	Selected bool   // 是否被读取并参与合并
+	Reason   string // 选中或未选中的原因
+}
+
+// probeTrace 收集 [WithConfigPathsTrace] 的检查结果；nil 表示未启用，各方法均为空操作。
+type probeTrace struct {
+	probes []PathProbe
+}
+
+func (t *probeTrace) add(probe PathProbe) {
+	if t == nil {
+		return
+	}
+	t.probes = append(t.probes, probe)
+	slog.Debug("Config path probed", "path", probe.Path, "exists", probe.Exists, "selected", probe.Selected, "reason", probe.Reason)
+}
+
+// file 记录一个已尝试读取的文件，ok 表示已读取并参与合并。
+func (t *probeTrace) file(path string, options *options, ok bool) {
+	if t == nil {
+		return
+	}
+	if ok {
+		reason := "selected: first existing path"
+		if options.mergeAllPaths {
+			reason = "selected: merged (WithMergeAllPaths)"
+		}
+		t.add(PathProbe{Path: path, Exists: true, Selected: true, Reason: reason})
+
+		return
+	}
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+		t.add(PathProbe{Path: path, Reason: "not found"})
+	case err != nil:
+		t.add(PathProbe{Path: path, Exists: true, Reason: err.Error()})
+	case info.IsDir():
+		t.add(PathProbe{Path: path, Exists: true, Reason: "is a directory"})
+	case options.isStale(info):
+		t.add(PathProbe{Path: path, Exists: true, Reason: "skipped: older than WithConfigPathsNewerThan"})
+	default:
+		if _, ok := existingConfigFile(path, options); !ok {
+			t.add(PathProbe{Path: path, Exists: true, Reason: "cannot open file"})
+
+			return
+		}
+		t.add(PathProbe{Path: path, Exists: true, Reason: "skipped: invalid config file"})
+	}
+}
+
+// shadowed 记录命中 selected 后不再读取的候选路径。
+func (t *probeTrace) shadowed(paths []string, selected string) {
+	if t == nil {
+		return
+	}
+	for _, pattern := range paths {
+		files := expandConfigPath(pattern)
+		if len(files) == 0 {
+			files = []string{pattern}
+		}
+		for _, path := range files {
+			if _, err := os.Stat(path); err == nil {
+				t.add(PathProbe{Path: path, Exists: true, Reason: "not read: shadowed by " + selected + " (see WithMergeAllPaths)"})
+			} else {
+				t.add(PathProbe{Path: path, Reason: "not read: " + selected + " was selected first"})
+			}
+		}
+	}
+}
+
+// MergePreview 返回配置文件合并链，用于排查各 key 由哪个文件设置。
+//
+// 执行与 [Load] 相同的路径发现、模板展开与解析，但不合并环境变量/CLI，也不解码到结构体。
+// 返回顺序即合并顺序（优先级从低到高），通常与 [WithMergeAllPaths] 搭配使用；
+// 未启用时最多返回首个命中的文件。
+//
+// 示例：
+//
+//	layers, err := cfgm.MergePreview(
+//	    cfgm.WithAppName("myapp"),
+//	    cfgm.WithMergeAllPaths(),
+//	)
+//	for _, layer := range layers {
+//	    fmt.Println(layer.Path, layer.Keys, layer.Overridden)
+//	}
+func MergePreview(opts ...Option) ([]LayerInfo, error) {
+	options := newOptions(opts...)
+	options.resolve(0)
+
+	layers, err := loadConfigFiles(options, &loadReport{})
+	if err != nil {
+		return nil, err
+	}
+
+	seen := make(map[string]bool)
+	infos := make([]LayerInfo, 0, len(layers))
+	for _, layer := range layers {
+		info := LayerInfo{Path: layer.path, Keys: flattenMapKeys(layer.data, options.keyDelim())}
+		slices.Sort(info.Keys)
+		for _, key := range info.Keys {
+			if seen[key] {
+				info.Overridden = append(info.Overridden, key)
+			}
+			seen[key] = true
+		}
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// ResolveConfigPath 返回 [Load] 会读取的首个配置文件路径，不存在任何候选文件时 ok 为 false。
+//
+// 只执行路径发现：与 [Load] 一样处理 [WithAppName]、[WithConfigPaths]、[WithBaseDir]、glob、
+// [WithConfigPathsCaseInsensitive]、[WithConfigPathsResolveSymlinks] 等选项，
+// 以及 [WithFailFastPaths] 等路径检查，但不读取或解析文件内容。
+// 适用于 "myapp config path" 之类的子命令：
+//
+//	path, ok, err := cfgm.ResolveConfigPath(cfgm.WithAppName("myapp"))
+func ResolveConfigPath(opts ...Option) (string, bool, error) {
+	options := newOptions(opts...)
+	options.resolve(0)
+
+	paths := options.resolvedPaths()
+	if err := checkConfigPathsLimit(options, paths); err != nil {
+		return "", false, err
+	}
+	if err := checkConfigPaths(options, paths); err != nil {
+		return "", false, err
+	}
+
+	for _, pattern := range paths {
+		for _, path := range options.preferFormats(expandConfigPath(pattern)) {
+			if path, ok := existingConfigFile(path, options); ok {
+				return path, true, nil
+			}
+		}
+	}
+
+	return "", false, nil
+}
+
+// CandidateResult 描述配置文件发现中的一个候选文件，见 [ProbeConfigPaths]。
+type CandidateResult struct {
+	Pattern  string // 候选路径（已按 baseDir 解析；glob 为模式本身）
+	Path     string // 绝对路径，glob 为匹配到的文件；未匹配任何文件时为空
+	Exists   bool   // 路径是否存在
+	Readable bool   // 是否为可打开的普通文件
+	Format   string // 读取时使用的格式（"yaml" 或 "json"）
+	Selected bool   // 是否会被 Load 读取并参与合并
+}
+
+// ProbeConfigPaths 返回配置文件发现的完整结果：每个候选文件的绝对路径、是否存在、可读与格式，以及哪些会被读取。
+//
+// 与 [ResolveConfigPath] 执行相同的路径发现与检查，但返回全部候选，适合 "myapp doctor" 之类的诊断命令。
+// 不读取或解析文件内容，因此内容无效的文件仍可能标记为 Selected；
+// 未启用 [WithMergeAllPaths] 时只有首个命中的文件为 Selected。
+//
+// 示例：
+//
+//	candidates, err := cfgm.ProbeConfigPaths(cfgm.WithAppName("myapp"))
+//	for _, c := range candidates {
+//	    fmt.Printf("%-40s exists=%t readable=%t selected=%t\n", c.Path, c.Exists, c.Readable, c.Selected)
+//	}
+func ProbeConfigPaths(opts ...Option) ([]CandidateResult, error) {
+	options := newOptions(opts...)
+	options.resolve(0)
+
+	paths := options.resolvedPaths()
+	if err := checkConfigPathsLimit(options, paths); err != nil {
+		return nil, err
+	}
+	if err := checkConfigPaths(options, paths); err != nil {
+		return nil, err
+	}
+
+	var results []CandidateResult
+	selected := false
+	for _, pattern := range paths {
+		files := options.preferFormats(expandConfigPath(pattern))
+		if len(files) == 0 {
+			results = append(results, CandidateResult{Pattern: pattern})
+
+			continue
+		}
+		matched := false
+		for _, path := range files {
+			result := CandidateResult{Pattern: pattern, Path: path, Format: options.fileFormat(path)}
+			if abs, err := filepath.Abs(path); err == nil {
+				result.Path = abs
+			}
+			if result.Format == "" {
+				result.Format = formatYAML
+			}
+			info, err := os.Stat(path)
+			result.Exists = err == nil
+			_, result.Readable = existingConfigFile(path, options)
+			if result.Readable && !options.isStale(info) && (!selected || options.mergeAllPaths) {
+				result.Selected = true
+				matched = true
+			}
+			results = append(results, result)
+		}
+		selected = selected || matched
+	}
+
+	return results, nil
+}
+
+// existingConfigFile 按 readConfigLayer 的规则判断 path 是否为可读取的配置文件，返回实际读取的路径。
+func existingConfigFile(path string, options *options) (string, bool) {
+	if options.resolveSymlinks {
+		realPath, err := filepath.EvalSymlinks(path)
+		if err != nil {
+			return "", false
+		}
+		path = realPath
+	}
+
+	file, err := os.Open(path) //nolint:gosec // path is from trusted config
+	if err != nil {
+		return "", false
+	}
+	defer func() { _ = file.Close() }()
+	if info, err := file.Stat(); err != nil || info.IsDir() {
+		return "", false
+	}
+
+	return path, true
+}
@@ -0,0 +1,140 @@
+package cfgm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// valueProcessor 是 [WithValueProcessor] 注册的按类型处理函数。
+type valueProcessor struct {
+	typ reflect.Type
+	fn  func(v any) (any, error)
+}
+
+// interceptFunc 是 [WithFieldInterceptor] 注册的字段拦截函数。
+type interceptFunc func(path string, raw any, typ reflect.Type) (any, error)
+
+// applyFieldInterceptors 按 walkConfigFields 的顺序对每个叶子字段依次调用已注册的拦截函数，
+// 并将结果写回合并后的配置树；出错的字段汇总为一个错误。
+func applyFieldInterceptors(configMap map[string]any, typ reflect.Type, options *options) error {
+	if len(options.interceptors) == 0 {
+		return nil
+	}
+
+	var errs []error
+	walkConfigFields(typ, "", options.keyDelim(), options.tagChain(), func(key string, field reflect.StructField) {
+		parts := options.splitKey(key)
+		raw, ok := getByPath(configMap, parts)
+		value := raw
+		for _, fn := range options.interceptors {
+			out, err := fn(key, value, field.Type)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("config %s: %w", key, err))
+
+				return
+			}
+			value = out
+		}
+		// 未出现的 key 仅在拦截函数返回非 nil 值时写入，避免为全部字段生成 null
+		if ok || value != nil {
+			setByPath(configMap, parts, value)
+		}
+	})
+
+	return errors.Join(errs...)
+}
+
+// applyValueProcessors 对解码后的配置按字段类型调用已注册的处理函数，
+// 出错的字段汇总为一个错误，key 以 delim 拼接。
+func applyValueProcessors(cfg any, processors []valueProcessor, delim string, tags []string) error {
+	if len(processors) == 0 {
+		return nil
+	}
+
+	var errs []error
+	processValue(reflect.ValueOf(cfg), "", delim, tags, processors, &errs)
+
+	return errors.Join(errs...)
+}
+
+// processValue 按 walkConfigFields 的遍历规则递归处理结构体字段，
+// 并进入切片、数组元素与 map 值；nil 指针与 nil 接口不处理。
+func processValue(val reflect.Value, path, delim string, tags []string, processors []valueProcessor, errs *[]error) {
+	if path != "" && val.CanSet() {
+		for _, p := range processors {
+			if val.Type() != p.typ {
+				continue
+			}
+			if val.Kind() == reflect.Pointer && val.IsNil() {
+				break
+			}
+			if err := runValueProcessor(val, p); err != nil {
+				*errs = append(*errs, fmt.Errorf("config %s: %w", path, err))
+
+				return
+			}
+		}
+	}
+
+	switch val.Kind() {
+	case reflect.Pointer:
+		if !val.IsNil() {
+			processValue(val.Elem(), path, delim, tags, processors, errs)
+		}
+	case reflect.Struct:
+		typ := val.Type()
+		for i := range typ.NumField() {
+			field := typ.Field(i)
+			key := configTagName(field, tags)
+			if key == "" {
+				continue
+			}
+			if path != "" {
+				key = path + delim + key
+			}
+			processValue(val.Field(i), key, delim, tags, processors, errs)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := range val.Len() {
+			processValue(val.Index(i), path+"["+strconv.Itoa(i)+"]", delim, tags, processors, errs)
+		}
+	case reflect.Map:
+		if val.IsNil() || !val.CanSet() {
+			return
+		}
+		for _, k := range val.MapKeys() {
+			// map 值不可寻址，复制后处理再写回
+			elem := reflect.New(val.Type().Elem()).Elem()
+			elem.Set(val.MapIndex(k))
+			processValue(elem, path+delim+fmt.Sprint(k.Interface()), delim, tags, processors, errs)
+			val.SetMapIndex(k, elem)
+		}
+	}
+}
+
+// runValueProcessor 调用处理函数并写回结果，结果须可赋值给字段类型。
+func runValueProcessor(val reflect.Value, p valueProcessor) error {
+	out, err := p.fn(val.Interface())
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		switch val.Kind() {
+		case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+			val.SetZero()
+
+			return nil
+		}
+
+		return fmt.Errorf("value processor returned nil for %s", val.Type())
+	}
+	result := reflect.ValueOf(out)
+	if !result.Type().AssignableTo(val.Type()) {
+		return fmt.Errorf("value processor returned %s, want %s", result.Type(), val.Type())
+	}
+	val.Set(result)
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package cfgm
+
+import "time"
+
+// EnvSource 标识环境变量绑定的来源。
+type EnvSource string
+
+const (
+	// EnvSourcePrefix 由 [WithEnvPrefix] / [WithEnvPrefixes] 根据结构体 key 自动生成的绑定。
+	EnvSourcePrefix EnvSource = "prefix"
+	// EnvSourceBinding 由 [WithEnvBinding] 显式声明的绑定。
+	EnvSourceBinding EnvSource = "binding"
+	// EnvSourceTag 由 [WithEnvBindKey] 指定的字段 tag 声明的绑定。
+	EnvSourceTag EnvSource = "tag"
+	// EnvSourceFlag 由 [WithEnvBindingsFromFlags] 从 CLI flag 声明的环境变量推导的绑定。
+	EnvSourceFlag EnvSource = "flag"
+	// EnvSourceTop 由 [WithEnvBindingTop] 声明、优先级高于 CLI flags 的绑定。
+	EnvSourceTop EnvSource = "top"
+	// EnvSourceTrim 由 [WithEnvBindingsTrimPrefix] 按环境变量名映射的绑定。
+	EnvSourceTrim EnvSource = "trim"
+)
+
+// EnvBindingResult 描述一次加载中的单个环境变量绑定，见 [LoadWithEnvReport]。
+type EnvBindingResult struct {
+	EnvKey     string    // 环境变量名，如 MYAPP_SERVER_URL
+	ConfigPath string    // 目标配置 key，如 server.url
+	Applied    bool      // 环境变量已设置且写入了配置
+	Source     EnvSource // 绑定来源
+}
+
+// LoadMetrics 是一次加载的耗时分解与来源统计，见 [WithMetrics]。
+//
+// 各阶段耗时使用单调时钟测量；多个文件的读取、模板展开与解析耗时累加。
+type LoadMetrics struct {
+	Total     time.Duration // 整个加载的耗时
+	Discovery time.Duration // 路径发现与文件检查（配置文件加载中除读取、模板与解析外的部分）
+	Read      time.Duration // 读取文件内容（含校验与解压）
+	Template  time.Duration // 模板展开
+	Parse     time.Duration // YAML/JSON 解析
+	Env       time.Duration // 应用环境变量绑定
+	CLI       time.Duration // 应用 CLI flags
+	Unmarshal time.Duration // 解码到结构体
+
+	Files      int // 参与合并的配置文件数（含内嵌配置、目录片段）
+	EnvApplied int // 写入配置的环境变量绑定数
+}
+
+// loadReport 收集一次加载过程中的诊断信息。
+type loadReport struct {
+	envBindings []EnvBindingResult
+	warnings    []Warning
+	metrics     LoadMetrics
+	fileKeys    map[string]string // WithEnvBindingsInverse: 配置文件设置的叶子 key → 最后设置它的文件
+}
+
+// recordFileKeys 记录配置文件层设置的全部叶子 key，见 [WithEnvBindingsInverse]。
+func (r *loadReport) recordFileKeys(layer configLayer, delim string) {
+	if r.fileKeys == nil {
+		r.fileKeys = make(map[string]string)
+	}
+	for _, key := range flattenMapKeys(layer.data, delim) {
+		r.fileKeys[key] = layer.path
+	}
+}
+
+// checkFileOverride 在已应用的环境变量覆盖了配置文件设置的 key 时记录 [WarnEnvOverridesFile]。
+func (r *loadReport) checkFileOverride(envKey, configPath string) {
+	if file, ok := r.fileKeys[configPath]; ok {
+		r.addWarning(WarnEnvOverridesFile, configPath, "env %s overrides %s set in %s", envKey, configPath, file)
+	}
+}
+
+// observe 将 start 以来的耗时累加到 d。
+func (r *loadReport) observe(d *time.Duration, start time.Time) {
+	*d += time.Since(start)
+}
+
+// finishMetrics 补全总耗时与环境变量统计后调用 [WithMetrics] 注册的回调。
+func (r *loadReport) finishMetrics(options *options, start time.Time) {
+	if options.onMetrics == nil {
+		return
+	}
+	r.metrics.Total = time.Since(start)
+	r.metrics.EnvApplied = 0
+	for _, binding := range r.envBindings {
+		if binding.Applied {
+			r.metrics.EnvApplied++
+		}
+	}
+	options.onMetrics(r.metrics)
+}
+
+// LoadWithEnvReport 与 [Load] 相同，额外返回本次加载涉及的全部环境变量绑定。
+//
+// 结果按应用顺序排列（同一来源内按环境变量名排序），
+// 未设置的环境变量也会出现在结果中（Applied 为 false），便于审计与排查覆盖关系。
+//
+// 示例：
+//
+//	cfg, bindings, err := cfgm.LoadWithEnvReport(DefaultConfig(),
+//	    cfgm.WithEnvPrefix("MYAPP_"),
+//	)
+//	for _, b := range bindings {
+//	    if b.Applied {
+//	        slog.Info("env override", "env", b.EnvKey, "path", b.ConfigPath)
+//	    }
+//	}
+func LoadWithEnvReport[T any](defaultConfig T, opts ...Option) (*T, []EnvBindingResult, error) {
+	cfg, report, err := load(defaultConfig, 1, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, report.envBindings, nil
+}
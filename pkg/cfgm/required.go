@@ -0,0 +1,59 @@
+package cfgm
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// requiredTag 是 [WithRequiredTags] 读取的 struct tag 名称。
+const requiredTag = "required"
+
+// checkRequiredFields 检查带 required:"true" tag 的字段在解码后均不为零值，
+// 缺失的字段汇总为一个错误，key 以 delim 拼接。
+func checkRequiredFields(cfg any, delim string, tags []string) error {
+	var missing []string
+	collectMissingRequired(reflect.ValueOf(cfg), "", delim, tags, &missing)
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("missing required config: %s", strings.Join(missing, ", "))
+}
+
+// collectMissingRequired 按 walkConfigFields 的遍历规则递归检查结构体字段。
+//
+// nil 指针结构体按零值继续检查，其下的必填字段同样视为缺失。
+func collectMissingRequired(val reflect.Value, prefix, delim string, tags []string, missing *[]string) {
+	if val.Kind() == reflect.Pointer {
+		if val.IsNil() {
+			val = reflect.Zero(val.Type().Elem())
+		} else {
+			val = val.Elem()
+		}
+	}
+	if val.Kind() != reflect.Struct {
+		return
+	}
+
+	typ := val.Type()
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		key := configTagName(field, tags)
+		if key == "" {
+			continue
+		}
+		if prefix != "" {
+			key = prefix + delim + key
+		}
+
+		fieldVal := val.Field(i)
+		if required, _ := strconv.ParseBool(field.Tag.Get(requiredTag)); required && fieldVal.IsZero() {
+			*missing = append(*missing, key)
+		}
+		if isStructType(field.Type) {
+			collectMissingRequired(fieldVal, key, delim, tags, missing)
+		}
+	}
+}
@@ -0,0 +1,600 @@
+package cfgm
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// SchemaError 描述合并后的配置树中一处不满足 JSON Schema 的值，见 [WithSchema]。
+type SchemaError struct {
+	Path    string // 配置 key 路径，根节点为空字符串
+	Message string
+}
+
+// Error 实现 error 接口。
+func (e *SchemaError) Error() string {
+	return schemaPathName(e.Path) + ": " + e.Message
+}
+
+// jsonSchema 是 [WithSchema] 支持的 JSON Schema 关键字子集。
+type jsonSchema struct {
+	Type                 schemaTypes            `json:"type"`
+	Enum                 []any                  `json:"enum"`
+	Const                *any                   `json:"const"`
+	Properties           map[string]*jsonSchema `json:"properties"`
+	Required             []string               `json:"required"`
+	AdditionalProperties *schemaOrBool          `json:"additionalProperties"`
+	Items                *jsonSchema            `json:"items"`
+	MinItems             *int                   `json:"minItems"`
+	MaxItems             *int                   `json:"maxItems"`
+	Minimum              *float64               `json:"minimum"`
+	Maximum              *float64               `json:"maximum"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum"`
+	MinLength            *int                   `json:"minLength"`
+	MaxLength            *int                   `json:"maxLength"`
+	Pattern              string                 `json:"pattern"`
+	AllOf                []*jsonSchema          `json:"allOf"`
+	AnyOf                []*jsonSchema          `json:"anyOf"`
+
+	pattern *regexp.Regexp
+}
+
+// schemaTypes 兼容 "type" 的字符串与数组两种写法。
+type schemaTypes []string
+
+func (t *schemaTypes) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*t = schemaTypes{single}
+
+		return nil
+	}
+
+	var multi []string
+	if err := json.Unmarshal(data, &multi); err != nil {
+		return fmt.Errorf("type must be string or array of strings: %w", err)
+	}
+	*t = multi
+
+	return nil
+}
+
+// schemaOrBool 兼容 "additionalProperties" 的布尔与 schema 两种写法。
+type schemaOrBool struct {
+	allowed bool
+	schema  *jsonSchema
+}
+
+func (s *schemaOrBool) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &s.allowed); err == nil {
+		return nil
+	}
+	s.allowed = true
+
+	return json.Unmarshal(data, &s.schema)
+}
+
+// parseSchema 解析 schema 文档并预编译其中的 pattern。
+func parseSchema(data []byte) (*jsonSchema, error) {
+	var schema jsonSchema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+	if err := schema.compile(""); err != nil {
+		return nil, fmt.Errorf("invalid schema: %w", err)
+	}
+
+	return &schema, nil
+}
+
+// schemaFile 是 [WithSchemaFile] 指定的 schema 文件，解析结果按修改时间与大小缓存。
+type schemaFile struct {
+	path string
+
+	mu       sync.Mutex
+	resolved string // 上次读取的路径（相对路径基于 baseDir 解析后）
+	modTime  time.Time
+	size     int64
+	schema   *jsonSchema
+}
+
+// load 返回 schema 文件解析后的结果，文件未变化时复用上次的解析结果。
+func (f *schemaFile) load(baseDir string) (*jsonSchema, error) {
+	path := f.path
+	if baseDir != "" && !filepath.IsAbs(path) {
+		path = filepath.Join(baseDir, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("schema file %s: %w", path, err)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.schema != nil && f.resolved == path && f.modTime.Equal(info.ModTime()) && f.size == info.Size() {
+		return f.schema, nil
+	}
+	data, err := os.ReadFile(path) //nolint:gosec // path is from trusted config
+	if err != nil {
+		return nil, fmt.Errorf("schema file %s: %w", path, err)
+	}
+	schema, err := parseSchema(data)
+	if err != nil {
+		return nil, fmt.Errorf("schema file %s: %w", path, err)
+	}
+	f.resolved, f.modTime, f.size, f.schema = path, info.ModTime(), info.Size(), schema
+
+	return schema, nil
+}
+
+func (s *jsonSchema) compile(path string) error {
+	if s.Pattern != "" {
+		re, err := regexp.Compile(s.Pattern)
+		if err != nil {
+			return fmt.Errorf("%s: pattern: %w", schemaPathName(path), err)
+		}
+		s.pattern = re
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(s.Properties)) {
+		if prop := s.Properties[key]; prop != nil {
+			if err := prop.compile(joinSchemaPath(path, key, ".")); err != nil {
+				return err
+			}
+		}
+	}
+	children := slices.Concat(s.AllOf, s.AnyOf, []*jsonSchema{s.Items})
+	if s.AdditionalProperties != nil {
+		children = append(children, s.AdditionalProperties.schema)
+	}
+	for _, child := range children {
+		if child == nil {
+			continue
+		}
+		if err := child.compile(path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func schemaPathName(path string) string {
+	if path == "" {
+		return "(root)"
+	}
+
+	return path
+}
+
+// validateSchema 校验配置树，返回全部 [SchemaError]（以 errors.Join 合并），无错误时返回 nil。
+func validateSchema(schema *jsonSchema, data map[string]any, delim string) error {
+	var errs []error
+	schema.validate(schemaValue(data), "", delim, &errs)
+
+	return errors.Join(errs...)
+}
+
+func (s *jsonSchema) validate(val any, path, delim string, errs *[]error) {
+	fail := func(format string, args ...any) {
+		*errs = append(*errs, &SchemaError{Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if len(s.Type) > 0 {
+		coerced, ok := coerceSchemaType(val, s.Type)
+		if !ok {
+			fail("expected %s, got %s", strings.Join(s.Type, " or "), schemaTypeName(val))
+
+			return
+		}
+		val = coerced
+	}
+	if len(s.Enum) > 0 && !slices.ContainsFunc(s.Enum, func(v any) bool { return schemaEqual(v, val) }) {
+		fail("value %v is not one of %v", formatSchemaValue(val), formatSchemaValue(s.Enum))
+	}
+	if s.Const != nil && !schemaEqual(*s.Const, val) {
+		fail("value %v does not equal const %v", formatSchemaValue(val), formatSchemaValue(*s.Const))
+	}
+
+	switch typed := val.(type) {
+	case map[string]any:
+		s.validateObject(typed, path, delim, errs)
+	case []any:
+		if s.MinItems != nil && len(typed) < *s.MinItems {
+			fail("expected at least %d items, got %d", *s.MinItems, len(typed))
+		}
+		if s.MaxItems != nil && len(typed) > *s.MaxItems {
+			fail("expected at most %d items, got %d", *s.MaxItems, len(typed))
+		}
+		if s.Items != nil {
+			for i, item := range typed {
+				s.Items.validate(item, joinSchemaPath(path, strconv.Itoa(i), delim), delim, errs)
+			}
+		}
+	case float64:
+		if s.Minimum != nil && typed < *s.Minimum {
+			fail("must be >= %v, got %v", *s.Minimum, typed)
+		}
+		if s.Maximum != nil && typed > *s.Maximum {
+			fail("must be <= %v, got %v", *s.Maximum, typed)
+		}
+		if s.ExclusiveMinimum != nil && typed <= *s.ExclusiveMinimum {
+			fail("must be > %v, got %v", *s.ExclusiveMinimum, typed)
+		}
+		if s.ExclusiveMaximum != nil && typed >= *s.ExclusiveMaximum {
+			fail("must be < %v, got %v", *s.ExclusiveMaximum, typed)
+		}
+	case string:
+		length := utf8.RuneCountInString(typed)
+		if s.MinLength != nil && length < *s.MinLength {
+			fail("length must be >= %d, got %d", *s.MinLength, length)
+		}
+		if s.MaxLength != nil && length > *s.MaxLength {
+			fail("length must be <= %d, got %d", *s.MaxLength, length)
+		}
+		if s.pattern != nil && !s.pattern.MatchString(typed) {
+			fail("value %q does not match pattern %q", typed, s.Pattern)
+		}
+	}
+
+	for _, sub := range s.AllOf {
+		sub.validate(val, path, delim, errs)
+	}
+	if len(s.AnyOf) > 0 {
+		matched := slices.ContainsFunc(s.AnyOf, func(sub *jsonSchema) bool {
+			var subErrs []error
+			sub.validate(val, path, delim, &subErrs)
+
+			return len(subErrs) == 0
+		})
+		if !matched {
+			fail("value does not match any schema in anyOf")
+		}
+	}
+}
+
+func (s *jsonSchema) validateObject(obj map[string]any, path, delim string, errs *[]error) {
+	// null 值（如切片默认值 nil 或 YAML 中的空值）解码为零值，视为未设置
+	for _, key := range s.Required {
+		if obj[key] == nil {
+			*errs = append(*errs, &SchemaError{Path: joinSchemaPath(path, key, delim), Message: "required key is missing"})
+		}
+	}
+
+	for _, key := range slices.Sorted(maps.Keys(obj)) {
+		if obj[key] == nil {
+			continue
+		}
+		childPath := joinSchemaPath(path, key, delim)
+		if prop, ok := s.Properties[key]; ok {
+			if prop != nil {
+				prop.validate(obj[key], childPath, delim, errs)
+			}
+
+			continue
+		}
+		if s.AdditionalProperties == nil {
+			continue
+		}
+		if !s.AdditionalProperties.allowed {
+			*errs = append(*errs, &SchemaError{Path: childPath, Message: "additional key is not allowed"})
+
+			continue
+		}
+		if s.AdditionalProperties.schema != nil {
+			s.AdditionalProperties.schema.validate(obj[key], childPath, delim, errs)
+		}
+	}
+}
+
+func joinSchemaPath(prefix, key, delim string) string {
+	if prefix == "" {
+		return key
+	}
+
+	return prefix + delim + key
+}
+
+// coerceSchemaType 判断 val 是否满足 types 之一。
+//
+// 与解码阶段的弱类型规则一致，环境变量与 CLI 写入的字符串在能解析时视为 number / integer / boolean。
+func coerceSchemaType(val any, types []string) (any, bool) {
+	for _, typ := range types {
+		switch typ {
+		case "object":
+			if _, ok := val.(map[string]any); ok {
+				return val, true
+			}
+		case "array":
+			if _, ok := val.([]any); ok {
+				return val, true
+			}
+		case "string":
+			if _, ok := val.(string); ok {
+				return val, true
+			}
+		case "null":
+			if val == nil {
+				return val, true
+			}
+		case "boolean":
+			switch typed := val.(type) {
+			case bool:
+				return val, true
+			case string:
+				if b, err := strconv.ParseBool(typed); err == nil {
+					return b, true
+				}
+			}
+		case "number", "integer":
+			num, ok := val.(float64)
+			if str, isStr := val.(string); isStr {
+				parsed, err := strconv.ParseFloat(strings.TrimSpace(str), 64)
+				num, ok = parsed, err == nil
+			}
+			if ok && (typ == "number" || num == math.Trunc(num)) {
+				return num, true
+			}
+		}
+	}
+
+	return val, false
+}
+
+func schemaTypeName(val any) string {
+	switch typed := val.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case float64:
+		if typed == math.Trunc(typed) {
+			return "integer"
+		}
+
+		return "number"
+	case string:
+		return "string"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", val)
+	}
+}
+
+func schemaEqual(a, b any) bool {
+	return reflect.DeepEqual(schemaValue(a), schemaValue(b))
+}
+
+func formatSchemaValue(val any) string {
+	out, err := json.Marshal(val)
+	if err != nil {
+		return fmt.Sprintf("%v", val)
+	}
+
+	return string(out)
+}
+
+// schemaValue 将配置树转换为 JSON 数据模型（nil / bool / float64 / string / []any / map[string]any）。
+//
+// time.Duration 与实现了 encoding.TextMarshaler 的值按其字符串形式校验，与配置文件中的写法一致。
+func schemaValue(val any) any {
+	switch typed := val.(type) {
+	case nil, bool, float64, string:
+		return val
+	case time.Duration:
+		return typed.String()
+	case encoding.TextMarshaler:
+		text, err := typed.MarshalText()
+		if err != nil {
+			return fmt.Sprintf("%v", val)
+		}
+
+		return string(text)
+	case map[string]any:
+		out := make(map[string]any, len(typed))
+		for key, value := range typed {
+			out[key] = schemaValue(value)
+		}
+
+		return out
+	}
+
+	rv := reflect.ValueOf(val)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.String:
+		return rv.String()
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		out := make([]any, rv.Len())
+		for i := range rv.Len() {
+			out[i] = schemaValue(rv.Index(i).Interface())
+		}
+
+		return out
+	case reflect.Map:
+		if rv.IsNil() {
+			return nil
+		}
+		out := make(map[string]any, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprintf("%v", iter.Key().Interface())] = schemaValue(iter.Value().Interface())
+		}
+
+		return out
+	case reflect.Pointer, reflect.Interface:
+		if rv.IsNil() {
+			return nil
+		}
+
+		return schemaValue(rv.Elem().Interface())
+	default:
+		return fmt.Sprintf("%v", val)
+	}
+}
+
+// schemaDraft 是 [GenerateSchema] 输出文档声明的 JSON Schema 版本。
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// durationPattern 匹配 time.ParseDuration 接受的字符串，如 "1h30m"、"500ms"。
+const durationPattern = `^-?(0|([0-9]+(\.[0-9]*)?(ns|us|µs|ms|s|m|h))+)$`
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// GenerateSchema 根据配置结构体生成 JSON Schema 文档，可用于编辑器补全与校验
+// （如 VS Code 的 yaml.schemas 关联）。
+//
+// key 使用 json tag，description 读取 desc、comment、usage tag，default 来自 defaultConfig 中的值。
+// 嵌套结构体生成带 properties 的 object 且不允许未声明的 key；map 生成 additionalProperties，
+// 切片生成 items；time.Duration 生成带格式 pattern 的 string，time.Time 生成 date-time 格式的 string。
+// 生成的文档可直接用于 [WithSchema]。
+//
+// 使用示例：
+//
+//	schema, err := cfgm.GenerateSchema(DefaultConfig())
+//	os.WriteFile("config/config.schema.json", schema, 0644)
+func GenerateSchema[T any](defaultConfig T) ([]byte, error) {
+	val := reflect.ValueOf(&defaultConfig).Elem()
+	if !isStructType(val.Type()) {
+		return nil, fmt.Errorf("cfgm: generate schema: %T is not a struct", defaultConfig)
+	}
+
+	doc := typeSchema(val, val.Type())
+	doc["$schema"] = schemaDraft
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("cfgm: generate schema: %w", err)
+	}
+
+	return append(data, '\n'), nil
+}
+
+// typeSchema 生成 typ 的 schema，val 有效时写入默认值。
+func typeSchema(val reflect.Value, typ reflect.Type) map[string]any {
+	if typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+		if val.IsValid() {
+			val = val.Elem()
+		}
+	}
+
+	out := leafTypeSchema(typ)
+	if out == nil && isStructType(typ) {
+		return structSchema(val, typ)
+	}
+	if out == nil {
+		out = compositeTypeSchema(typ)
+	}
+
+	if val.IsValid() && !isNilValue(val) {
+		if def := schemaValue(valueToAny(val, typ, defaultConfigTags)); def != nil {
+			out["default"] = def
+		}
+	}
+
+	return out
+}
+
+// leafTypeSchema 返回标量类型的 schema，非标量类型返回 nil。
+func leafTypeSchema(typ reflect.Type) map[string]any {
+	switch {
+	case typ == durationType:
+		return map[string]any{"type": "string", "pattern": durationPattern}
+	case typ == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case typ == secretRefType, reflect.PointerTo(typ).Implements(textUnmarshalerType):
+		return map[string]any{"type": "string"}
+	}
+
+	switch typ.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return map[string]any{"type": "integer"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return map[string]any{"type": "integer", "minimum": 0}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	default:
+		return nil
+	}
+}
+
+// compositeTypeSchema 返回切片、map 与 interface 类型的 schema。
+func compositeTypeSchema(typ reflect.Type) map[string]any {
+	switch typ.Kind() {
+	case reflect.Slice, reflect.Array:
+		return map[string]any{"type": "array", "items": typeSchema(reflect.Value{}, typ.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": typeSchema(reflect.Value{}, typ.Elem())}
+	default:
+		return map[string]any{}
+	}
+}
+
+// structSchema 生成结构体的 schema，默认值写入各字段的 schema 中。
+func structSchema(val reflect.Value, typ reflect.Type) map[string]any {
+	properties := make(map[string]any)
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		key := configTagName(field, defaultConfigTags)
+		if key == "" {
+			continue
+		}
+
+		var fieldVal reflect.Value
+		if val.IsValid() {
+			fieldVal = val.Field(i)
+		}
+		prop := typeSchema(fieldVal, field.Type)
+		if comment := fieldComment(field); comment != "" {
+			prop["description"] = comment
+		}
+		properties[key] = prop
+	}
+
+	return map[string]any{"type": "object", "properties": properties, "additionalProperties": false}
+}
+
+func isNilValue(val reflect.Value) bool {
+	switch val.Kind() {
+	case reflect.Pointer, reflect.Interface, reflect.Map, reflect.Slice:
+		return val.IsNil()
+	default:
+		return false
+	}
+}
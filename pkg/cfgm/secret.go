@@ -0,0 +1,84 @@
+package cfgm
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+var secretRefType = reflect.TypeFor[SecretRef]()
+
+// SecretResolver 按名称解析密钥，见 [WithSecretRefResolver]。
+type SecretResolver func(name string) (string, error)
+
+// SecretRef 是延迟解析的密钥引用。
+//
+// 配置文件、环境变量与 CLI 中只写入密钥名称，加载结果中也只保存名称；
+// 调用 [SecretRef.Resolve] 时才通过 [WithSecretRefResolver] 注册的函数取回密钥。
+// 序列化（[Marshal]、[ExampleYAML] 等）只输出名称，不会触发解析。
+type SecretRef struct {
+	name     string
+	resolver SecretResolver
+}
+
+// NewSecretRef 创建指向 name 的密钥引用，常用于默认配置。
+func NewSecretRef(name string) SecretRef {
+	return SecretRef{name: name}
+}
+
+// Name 返回引用的密钥名称。
+func (r SecretRef) Name() string {
+	return r.name
+}
+
+// Resolve 调用注册的解析函数取回密钥，每次调用都会重新解析。
+func (r SecretRef) Resolve() (string, error) {
+	if r.name == "" {
+		return "", nil
+	}
+	if r.resolver == nil {
+		return "", errors.New("cfgm: no secret resolver registered (see WithSecretRefResolver)")
+	}
+
+	secret, err := r.resolver(r.name)
+	if err != nil {
+		return "", fmt.Errorf("resolve secret %q: %w", r.name, err)
+	}
+
+	return secret, nil
+}
+
+// String 返回密钥名称，避免在日志中意外输出密钥。
+func (r SecretRef) String() string {
+	return r.name
+}
+
+// MarshalText 实现 encoding.TextMarshaler，序列化为密钥名称。
+func (r SecretRef) MarshalText() ([]byte, error) {
+	return []byte(r.name), nil
+}
+
+// UnmarshalText 实现 encoding.TextUnmarshaler，从密钥名称还原引用。
+func (r *SecretRef) UnmarshalText(text []byte) error {
+	r.name = string(text)
+
+	return nil
+}
+
+// secretRefHookFunc 返回将字符串或已有引用解码为 [SecretRef] 并绑定解析函数的 DecodeHook。
+func secretRefHookFunc(resolver SecretResolver) func(reflect.Type, reflect.Type, any) (any, error) {
+	return func(_, to reflect.Type, data any) (any, error) {
+		if to != secretRefType {
+			return data, nil
+		}
+
+		switch typed := data.(type) {
+		case string:
+			return SecretRef{name: typed, resolver: resolver}, nil
+		case SecretRef:
+			return SecretRef{name: typed.name, resolver: resolver}, nil
+		default:
+			return data, nil
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package cfgm
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretRef(t *testing.T) {
+	type DBConfig struct {
+		Password SecretRef `json:"password"`
+		Token    SecretRef `json:"token"`
+	}
+	type Config struct {
+		DB DBConfig `json:"db"`
+	}
+
+	calls := 0
+	resolver := func(name string) (string, error) {
+		calls++
+		if name == "missing" {
+			return "", errors.New("not found")
+		}
+
+		return "secret-of-" + name, nil
+	}
+
+	path := writeTempConfig(t, `
+db:
+  password: "db/password"
+`)
+	t.Setenv("SECRET_DB_TOKEN", "missing")
+
+	cfg, err := Load(
+		Config{DB: DBConfig{Password: NewSecretRef("default"), Token: NewSecretRef("token")}},
+		WithConfigPaths(path),
+		WithEnvPrefix("SECRET_"),
+		WithSecretRefResolver(resolver),
+	)
+	require.NoError(t, err)
+
+	a := assert.New(t)
+	a.Equal(0, calls, "secrets are not resolved during Load")
+	a.Equal("db/password", cfg.DB.Password.Name())
+	a.Equal("db/password", cfg.DB.Password.String())
+
+	secret, err := cfg.DB.Password.Resolve()
+	require.NoError(t, err)
+	a.Equal("secret-of-db/password", secret)
+	a.Equal(1, calls)
+
+	_, err = cfg.DB.Token.Resolve()
+	require.Error(t, err, "env-provided name resolved through resolver")
+	a.Contains(err.Error(), `resolve secret "missing"`)
+
+	t.Run("without resolver", func(t *testing.T) {
+		cfg, err := Load(Config{DB: DBConfig{Password: NewSecretRef("x")}}, WithConfigPaths("nonexistent.yaml"))
+		require.NoError(t, err)
+		assert.Equal(t, "x", cfg.DB.Password.Name(), "default kept")
+		_, err = cfg.DB.Password.Resolve()
+		require.Error(t, err)
+	})
+
+	t.Run("marshal writes name only", func(t *testing.T) {
+		data, err := Marshal(cfg, "yaml")
+		require.NoError(t, err)
+		assert.Contains(t, string(data), "password: db/password")
+		assert.NotContains(t, string(data), "secret-of")
+	})
+}
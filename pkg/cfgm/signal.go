@@ -0,0 +1,70 @@
+package cfgm
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"sync"
+)
+
+// WatchSignal 在每次收到信号 sig（通常为 syscall.SIGHUP）时按 opts 重新加载配置，并以结果调用 onReload。
+//
+// 适合偏好显式触发、而不是轮询文件变化（见 [Loader.Watch]）的服务。安装处理器时不加载配置，
+// 首次加载仍由调用方通过 [Load] 完成。成功时 onReload 收到新配置，失败时收到 nil 与错误。
+// 重新加载在独立的 goroutine 中依次执行，加载期间到达的多个信号合并为一次。
+//
+// 返回的 stop 移除信号处理器并等待进行中的重新加载结束，之后 sig 恢复默认行为；stop 可重复调用，
+// 不要在 onReload 中调用，否则会等待自身返回而阻塞。sig 或 onReload 为 nil 时返回错误。
+//
+// 示例：
+//
+//	stop, err := cfgm.WatchSignal(DefaultConfig(), syscall.SIGHUP, func(cfg *Config, err error) {
+//	    if err != nil {
+//	        slog.Warn("reload config", "error", err)
+//	        return
+//	    }
+//	    apply(cfg)
+//	}, cfgm.WithAppName("myapp"))
+//	defer stop()
+func WatchSignal[T any](defaultConfig T, sig os.Signal, onReload func(cfg *T, err error), opts ...Option) (stop func(), err error) {
+	if sig == nil {
+		return nil, errors.New("cfgm: WatchSignal requires a signal")
+	}
+	if onReload == nil {
+		return nil, errors.New("cfgm: WatchSignal requires an onReload callback")
+	}
+
+	options := newOptions(opts...)
+	options.resolve(0)
+	l := &Loader[T]{options: options, defaults: defaultConfig, closed: make(chan struct{})}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, sig)
+	done := make(chan struct{})
+	exited := make(chan struct{})
+	go func() {
+		defer close(exited)
+		for {
+			select {
+			case <-done:
+				return
+			case <-signals:
+				if err := l.Reload(); err != nil {
+					onReload(nil, err)
+				} else {
+					onReload(l.Snapshot(), nil)
+				}
+			}
+		}
+	}()
+
+	var once sync.Once
+
+	return func() {
+		once.Do(func() {
+			signal.Stop(signals)
+			close(done)
+			<-exited
+		})
+	}, nil
+}
@@ -0,0 +1,278 @@
+package cfgm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ConfigSource 是 [WithConfigSources] 的可插拔配置来源，可接入配置中心等自定义存储。
+//
+// Read 返回来源名称（用于错误信息与 [WithOnValueSet] 等诊断）、格式（"yaml"/"yml"/"json"，
+// 空字符串表示按 name 的扩展名判断，无法判断时按 YAML 解析）与原始内容。
+// 来源不存在时返回包装了 [fs.ErrNotExist] 的错误，加载时跳过该来源
+// （[WithConfigPathsStopOnError] 的 onMissing 为 [ErrorModeFail] 时加载失败）。
+// ctx 在 [WithSourceTimeout] 设置的时长（默认 30s）后取消，实现应在取消后尽快返回。
+//
+// 内置实现见 [FileSource]、[FSSource]、[BytesSource]、[HTTPSource]、[HTTPClientSource]、[S3Source] 与 [GCSSource]。自定义实现示例：
+//
+//	type consulSource struct{ kv *consulapi.KV; key string }
+//
+//	func (s consulSource) Read(ctx context.Context) (string, string, []byte, error) {
+//	    pair, _, err := s.kv.Get(s.key, (&consulapi.QueryOptions{}).WithContext(ctx))
+//	    switch {
+//	    case err != nil:
+//	        return "consul " + s.key, "", nil, err
+//	    case pair == nil:
+//	        return "consul " + s.key, "", nil, fs.ErrNotExist
+//	    }
+//	    return "consul " + s.key, "", pair.Value, nil
+//	}
+type ConfigSource interface {
+	Read(ctx context.Context) (name, format string, data []byte, err error)
+}
+
+// fileSource 是 [FileSource] 返回的本地文件来源。
+type fileSource struct {
+	path string
+}
+
+// FileSource 返回读取本地文件 path 的 [ConfigSource]，格式按扩展名判断。
+//
+// 相对路径基于工作目录解析，不受 [WithBaseDir] 影响；!include 相对于文件所在目录解析。
+func FileSource(path string) ConfigSource {
+	return fileSource{path: path}
+}
+
+func (s fileSource) Read(context.Context) (string, string, []byte, error) {
+	data, err := os.ReadFile(s.path)
+
+	return s.path, "", data, err
+}
+
+// fsSource 是 [FSSource] 返回的 fs.FS 文件来源。
+type fsSource struct {
+	fsys fs.FS
+	path string
+}
+
+// FSSource 返回读取 fsys 中 path 的 [ConfigSource]（如 embed.FS），格式按扩展名判断。
+func FSSource(fsys fs.FS, path string) ConfigSource {
+	return fsSource{fsys: fsys, path: path}
+}
+
+func (s fsSource) Read(context.Context) (string, string, []byte, error) {
+	data, err := fs.ReadFile(s.fsys, s.path)
+
+	return s.path, "", data, err
+}
+
+// bytesSource 是 [BytesSource] 返回的内存来源。
+type bytesSource struct {
+	name   string
+	format string
+	data   []byte
+}
+
+// BytesSource 返回以 data 为内容的 [ConfigSource]，format 为 "yaml"/"yml"/"json"，name 用于错误信息。
+func BytesSource(name, format string, data []byte) ConfigSource {
+	return bytesSource{name: name, format: format, data: data}
+}
+
+func (s bytesSource) Read(context.Context) (string, string, []byte, error) {
+	return s.name, s.format, s.data, nil
+}
+
+// defaultSourceTimeout 是单次读取配置来源的默认超时，见 [WithSourceTimeout]。
+const defaultSourceTimeout = 30 * time.Second
+
+// sourceContext 返回单次读取配置来源（[ConfigSource]、对象存储与 etcd）使用的 context。
+func (o *options) sourceContext() (context.Context, context.CancelFunc) {
+	switch {
+	case o.sourceTimeout < 0:
+		return context.WithCancel(context.Background())
+	case o.sourceTimeout == 0:
+		return context.WithTimeout(context.Background(), defaultSourceTimeout)
+	default:
+		return context.WithTimeout(context.Background(), o.sourceTimeout)
+	}
+}
+
+// httpSource 是 [HTTPSource] 返回的 HTTP 来源。
+type httpSource struct {
+	url    string
+	client *http.Client // nil 时使用 http.DefaultClient
+}
+
+// HTTPSource 返回以 GET 请求读取 rawURL 的 [ConfigSource]，使用 [http.DefaultClient]。
+//
+// 格式依次按响应的 Content-Type（忽略 charset 等参数）与 URL 路径的扩展名判断，均无法判断时按 YAML 解析，
+// 因此 /config 这类没有扩展名的端点只要返回正确的 Content-Type 即可按 JSON 解析。
+// 识别 application/json、application/yaml、application/x-yaml、text/yaml 及 +json、+yaml 后缀；
+// application/toml 等不支持的格式使加载失败，text/plain、application/octet-stream 等通用类型视为未声明格式。
+// 404 视为来源不存在，其余非 2xx 状态码使加载失败。
+// 请求受 [WithSourceTimeout] 限制（默认 30s），服务端无响应时加载失败而不会一直阻塞。
+// 需要认证、代理等时使用 [HTTPClientSource] 传入自定义的 [http.Client]。
+func HTTPSource(rawURL string) ConfigSource {
+	return httpSource{url: rawURL}
+}
+
+// HTTPClientSource 与 [HTTPSource] 相同，但使用 client 发送请求，可配置认证、TLS 与 client.Timeout 等。
+// client 为 nil 时使用 [http.DefaultClient]。
+func HTTPClientSource(client *http.Client, rawURL string) ConfigSource {
+	return httpSource{url: rawURL, client: client}
+}
+
+func (s httpSource) Read(ctx context.Context) (string, string, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return s.url, "", nil, err
+	}
+	client := s.client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return s.url, "", nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return s.url, "", nil, fmt.Errorf("http %s: %w", resp.Status, fs.ErrNotExist)
+	case resp.StatusCode < 200 || resp.StatusCode > 299:
+		return s.url, "", nil, fmt.Errorf("http %s", resp.Status)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return s.url, "", nil, err
+	}
+
+	format := mediaTypeFormat(resp.Header.Get("Content-Type"))
+	if u, err := url.Parse(s.url); format == "" && err == nil {
+		format = normalizeFormat(path.Ext(trimGzipExt(u.Path)))
+	}
+
+	return s.url, format, data, nil
+}
+
+// mediaTypeFormat 返回 Content-Type 对应的配置格式，参数（如 charset）被忽略。
+//
+// application/json、text/json 与 +json 后缀为 JSON，application/yaml、application/x-yaml、text/yaml 与 +yaml 后缀为 YAML；
+// application/toml 返回 "toml"，由调用方报告不支持的格式；其余类型与无法解析的值返回空字符串。
+func mediaTypeFormat(contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return ""
+	}
+	_, subtype, _ := strings.Cut(mediaType, "/")
+	if _, suffix, ok := strings.Cut(subtype, "+"); ok {
+		subtype = suffix
+	}
+	switch subtype {
+	case "json":
+		return formatJSON
+	case "yaml", "x-yaml":
+		return formatYAML
+	case "toml", "x-toml":
+		return "toml"
+	default:
+		return ""
+	}
+}
+
+// readSourceLayers 依次读取 [WithConfigSources] 的来源，按声明顺序返回（靠后的优先）。
+func readSourceLayers(options *options, report *loadReport) ([]configLayer, error) {
+	var layers []configLayer
+	for _, src := range options.sources {
+		layer, ok, err := readSourceLayer(src, options, report)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			layers = append(layers, layer)
+		}
+	}
+
+	return layers, nil
+}
+
+// readSourceLayer 读取单个来源并按配置文件的规则处理（大小限制、校验和、解压、模板展开与解析）。
+//
+// 来源不存在时返回 false。
+func readSourceLayer(src ConfigSource, options *options, report *loadReport) (configLayer, bool, error) {
+	readStart := time.Now()
+	ctx, cancel := options.sourceContext()
+	name, format, content, err := src.Read(ctx)
+	cancel()
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) && options.onMissingFile != ErrorModeFail {
+			slog.Debug("Config source not found", "source", name, "error", err)
+
+			return configLayer{}, false, nil
+		}
+
+		return configLayer{}, false, fmt.Errorf("config source %s: %w", name, err)
+	}
+	// WithSecureFilePermissions: 本地文件来源与配置文件同样检查权限
+	if file, ok := src.(fileSource); ok && options.securePermissions {
+		info, err := os.Stat(file.path)
+		if err != nil {
+			return configLayer{}, false, fmt.Errorf("config source %s: %w", name, err)
+		}
+		if err := checkFilePermissions(file.path, info); err != nil {
+			return configLayer{}, false, err
+		}
+	}
+	if options.maxFileSize > 0 && int64(len(content)) > options.maxFileSize {
+		return configLayer{}, false, fmt.Errorf("config %s exceeds max size of %d bytes", name, options.maxFileSize)
+	}
+
+	// 显式格式通过追加扩展名传递给解析流程，与 LoadReader 的 "<reader>.yaml" 一致
+	parseName := name
+	if format != "" {
+		normalized := normalizeFormat(format)
+		if normalized == "" {
+			return configLayer{}, false, fmt.Errorf("config source %s: unsupported format %q", name, format)
+		}
+		if normalizeFormat(filepath.Ext(trimGzipExt(name))) != normalized {
+			parseName = name + "." + normalized
+		}
+	}
+
+	if err := verifyChecksum(options, name, content); err != nil {
+		return configLayer{}, false, err
+	}
+	if content, err = decompressConfig(parseName, content, options.maxFileSize); err != nil {
+		return configLayer{}, false, err
+	}
+	report.observe(&report.metrics.Read, readStart)
+
+	dir := options.baseDir
+	if file, ok := src.(fileSource); ok {
+		dir = filepath.Dir(file.path)
+	}
+	parse := parseConfigFile
+	if _, ok := src.(bytesSource); ok {
+		parse = parseStaticConfigFile
+	}
+	data, err := parse(parseName, dir, content, options, report)
+	if err != nil {
+		return configLayer{}, false, err
+	}
+	slog.Debug("Loaded config from source", "source", name)
+
+	return configLayer{path: name, data: data}, true, nil
+}
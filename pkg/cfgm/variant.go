@@ -0,0 +1,111 @@
+package cfgm
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"sync"
+)
+
+// variantKey 是多态配置对象中选择具体类型的键，见 [RegisterVariant]。
+const variantKey = "type"
+
+// variants 保存 [RegisterVariant] 注册的判别值 → 原型。
+var variants = struct {
+	sync.RWMutex
+	m map[string]reflect.Value
+}{m: make(map[string]reflect.Value)}
+
+// RegisterVariant 注册多态配置字段的一个具体类型，discriminator 为配置对象中 "type" 键的取值。
+//
+// 字段声明为非空接口（如 Auth）时，对象中的 "type" 决定解码目标：按判别值找到原型，
+// 以原型的字段值为默认值解码其余键，结果须实现字段的接口类型。prototype 可以是结构体或结构体指针，
+// 解码结果与其形式一致。通常在 init 中调用；判别值为空、prototype 为 nil 或同一判别值注册不同类型时 panic。
+//
+// 示例：
+//
+//	type Auth interface{ Kind() string }
+//
+//	func init() {
+//	    cfgm.RegisterVariant("oauth", &OAuthConfig{Scopes: []string{"openid"}})
+//	    cfgm.RegisterVariant("basic", &BasicAuthConfig{})
+//	}
+//
+//	// auth:
+//	//   type: oauth
+//	//   client_id: abc
+func RegisterVariant(discriminator string, prototype any) {
+	if discriminator == "" {
+		panic("cfgm: RegisterVariant with empty discriminator")
+	}
+	val := reflect.ValueOf(prototype)
+	if !val.IsValid() || (val.Kind() == reflect.Pointer && val.IsNil()) {
+		panic("cfgm: RegisterVariant with nil prototype for " + discriminator)
+	}
+
+	variants.Lock()
+	defer variants.Unlock()
+	if prev, ok := variants.m[discriminator]; ok && prev.Type() != val.Type() {
+		panic(fmt.Sprintf("cfgm: variant %q registered twice (%s, %s)", discriminator, prev.Type(), val.Type()))
+	}
+	variants.m[discriminator] = val
+}
+
+// lookupVariant 返回判别值对应的原型。
+func lookupVariant(discriminator string) (reflect.Value, bool) {
+	variants.RLock()
+	defer variants.RUnlock()
+	val, ok := variants.m[discriminator]
+
+	return val, ok
+}
+
+// variantHookFunc 返回将带 "type" 键的对象解码为已注册具体类型的 DecodeHook，见 [RegisterVariant]。
+//
+// 仅作用于非空接口类型的目标；any 等空接口字段保持原样，不含 "type" 键的对象交由默认规则处理。
+func variantHookFunc(o *options) func(reflect.Type, reflect.Type, any) (any, error) {
+	return func(_, to reflect.Type, data any) (any, error) {
+		if to.Kind() != reflect.Interface || to.NumMethod() == 0 {
+			return data, nil
+		}
+		obj, ok := data.(map[string]any)
+		if !ok {
+			return data, nil
+		}
+		disc, ok := obj[variantKey]
+		if !ok {
+			return data, nil
+		}
+
+		name := fmt.Sprintf("%v", disc)
+		proto, ok := lookupVariant(name)
+		if !ok {
+			return nil, fmt.Errorf("unknown variant %q for %s (see RegisterVariant)", name, to)
+		}
+		if !proto.Type().Implements(to) {
+			return nil, fmt.Errorf("variant %q (%s) does not implement %s", name, proto.Type(), to)
+		}
+
+		// 原型先转为配置树再与其余键合并，解码到新值，避免与原型共享切片等引用
+		fields := maps.Clone(obj)
+		delete(fields, variantKey)
+		if defaults := structToMap(proto.Interface(), o.tagChain()); len(defaults) > 0 {
+			mergeMaps(defaults, fields)
+			fields = defaults
+		}
+
+		var target, result reflect.Value
+		if proto.Kind() == reflect.Pointer {
+			target = reflect.New(proto.Type().Elem())
+			result = target
+		} else {
+			target = reflect.New(proto.Type())
+			result = target.Elem()
+		}
+		if err := decodeConfigValue(fields, target.Interface(), o); err != nil {
+			return nil, fmt.Errorf("variant %q: %w", name, err)
+		}
+
+		return result.Interface(), nil
+	}
+}
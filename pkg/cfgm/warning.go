@@ -0,0 +1,135 @@
+package cfgm
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// WarningCode 标识加载警告的类别。
+type WarningCode string
+
+const (
+	// WarnUnknownKey 配置文件中出现了配置结构体未定义的 key（常见于拼写错误或已废弃的 key）。
+	WarnUnknownKey WarningCode = "unknown_key"
+	// WarnInvalidFile 无法解析的配置文件被跳过，见 [WithConfigPathsStopOnError]。
+	WarnInvalidFile WarningCode = "invalid_file"
+	// WarnEnvBindingCase [WithEnvBinding] 的 config path 与结构体 key 仅大小写不同，见 [WithEnvBindingsCaseFold]。
+	WarnEnvBindingCase WarningCode = "env_binding_case"
+	// WarnConfigVersion 配置文件的版本与 [WithConfigVersion] 期望的不一致。
+	WarnConfigVersion WarningCode = "config_version"
+	// WarnUnusedEnvBinding [WithEnvBinding] 绑定的环境变量在加载时未设置，见 [WithEnvBindingsReport]。
+	WarnUnusedEnvBinding WarningCode = "unused_env_binding"
+	// WarnStaleFile 配置文件的修改时间早于 [WithConfigPathsNewerThan] 的时刻，已被跳过。
+	WarnStaleFile WarningCode = "stale_file"
+	// WarnStatTimeout 访问配置文件（stat、读取或 glob 展开）超过 [WithStatTimeout] 的时限，按不存在处理。
+	WarnStatTimeout WarningCode = "stat_timeout"
+	// WarnEnvOverridesFile 已设置的环境变量绑定覆盖了配置文件中同一 key 的值，见 [WithEnvBindingsInverse]。
+	WarnEnvOverridesFile WarningCode = "env_overrides_file"
+)
+
+// Warning 描述加载过程中发现的非致命问题，见 [LoadWithWarnings]。
+type Warning struct {
+	Code    WarningCode // 警告类别
+	Message string      // 可读的描述
+	Path    string      // 相关的配置 key（[WarnInvalidFile] 为文件路径）
+}
+
+// String 返回 "code: message" 形式的描述。
+func (w Warning) String() string {
+	return string(w.Code) + ": " + w.Message
+}
+
+// LoadWithWarnings 与 [Load] 相同，额外返回加载过程中收集到的警告。
+//
+// 警告不影响加载结果，调用方可自行记录或通过健康检查接口展示。
+// 使用 [Load] 时警告仅以 slog Debug 级别输出。
+//
+// 示例：
+//
+//	cfg, warnings, err := cfgm.LoadWithWarnings(DefaultConfig(), cfgm.WithAppName("myapp"))
+//	for _, w := range warnings {
+//	    slog.Warn("config", "code", w.Code, "path", w.Path, "msg", w.Message)
+//	}
+func LoadWithWarnings[T any](defaultConfig T, opts ...Option) (*T, []Warning, error) {
+	cfg, report, err := load(defaultConfig, 1, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return cfg, report.warnings, nil
+}
+
+// LoadWithUnusedKeys 与 [Load] 相同，额外返回配置中没有对应结构体字段、因而在解码时被忽略的 key，
+// 便于调用方记录可能的拼写错误而不中断启动。
+//
+// key 以 [WithKeyDelim] 的分隔符拼接，去重后按字典序排列，没有时为 nil。与 [WarnUnknownKey] 的判定一致：
+// 检查全部配置文件以及 etcd、[WithFlatEnvKeys] 与 [WithForcedValues] 等配置树来源，
+// map、切片、interface 等非结构体字段下的子 key 视为已使用；各 key 出自哪个文件可通过 [LoadWithWarnings] 查看。
+//
+// 示例：
+//
+//	cfg, unused, err := cfgm.LoadWithUnusedKeys(DefaultConfig(), cfgm.WithAppName("myapp"))
+//	if len(unused) > 0 {
+//	    slog.Warn("unused config keys", "keys", unused)
+//	}
+func LoadWithUnusedKeys[T any](defaultConfig T, opts ...Option) (*T, []string, error) {
+	cfg, report, err := load(defaultConfig, 1, opts...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var keys []string
+	for _, w := range report.warnings {
+		if w.Code == WarnUnknownKey {
+			keys = append(keys, w.Path)
+		}
+	}
+	slices.Sort(keys)
+
+	return cfg, slices.Compact(keys), nil
+}
+
+// addWarning 记录一条警告。
+func (r *loadReport) addWarning(code WarningCode, path, format string, args ...any) {
+	w := Warning{Code: code, Message: fmt.Sprintf(format, args...), Path: path}
+	r.warnings = append(r.warnings, w)
+	slog.Debug("Config warning", "code", w.Code, "path", w.Path, "message", w.Message)
+}
+
+// checkUnknownKeys 对配置文件中结构体未定义的 key 记录 [WarnUnknownKey]。
+//
+// map、切片、interface 等非结构体字段下的子 key 视为已定义；配置类型不是结构体时不检查。
+func checkUnknownKeys(layer configLayer, typ reflect.Type, options *options, report *loadReport) {
+	if typ == nil || !isStructType(typ) {
+		return
+	}
+	delim := options.keyDelim()
+	leaves := collectConfigKeyTypes(typ, delim, options.tagChain())
+	if options.normalizeKeys {
+		for key, typ := range leaves {
+			leaves[strings.ToLower(key)] = typ
+		}
+	}
+
+	keys := flattenMapKeys(layer.data, delim)
+	slices.Sort(keys)
+	for _, key := range keys {
+		if !isKnownConfigKey(key, leaves, delim) {
+			report.addWarning(WarnUnknownKey, key, "%s: unknown config key %q", layer.path, key)
+		}
+	}
+}
+
+// isKnownConfigKey 判断 key 是否对应结构体中的叶子字段、其子 key 或其父级。
+func isKnownConfigKey(key string, leaves map[string]reflect.Type, delim string) bool {
+	for leaf := range leaves {
+		if key == leaf || strings.HasPrefix(key, leaf+delim) || strings.HasPrefix(leaf, key+delim) {
+			return true
+		}
+	}
+
+	return false
+}
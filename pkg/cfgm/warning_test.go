@@ -0,0 +1,251 @@
+package cfgm
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadWithWarnings(t *testing.T) {
+	type ServerConfig struct {
+		Host string `json:"host"`
+	}
+	type Config struct {
+		Name   string            `json:"name"`
+		Server ServerConfig      `json:"server"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	path := writeTempConfig(t, `
+name: app
+nmae: typo
+server:
+  host: localhost
+  prot: 8080
+labels:
+  team: core
+`)
+
+	cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(path))
+	require.NoError(t, err)
+	assert.Equal(t, "app", cfg.Name)
+
+	require.Len(t, warnings, 2)
+	assert.Equal(t, WarnUnknownKey, warnings[0].Code)
+	assert.Equal(t, "nmae", warnings[0].Path)
+	assert.Contains(t, warnings[0].Message, path)
+	assert.Equal(t, "server.prot", warnings[1].Path)
+	assert.Equal(t, `unknown_key: `+warnings[1].Message, warnings[1].String())
+
+	t.Run("no warnings", func(t *testing.T) {
+		clean := writeTempConfig(t, "name: app\nserver: {}\n")
+		_, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(clean))
+		require.NoError(t, err)
+		assert.Empty(t, warnings)
+	})
+}
+
+func TestLoadWithUnusedKeys(t *testing.T) {
+	type ServerConfig struct {
+		Host string `json:"host"`
+	}
+	type Config struct {
+		Name   string            `json:"name"`
+		Server ServerConfig      `json:"server"`
+		Labels map[string]string `json:"labels"`
+	}
+
+	base := writeTempConfig(t, "nmae: typo\nserver:\n  prot: 8080\n")
+	overlay := writeTempConfig(t, "name: app\nnmae: again\nlabels:\n  team: core\nextra:\n  a: 1\n  b: 2\n")
+
+	cfg, unused, err := LoadWithUnusedKeys(Config{}, WithConfigPaths(overlay, base), WithMergeAllPaths())
+	require.NoError(t, err)
+	assert.Equal(t, "app", cfg.Name)
+	assert.Equal(t, []string{"extra.a", "extra.b", "nmae", "server.prot"}, unused, "deduplicated across files and sorted")
+
+	_, unused, err = LoadWithUnusedKeys(Config{}, WithConfigPaths(overlay), WithKeyDelim("/"))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"extra/a", "extra/b", "nmae"}, unused)
+
+	clean := writeTempConfig(t, "name: app\n")
+	_, unused, err = LoadWithUnusedKeys(Config{}, WithConfigPaths(clean))
+	require.NoError(t, err)
+	assert.Nil(t, unused)
+}
+
+func TestLoadWithEnvBindingsReport(t *testing.T) {
+	type Config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+		Name string `json:"name" env:"EBR_NAME"`
+	}
+
+	t.Setenv("EBR_HOST", "example.com")
+	opts := []Option{
+		WithConfigPaths(),
+		WithEnvBinding("EBR_HOST", "host"),
+		WithEnvBinding("EBR_PORT", "port"),
+		WithEnvBindKey("env"),
+	}
+
+	_, warnings, err := LoadWithWarnings(Config{}, append(opts, WithEnvBindingsReport())...)
+	require.NoError(t, err)
+	require.Len(t, warnings, 1, "tag bindings are not reported")
+	assert.Equal(t, WarnUnusedEnvBinding, warnings[0].Code)
+	assert.Equal(t, "port", warnings[0].Path)
+	assert.Equal(t, "env binding EBR_PORT is not set", warnings[0].Message)
+
+	_, warnings, err = LoadWithWarnings(Config{}, opts...)
+	require.NoError(t, err)
+	assert.Empty(t, warnings, "disabled by default")
+}
+
+func TestLoadWithEnvBindingsInverse(t *testing.T) {
+	type DB struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		DB   DB     `json:"db"`
+		Name string `json:"name"`
+	}
+
+	path := writeTempConfig(t, "db:\n  host: file-host\n  port: 5432\nname: file\n")
+	t.Setenv("EBI_DB_HOST", "env-host")
+	t.Setenv("EBI_PORT", "6543")
+	opts := []Option{
+		WithConfigPaths(path),
+		WithEnvPrefix("EBI_"),
+		WithEnvBinding("EBI_PORT", "db.port"),
+		WithEnvBinding("EBI_UNSET", "name"),
+	}
+
+	cfg, warnings, err := LoadWithWarnings(Config{}, append(opts, WithEnvBindingsInverse())...)
+	require.NoError(t, err)
+	assert.Equal(t, "env-host", cfg.DB.Host)
+	require.Len(t, warnings, 2)
+	assert.Equal(t, WarnEnvOverridesFile, warnings[0].Code)
+	assert.Equal(t, "db.host", warnings[0].Path)
+	assert.Equal(t, "env EBI_DB_HOST overrides db.host set in "+path, warnings[0].Message)
+	assert.Equal(t, "db.port", warnings[1].Path)
+
+	_, warnings, err = LoadWithWarnings(Config{}, opts...)
+	require.NoError(t, err)
+	assert.Empty(t, warnings, "disabled by default")
+}
+
+func TestLoadWithConfigPathsNewerThan(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	dir := t.TempDir()
+	stale := filepath.Join(dir, "stale.yaml")
+	fresh := filepath.Join(dir, "fresh.yaml")
+	require.NoError(t, os.WriteFile(stale, []byte("name: stale\n"), 0o600))
+	require.NoError(t, os.WriteFile(fresh, []byte("name: fresh\n"), 0o600))
+	threshold := time.Now().Add(-time.Hour)
+	require.NoError(t, os.Chtimes(stale, threshold.Add(-time.Hour), threshold.Add(-time.Hour)))
+
+	cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(stale, fresh), WithConfigPathsNewerThan(threshold))
+	require.NoError(t, err)
+	assert.Equal(t, "fresh", cfg.Name)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, WarnStaleFile, warnings[0].Code)
+	assert.Equal(t, stale, warnings[0].Path)
+
+	cfg, err = Load(Config{}, WithConfigPaths(stale, fresh))
+	require.NoError(t, err)
+	assert.Equal(t, "stale", cfg.Name, "no check by default")
+
+	_, err = Load(Config{}, WithConfigPaths(stale, fresh), WithConfigPathsNewerThan(threshold), WithFailFastPaths())
+	require.ErrorContains(t, err, "config file "+stale+": modified at")
+}
+
+func TestLoadWithStatTimeout(t *testing.T) {
+	type Config struct {
+		Name string `json:"name"`
+	}
+	hung := filepath.Join(t.TempDir(), "nfs", "config.yaml")
+	local := writeTempConfig(t, "name: local\n")
+
+	// 模拟失效的挂载点：hung 下的 stat 一直阻塞到测试结束
+	release := make(chan struct{})
+	t.Cleanup(func() { close(release) })
+	orig := osStat
+	osStat = func(name string) (os.FileInfo, error) {
+		if name == hung {
+			<-release
+		}
+
+		return orig(name)
+	}
+	t.Cleanup(func() { osStat = orig })
+
+	start := time.Now()
+	cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(hung, local), WithStatTimeout(20*time.Millisecond))
+	require.NoError(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.Equal(t, "local", cfg.Name)
+	require.Len(t, warnings, 1)
+	assert.Equal(t, WarnStatTimeout, warnings[0].Code)
+	assert.Equal(t, hung, warnings[0].Path)
+
+	t.Run("read blocks after stat", func(t *testing.T) {
+		slow := writeTempConfig(t, "name: slow\n")
+		orig := openConfigFile
+		openConfigFile = func(name string) (configFileHandle, error) {
+			file, err := orig(name)
+			if err != nil || name != slow {
+				return file, err
+			}
+
+			return blockingFile{configFileHandle: file, release: release}, nil
+		}
+		t.Cleanup(func() { openConfigFile = orig })
+
+		start := time.Now()
+		cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(slow, local), WithStatTimeout(20*time.Millisecond))
+		require.NoError(t, err)
+		assert.Less(t, time.Since(start), time.Second)
+		assert.Equal(t, "local", cfg.Name)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, WarnStatTimeout, warnings[0].Code)
+		assert.Contains(t, warnings[0].Message, "read timed out")
+	})
+
+	t.Run("glob blocks", func(t *testing.T) {
+		pattern := filepath.Join(filepath.Dir(hung), "*.yaml")
+		orig := osGlob
+		osGlob = func(p string) ([]string, error) {
+			if p == pattern {
+				<-release
+			}
+
+			return orig(p)
+		}
+		t.Cleanup(func() { osGlob = orig })
+
+		cfg, warnings, err := LoadWithWarnings(Config{}, WithConfigPaths(pattern, local), WithStatTimeout(20*time.Millisecond))
+		require.NoError(t, err)
+		assert.Equal(t, "local", cfg.Name)
+		require.Len(t, warnings, 1)
+		assert.Equal(t, pattern, warnings[0].Path)
+		assert.Contains(t, warnings[0].Message, "glob timed out")
+	})
+}
+
+// blockingFile 的 Read 阻塞到 release 关闭，模拟 stat 成功后读取阻塞的挂载点。
+type blockingFile struct {
+	configFileHandle
+	release <-chan struct{}
+}
+
+func (f blockingFile) Read(p []byte) (int, error) {
+	<-f.release
+
+	return f.configFileHandle.Read(p)
+}
@@ -0,0 +1,246 @@
+package cfgm
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// defaultWatchInterval 是 [Loader.Watch] 的默认轮询间隔。
+const defaultWatchInterval = time.Second
+
+// defaultReloadGracePeriod 是 [Loader.Watch] 遇到空白配置文件时的默认重新检查间隔，见 [WithConfigReloadGracePeriod]。
+const defaultReloadGracePeriod = 100 * time.Millisecond
+
+// fileState 记录一个候选配置路径在某一时刻的状态。
+type fileState struct {
+	realPath string // 解析符号链接后的真实路径，文件不存在时为空
+	modTime  time.Time
+	size     int64
+}
+
+// ReloadEvent 描述 [Loader.Watch] 触发的一次重新加载，见 [WithOnConfigReload]。
+type ReloadEvent struct {
+	Time     time.Time     // 开始重新加载的时间
+	Duration time.Duration // 重新加载的耗时
+	Changed  bool          // 合并后的配置是否与之前不同（仅改动注释、格式时为 false）
+	Err      error         // 重新加载失败的原因，成功时为 nil
+}
+
+// Watch 监听配置文件变化并自动重新加载，阻塞直到 ctx 结束或调用 [Loader.Close]。
+//
+// 每次重新加载后调用 onChange：成功时传入新配置，失败（含 [WithSchema] 等校验失败）时传入错误且保留当前配置，
+// 见 [WithConfigPathsReloadValidation]。
+// 采用轮询实现（间隔见 [WithWatchInterval]），每轮都会重新解析候选路径的符号链接，
+// 因此既能发现原地写入，也能发现 Kubernetes ConfigMap 通过替换 ..data 链接完成的更新；
+// 候选路径中的文件新增或删除同样会触发重新加载，[WithConfigPathsDir] 等片段目录同样被监听。
+// 使用 [WithConfigReloadThrottle] 可限制重新加载的频率，[WithWatchDebounce] 可将一批变化合并为一次重新加载。
+// 被截断为空（或只含空白字符）的配置文件视为替换过程中的中间状态，默认不重新加载：
+// 宽限期（默认 100ms）后重新检查一次，仍为空白时以 slog.Warn 记录一次，之后按轮询间隔等待写入内容，
+// 因此有意清空的配置文件不会被 Watch 加载，需显式调用 [Loader.Reload]，见 [WithConfigReloadGracePeriod]。
+//
+// 示例：
+//
+//	go loader.Watch(ctx, func(cfg *Config, err error) {
+//	    if err != nil {
+//	        slog.Warn("reload config", "error", err)
+//	        return
+//	    }
+//	    apply(cfg)
+//	})
+func (l *Loader[T]) Watch(ctx context.Context, onChange func(cfg *T, err error)) {
+	// 在 closeMu 下登记，保证 Close 等待期间不会再有新的 Watch 开始
+	l.closeMu.Lock()
+	if l.isClosed() {
+		l.closeMu.Unlock()
+
+		return
+	}
+	l.watchers.Add(1)
+	l.closeMu.Unlock()
+	defer l.watchers.Done()
+
+	interval := l.options.watchInterval
+	if interval <= 0 {
+		interval = defaultWatchInterval
+	}
+	throttle := l.options.reloadThrottle
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	// WithConfigReloadThrottle: 节流窗口内发现的变化推迟到窗口结束时处理，以最新状态重新加载
+	var lastReload time.Time
+	var pending *time.Timer
+	var pendingC <-chan time.Time
+	// WithConfigReloadGracePeriod: 当前保持空白的文件及是否已为其记录警告
+	var blankPath string
+	var blankWarned bool
+	defer func() {
+		if pending != nil {
+			pending.Stop()
+		}
+	}()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-l.closed:
+			return
+		case <-ticker.C:
+			if pendingC != nil {
+				continue
+			}
+		case <-pendingC:
+			pendingC = nil
+		}
+
+		l.mu.RLock()
+		last := l.files
+		l.mu.RUnlock()
+
+		current := snapshotFiles(l.options.watchedPaths())
+		if slices.Equal(current, last) {
+			continue
+		}
+		if wait := throttle - time.Since(lastReload); throttle > 0 && wait > 0 {
+			slog.Debug("Config file changed, reload throttled", "wait", wait)
+			pending = time.NewTimer(wait)
+			pendingC = pending.C
+
+			continue
+		}
+
+		// WithWatchDebounce: 等待连续 watchDebounce 时长内没有新的变化
+		if debounce := l.options.watchDebounce; debounce > 0 {
+			var ok bool
+			if current, ok = l.waitStable(ctx, current, debounce); !ok {
+				return
+			}
+		}
+
+		// WithConfigReloadGracePeriod: 空白文件视为写入中途的状态，暂不重新加载；
+		// 宽限期后重新检查一次，仍为空白时记录一次警告，之后按 interval 检查
+		if grace := l.options.reloadGrace(); grace > 0 {
+			if path, blank := blankConfigFile(current, last); blank {
+				switch {
+				case path != blankPath:
+					slog.Debug("Config file is empty, waiting for content", "path", path, "grace", grace)
+					blankPath, blankWarned = path, false
+					pending = time.NewTimer(grace)
+					pendingC = pending.C
+				case !blankWarned:
+					slog.Warn("Config file is still empty, reload skipped until it has content", "path", path)
+					blankWarned = true
+				}
+
+				continue
+			}
+			blankPath = ""
+		}
+
+		if l.options.statCache != nil {
+			l.options.statCache.invalidate(l.options.watchedPaths()...)
+		}
+
+		slog.Debug("Config file changed, reloading")
+		lastReload = time.Now()
+		changed, err := l.reload()
+		if errors.Is(err, ErrClosed) {
+			return
+		}
+		if l.options.onReload != nil {
+			l.options.onReload(ReloadEvent{Time: lastReload, Duration: time.Since(lastReload), Changed: changed, Err: err})
+		}
+		if err != nil {
+			// 记录失败时的状态，避免对同一份错误内容反复重试
+			l.mu.Lock()
+			l.files = current
+			l.mu.Unlock()
+			onChange(nil, err)
+
+			continue
+		}
+		onChange(l.Config(), nil)
+	}
+}
+
+// waitStable 每隔 window 重新检查候选路径，直到状态不再变化，返回最终状态。
+//
+// ctx 结束或 Loader 关闭时 ok 为 false。
+func (l *Loader[T]) waitStable(ctx context.Context, current []fileState, window time.Duration) ([]fileState, bool) {
+	timer := time.NewTimer(window)
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil, false
+		case <-l.closed:
+			return nil, false
+		case <-timer.C:
+		}
+
+		next := snapshotFiles(l.options.watchedPaths())
+		if slices.Equal(next, current) {
+			return current, true
+		}
+		slog.Debug("Config files still changing, waiting", "window", window)
+		current = next
+		timer.Reset(window)
+	}
+}
+
+// blankConfigFile 返回 states 中第一个相对 last 发生变化、内容为空或只含空白字符的文件。
+//
+// 未变化的空白文件（如有意留空的片段）不影响重新加载。
+func blankConfigFile(states, last []fileState) (string, bool) {
+	for _, state := range states {
+		if state.realPath == "" || slices.Contains(last, state) {
+			continue
+		}
+		content, err := os.ReadFile(state.realPath)
+		if err == nil && len(bytes.TrimSpace(content)) == 0 {
+			return state.realPath, true
+		}
+	}
+
+	return "", false
+}
+
+// snapshotFiles 返回各候选路径当前的状态，glob 模式按匹配结果展开，顺序与 paths 一致。
+func snapshotFiles(paths []string) []fileState {
+	var states []fileState
+	for _, pattern := range paths {
+		matches := expandConfigPath(pattern)
+		if len(matches) == 0 {
+			states = append(states, fileState{})
+
+			continue
+		}
+		for _, path := range matches {
+			states = append(states, statFile(path))
+		}
+	}
+
+	return states
+}
+
+// statFile 返回单个路径的状态，文件不存在时返回零值。
+func statFile(path string) fileState {
+	realPath, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return fileState{}
+	}
+	info, err := os.Stat(realPath)
+	if err != nil {
+		return fileState{}
+	}
+
+	return fileState{realPath: realPath, modTime: info.ModTime(), size: info.Size()}
+}
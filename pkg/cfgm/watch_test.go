@@ -0,0 +1,503 @@
+package cfgm
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type watchConfig struct {
+	Name string `json:"name"`
+}
+
+// startWatch 在后台运行 Watch，返回接收重新加载结果的 channel。
+func startWatch(t *testing.T, loader *Loader[watchConfig]) <-chan string {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	names := make(chan string, 16)
+	go func() {
+		defer close(done)
+		loader.Watch(ctx, func(cfg *watchConfig, err error) {
+			if err != nil {
+				names <- "error: " + err.Error()
+
+				return
+			}
+			names <- cfg.Name
+		})
+	}()
+
+	return names
+}
+
+// logBuffer 是并发安全的日志输出缓冲区。
+type logBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.buf.String()
+}
+
+// captureLogs 在测试期间将 slog 默认 logger 的 Debug 及以上日志写入缓冲区。
+func captureLogs(t *testing.T) *logBuffer {
+	t.Helper()
+	logs := &logBuffer{}
+	orig := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(logs, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(orig) })
+
+	return logs
+}
+
+func waitReload(t *testing.T, names <-chan string) string {
+	t.Helper()
+	select {
+	case name := <-names:
+		return name
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload")
+
+		return ""
+	}
+}
+
+func TestLoaderWatch(t *testing.T) {
+	t.Run("in-place write", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: v1\n"), 0o600))
+
+		loader, err := NewLoader(watchConfig{}, WithConfigPaths(path), WithWatchInterval(10*time.Millisecond))
+		require.NoError(t, err)
+		names := startWatch(t, loader)
+
+		require.NoError(t, os.WriteFile(path, []byte("name: v2-longer\n"), 0o600))
+		assert.Equal(t, "v2-longer", waitReload(t, names))
+		assert.Equal(t, "v2-longer", loader.Config().Name)
+	})
+
+	t.Run("kubernetes symlink swap", func(t *testing.T) {
+		// 挂载目录结构: config.yaml -> ..data/config.yaml, ..data -> ..v1
+		dir := t.TempDir()
+		writeVersion := func(version, content string) {
+			require.NoError(t, os.Mkdir(filepath.Join(dir, version), 0o750))
+			require.NoError(t, os.WriteFile(filepath.Join(dir, version, "config.yaml"), []byte(content), 0o600))
+		}
+		writeVersion("..v1", "name: v1\n")
+		require.NoError(t, os.Symlink("..v1", filepath.Join(dir, "..data")))
+		path := filepath.Join(dir, "config.yaml")
+		require.NoError(t, os.Symlink(filepath.Join("..data", "config.yaml"), path))
+
+		loader, err := NewLoader(watchConfig{}, WithConfigPaths(path), WithWatchInterval(10*time.Millisecond))
+		require.NoError(t, err)
+		require.Equal(t, "v1", loader.Config().Name)
+		names := startWatch(t, loader)
+
+		// kubelet 的更新顺序: 写入新版本目录 → 创建临时链接 → rename 覆盖 ..data → 删除旧版本
+		writeVersion("..v2", "name: v2\n")
+		require.NoError(t, os.Symlink("..v2", filepath.Join(dir, "..data_tmp")))
+		require.NoError(t, os.Rename(filepath.Join(dir, "..data_tmp"), filepath.Join(dir, "..data")))
+		require.NoError(t, os.RemoveAll(filepath.Join(dir, "..v1")))
+
+		assert.Equal(t, "v2", waitReload(t, names))
+		assert.Equal(t, "v2", loader.Config().Name)
+	})
+
+	t.Run("failed reload keeps config", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: good\n"), 0o600))
+
+		loader, err := NewLoader(watchConfig{}, WithConfigPaths(path), WithWatchInterval(10*time.Millisecond))
+		require.NoError(t, err)
+		names := startWatch(t, loader)
+
+		require.NoError(t, os.WriteFile(path, []byte("name: [\n"), 0o600))
+		assert.Contains(t, waitReload(t, names), "error: ")
+		assert.Equal(t, "good", loader.Config().Name)
+	})
+}
+
+func TestLoaderWatchReloadThrottle(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: v1\n"), 0o600))
+
+	const throttle = 300 * time.Millisecond
+	loader, err := NewLoader(watchConfig{}, WithConfigPaths(path),
+		WithWatchInterval(10*time.Millisecond), WithConfigReloadThrottle(throttle))
+	require.NoError(t, err)
+	names := startWatch(t, loader)
+
+	require.NoError(t, os.WriteFile(path, []byte("name: v2\n"), 0o600))
+	assert.Equal(t, "v2", waitReload(t, names), "first change reloads immediately")
+	first := time.Now()
+
+	for _, name := range []string{"v3-a", "v4-ab", "v5-abc"} {
+		require.NoError(t, os.WriteFile(path, []byte("name: "+name+"\n"), 0o600))
+		time.Sleep(30 * time.Millisecond)
+	}
+
+	assert.Equal(t, "v5-abc", waitReload(t, names), "changes in the window coalesce into the latest state")
+	assert.GreaterOrEqual(t, time.Since(first), throttle-20*time.Millisecond)
+	select {
+	case name := <-names:
+		t.Fatalf("unexpected extra reload: %s", name)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestLoaderClose(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("name: v1\n"), 0o600))
+
+	loader, err := NewLoader(watchConfig{}, WithConfigPaths(path), WithWatchInterval(10*time.Millisecond))
+	require.NoError(t, err)
+
+	returned := make(chan struct{})
+	reloaded := make(chan struct{}, 1)
+	go func() {
+		defer close(returned)
+		loader.Watch(context.Background(), func(*watchConfig, error) {
+			select {
+			case reloaded <- struct{}{}:
+			default:
+			}
+		})
+	}()
+	// 观察到一次重新加载，确认 Watch 已在运行
+	require.NoError(t, os.WriteFile(path, []byte("name: v2\n"), 0o600))
+	select {
+	case <-reloaded:
+	case <-time.After(2 * time.Second):
+		t.Fatal("timed out waiting for reload")
+	}
+
+	require.NoError(t, loader.Close())
+	select {
+	case <-returned:
+	default:
+		t.Fatal("Close returned before Watch stopped")
+	}
+
+	require.ErrorIs(t, loader.Reload(), ErrClosed)
+	assert.Equal(t, "v2", loader.Config().Name, "last config stays available")
+	require.NoError(t, loader.Close(), "Close is idempotent")
+
+	// 关闭后启动的 Watch 立即返回
+	loader.Watch(context.Background(), func(*watchConfig, error) { t.Error("unexpected reload") })
+}
+
+func TestLoaderWatchOnConfigReload(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	// 各次写入长度不同，文件系统时间戳精度较粗时同样能被发现
+	require.NoError(t, os.WriteFile(path, []byte("name: v1-initial\n"), 0o600))
+
+	events := make(chan ReloadEvent, 16)
+	loader, err := NewLoader(watchConfig{}, WithConfigPaths(path), WithWatchInterval(10*time.Millisecond),
+		WithOnConfigReload(func(e ReloadEvent) { events <- e }))
+	require.NoError(t, err)
+	require.NoError(t, loader.Reload())
+	assert.Empty(t, events, "manual reload does not fire the hook")
+
+	names := startWatch(t, loader)
+	a := assert.New(t)
+	// 原子替换，避免轮询读到写入一半的文件而多触发一次重新加载
+	write := func(content string) {
+		tmp := path + ".tmp"
+		require.NoError(t, os.WriteFile(tmp, []byte(content), 0o600))
+		require.NoError(t, os.Rename(tmp, path))
+	}
+
+	write("name: v2\n")
+	a.Equal("v2", waitReload(t, names))
+	event := <-events
+	a.True(event.Changed)
+	a.NoError(event.Err)
+	a.False(event.Time.IsZero())
+	a.Positive(event.Duration)
+
+	write("# comment only\nname: v2\n")
+	a.Equal("v2", waitReload(t, names))
+	a.False((<-events).Changed, "formatting change leaves config unchanged")
+
+	write("name: [\n")
+	a.Contains(waitReload(t, names), "error:")
+	event = <-events
+	a.Error(event.Err)
+	a.False(event.Changed)
+}
+
+func TestLoaderWatchStatCache(t *testing.T) {
+	dir := t.TempDir()
+	override := filepath.Join(dir, "override.yaml")
+	base := filepath.Join(dir, "base.yaml")
+	require.NoError(t, os.WriteFile(base, []byte("name: base\n"), 0o600))
+
+	var stats atomic.Int32
+	orig := osStat
+	osStat = func(name string) (os.FileInfo, error) {
+		if name == override {
+			stats.Add(1)
+		}
+
+		return orig(name)
+	}
+	t.Cleanup(func() { osStat = orig })
+
+	loader, err := NewLoader(watchConfig{}, WithConfigPaths(override, base),
+		WithStatCache(time.Hour), WithWatchInterval(10*time.Millisecond))
+	require.NoError(t, err)
+	require.NoError(t, loader.Reload())
+	require.NoError(t, loader.Reload())
+	assert.Equal(t, int32(1), stats.Load(), "missing path is stat'ed once within the TTL")
+
+	names := startWatch(t, loader)
+	require.NoError(t, os.WriteFile(override, []byte("name: override\n"), 0o600))
+	assert.Equal(t, "override", waitReload(t, names), "watch invalidates the cached result")
+}
+
+func TestLoaderWatchConfigDirDebounce(t *testing.T) {
+	dir := t.TempDir()
+	fragments := filepath.Join(dir, "conf.d")
+	require.NoError(t, os.Mkdir(fragments, 0o750))
+	writeFragment := func(name, content string) {
+		require.NoError(t, os.WriteFile(filepath.Join(fragments, name), []byte(content), 0o600))
+	}
+	writeFragment("10-base.yaml", "name: base\n")
+	writeFragment("20-old.yaml", "name: old\n")
+
+	loader, err := NewLoader(watchConfig{}, WithConfigPaths(filepath.Join(dir, "missing.yaml")),
+		WithConfigPathsDir(fragments), WithWatchInterval(10*time.Millisecond), WithWatchDebounce(150*time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, "old", loader.Config().Name)
+	names := startWatch(t, loader)
+
+	// 在防抖窗口内连续新增、删除与修改多个片段
+	writeFragment("30-new.yaml", "name: new-partial\n")
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, os.Remove(filepath.Join(fragments, "20-old.yaml")))
+	time.Sleep(30 * time.Millisecond)
+	writeFragment("10-base.yaml", "name: base-v2\n")
+	time.Sleep(30 * time.Millisecond)
+	writeFragment("30-new.yaml", "name: new-complete\n")
+
+	assert.Equal(t, "new-complete", waitReload(t, names), "one reload with the final merged set")
+	select {
+	case name := <-names:
+		t.Fatalf("unexpected extra reload: %s", name)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
+
+func TestWatchSignal(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("signals cannot be sent to the current process on windows")
+	}
+	path := writeTempConfig(t, "name: v1\n")
+
+	_, err := WatchSignal(watchConfig{}, nil, func(*watchConfig, error) {}, WithConfigPaths(path))
+	require.Error(t, err)
+
+	results := make(chan string, 4)
+	stop, err := WatchSignal(watchConfig{}, syscall.SIGHUP, func(cfg *watchConfig, err error) {
+		if err != nil {
+			results <- "error: " + err.Error()
+			return
+		}
+		results <- cfg.Name
+	}, WithConfigPaths(path))
+	require.NoError(t, err)
+	t.Cleanup(stop)
+
+	sendSignal := func() {
+		t.Helper()
+		process, err := os.FindProcess(os.Getpid())
+		require.NoError(t, err)
+		require.NoError(t, process.Signal(syscall.SIGHUP))
+	}
+	receive := func() string {
+		t.Helper()
+		select {
+		case got := <-results:
+			return got
+		case <-time.After(5 * time.Second):
+			t.Fatal("no reload after signal")
+			return ""
+		}
+	}
+
+	sendSignal()
+	assert.Equal(t, "v1", receive())
+
+	require.NoError(t, os.WriteFile(path, []byte("name: [\n"), 0o600))
+	sendSignal()
+	assert.Contains(t, receive(), "error: ")
+
+	require.NoError(t, os.WriteFile(path, []byte("name: v2\n"), 0o600))
+	sendSignal()
+	assert.Equal(t, "v2", receive())
+
+	// stop 后仍需捕获 SIGHUP，避免测试进程被默认行为终止
+	guard := make(chan os.Signal, 1)
+	signal.Notify(guard, syscall.SIGHUP)
+	defer signal.Stop(guard)
+	stop()
+	stop()
+	sendSignal()
+	select {
+	case got := <-results:
+		t.Fatalf("reload after stop: %s", got)
+	case <-guard:
+	}
+}
+
+func TestLoaderWatchReloadGracePeriod(t *testing.T) {
+	t.Run("skips transient empty file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: v1\n"), 0o600))
+
+		loader, err := NewLoader(watchConfig{Name: "default"}, WithConfigPaths(path),
+			WithWatchInterval(10*time.Millisecond), WithConfigReloadGracePeriod(20*time.Millisecond))
+		require.NoError(t, err)
+		names := startWatch(t, loader)
+
+		for _, blank := range []string{"", " \n\t\n"} {
+			require.NoError(t, os.WriteFile(path, []byte(blank), 0o600))
+			select {
+			case name := <-names:
+				t.Fatalf("unexpected reload of blank file %q: %s", blank, name)
+			case <-time.After(100 * time.Millisecond):
+			}
+			assert.Equal(t, "v1", loader.Config().Name)
+		}
+
+		require.NoError(t, os.WriteFile(path, []byte("name: v2\n"), 0o600))
+		assert.Equal(t, "v2", waitReload(t, names))
+	})
+
+	t.Run("warns once and falls back to interval", func(t *testing.T) {
+		logs := captureLogs(t)
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: v1\n"), 0o600))
+
+		loader, err := NewLoader(watchConfig{Name: "default"}, WithConfigPaths(path),
+			WithWatchInterval(10*time.Millisecond), WithConfigReloadGracePeriod(time.Millisecond))
+		require.NoError(t, err)
+		names := startWatch(t, loader)
+
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+		select {
+		case name := <-names:
+			t.Fatalf("unexpected reload of blank file: %s", name)
+		case <-time.After(150 * time.Millisecond):
+		}
+		out := logs.String()
+		assert.Equal(t, 1, strings.Count(out, "waiting for content"), "grace retry is armed only once")
+		assert.Equal(t, 1, strings.Count(out, "level=WARN"), out)
+		assert.Contains(t, out, "reload skipped until it has content")
+
+		require.NoError(t, os.WriteFile(path, []byte("name: v2\n"), 0o600))
+		assert.Equal(t, "v2", waitReload(t, names))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: v1\n"), 0o600))
+
+		loader, err := NewLoader(watchConfig{Name: "default"}, WithConfigPaths(path),
+			WithWatchInterval(10*time.Millisecond), WithConfigReloadGracePeriod(-1))
+		require.NoError(t, err)
+		names := startWatch(t, loader)
+
+		require.NoError(t, os.WriteFile(path, nil, 0o600))
+		assert.Equal(t, "default", waitReload(t, names))
+	})
+}
+
+func TestLoaderWatchReloadValidation(t *testing.T) {
+	t.Run("schema failure keeps last good", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: good\n"), 0o600))
+		schema := []byte(`{"properties": {"name": {"type": "string", "minLength": 3}}}`)
+
+		loader, err := NewLoader(watchConfig{}, WithConfigPaths(path), WithSchema(schema), WithWatchInterval(10*time.Millisecond))
+		require.NoError(t, err)
+		var notified atomic.Int32
+		loader.OnReload(func(_, _ *watchConfig) { notified.Add(1) })
+		names := startWatch(t, loader)
+
+		require.NoError(t, os.WriteFile(path, []byte("name: x\n"), 0o600))
+		assert.Contains(t, waitReload(t, names), "config schema validation failed")
+		assert.Equal(t, "good", loader.Config().Name)
+		assert.Equal(t, int32(0), notified.Load())
+
+		require.NoError(t, os.WriteFile(path, []byte("name: fixed\n"), 0o600))
+		assert.Equal(t, "fixed", waitReload(t, names))
+		assert.Equal(t, int32(1), notified.Load())
+	})
+
+	t.Run("validation func", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "config.yaml")
+		require.NoError(t, os.WriteFile(path, []byte("name: prod-a\n"), 0o600))
+
+		var calls atomic.Int32
+		loader, err := NewLoader(watchConfig{},
+			WithConfigPaths(path),
+			WithWatchInterval(10*time.Millisecond),
+			WithConfigPathsReloadValidation(func(prev, next *watchConfig) error {
+				calls.Add(1)
+				if !strings.HasPrefix(next.Name, "prod-") {
+					return fmt.Errorf("name %q must keep prefix of %q", next.Name, prev.Name)
+				}
+
+				return nil
+			}),
+		)
+		require.NoError(t, err)
+		assert.Equal(t, int32(0), calls.Load(), "initial load is not validated")
+		names := startWatch(t, loader)
+
+		require.NoError(t, os.WriteFile(path, []byte("name: dev\n"), 0o600))
+		assert.Equal(t, `error: reload validation failed: name "dev" must keep prefix of "prod-a"`, waitReload(t, names))
+		assert.Equal(t, "prod-a", loader.Config().Name)
+
+		require.NoError(t, os.WriteFile(path, []byte("name: prod-b\n"), 0o600))
+		assert.Equal(t, "prod-b", waitReload(t, names))
+		assert.Equal(t, int32(2), calls.Load())
+	})
+
+	t.Run("type mismatch", func(t *testing.T) {
+		_, err := NewLoader(watchConfig{}, WithConfigPaths(), WithConfigPathsReloadValidation(func(_, _ *int) error { return nil }))
+		require.ErrorContains(t, err, "reload validation func: got func(*int, *int) error")
+	})
+}
@@ -14,6 +14,8 @@
 //  3. ":=" 赋值仅作用于当前展开过程
 //  4. 无法识别的表达式保持原样
 //
+// 展开不受信任的模板时可用 [WithMaxOutputSize] 限制输出大小。
+//
 // # 快速开始
 //
 // 展开配置文件中的环境变量引用：
@@ -95,3 +95,143 @@ func TestExpandTemplate_JSONConfig(t *testing.T) {
 	assert.Contains(t, expanded, "gpt-4", "MODEL should be expanded to gpt-4")
 	assert.Contains(t, expanded, "sk-test-123", "API_KEY should be expanded")
 }
+
+func TestExpandTemplate_WithStrictMissing(t *testing.T) {
+	t.Setenv("STRICT_SET", "v")
+	t.Setenv("STRICT_EMPTY", "")
+
+	tests := []struct {
+		name     string
+		template string
+		want     string
+		errMsg   string
+	}{
+		{"set variable", `${STRICT_SET}`, "v", ""},
+		{"empty counts as set", `x=${STRICT_EMPTY}`, "x=", ""},
+		{"default still allowed", `${STRICT_MISSING:-d}`, "d", ""},
+		{"nested default allowed", `${STRICT_MISSING:-${STRICT_SET}}`, "v", ""},
+		{"missing errors", `x=${STRICT_MISSING}`, "", "STRICT_MISSING: parameter not set"},
+		{"missing inside default errors", `${STRICT_MISSING:-${STRICT_OTHER}}`, "", "STRICT_OTHER: parameter not set"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := templexp.ExpandTemplate(tt.template, templexp.WithStrictMissing())
+			if tt.errMsg != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errMsg)
+
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestExpandTemplate_WithVars(t *testing.T) {
+	t.Setenv("VARS_ENV", "from-env")
+	t.Setenv("VARS_BOTH", "from-env")
+
+	vars := map[string]string{"VARS_DATA": "from-data", "VARS_BOTH": "from-data"}
+	got, err := templexp.ExpandTemplate(`${VARS_DATA}/${VARS_BOTH}/${VARS_ENV}/${VARS_DATA:=assigned}`, templexp.WithVars(vars))
+	require.NoError(t, err)
+	assert.Equal(t, "from-data/from-data/from-env/from-data", got)
+
+	got, err = templexp.ExpandTemplate(`${VARS_NEW:=x}`, templexp.WithVars(vars))
+	require.NoError(t, err)
+	assert.Equal(t, "x", got)
+	assert.NotContains(t, vars, "VARS_NEW", "assignment must not modify caller map")
+
+	got, err = templexp.ExpandTemplate(`${VARS_DATA}`, templexp.WithVars(vars), templexp.WithVars(map[string]string{"VARS_DATA": "later"}))
+	require.NoError(t, err)
+	assert.Equal(t, "later", got)
+}
+
+func TestExpandTemplate_WithEnv(t *testing.T) {
+	t.Setenv("ENVSNAP_PROCESS", "process")
+
+	env := map[string]string{"ENVSNAP_A": "a"}
+	got, err := templexp.ExpandTemplate(`${ENVSNAP_A}-${ENVSNAP_PROCESS:-unset}`, templexp.WithEnv(env))
+	require.NoError(t, err)
+	assert.Equal(t, "a-unset", got)
+
+	got, err = templexp.ExpandTemplate(`${ENVSNAP_A}`, templexp.WithEnv(env), templexp.WithVars(map[string]string{"ENVSNAP_A": "var"}))
+	require.NoError(t, err)
+	assert.Equal(t, "var", got, "WithVars still wins")
+}
+
+func TestExpandTemplate_WithMissingDefault(t *testing.T) {
+	env := map[string]string{"MD_SET": "set", "MD_EMPTY": ""}
+	opts := []templexp.Option{templexp.WithEnv(env), templexp.WithMissingDefault("none")}
+
+	got, err := templexp.ExpandTemplate(`${MD_SET}|${MD_MISSING}|${MD_EMPTY}|${MD_MISSING:-inline}|${MD_MISSING-dash}`, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "set|none||inline|dash", got)
+
+	got, err = templexp.ExpandTemplate(`${MD_MISSING}`, append(opts, templexp.WithStrictMissing())...)
+	require.NoError(t, err)
+	assert.Equal(t, "none", got, "default wins over strict missing")
+}
+
+func TestExpandTemplate_WithMaxOutputSize(t *testing.T) {
+	opts := []templexp.Option{templexp.WithEnv(map[string]string{"MO_VAL": "0123456789"}), templexp.WithMaxOutputSize(32)}
+
+	got, err := templexp.ExpandTemplate(`a=${MO_VAL} b=${MO_VAL}`, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "a=0123456789 b=0123456789", got)
+
+	_, err = templexp.ExpandTemplate(`${MO_VAL}${MO_VAL}${MO_VAL}${MO_VAL}`, opts...)
+	require.ErrorIs(t, err, templexp.ErrOutputTooLarge)
+	assert.EqualError(t, err, "templexp: output too large: exceeds 32 bytes")
+
+	// 每级赋值使长度翻倍，不设上限时输出随层数指数增长
+	doubling := `${A:=0123456789}${B:=${A}${A}}${C:=${B}${B}}${D:=${C}${C}}${E:=${D}${D}}`
+	_, err = templexp.ExpandTemplate(doubling, opts...)
+	require.ErrorIs(t, err, templexp.ErrOutputTooLarge)
+
+	got, err = templexp.ExpandTemplate(doubling, templexp.WithEnv(nil), templexp.WithMaxOutputSize(0))
+	require.NoError(t, err)
+	assert.Len(t, got, 310, "zero means no limit")
+}
+
+func TestExpandTemplate_WithRequiredVars(t *testing.T) {
+	env := map[string]string{"RV_SET": "set", "RV_EMPTY": ""}
+	opts := []templexp.Option{templexp.WithEnv(env), templexp.WithRequiredVars("RV_SET", "RV_EMPTY")}
+
+	got, err := templexp.ExpandTemplate(`${RV_SET}`, opts...)
+	require.NoError(t, err)
+	assert.Equal(t, "set", got, "empty but set counts as set")
+
+	_, err = templexp.ExpandTemplate(`${RV_A:-default} no reference to RV_B`,
+		append(opts, templexp.WithRequiredVars("RV_A", "RV_B", "RV_A"))...)
+	require.EqualError(t, err, "templexp: required variables not set: RV_A, RV_B")
+
+	_, err = templexp.ExpandTemplate(`x`, templexp.WithEnv(nil), templexp.WithVars(map[string]string{"RV_A": "v"}),
+		templexp.WithRequiredVars("RV_A"))
+	require.NoError(t, err, "WithVars satisfies the requirement")
+}
+
+func FuzzExpandTemplate_MaxOutputSize(f *testing.F) {
+	f.Add(`${A:=0123456789}${B:=${A}${A}}${C:=${B}${B}}${D:=${C}${C}}`, 64)
+	f.Add(`${X:-${Y:-${Z:-default}}}$${LITERAL}`, 8)
+	f.Add(`${V+${V}${V}}${V:?missing}`, 16)
+	f.Add(`plain text`, 4)
+
+	f.Fuzz(func(t *testing.T, text string, limit int) {
+		limit = 1 + abs(limit)%1024
+		env := map[string]string{"V": "value", "X": ""}
+		got, err := templexp.ExpandTemplate(text, templexp.WithEnv(env), templexp.WithMaxOutputSize(limit))
+		if err == nil && len(got) > limit {
+			t.Fatalf("output %d bytes exceeds limit %d", len(got), limit)
+		}
+	})
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+
+	return n
+}
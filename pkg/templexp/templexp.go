@@ -1,8 +1,10 @@
 package templexp
 
 import (
+	"errors"
 	"fmt"
 	"os"
+	"slices"
 	"strings"
 )
 
@@ -25,6 +27,119 @@ func newTemplateData() map[string]string {
 	return vars
 }
 
+// ═══════════════════════════════════════════════════════════════════════════
+// 展开选项
+// ═══════════════════════════════════════════════════════════════════════════
+
+// state 保存单次展开的变量快照与选项。
+type state struct {
+	vars          map[string]string
+	extraVars     map[string]string // WithVars 提供的变量，优先于环境变量
+	env           map[string]string // WithEnv 提供的环境变量快照，nil 表示读取进程环境
+	envSet        bool
+	strictMissing bool
+	missingValue  *string  // WithMissingDefault 提供的全局默认值
+	maxOutput     int      // WithMaxOutputSize 设置的输出上限（字节），0 表示不限制
+	required      []string // WithRequiredVars 列出的必须设置的变量
+}
+
+// ErrOutputTooLarge 表示展开结果超过了 [WithMaxOutputSize] 设置的上限。
+var ErrOutputTooLarge = errors.New("templexp: output too large")
+
+// Option 配置 [ExpandTemplate] 的展开行为。
+type Option func(*state)
+
+// WithStrictMissing 让未设置变量的 ${VAR} 直接报错（类似 Shell 的 set -u）。
+//
+// 默认 ${VAR} 在 VAR 未设置时展开为空字符串，拼写错误难以察觉。
+// 启用后仅影响不带操作符的 ${VAR}；${VAR:-default} 等带默认值的写法不受影响，
+// 已设置但为空的变量也视为已设置。
+func WithStrictMissing() Option {
+	return func(st *state) {
+		st.strictMissing = true
+	}
+}
+
+// WithMissingDefault 为未设置变量的 ${VAR} 提供统一的默认值，相当于每处都写成 ${VAR-value}。
+//
+// 仅影响不带操作符的 ${VAR}：${VAR:-default} 等写法中的默认值仍优先，已设置但为空的变量保持为空。
+// 同时使用 [WithStrictMissing] 时未设置的变量取该默认值，不再报错。
+func WithMissingDefault(value string) Option {
+	return func(st *state) {
+		st.missingValue = &value
+	}
+}
+
+// WithMaxOutputSize 限制展开结果的最大字节数，超过时中止展开并返回 [ErrOutputTooLarge]。
+//
+// 大量引用 ${VAR} 或 ":=" 赋值后反复引用都可能使输出远大于输入，
+// 对不可信的模板（如远程下载的配置）设置上限可避免内存被耗尽。n <= 0 表示不限制（默认）。
+func WithMaxOutputSize(n int) Option {
+	return func(st *state) {
+		st.maxOutput = max(n, 0)
+	}
+}
+
+// checkOutputSize 在 [WithMaxOutputSize] 设置了上限且 size 超出时返回错误。
+func (st *state) checkOutputSize(size int) error {
+	if st.maxOutput > 0 && size > st.maxOutput {
+		return fmt.Errorf("%w: exceeds %d bytes", ErrOutputTooLarge, st.maxOutput)
+	}
+
+	return nil
+}
+
+// WithRequiredVars 要求 names 中的变量在展开时均已设置，否则返回列出全部缺失变量的错误。
+//
+// 检查在展开前进行，与模板是否引用这些变量、引用时是否带默认值无关；
+// 已设置但为空的变量视为已设置。可多次使用，变量列表累加。
+func WithRequiredVars(names ...string) Option {
+	return func(st *state) {
+		st.required = append(st.required, names...)
+	}
+}
+
+// checkRequired 返回 [WithRequiredVars] 中未设置的变量对应的错误。
+func (st *state) checkRequired() error {
+	var missing []string
+	for _, name := range st.required {
+		if _, ok := st.vars[name]; !ok && !slices.Contains(missing, name) {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("templexp: required variables not set: %s", strings.Join(missing, ", "))
+}
+
+// WithEnv 使用 env 替代进程环境变量（os.Environ）作为变量来源。
+//
+// 用于让展开结果与进程环境解耦，例如在测试中得到可复现的结果；env 为 nil 时视为空环境。
+// [WithVars] 提供的变量仍优先于 env。
+func WithEnv(env map[string]string) Option {
+	return func(st *state) {
+		st.env = env
+		st.envSet = true
+	}
+}
+
+// WithVars 为展开提供额外的变量，同名时优先于环境变量。
+//
+// 用于在不修改进程环境的情况下向模板注入数据，例如 ${CLUSTER_NAME} 由代码提供。
+// 可多次使用，后者覆盖前者；":=" 的赋值同样只写入本次展开的快照，不会修改 vars。
+func WithVars(vars map[string]string) Option {
+	return func(st *state) {
+		if st.extraVars == nil {
+			st.extraVars = make(map[string]string, len(vars))
+		}
+		for name, val := range vars {
+			st.extraVars[name] = val
+		}
+	}
+}
+
 // ═══════════════════════════════════════════════════════════════════════════
 // Shell Parameter Expansion
 // ═══════════════════════════════════════════════════════════════════════════
@@ -79,30 +194,37 @@ func errorMessage(name, word string) error {
 	return fmt.Errorf("templexp: %s: %s", name, word)
 }
 
-func expandShellWord(word string, env map[string]string) (string, error) {
+func expandShellWord(word string, st *state) (string, error) {
 	if !strings.Contains(word, "${") {
 		return word, nil
 	}
 
-	return expandShellParameters(word, env)
+	return expandShellParameters(word, st)
 }
 
-func expandShellExpression(expr string, env map[string]string) (string, bool, error) {
+func expandShellExpression(expr string, st *state) (string, bool, error) {
 	name, op, word, ok := parseShellParameter(expr)
 	if !ok {
 		return "", false, nil
 	}
 
+	env := st.vars
 	val, isSet := env[name]
 	switch op {
 	case "":
 		if isSet {
 			return val, true, nil
 		}
+		if st.missingValue != nil {
+			return *st.missingValue, true, nil
+		}
+		if st.strictMissing {
+			return "", false, fmt.Errorf("templexp: %s: parameter not set", name)
+		}
 		return "", true, nil
 	case ":-":
 		if !isSet || val == "" {
-			expanded, err := expandShellWord(word, env)
+			expanded, err := expandShellWord(word, st)
 			if err != nil {
 				return "", false, err
 			}
@@ -111,7 +233,7 @@ func expandShellExpression(expr string, env map[string]string) (string, bool, er
 		return val, true, nil
 	case "-":
 		if !isSet {
-			expanded, err := expandShellWord(word, env)
+			expanded, err := expandShellWord(word, st)
 			if err != nil {
 				return "", false, err
 			}
@@ -120,7 +242,7 @@ func expandShellExpression(expr string, env map[string]string) (string, bool, er
 		return val, true, nil
 	case ":+": // set and not empty
 		if isSet && val != "" {
-			expanded, err := expandShellWord(word, env)
+			expanded, err := expandShellWord(word, st)
 			if err != nil {
 				return "", false, err
 			}
@@ -129,7 +251,7 @@ func expandShellExpression(expr string, env map[string]string) (string, bool, er
 		return "", true, nil
 	case "+":
 		if isSet {
-			expanded, err := expandShellWord(word, env)
+			expanded, err := expandShellWord(word, st)
 			if err != nil {
 				return "", false, err
 			}
@@ -148,7 +270,7 @@ func expandShellExpression(expr string, env map[string]string) (string, bool, er
 		return val, true, nil
 	case ":=":
 		if !isSet || val == "" {
-			expanded, err := expandShellWord(word, env)
+			expanded, err := expandShellWord(word, st)
 			if err != nil {
 				return "", false, err
 			}
@@ -158,7 +280,7 @@ func expandShellExpression(expr string, env map[string]string) (string, bool, er
 		return val, true, nil
 	case "=":
 		if !isSet {
-			expanded, err := expandShellWord(word, env)
+			expanded, err := expandShellWord(word, st)
 			if err != nil {
 				return "", false, err
 			}
@@ -171,7 +293,7 @@ func expandShellExpression(expr string, env map[string]string) (string, bool, er
 	return "", false, nil
 }
 
-func expandShellParameters(text string, env map[string]string) (string, error) {
+func expandShellParameters(text string, st *state) (string, error) {
 	var buf strings.Builder
 	buf.Grow(len(text))
 
@@ -208,7 +330,7 @@ func expandShellParameters(text string, env map[string]string) (string, error) {
 		}
 
 		expr := text[i+2 : end]
-		expanded, ok, err := expandShellExpression(expr, env)
+		expanded, ok, err := expandShellExpression(expr, st)
 		if err != nil {
 			return "", err
 		}
@@ -217,9 +339,15 @@ func expandShellParameters(text string, env map[string]string) (string, error) {
 		} else {
 			buf.WriteString(text[i : end+1])
 		}
+		if err := st.checkOutputSize(buf.Len()); err != nil {
+			return "", err
+		}
 
 		i = end + 1
 	}
+	if err := st.checkOutputSize(buf.Len()); err != nil {
+		return "", err
+	}
 
 	return buf.String(), nil
 }
@@ -256,8 +384,27 @@ func findMatchingBrace(text string, start int) int {
 //   - ${VAR:?msg} / ${VAR?msg} - 必填校验
 //   - ${VAR:=default} / ${VAR=default} - 赋值（仅作用于当前展开）
 //
-// 返回展开后的字符串；仅在必填校验失败时返回 error。
-func ExpandTemplate(text string) (string, error) {
-	data := newTemplateData()
-	return expandShellParameters(text, data)
+// 可通过 [Option] 调整展开行为，例如 [WithStrictMissing]、[WithVars]、[WithEnv]。
+// 返回展开后的字符串；仅在必填校验失败（或启用的严格校验失败）、输出超过 [WithMaxOutputSize] 时返回 error。
+func ExpandTemplate(text string, opts ...Option) (string, error) {
+	st := &state{}
+	for _, opt := range opts {
+		opt(st)
+	}
+	if st.envSet {
+		st.vars = make(map[string]string, len(st.env)+len(st.extraVars))
+		for name, val := range st.env {
+			st.vars[name] = val
+		}
+	} else {
+		st.vars = newTemplateData()
+	}
+	for name, val := range st.extraVars {
+		st.vars[name] = val
+	}
+	if err := st.checkRequired(); err != nil {
+		return "", err
+	}
+
+	return expandShellParameters(text, st)
 }